@@ -29,6 +29,7 @@ package b2
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -36,23 +37,52 @@ import (
 	"regexp"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/kurin/blazer/internal/blog"
 )
 
 // Client is a Backblaze B2 client.
 type Client struct {
+	downloaded int64    // bytes of download bodies read; first for alignment
+	calls      [3]int64 // calls made, by CallClass
+
 	backend beRootInterface
 
 	slock    sync.Mutex
 	sWriters map[string]*Writer
 	sReaders map[string]*Reader
 	sMethods []methodCounter
+	lastResp *ResponseInfo
 	opts     clientOptions
+	buckets  bucketCache
+	usage    usageCache
+	closing  bool          // set by Close; no new Readers or Writers
+	idle     chan struct{} // closed when Close has no more to wait for
+	scope    *scope        // set on clients made by Bucket.ScopedClient
+
+	// bg is the context of work the client does in the background, such as
+	// Key.Rotate's deletions; Close cancels it.
+	bg       context.Context
+	cancelBG context.CancelFunc
 }
 
 // NewClient creates and returns a new Client with valid B2 service account
 // tokens.
 func NewClient(ctx context.Context, account, key string, opts ...ClientOption) (*Client, error) {
+	c, err := newClient(clientOptions{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.backend.authorizeAccount(ctx, account, key, c.opts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newClient returns an unauthorized Client with opts applied to base.
+func newClient(base clientOptions, opts []ClientOption) (*Client, error) {
 	c := &Client{
 		backend: &beRoot{
 			b2i: &b2Root{},
@@ -64,16 +94,101 @@ func NewClient(ctx context.Context, account, key string, opts ...ClientOption) (
 			newMethodCounter(0, 0), // forever
 		},
 	}
+	c.bg, c.cancelBG = context.WithCancel(context.Background())
+	c.opts = base
 	opts = append(opts, client(c))
 	for _, f := range opts {
 		f(&c.opts)
 	}
-	if err := c.backend.authorizeAccount(ctx, account, key, c.opts); err != nil {
-		return nil, err
+	if c.opts.proxy != nil {
+		rt, err := proxyTransport(c.opts.transport, c.opts.proxy)
+		if err != nil {
+			return nil, err
+		}
+		c.opts.transport = rt
+	}
+	if c.opts.keepAlive != nil {
+		rt, err := c.opts.keepAlive.transport(c.opts.transport)
+		if err != nil {
+			return nil, err
+		}
+		c.opts.transport = rt
 	}
 	return c, nil
 }
 
+// ErrClientClosed is returned by requests made with a client, or with the
+// buckets, objects, readers, and writers it returned, after Close.
+var ErrClientClosed = errors.New("b2: client is closed")
+
+// Close shuts the client down, so that servers can drain their uploads and
+// downloads cleanly.  New Readers and Writers fail at once, and Close waits
+// for the Readers and Writers already in use to be closed.  If ctx is done
+// first, they are cancelled, and Close returns ctx's error.  Either way, the
+// client is then unusable: every request fails with ErrClientClosed, and the
+// client's idle connections are closed, and work scheduled in the background,
+// such as the deletions of keys replaced by Key.Rotate, is abandoned.  A
+// client returned by Bucket.ScopedClient also deletes its application key.
+func (c *Client) Close(ctx context.Context) error {
+	c.slock.Lock()
+	c.closing = true
+	if c.idle == nil && (len(c.sWriters) > 0 || len(c.sReaders) > 0) {
+		c.idle = make(chan struct{})
+	}
+	idle := c.idle
+	c.slock.Unlock()
+
+	var err error
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			err = ctx.Err()
+			c.cancelAll()
+		}
+	}
+
+	if c.cancelBG != nil {
+		c.cancelBG()
+	}
+	c.backend.close()
+	c.buckets.mu.Lock()
+	c.buckets.m = nil
+	c.buckets.listed = false
+	c.buckets.mu.Unlock()
+	if t, ok := c.opts.transport.(interface{ CloseIdleConnections() }); ok {
+		t.CloseIdleConnections()
+	}
+	if c.scope != nil {
+		if serr := c.scope.close(ctx); err == nil {
+			err = serr
+		}
+	}
+	return err
+}
+
+// cancelAll fails every outstanding Reader and Writer with ErrClientClosed.
+// Unfinished large files are cancelled with B2.
+func (c *Client) cancelAll() {
+	c.slock.Lock()
+	var ws []*Writer
+	for _, w := range c.sWriters {
+		ws = append(ws, w)
+	}
+	var rs []*Reader
+	for _, r := range c.sReaders {
+		rs = append(rs, r)
+	}
+	c.slock.Unlock()
+
+	for _, w := range ws {
+		w.setErr(ErrClientClosed)
+	}
+	for _, r := range rs {
+		r.setErr(ErrClientClosed)
+	}
+}
+
 type clientOptions struct {
 	client          *Client
 	transport       http.RoundTripper
@@ -81,13 +196,50 @@ type clientOptions struct {
 	expireTokens    bool
 	capExceeded     bool
 	apiBase         string
+	noCompression   bool
 	userAgents      []string
 	writerOpts      []WriterOption
+	readerOpts      []ReaderOption
+	clock           Clock
+	log             *blog.Logger
+	dumpDir         string
+	proxy           func(*http.Request) (*url.URL, error)
+	keepAlive       *keepAlive
+	bucketTTL       time.Duration
+	retry           RetrySettings
+	faults          *Faults
+	pricing         *Pricing
+	token           TokenFunc
+	reauth          TokenFunc
+	tokenAccount    string
+	keys            func(context.Context) (id, secret string, err error)
 }
 
 // A ClientOption allows callers to adjust various per-client settings.
 type ClientOption func(*clientOptions)
 
+// A Clock tells the time and waits for time to pass.  Clients use the system
+// clock unless another is given with WithClock.
+type Clock interface {
+	Now() time.Time
+	After(time.Duration) <-chan time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock sets the clock the client uses to wait between retries and to
+// decide when upload URLs must be renewed.  This allows code that uses blazer
+// to test its handling of retries and backoff with a fake clock, without
+// waiting in real time.
+func WithClock(c Clock) ClientOption {
+	return func(o *clientOptions) {
+		o.clock = c
+	}
+}
+
 // UserAgent sets the User-Agent HTTP header.  The default header is
 // "blazer/<version>"; the value set here will be prepended to that.  This can
 // be set multiple times.
@@ -114,6 +266,144 @@ func Transport(rt http.RoundTripper) ClientOption {
 	}
 }
 
+// Proxy sets the function the client uses to choose a proxy for each request,
+// in the manner of http.Transport's Proxy field.  Requests to all B2 hosts,
+// including upload and download hosts, are sent through it.  Proxy
+// credentials may be given as the user info of the returned URL.
+//
+// Without this option, clients use http.DefaultTransport, which takes its
+// proxy from the HTTP_PROXY, HTTPS_PROXY, and NO_PROXY environment
+// variables.  Proxy may be combined with Transport only if the transport is
+// an *http.Transport; it is copied, and the original is left unchanged.
+func Proxy(f func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(c *clientOptions) {
+		c.proxy = f
+	}
+}
+
+// ProxyURL sends all of the client's requests through the proxy at u.
+func ProxyURL(u *url.URL) ClientOption {
+	return Proxy(http.ProxyURL(u))
+}
+
+func proxyTransport(rt http.RoundTripper, proxy func(*http.Request) (*url.URL, error)) (http.RoundTripper, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("b2: cannot set a proxy on transport of type %T", rt)
+	}
+	t = t.Clone()
+	t.Proxy = proxy
+	return t, nil
+}
+
+// KeepAlive sets how many idle connections the client keeps open to each
+// host, and for how long they are kept.  Each of a Writer's concurrent
+// uploads sends its parts to its own upload URL, over its own connection, but
+// http.DefaultTransport keeps only two idle connections per host; writers
+// with more ConcurrentUploads, or many writers at once, may otherwise
+// reconnect for every part.  A perHost or timeout of zero or less leaves the
+// transport's own setting.  KeepAlive may be combined with Transport only if
+// the transport is an *http.Transport; it is copied, and the original is left
+// unchanged.
+func KeepAlive(perHost int, timeout time.Duration) ClientOption {
+	return func(c *clientOptions) {
+		c.keepAlive = &keepAlive{perHost: perHost, timeout: timeout}
+	}
+}
+
+type keepAlive struct {
+	perHost int
+	timeout time.Duration
+}
+
+func (k *keepAlive) transport(rt http.RoundTripper) (http.RoundTripper, error) {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	t, ok := rt.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("b2: cannot set keep-alives on transport of type %T", rt)
+	}
+	t = t.Clone()
+	if k.perHost > 0 {
+		t.MaxIdleConnsPerHost = k.perHost
+		if t.MaxIdleConns > 0 && t.MaxIdleConns < k.perHost {
+			t.MaxIdleConns = k.perHost
+		}
+	}
+	if k.timeout > 0 {
+		t.IdleConnTimeout = k.timeout
+	}
+	return t, nil
+}
+
+// DisableCompression prevents the client from requesting gzip-compressed
+// responses from B2's JSON API.  Compression can noticeably reduce transfer
+// for listing-heavy workloads, and is enabled by default.  It has no effect
+// on object uploads or downloads.
+func DisableCompression() ClientOption {
+	return func(c *clientOptions) {
+		c.noCompression = true
+	}
+}
+
+// Logging controls what a client logs, and where.  Clients that are not given
+// a Logging log to the standard log package, at the level set by the
+// B2_LOG_LEVEL environment variable.
+type Logging struct {
+	// Level is the verbosity of the log.  At level 1, errors and retries are
+	// logged; at level 2, every request and response is too.
+	Level int
+
+	// Output receives each message.  If nil, messages are written with the
+	// standard log package.
+	Output func(string)
+
+	// MaxBody is the number of bytes of each request and response body to
+	// log.  Zero means 1024 bytes, and a negative value logs bodies in full.
+	MaxBody int
+
+	// Sample, if greater than one, logs only one in every Sample requests and
+	// their responses.
+	Sample int
+
+	// AllowInfo lists the info keys whose values may be logged.  The values
+	// of all other info keys are redacted, as are authorization tokens.
+	AllowInfo []string
+
+	// Redact, if set, is called with each logged header and info key and its
+	// value, and returns the value to log in its place.
+	Redact func(key, value string) string
+}
+
+// WithLogging sets what the client logs.  Logging applies to the client's own
+// requests and responses, and to the readers and writers of its buckets.
+func WithLogging(l Logging) ClientOption {
+	return func(c *clientOptions) {
+		c.log = &blog.Logger{
+			Level:   int32(l.Level),
+			Output:  l.Output,
+			MaxBody: l.MaxBody,
+			Sample:  l.Sample,
+			Allow:   l.AllowInfo,
+			Redact:  l.Redact,
+		}
+	}
+}
+
+// DumpFailures is a debugging option that writes each request that fails with
+// an error that will not be retried, along with its response and timing, to a
+// file in dir.  Headers and bodies are redacted and truncated as they are for
+// logging (see WithLogging).  The directory must already exist.
+func DumpFailures(dir string) ClientOption {
+	return func(c *clientOptions) {
+		c.dumpDir = dir
+	}
+}
+
 // FailSomeUploads requests intermittent upload failures from the B2 service.
 // This is mostly useful for testing.
 func FailSomeUploads() ClientOption {
@@ -132,12 +422,21 @@ func ExpireSomeAuthTokens() ClientOption {
 
 // ForceCapExceeded requests a cap limit from the B2 service.  This causes all
 // uploads to be treated as if they would exceed the configure B2 capacity.
+// Such uploads fail with a *CapExceededError.
 func ForceCapExceeded() ClientOption {
 	return func(c *clientOptions) {
 		c.capExceeded = true
 	}
 }
 
+// WithRequestID returns a context that attaches the given correlation ID to
+// every B2 request made with it.  The ID is sent to B2 in the request headers
+// and included in debug logs, so that client logs can be joined with server
+// logs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return withRequestID(ctx, id)
+}
+
 func client(cl *Client) ClientOption {
 	return func(c *clientOptions) {
 		c.client = cl
@@ -155,14 +454,23 @@ func (ct *clientTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	if t == nil {
 		t = http.DefaultTransport
 	}
+	r, or := consistent(r)
 	b := time.Now()
 	resp, err := t.RoundTrip(r)
 	e := time.Now()
 	if err != nil {
 		return resp, err
 	}
+	if or != nil {
+		or.record(resp.Header)
+	}
 	if m != "" && ct.client != nil {
 		ct.client.slock.Lock()
+		ct.client.lastResp = &ResponseInfo{
+			Method:     m,
+			StatusCode: resp.StatusCode,
+			Header:     resp.Header,
+		}
 		m := method{
 			name:     m,
 			duration: e.Sub(b),
@@ -172,10 +480,29 @@ func (ct *clientTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 			counter.record(m)
 		}
 		ct.client.slock.Unlock()
+		atomic.AddInt64(&ct.client.calls[MethodClass(m.name)], 1)
+		if (m.name == "b2_download_file_by_id" || m.name == "b2_download_file_by_name") && resp.StatusCode/100 == 2 {
+			resp.Body = countingBody{ReadCloser: resp.Body, n: &ct.client.downloaded}
+		}
 	}
 	return resp, nil
 }
 
+// PartSizes returns B2's guidance on the size of large file parts for this
+// account: the recommended part size, which gives the best upload
+// performance, and the absolute minimum size of any part but the last.  Both
+// are given in bytes.
+func (c *Client) PartSizes(ctx context.Context) (recommended, absoluteMinimum int, err error) {
+	recommended, absoluteMinimum = c.backend.partSizes()
+	if recommended == 0 {
+		if err := c.backend.reauthorizeAccount(ctx); err != nil {
+			return 0, 0, err
+		}
+		recommended, absoluteMinimum = c.backend.partSizes()
+	}
+	return recommended, absoluteMinimum, nil
+}
+
 // Bucket is a reference to a B2 bucket.
 type Bucket struct {
 	b beBucketInterface
@@ -185,24 +512,63 @@ type Bucket struct {
 	urlPool *urlPool
 }
 
+func (b *Bucket) log() *blog.Logger {
+	if b.c == nil {
+		return nil
+	}
+	return b.c.opts.log
+}
+
+// BucketType is a bucket's access type.  Types that B2 reports but blazer
+// does not know about are passed through unchanged.
 type BucketType string
 
 const (
 	UnknownType BucketType = ""
-	Private                = "allPrivate"
-	Public                 = "allPublic"
-	Snapshot               = "snapshot"
+	Private     BucketType = "allPrivate"
+	Public      BucketType = "allPublic"
+	Snapshot    BucketType = "snapshot"
+
+	// Shared and Restricted are reported by B2 for buckets whose access is
+	// governed by other accounts or by bucket policy.
+	Shared     BucketType = "shared"
+	Restricted BucketType = "restricted"
 )
 
+// Known reports whether t is one of the bucket types defined above, other
+// than UnknownType.
+func (t BucketType) Known() bool {
+	switch t {
+	case Private, Public, Snapshot, Shared, Restricted:
+		return true
+	}
+	return false
+}
+
+// check rejects types that cannot be bucket types.  Types that are well formed
+// but not Known are passed to B2, which may know them.
+func (t BucketType) check() error {
+	for _, r := range t {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') {
+			return fmt.Errorf("b2: malformed bucket type %q", string(t))
+		}
+	}
+	return nil
+}
+
 // BucketAttrs holds a bucket's metadata attributes.
 type BucketAttrs struct {
 	// Type lists or sets the new bucket type.  If Type is UnknownType during a
-	// bucket.Update, the type is not changed.
+	// bucket.Update, the type is not changed; when creating a bucket, it means
+	// Private.  Types that are not Known are sent to B2 as they are, for B2 to
+	// accept or reject; types with characters other than letters and digits
+	// are an error.
 	Type BucketType
 
-	// Info records user data, limited to ten keys.  If nil during a
-	// bucket.Update, the existing bucket info is not modified.  A bucket's
-	// metadata can be removed by updating with an empty map.
+	// Info records user data, limited to ten keys and 7000 bytes; info that
+	// is larger causes an *InfoTooLargeError.  If nil during a bucket.Update,
+	// the existing bucket info is not modified.  A bucket's metadata can be
+	// removed by updating with an empty map.
 	Info map[string]string
 
 	// Reports or sets bucket lifecycle rules.  If nil during a bucket.Update,
@@ -228,6 +594,17 @@ type LifecycleRule struct {
 	// DaysHiddenUntilDeleted specifies the number of days after which a hidden
 	// file is deleted.  0 means "do not automatically delete hidden files".
 	DaysHiddenUntilDeleted int
+
+	// DaysUnfinishedUntilCanceled specifies the number of days after which a
+	// large file that was started but not finished is canceled, and its
+	// parts deleted.  0 means "do not automatically cancel large files".
+	DaysUnfinishedUntilCanceled int
+
+	// extra holds, as a JSON object, the fields B2 reported for the rule
+	// that blazer does not know, so that updating a bucket with the rules
+	// from its Attrs does not clobber them.  It is a string so that rules
+	// remain comparable.
+	extra string
 }
 
 type b2err struct {
@@ -240,6 +617,23 @@ func (e b2err) Error() string {
 	return e.err.Error()
 }
 
+// ResponseHeader returns the HTTP response headers B2 sent along with the given
+// error, or nil if the error did not originate from a B2 response.  These
+// headers include any server request identifiers, which should be included in
+// bug reports and support tickets.
+func ResponseHeader(err error) http.Header {
+	if berr, ok := err.(b2err); ok {
+		err = berr.err
+	}
+	switch e := err.(type) {
+	case *CapExceededError:
+		err = e.err
+	case *MissingCapabilityError:
+		err = e.err
+	}
+	return responseHeader(err)
+}
+
 // IsNotExist reports whether a given error indicates that an object or bucket
 // does not exist.
 func IsNotExist(err error) bool {
@@ -250,6 +644,110 @@ func IsNotExist(err error) bool {
 	return berr.notFoundErr
 }
 
+// A Cap is one of the usage caps that can be set on a B2 account.
+type Cap string
+
+const (
+	StorageCap     Cap = "storage"
+	DownloadCap    Cap = "download bandwidth"
+	TransactionCap Cap = "transactions"
+)
+
+// CapExceededError is returned when B2 refuses a request because the account
+// has reached one of its usage caps.  Caps are set, and usage is reported, on
+// the Caps & Alerts page of the B2 web interface.  These errors are not
+// retried, since they persist until the cap is raised or resets.
+type CapExceededError struct {
+	// Cap is the cap that was reached.
+	Cap Cap
+
+	err error
+}
+
+func (e *CapExceededError) Error() string { return e.err.Error() }
+func (e *CapExceededError) Unwrap() error { return e.err }
+
+// capExceeded returns a *CapExceededError if err reports that a cap was
+// reached, and err otherwise.
+func capExceeded(err error) error {
+	code, msgCode := errorCode(err)
+	if code != http.StatusForbidden {
+		return err
+	}
+	var c Cap
+	switch msgCode {
+	case "storage_cap_exceeded", "cap_exceeded":
+		// B2 refuses uploads over the storage cap with the bare code.
+		c = StorageCap
+	case "download_cap_exceeded":
+		c = DownloadCap
+	case "transaction_cap_exceeded":
+		c = TransactionCap
+	default:
+		return err
+	}
+	return &CapExceededError{Cap: c, err: err}
+}
+
+// MissingCapabilityError is returned when B2 refuses a request because the
+// application key lacks a capability the request needs.  Keys that are
+// restricted to a bucket or prefix get the same error for requests outside of
+// it.
+type MissingCapabilityError struct {
+	// Capability is the capability that the request needs, such as
+	// "listBuckets", or the empty string if it is not known.
+	Capability string
+
+	err error
+}
+
+func (e *MissingCapabilityError) Error() string { return e.err.Error() }
+func (e *MissingCapabilityError) Unwrap() error { return e.err }
+
+// apiCapabilities maps B2 API calls to the capability each needs.
+var apiCapabilities = map[string]string{
+	"b2_list_buckets":                "listBuckets",
+	"b2_create_bucket":               "writeBuckets",
+	"b2_update_bucket":               "writeBuckets",
+	"b2_delete_bucket":               "deleteBuckets",
+	"b2_list_file_names":             "listFiles",
+	"b2_list_file_versions":          "listFiles",
+	"b2_list_unfinished_large_files": "listFiles",
+	"b2_list_parts":                  "listFiles",
+	"b2_get_file_info":               "readFiles",
+	"b2_download_file_by_id":         "readFiles",
+	"b2_download_file_by_name":       "readFiles",
+	"b2_get_download_authorization":  "shareFiles",
+	"b2_get_upload_url":              "writeFiles",
+	"b2_upload_file":                 "writeFiles",
+	"b2_start_large_file":            "writeFiles",
+	"b2_get_upload_part_url":         "writeFiles",
+	"b2_upload_part":                 "writeFiles",
+	"b2_copy_file":                   "writeFiles",
+	"b2_copy_part":                   "writeFiles",
+	"b2_finish_large_file":           "writeFiles",
+	"b2_cancel_large_file":           "writeFiles",
+	"b2_hide_file":                   "writeFiles",
+	"b2_delete_file_version":         "deleteFiles",
+	"b2_create_key":                  "writeKeys",
+	"b2_list_keys":                   "listKeys",
+	"b2_delete_key":                  "deleteKeys",
+}
+
+// missingCapability returns a *MissingCapabilityError if err reports that the
+// key is not allowed to make the request, and err otherwise.
+func missingCapability(err error) error {
+	code, msgCode := errorCode(err)
+	if code != http.StatusUnauthorized || msgCode != "unauthorized" {
+		return err
+	}
+	method := errorMethod(err)
+	if method == "b2_authorize_account" {
+		return err
+	}
+	return &MissingCapabilityError{Capability: apiCapabilities[method], err: err}
+}
+
 const uploadURLPoolSize = 100
 
 type urlPool struct {
@@ -282,12 +780,24 @@ func (p *urlPool) put(u beURLInterface) {
 
 // Bucket returns a bucket if it exists.
 func (c *Client) Bucket(ctx context.Context, name string) (*Bucket, error) {
+	if c.backend.closed() {
+		return nil, ErrClientClosed
+	}
+	if bucket, ok := c.cachedBucket(ctx, name); ok {
+		return &Bucket{
+			b:       bucket,
+			r:       c.backend,
+			c:       c,
+			urlPool: newURLPool(),
+		}, nil
+	}
 	buckets, err := c.backend.listBuckets(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 	for _, bucket := range buckets {
 		if bucket.name() == name {
+			c.cacheBuckets([]beBucketInterface{bucket}, false)
 			return &Bucket{
 				b:       bucket,
 				r:       c.backend,
@@ -306,12 +816,24 @@ func (c *Client) Bucket(ctx context.Context, name string) (*Bucket, error) {
 // if it does not already exist.  If attrs is nil, it is created as a private
 // bucket with no info metadata and no lifecycle rules.
 func (c *Client) NewBucket(ctx context.Context, name string, attrs *BucketAttrs) (*Bucket, error) {
+	if c.backend.closed() {
+		return nil, ErrClientClosed
+	}
+	if bucket, ok := c.cachedBucket(ctx, name); ok {
+		return &Bucket{
+			b:       bucket,
+			r:       c.backend,
+			c:       c,
+			urlPool: newURLPool(),
+		}, nil
+	}
 	buckets, err := c.backend.listBuckets(ctx, name)
 	if err != nil {
 		return nil, err
 	}
 	for _, bucket := range buckets {
 		if bucket.name() == name {
+			c.cacheBuckets([]beBucketInterface{bucket}, false)
 			return &Bucket{
 				b:       bucket,
 				r:       c.backend,
@@ -321,12 +843,23 @@ func (c *Client) NewBucket(ctx context.Context, name string, attrs *BucketAttrs)
 		}
 	}
 	if attrs == nil {
-		attrs = &BucketAttrs{Type: Private}
+		attrs = &BucketAttrs{}
+	}
+	if err := attrs.Type.check(); err != nil {
+		return nil, err
+	}
+	if err := ValidateBucketInfo(attrs.Info); err != nil {
+		return nil, err
+	}
+	btype := attrs.Type
+	if btype == UnknownType {
+		btype = Private
 	}
-	b, err := c.backend.createBucket(ctx, name, string(attrs.Type), attrs.Info, attrs.LifecycleRules)
+	b, err := c.backend.createBucket(ctx, name, string(btype), attrs.Info, attrs.LifecycleRules)
 	if err != nil {
 		return nil, err
 	}
+	c.cacheBuckets([]beBucketInterface{b}, false)
 	return &Bucket{
 		b:       b,
 		r:       c.backend,
@@ -335,11 +868,45 @@ func (c *Client) NewBucket(ctx context.Context, name string, attrs *BucketAttrs)
 	}, err
 }
 
+// AccountInfo describes the account and key that a Client is authorized with.
+type AccountInfo struct {
+	AccountID string
+
+	// Capabilities lists what the key may do, such as "listBuckets".
+	Capabilities []string
+
+	// BucketID and Prefix, if set, restrict the key to the bucket with that
+	// ID, and to objects whose names begin with Prefix.
+	BucketID string
+	Prefix   string
+
+	APIURL      string
+	DownloadURL string
+
+	// RecommendedPartSize and AbsoluteMinimumPartSize are the large file part
+	// sizes, in bytes, that B2 suggests and allows.
+	RecommendedPartSize     int
+	AbsoluteMinimumPartSize int
+}
+
+// AccountInfo returns the details of the client's current authorization.
+func (c *Client) AccountInfo() *AccountInfo {
+	return c.backend.accountInfo()
+}
+
 // ListBuckets returns all the available buckets.
 func (c *Client) ListBuckets(ctx context.Context) ([]*Bucket, error) {
-	bs, err := c.backend.listBuckets(ctx, "")
-	if err != nil {
-		return nil, err
+	if c.backend.closed() {
+		return nil, ErrClientClosed
+	}
+	bs, ok := c.cachedBuckets(ctx)
+	if !ok {
+		var err error
+		bs, err = c.backend.listBuckets(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		c.cacheBuckets(bs, true)
 	}
 	var buckets []*Bucket
 	for _, b := range bs {
@@ -367,12 +934,24 @@ func IsUpdateConflict(err error) bool {
 // this method could fail with an update conflict, in which case you should
 // retrieve the latest bucket attributes with Attrs and try again.
 func (b *Bucket) Update(ctx context.Context, attrs *BucketAttrs) error {
-	return b.b.updateBucket(ctx, attrs)
+	if attrs != nil {
+		if err := attrs.Type.check(); err != nil {
+			return err
+		}
+		if err := ValidateBucketInfo(attrs.Info); err != nil {
+			return err
+		}
+	}
+	if err := b.b.updateBucket(ctx, attrs); err != nil {
+		return err
+	}
+	b.c.cacheBuckets([]beBucketInterface{b.b}, false)
+	return nil
 }
 
 // Attrs retrieves and returns the current bucket's attributes.
 func (b *Bucket) Attrs(ctx context.Context) (*BucketAttrs, error) {
-	bucket, err := b.c.Bucket(ctx, b.Name())
+	bucket, err := b.c.Bucket(WithoutBucketCache(ctx), b.Name())
 	if err != nil {
 		return nil, err
 	}
@@ -385,6 +964,9 @@ var bNotExist = regexp.MustCompile("Bucket.*does not exist")
 // Delete removes a bucket.  The bucket must be empty.
 func (b *Bucket) Delete(ctx context.Context) error {
 	err := b.b.deleteBucket(ctx)
+	if err == nil || bNotExist.MatchString(err.Error()) {
+		b.c.uncacheBucket(b.Name())
+	}
 	if err == nil {
 		return err
 	}
@@ -419,6 +1001,7 @@ type Object struct {
 	name  string
 	f     beFileInterface
 	b     *Bucket
+	resp  *objectResponse // see Header
 }
 
 // Attrs holds an object's metadata.
@@ -431,6 +1014,49 @@ type Attrs struct {
 	SHA1            string            // Can be "none" for large files.  If set on upload, will be used for large files.
 	LastModified    time.Time         // If present, and there are fewer than 10 keys in the Info field, this is saved on upload.
 	Info            map[string]string // Save arbitrary metadata on upload, but limited to 10 keys.
+	Retention       Retention         // Not used on upload.
+	LegalHold       bool              // Not used on upload.
+	Encryption      Encryption        // Not used on upload.
+
+	// These are saved on upload as the b2-content-disposition,
+	// b2-cache-control, and b2-expires info keys, which count towards the limit
+	// of 10, and B2 serves them as the corresponding headers on download.
+	ContentDisposition string
+	CacheControl       string
+	Expires            time.Time
+
+	// RawInfo is the object's info exactly as B2 stores it, including the
+	// keys that Attrs represents as fields of its own.  On upload, the keys
+	// in RawInfo that begin with "b2-", which B2 reserves, are kept unless
+	// Info sets them, so that writing back the Attrs of an object does not
+	// lose reserved keys that blazer does not know.  The keys of the fields
+	// above are never taken from RawInfo, so clearing a field clears its key.
+	RawInfo map[string]string
+}
+
+// Info keys with special meaning to B2 or to blazer, which Attrs represents
+// as fields of their own.
+const (
+	infoLastModified       = "src_last_modified_millis"
+	infoLargeFileSHA1      = "large_file_sha1"
+	infoContentDisposition = "b2-content-disposition"
+	infoCacheControl       = "b2-cache-control"
+	infoExpires            = "b2-expires"
+)
+
+// Retention describes an object's retention setting.  Mode is "governance" or
+// "compliance", or empty if the object has no retention setting or if the
+// client's key is not allowed to read it.
+type Retention struct {
+	Mode  string
+	Until time.Time
+}
+
+// Encryption describes the server-side encryption of an object.  Mode is,
+// for instance, "SSE-B2", or empty if the object is not encrypted.
+type Encryption struct {
+	Mode      string
+	Algorithm string
 }
 
 // Name returns an object's name
@@ -445,6 +1071,7 @@ func (o *Object) ID() string {
 
 // Attrs returns an object's attributes.
 func (o *Object) Attrs(ctx context.Context) (*Attrs, error) {
+	ctx = o.forObject(ctx)
 	if err := o.ensure(ctx); err != nil {
 		return nil, err
 	}
@@ -452,6 +1079,10 @@ func (o *Object) Attrs(ctx context.Context) (*Attrs, error) {
 	if err != nil {
 		return nil, err
 	}
+	return attrsFromInfo(fi)
+}
+
+func attrsFromInfo(fi beFileInfoInterface) (*Attrs, error) {
 	name, sha, size, ct, info, st, stamp := fi.stats()
 	var state ObjectState
 	switch st {
@@ -464,18 +1095,45 @@ func (o *Object) Attrs(ctx context.Context) (*Attrs, error) {
 	case "folder":
 		state = Folder
 	}
+	// Don't modify the backend's copy of the info map.
+	var raw map[string]string
+	if info != nil {
+		m := make(map[string]string, len(info))
+		raw = make(map[string]string, len(info))
+		for k, v := range info {
+			m[k] = v
+			raw[k] = v
+		}
+		info = m
+	}
 	var mtime time.Time
-	if v, ok := info["src_last_modified_millis"]; ok {
+	if v, ok := info[infoLastModified]; ok {
 		ms, err := strconv.ParseInt(v, 10, 64)
 		if err != nil {
 			return nil, err
 		}
 		mtime = time.Unix(ms/1e3, (ms%1e3)*1e6)
-		delete(info, "src_last_modified_millis")
+		delete(info, infoLastModified)
 	}
-	if v, ok := info["large_file_sha1"]; ok {
+	if v, ok := info[infoLargeFileSHA1]; ok {
 		sha = v
 	}
+	disposition, ok := info[infoContentDisposition]
+	if ok {
+		delete(info, infoContentDisposition)
+	}
+	cacheControl, ok := info[infoCacheControl]
+	if ok {
+		delete(info, infoCacheControl)
+	}
+	var expires time.Time
+	if v, ok := info[infoExpires]; ok {
+		// Leave values that aren't HTTP dates in Info, rather than fail.
+		if t, err := http.ParseTime(v); err == nil {
+			expires = t
+			delete(info, infoExpires)
+		}
+	}
 	return &Attrs{
 		Name:            name,
 		Size:            size,
@@ -485,6 +1143,14 @@ func (o *Object) Attrs(ctx context.Context) (*Attrs, error) {
 		Info:            info,
 		Status:          state,
 		LastModified:    mtime,
+		Retention:       fi.retention(),
+		LegalHold:       fi.legalHold(),
+		Encryption:      fi.encryption(),
+
+		ContentDisposition: disposition,
+		CacheControl:       cacheControl,
+		Expires:            expires,
+		RawInfo:            raw,
 	}, nil
 }
 
@@ -514,20 +1180,40 @@ func (b *Bucket) Object(name string) *Object {
 	return &Object{
 		name: name,
 		b:    b,
+		resp: &objectResponse{},
 	}
 }
 
+// ObjectByID returns a reference to the version of an object in the bucket
+// with the given ID, which may be hidden or superseded.  Deleting it deletes
+// only that version.  To read it, pass ReadVersion(id) to NewReader.
+func (b *Bucket) ObjectByID(ctx context.Context, id string) (*Object, error) {
+	fi, err := b.b.file(id, "").getFileInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, _, _, _, _, _, _ := fi.stats()
+	return &Object{
+		name: name,
+		f:    b.b.file(id, name),
+		b:    b,
+		resp: &objectResponse{},
+	}, nil
+}
+
 // URL returns the full URL to the given object.
 func (o *Object) URL() string {
 	return fmt.Sprintf("%s/file/%s/%s", o.b.BaseURL(), o.b.Name(), o.name)
 }
 
 // NewWriter returns a new writer for the given object.  Objects that are
-// overwritten are not deleted, but are "hidden".
+// overwritten are not deleted, but are "hidden".  If the object's name is not
+// one B2 accepts (see ValidateName), the first write fails with a *NameError,
+// and nothing is uploaded.
 //
 // Callers must close the writer when finished and check the error status.
 func (o *Object) NewWriter(ctx context.Context, opts ...WriterOption) *Writer {
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithCancel(o.forObject(ctx))
 	w := &Writer{
 		o:      o,
 		name:   o.name,
@@ -545,22 +1231,76 @@ func (o *Object) NewWriter(ctx context.Context, opts ...WriterOption) *Writer {
 
 // NewRangeReader returns a reader for the given object, reading up to length
 // bytes.  If length is negative, the rest of the object is read.
-func (o *Object) NewRangeReader(ctx context.Context, offset, length int64) *Reader {
-	ctx, cancel := context.WithCancel(ctx)
-	return &Reader{
+//
+// If the object's file ID is known, for instance because it was returned from
+// a listing, the reader downloads that exact version by ID, falling back to
+// downloading it by name if the download by ID fails and the name still refers
+// to that version; otherwise it downloads whichever version currently has the
+// object's name.
+//
+// Options are applied after the client's defaults.
+func (o *Object) NewRangeReader(ctx context.Context, offset, length int64, opts ...ReaderOption) *Reader {
+	ctx, cancel := context.WithCancel(o.forObject(ctx))
+	r := &Reader{
 		ctx:    ctx,
 		cancel: cancel,
 		o:      o,
+		f:      o.f,
 		name:   o.name,
 		chunks: make(map[int]*rchunk),
 		length: length,
 		offset: offset,
+		nth:    -1,
+	}
+	for _, f := range o.b.c.opts.readerOpts {
+		f(r)
+	}
+	for _, f := range opts {
+		f(r)
 	}
+	if o.IsDir() {
+		r.err = dirErr(o.name)
+	}
+	if length == 0 {
+		// Nothing to read; in particular, don't ask for a zero-byte range,
+		// which B2 takes to mean the whole object.
+		r.err = io.EOF
+	}
+	return r
+}
+
+// NewReader returns a reader for the given object.  Options are applied as
+// with NewRangeReader.
+func (o *Object) NewReader(ctx context.Context, opts ...ReaderOption) *Reader {
+	return o.NewRangeReader(ctx, 0, -1, opts...)
 }
 
-// NewReader returns a reader for the given object.
-func (o *Object) NewReader(ctx context.Context) *Reader {
-	return o.NewRangeReader(ctx, 0, -1)
+// nthVersion returns the nth newest uploaded version of the object.
+func (o *Object) nthVersion(ctx context.Context, n int) (beFileInterface, error) {
+	iter := o.b.List(ctx, ListHidden(), ListPrefix(o.name), ListPageSize(100))
+	for iter.Next() {
+		v := iter.Object()
+		if v.name != o.name {
+			if v.name > o.name {
+				break
+			}
+			continue
+		}
+		if v.f.status() != "upload" {
+			continue
+		}
+		if n == 0 {
+			return v.f, nil
+		}
+		n--
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return nil, b2err{
+		err:         fmt.Errorf("%s: version not found", o.name),
+		notFoundErr: true,
+	}
 }
 
 func (o *Object) ensure(ctx context.Context) error {
@@ -574,8 +1314,23 @@ func (o *Object) ensure(ctx context.Context) error {
 	return nil
 }
 
+// IsDir reports whether o is a "folder" entry returned by a listing made with
+// ListDelimiter, rather than an actual object.  Such entries have attributes,
+// but cannot be read, deleted, or hidden.
+func (o *Object) IsDir() bool {
+	return o.f != nil && o.f.status() == "folder"
+}
+
+func dirErr(name string) error {
+	return fmt.Errorf("b2: %s is a folder, not an object", name)
+}
+
 // Delete removes the given object.
 func (o *Object) Delete(ctx context.Context) error {
+	ctx = o.forObject(ctx)
+	if o.IsDir() {
+		return dirErr(o.name)
+	}
 	if err := o.ensure(ctx); err != nil {
 		return err
 	}
@@ -584,6 +1339,10 @@ func (o *Object) Delete(ctx context.Context) error {
 
 // Hide hides the object from name-based listing.
 func (o *Object) Hide(ctx context.Context) error {
+	ctx = o.forObject(ctx)
+	if o.IsDir() {
+		return dirErr(o.name)
+	}
 	if err := o.ensure(ctx); err != nil {
 		return err
 	}
@@ -618,7 +1377,7 @@ func (discard) Write(p []byte) (int, error) {
 }
 
 func (b *Bucket) getObject(ctx context.Context, name string) (*Object, error) {
-	fr, err := b.b.downloadFileByName(ctx, name, 0, 0, true)
+	fr, err := b.b.downloadFileByName(ctx, name, "", 0, 0, true)
 	if err != nil {
 		return nil, err
 	}
@@ -628,6 +1387,7 @@ func (b *Bucket) getObject(ctx context.Context, name string) (*Object, error) {
 		name: name,
 		f:    b.b.file(fr.id(), name),
 		b:    b,
+		resp: &objectResponse{},
 	}, nil
 }
 
@@ -15,16 +15,28 @@
 package b2
 
 import (
+	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/hmac"
 	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -37,6 +49,36 @@ const (
 
 var gmux = &sync.Mutex{}
 
+// testClock is a fake Clock.  Its time moves only when something waits on it,
+// and waits return immediately.
+type testClock struct {
+	mu    sync.Mutex
+	now   time.Time
+	waits []time.Duration
+}
+
+func (c *testClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *testClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.waits = append(c.waits, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func (c *testClock) calls() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.waits...)
+}
+
 type testError struct {
 	retry    bool
 	backoff  time.Duration
@@ -66,9 +108,12 @@ func (e *errCont) getError(name string) error {
 }
 
 type testRoot struct {
-	errs      *errCont
-	auths     int
-	bucketMap map[string]map[string]string
+	errs        *errCont
+	auths       int
+	bucketMap   map[string]map[string]string
+	recPartSize int
+	minPartSize int
+	lists       int
 }
 
 func (t *testRoot) authorizeAccount(context.Context, string, string, clientOptions) error {
@@ -100,6 +145,11 @@ func (t *testRoot) reupload(err error) bool {
 	return e.reupload
 }
 
+func (t *testRoot) partSizes() (int, int) { return t.recPartSize, t.minPartSize }
+func (t *testRoot) accountInfo() *AccountInfo {
+	return &AccountInfo{AccountID: "test", RecommendedPartSize: t.recPartSize, AbsoluteMinimumPartSize: t.minPartSize}
+}
+
 func (t *testRoot) transient(err error) bool {
 	e, ok := err.(testError)
 	if !ok {
@@ -115,7 +165,7 @@ func (t *testRoot) listKeys(context.Context, int, string) ([]b2KeyInterface, str
 	return nil, "", nil
 }
 
-func (t *testRoot) createBucket(_ context.Context, name, _ string, _ map[string]string, _ []LifecycleRule) (b2BucketInterface, error) {
+func (t *testRoot) createBucket(_ context.Context, name, btype string, _ map[string]string, _ []LifecycleRule) (b2BucketInterface, error) {
 	if err := t.errs.getError("createBucket"); err != nil {
 		return nil, err
 	}
@@ -128,10 +178,12 @@ func (t *testRoot) createBucket(_ context.Context, name, _ string, _ map[string]
 		n:     name,
 		errs:  t.errs,
 		files: m,
+		typ:   btype,
 	}, nil
 }
 
 func (t *testRoot) listBuckets(context.Context, string) ([]b2BucketInterface, error) {
+	t.lists++
 	var b []b2BucketInterface
 	for k, v := range t.bucketMap {
 		b = append(b, &testBucket{
@@ -144,18 +196,30 @@ func (t *testRoot) listBuckets(context.Context, string) ([]b2BucketInterface, er
 }
 
 type testBucket struct {
-	n     string
-	errs  *errCont
-	files map[string]string
+	n          string
+	errs       *errCont
+	files      map[string]string
+	unfinished []b2FileInterface
+	versions   []b2FileInterface // if set, returned by listFileVersions
+	ranges     [][2]int64        // offset and size of each download
+	empty      int               // empty listFileNames pages to return; -1 for all
+	typ        string            // the bucket type, if not allPrivate
+	sums       map[string]string // if set, the SHA1s reported on download, by name
 }
 
 func (t *testBucket) name() string                                     { return t.n }
-func (t *testBucket) btype() string                                    { return "allPrivate" }
 func (t *testBucket) attrs() *BucketAttrs                              { return nil }
 func (t *testBucket) deleteBucket(context.Context) error               { return nil }
 func (t *testBucket) updateBucket(context.Context, *BucketAttrs) error { return nil }
 func (t *testBucket) id() string                                       { return "" }
 
+func (t *testBucket) btype() string {
+	if t.typ == "" {
+		return "allPrivate"
+	}
+	return t.typ
+}
+
 func (t *testBucket) getUploadURL(context.Context) (b2URLInterface, error) {
 	if err := t.errs.getError("getUploadURL"); err != nil {
 		return nil, err
@@ -179,18 +243,49 @@ func (t *testBucket) listFileNames(ctx context.Context, count int, cont, pfx, de
 	gmux.Lock()
 	defer gmux.Unlock()
 	for name := range t.files {
-		f = append(f, name)
+		if strings.HasPrefix(name, pfx) {
+			f = append(f, name)
+		}
 	}
 	sort.Strings(f)
+	if del != "" {
+		// Collapse names into folders, as B2 does.
+		var d []string
+		for _, name := range f {
+			if i := strings.Index(name[len(pfx):], del); i >= 0 {
+				name = name[:len(pfx)+i+len(del)]
+			}
+			if len(d) == 0 || d[len(d)-1] != name {
+				d = append(d, name)
+			}
+		}
+		f = d
+	}
 	idx := sort.SearchStrings(f, cont)
+	if t.empty != 0 {
+		if t.empty > 0 {
+			t.empty--
+		}
+		// An empty page that continues where this one should have started.
+		if idx < len(f) {
+			return nil, f[idx], nil
+		}
+		return nil, "", nil
+	}
 	var b []b2FileInterface
 	var next string
 	for i := idx; i < len(f) && i-idx < count; i++ {
-		b = append(b, &testFile{
+		tf := &testFile{
 			n:     f[i],
 			s:     int64(len(t.files[f[i]])),
+			a:     "upload",
 			files: t.files,
-		})
+			sums:  t.sums,
+		}
+		if _, ok := t.files[f[i]]; !ok {
+			tf.a = "folder"
+		}
+		b = append(b, tf)
 		if i+1 < len(f) {
 			next = f[i+1]
 		}
@@ -202,57 +297,104 @@ func (t *testBucket) listFileNames(ctx context.Context, count int, cont, pfx, de
 }
 
 func (t *testBucket) listFileVersions(ctx context.Context, count int, a, b, c, d string) ([]b2FileInterface, string, string, error) {
+	gmux.Lock()
+	vs := t.versions
+	gmux.Unlock()
+	if vs != nil {
+		return vs, "", "", nil
+	}
 	x, y, z := t.listFileNames(ctx, count, a, c, d)
 	return x, y, "", z
 }
 
 func (t *testBucket) listUnfinishedLargeFiles(ctx context.Context, count int, cont string) ([]b2FileInterface, string, error) {
-	return nil, "", fmt.Errorf("testBucket.listUnfinishedLargeFiles(ctx, %d, %q): not implemented", count, cont)
+	return t.unfinished, "", nil
 }
 
-func (t *testBucket) downloadFileByName(_ context.Context, name string, offset, size int64, _ bool) (b2FileReaderInterface, error) {
+func (t *testBucket) downloadFileByName(_ context.Context, name, _ string, offset, size int64, _ bool) (b2FileReaderInterface, error) {
 	gmux.Lock()
 	defer gmux.Unlock()
-	f := t.files[name]
+	t.ranges = append(t.ranges, [2]int64{offset, size})
+	f, ok := t.files[name]
+	if !ok {
+		return nil, b2err{err: fmt.Errorf("%s: not found", name), notFoundErr: true}
+	}
 	end := int(offset + size)
-	if end >= len(f) {
+	if end >= len(f) || size == 0 {
 		end = len(f)
 	}
-	if int(offset) >= len(f) {
+	// As with B2, a request without a range (offset and size 0) succeeds even
+	// for an empty file.
+	if int(offset) >= len(f) && (offset != 0 || size != 0) {
 		return nil, errNoMoreContent
 	}
+	var body io.Reader = bytes.NewBufferString(f[offset:end])
+	if err := t.errs.getError("readBody"); err != nil {
+		// Fail halfway through the body.
+		half := (end - int(offset)) / 2
+		body = io.MultiReader(bytes.NewBufferString(f[offset:int(offset)+half]), errReader{err})
+	}
 	return &testFileReader{
-		b: ioutil.NopCloser(bytes.NewBufferString(f[offset:end])),
-		s: end - int(offset),
-		n: name,
+		b:   ioutil.NopCloser(body),
+		s:   end - int(offset),
+		n:   name,
+		sha: fmt.Sprintf("%x", sha1.Sum([]byte(f))),
 	}, nil
 }
 
+type errReader struct{ err error }
+
+func (e errReader) Read([]byte) (int, error) { return 0, e.err }
+
 func (t *testBucket) hideFile(context.Context, string) (b2FileInterface, error) { return nil, nil }
 func (t *testBucket) getDownloadAuthorization(context.Context, string, time.Duration, string) (string, error) {
 	return "", nil
 }
-func (t *testBucket) baseURL() string                      { return "" }
-func (t *testBucket) file(id, name string) b2FileInterface { return nil }
+func (t *testBucket) baseURL() string { return "" }
+func (t *testBucket) file(id, name string) b2FileInterface {
+	if name == "" {
+		// IDs are names here.
+		name = id
+	}
+	return &testFile{n: name, s: int64(len(t.files[name])), files: t.files, sums: t.sums}
+}
 
 type testURL struct {
-	files map[string]string
+	files   map[string]string
+	reloads int
 }
 
-func (t *testURL) reload(context.Context) error { return nil }
+func (t *testURL) reload(context.Context) error {
+	t.reloads++
+	return nil
+}
 
-func (t *testURL) uploadFile(_ context.Context, r io.Reader, _ int, name, _, _ string, _ map[string]string) (b2FileInterface, error) {
+func (t *testURL) uploadFile(_ context.Context, r io.Reader, _ int, name, _, hash string, _ map[string]string) (b2FileInterface, error) {
 	buf := &bytes.Buffer{}
 	if _, err := io.Copy(buf, r); err != nil {
 		return nil, err
 	}
+	body := buf.String()
+	if hash == "hex_digits_at_end" {
+		if len(body) < 40 {
+			return nil, fmt.Errorf("short upload: %d bytes", len(body))
+		}
+		hash = body[len(body)-40:]
+		body = body[:len(body)-40]
+		if got := fmt.Sprintf("%x", sha1.Sum([]byte(body))); got != hash {
+			return nil, fmt.Errorf("bad trailing sha1: got %s, want %s", hash, got)
+		}
+	} else if got := fmt.Sprintf("%x", sha1.Sum([]byte(body))); hash != "" && got != hash {
+		return nil, fmt.Errorf("bad sha1: got %s, want %s", hash, got)
+	}
 	gmux.Lock()
 	defer gmux.Unlock()
-	t.files[name] = buf.String()
+	t.files[name] = body
 	return &testFile{
 		n:     name,
 		s:     int64(len(t.files[name])),
 		files: t.files,
+		body:  &body,
 	}, nil
 }
 
@@ -263,6 +405,8 @@ type testLargeFile struct {
 	errs  *errCont
 }
 
+func (t *testLargeFile) id() string { return t.name }
+
 func (t *testLargeFile) finishLargeFile(context.Context) (b2FileInterface, error) {
 	var total []byte
 	gmux.Lock()
@@ -287,16 +431,37 @@ func (t *testLargeFile) getUploadPartURL(context.Context) (b2FileChunkInterface,
 	}, nil
 }
 
+func (t *testLargeFile) copyPart(_ context.Context, src string, index int, offset, size int64) (int64, error) {
+	gmux.Lock()
+	defer gmux.Unlock()
+	body, ok := t.files[src]
+	if !ok {
+		return 0, fmt.Errorf("%s: not found", src)
+	}
+	if size <= 0 {
+		offset, size = 0, int64(len(body))
+	}
+	if offset+size > int64(len(body)) {
+		return 0, fmt.Errorf("%s: range %d+%d out of bounds", src, offset, size)
+	}
+	t.parts[index] = []byte(body[offset : offset+size])
+	return size, nil
+}
+
 func (t *testLargeFile) cancel(ctx context.Context) error { return ctx.Err() }
 
 type testFileChunk struct {
-	parts map[int][]byte
-	errs  *errCont
+	parts   map[int][]byte
+	errs    *errCont
+	reloads int
 }
 
-func (t *testFileChunk) reload(context.Context) error { return nil }
+func (t *testFileChunk) reload(context.Context) error {
+	t.reloads++
+	return nil
+}
 
-func (t *testFileChunk) uploadPart(_ context.Context, r io.Reader, _ string, _, index int) (int, error) {
+func (t *testFileChunk) uploadPart(_ context.Context, r io.Reader, hash string, _, index int) (int, error) {
 	if err := t.errs.getError("uploadPart"); err != nil {
 		return 0, err
 	}
@@ -305,21 +470,35 @@ func (t *testFileChunk) uploadPart(_ context.Context, r io.Reader, _ string, _,
 	if err != nil {
 		return int(i), err
 	}
+	part := buf.Bytes()
+	if hash == "hex_digits_at_end" && len(part) >= 40 {
+		part = part[:len(part)-40]
+	}
 	gmux.Lock()
 	defer gmux.Unlock()
-	t.parts[index] = buf.Bytes()
+	t.parts[index] = part
 	return int(i), nil
 }
 
 type testFile struct {
 	n     string
+	fid   string // the file ID, if not n
 	s     int64
 	t     time.Time
 	a     string
 	files map[string]string
+	body  *string           // this version's contents, if known
+	byID  int               // downloads by ID
+	sums  map[string]string // if set, the SHA1s reported on download, by name
+}
+
+func (t *testFile) id() string {
+	if t.fid != "" {
+		return t.fid
+	}
+	return t.n
 }
 
-func (t *testFile) id() string           { return t.n }
 func (t *testFile) name() string         { return t.n }
 func (t *testFile) size() int64          { return t.s }
 func (t *testFile) timestamp() time.Time { return t.t }
@@ -329,14 +508,144 @@ func (t *testFile) compileParts(int64, map[int]string) b2LargeFileInterface {
 	panic("not implemented")
 }
 
+func (t *testFile) downloadFileByID(_ context.Context, offset, size int64, _ bool) (b2FileReaderInterface, error) {
+	gmux.Lock()
+	defer gmux.Unlock()
+	t.byID++
+	f, ok := t.files[t.n]
+	if t.body != nil {
+		f, ok = *t.body, true
+	}
+	if !ok {
+		return nil, b2err{err: fmt.Errorf("%s: not found", t.n), notFoundErr: true}
+	}
+	end := int(offset + size)
+	if end >= len(f) || size == 0 {
+		end = len(f)
+	}
+	if int(offset) >= len(f) && (offset != 0 || size != 0) {
+		return nil, errNoMoreContent
+	}
+	sha, ok := t.sums[t.n]
+	if !ok {
+		sha = fmt.Sprintf("%x", sha1.Sum([]byte(f)))
+	}
+	return &testFileReader{
+		b:   ioutil.NopCloser(bytes.NewBufferString(f[offset:end])),
+		s:   end - int(offset),
+		n:   t.n,
+		sha: sha,
+	}, nil
+}
+
 func (t *testFile) getFileInfo(context.Context) (b2FileInfoInterface, error) {
-	return nil, nil
+	return &testFileInfo{
+		name:   t.n,
+		size:   t.s,
+		status: t.a,
+	}, nil
+}
+
+type testFileInfo struct {
+	name   string
+	size   int64
+	status string // "upload" if empty
+	info   map[string]string
+	ret    Retention
+	hold   bool
+	sse    Encryption
+}
+
+func (t *testFileInfo) stats() (string, string, int64, string, map[string]string, string, time.Time) {
+	status := t.status
+	if status == "" {
+		status = "upload"
+	}
+	return t.name, "", t.size, "application/octet-stream", t.info, status, time.Time{}
 }
 
+func (t *testFileInfo) retention() Retention   { return t.ret }
+func (t *testFileInfo) legalHold() bool        { return t.hold }
+func (t *testFileInfo) encryption() Encryption { return t.sse }
+
 func (t *testFile) listParts(context.Context, int, int) ([]b2FilePartInterface, int, error) {
 	return nil, 0, nil
 }
 
+type testFilePart struct {
+	n int
+	s int64
+	h string
+	t time.Time
+}
+
+func (t *testFilePart) number() int          { return t.n }
+func (t *testFilePart) sha1() string         { return t.h }
+func (t *testFilePart) size() int64          { return t.s }
+func (t *testFilePart) timestamp() time.Time { return t.t }
+
+// testPartsFile is an unfinished large file with the given parts.
+type testPartsFile struct {
+	testFile
+	fid     string
+	parts   []*testFilePart
+	calls   int
+	resumed bool
+}
+
+func (t *testPartsFile) id() string {
+	if t.fid != "" {
+		return t.fid
+	}
+	return t.n
+}
+
+func (t *testPartsFile) compileParts(int64, map[int]string) b2LargeFileInterface {
+	t.resumed = true
+	return &testLargeFile{
+		name:  t.n,
+		parts: make(map[int][]byte),
+		files: t.files,
+		errs:  &errCont{},
+	}
+}
+
+func (t *testPartsFile) listParts(_ context.Context, next, count int) ([]b2FilePartInterface, int, error) {
+	t.calls++
+	var rtn []b2FilePartInterface
+	for _, p := range t.parts {
+		if p.n < next {
+			continue
+		}
+		if len(rtn) == count {
+			return rtn, p.n, nil
+		}
+		rtn = append(rtn, p)
+	}
+	return rtn, 0, nil
+}
+
+func (t *testFile) copyFile(_ context.Context, name string, offset, size int64, _ string, _ map[string]string) (b2FileInterface, error) {
+	gmux.Lock()
+	defer gmux.Unlock()
+	body, ok := t.files[t.n]
+	if !ok {
+		return nil, fmt.Errorf("%s: not found", t.n)
+	}
+	if size > 0 {
+		if offset+size > int64(len(body)) {
+			return nil, fmt.Errorf("%s: range %d+%d out of bounds", t.n, offset, size)
+		}
+		body = body[offset : offset+size]
+	}
+	t.files[name] = body
+	return &testFile{
+		n:     name,
+		s:     int64(len(body)),
+		files: t.files,
+	}, nil
+}
+
 func (t *testFile) deleteFileVersion(context.Context) error {
 	gmux.Lock()
 	defer gmux.Unlock()
@@ -345,14 +654,15 @@ func (t *testFile) deleteFileVersion(context.Context) error {
 }
 
 type testFileReader struct {
-	b io.ReadCloser
-	s int
-	n string
+	b   io.ReadCloser
+	s   int
+	n   string
+	sha string
 }
 
 func (t *testFileReader) Read(p []byte) (int, error)                      { return t.b.Read(p) }
 func (t *testFileReader) Close() error                                    { return nil }
-func (t *testFileReader) stats() (int, string, string, map[string]string) { return t.s, "", "", nil }
+func (t *testFileReader) stats() (int, string, string, map[string]string) { return t.s, "", t.sha, nil }
 func (t *testFileReader) id() string                                      { return t.n }
 
 type zReader struct{}
@@ -473,13 +783,7 @@ func TestBackoff(t *testing.T) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	var calls []time.Duration
-	ch := make(chan time.Time)
-	close(ch)
-	after = func(d time.Duration) <-chan time.Time {
-		calls = append(calls, d)
-		return ch
-	}
+	clk := &testClock{}
 
 	table := []struct {
 		root *testRoot
@@ -518,7 +822,8 @@ func TestBackoff(t *testing.T) {
 	for _, ent := range table {
 		client := &Client{
 			backend: &beRoot{
-				b2i: ent.root,
+				b2i:     ent.root,
+				options: clientOptions{clock: clk},
 			},
 		}
 		b, err := client.NewBucket(ctx, "fun", &BucketAttrs{Type: Private})
@@ -535,7 +840,7 @@ func TestBackoff(t *testing.T) {
 		}
 		total += ent.want
 	}
-	if len(calls) != total {
+	if calls := clk.calls(); len(calls) != total {
 		t.Errorf("got %d calls, wanted %d", len(calls), total)
 	}
 }
@@ -545,13 +850,7 @@ func TestBackoffWithoutRetryAfter(t *testing.T) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
-	var calls []time.Duration
-	ch := make(chan time.Time)
-	close(ch)
-	after = func(d time.Duration) <-chan time.Time {
-		calls = append(calls, d)
-		return ch
-	}
+	clk := &testClock{}
 
 	root := &testRoot{
 		bucketMap: make(map[string]map[string]string),
@@ -566,17 +865,165 @@ func TestBackoffWithoutRetryAfter(t *testing.T) {
 	}
 	client := &Client{
 		backend: &beRoot{
-			b2i: root,
+			b2i:     root,
+			options: clientOptions{clock: clk},
 		},
 	}
 	if _, err := client.NewBucket(ctx, "fun", &BucketAttrs{Type: Private}); err != nil {
 		t.Errorf("bucket should not err, got %v", err)
 	}
-	if len(calls) != 2 {
+	if calls := clk.calls(); len(calls) != 2 {
 		t.Errorf("wrong number of backoff calls; got %d, want 2", len(calls))
 	}
 }
 
+func TestRetryPolicy(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	table := []struct {
+		settings RetrySettings
+		want     []time.Duration
+	}{
+		{
+			settings: RetrySettings{Jitter: -1},
+			want:     []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			settings: RetrySettings{Initial: time.Second, Max: 3 * time.Second, Jitter: -1},
+			want:     []time.Duration{time.Second, 2 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+	}
+	for _, ent := range table {
+		clk := &testClock{}
+		root := &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs: &errCont{
+				errMap: map[string]map[int]error{
+					"createBucket": {
+						0: testError{retry: true},
+						1: testError{retry: true},
+						2: testError{retry: true},
+						3: testError{retry: true},
+					},
+				},
+			},
+		}
+		client := &Client{
+			backend: &beRoot{
+				b2i:     root,
+				options: clientOptions{clock: clk, retry: ent.settings},
+			},
+			opts: clientOptions{retry: ent.settings},
+		}
+		if _, err := client.NewBucket(ctx, "fun", &BucketAttrs{Type: Private}); err != nil {
+			t.Fatal(err)
+		}
+		if got := clk.calls(); !reflect.DeepEqual(got, ent.want) {
+			t.Errorf("%+v: got waits %v, want %v", ent.settings, got, ent.want)
+		}
+	}
+
+	c := &Client{}
+	if got := c.RetrySettings(); got != DefaultRetrySettings {
+		t.Errorf("RetrySettings(): got %+v, want %+v", got, DefaultRetrySettings)
+	}
+	RetryPolicy(RetrySettings{UploadMax: time.Minute})(&c.opts)
+	want := DefaultRetrySettings
+	want.UploadMax = time.Minute
+	if got := c.RetrySettings(); got != want {
+		t.Errorf("RetrySettings(): got %+v, want %+v", got, want)
+	}
+
+	s := DefaultRetrySettings
+	for i := 0; i < 100; i++ {
+		d := s.next(0)
+		if d < 1010*time.Millisecond || d >= 1030*time.Millisecond {
+			t.Fatalf("first wait with jitter: got %v, want [1.01s, 1.03s)", d)
+		}
+	}
+}
+
+// stepClock is a Clock whose waits end only when step is called.
+type stepClock struct {
+	testClock
+	waiting chan chan time.Time
+}
+
+func (c *stepClock) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	c.waiting <- ch
+	return ch
+}
+
+// step waits for something to wait on c, calls f, and then ends the wait.
+func (c *stepClock) step(f func()) {
+	ch := <-c.waiting
+	f()
+	ch <- c.Now()
+}
+
+func TestWatch(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	clk := &stepClock{waiting: make(chan chan time.Time, 1)}
+	root := &beRoot{b2i: &testRoot{}, options: clientOptions{clock: clk}}
+	tb := &testBucket{n: "watched", files: make(map[string]string)}
+	bucket := &Bucket{b: &beBucket{b2bucket: tb, ri: root}, r: root, c: &Client{backend: root}}
+
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	version := func(name, id, status string, age time.Duration) b2FileInterface {
+		return &testFile{n: name, fid: id, a: status, t: t0.Add(age)}
+	}
+	setVersions := func(vs ...b2FileInterface) {
+		gmux.Lock()
+		defer gmux.Unlock()
+		tb.versions = vs
+	}
+	a1 := version("a", "a1", "upload", 0)
+	b1 := version("b", "b1", "upload", 0)
+	setVersions(a1, b1, version("c", "c0", "start", 0))
+
+	// The first listing is the baseline, and each step polls again.
+	events := bucket.Watch(ctx, "", time.Second)
+
+	clk.step(func() {
+		setVersions(version("a", "a2", "upload", 2*time.Second), a1, version("b", "b2", "hide", time.Second), b1, version("c", "c0", "start", 0))
+	})
+	want := []Event{
+		{Type: ObjectHidden, Name: "b", ID: "b2"},
+		{Type: ObjectCreated, Name: "a", ID: "a2"},
+	}
+	for _, w := range want {
+		e := <-events
+		if e.Type != w.Type || e.Name != w.Name || e.ID != w.ID || e.Object == nil || e.Err != nil {
+			t.Errorf("got event %+v, want %+v", e, w)
+		}
+	}
+
+	clk.step(func() { setVersions(version("a", "a2", "upload", 2*time.Second), b1) })
+	want = []Event{
+		{Type: ObjectDeleted, Name: "a", ID: "a1"},
+		{Type: ObjectDeleted, Name: "b", ID: "b2"},
+	}
+	for _, w := range want {
+		e := <-events
+		if e.Type != w.Type || e.Name != w.Name || e.ID != w.ID || e.Object != nil {
+			t.Errorf("got event %+v, want %+v", e, w)
+		}
+	}
+
+	// Nothing changes, and the watch ends.
+	clk.step(func() {})
+	clk.step(cancel)
+	for e := range events {
+		t.Errorf("got unexpected event %+v", e)
+	}
+}
+
 type badTransport struct{}
 
 func (badTransport) RoundTrip(r *http.Request) (*http.Response, error) {
@@ -600,36 +1047,134 @@ func TestCustomTransport(t *testing.T) {
 	}
 }
 
-func TestReaderDoubleClose(t *testing.T) {
+type headerTransport struct {
+	headers []http.Header
+}
+
+func (ht *headerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ht.headers = append(ht.headers, r.Header)
+	return badTransport{}.RoundTrip(r)
+}
+
+func TestRequestID(t *testing.T) {
+	ctx := WithRequestID(context.Background(), "some-correlation-id")
+	ht := &headerTransport{}
+	if _, err := NewClient(ctx, "abcd", "efgh", Transport(ht)); err == nil {
+		t.Fatal("NewClient returned successfully, expected an error")
+	}
+	if len(ht.headers) == 0 {
+		t.Fatal("no requests were made")
+	}
+	for _, h := range ht.headers {
+		if got := h.Get("X-Blazer-Correlation-ID"); got != "some-correlation-id" {
+			t.Errorf("X-Blazer-Correlation-ID: got %q, want %q", got, "some-correlation-id")
+		}
+	}
+}
+
+type requestIDTransport struct{}
+
+func (requestIDTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return &http.Response{
+		Status:     "400 Bad Request",
+		StatusCode: 400,
+		Header:     http.Header{"X-Bz-Request-Id": []string{"abc123"}},
+		Body:       ioutil.NopCloser(bytes.NewBufferString(`{"status": 400, "code": "bad_request", "message": "no"}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestResponseHeader(t *testing.T) {
+	ctx := context.Background()
+	_, err := NewClient(ctx, "abcd", "efgh", Transport(requestIDTransport{}))
+	if err == nil {
+		t.Fatal("NewClient returned successfully, expected an error")
+	}
+	if got := ResponseHeader(err).Get("X-Bz-Request-Id"); got != "abc123" {
+		t.Errorf("ResponseHeader(%v): got %q, want %q", err, got, "abc123")
+	}
+}
+
+type gzipTransport struct {
+	acceptEncoding string
+}
+
+func (gt *gzipTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	gt.acceptEncoding = r.Header.Get("Accept-Encoding")
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	io.WriteString(gz, `{"status": 400, "code": "bad_request", "message": "compressed message"}`)
+	gz.Close()
+	return &http.Response{
+		Status:     "400 Bad Request",
+		StatusCode: 400,
+		Header:     http.Header{"Content-Encoding": []string{"gzip"}},
+		Body:       ioutil.NopCloser(buf),
+		Request:    r,
+	}, nil
+}
+
+func TestCompression(t *testing.T) {
+	ctx := context.Background()
+	gt := &gzipTransport{}
+	_, err := NewClient(ctx, "abcd", "efgh", Transport(gt))
+	if err == nil {
+		t.Fatal("NewClient returned successfully, expected an error")
+	}
+	if gt.acceptEncoding != "gzip" {
+		t.Errorf("Accept-Encoding: got %q, want %q", gt.acceptEncoding, "gzip")
+	}
+	if !strings.Contains(err.Error(), "compressed message") {
+		t.Errorf("expected decompressed error message, got %v", err)
+	}
+
+	gt = &gzipTransport{}
+	NewClient(ctx, "abcd", "efgh", Transport(gt), DisableCompression())
+	if gt.acceptEncoding != "" {
+		t.Errorf("Accept-Encoding: got %q, want none", gt.acceptEncoding)
+	}
+}
+
+func TestPartSizes(t *testing.T) {
 	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
 	client := &Client{
 		backend: &beRoot{
 			b2i: &testRoot{
-				bucketMap: make(map[string]map[string]string),
-				errs:      &errCont{},
+				bucketMap:   make(map[string]map[string]string),
+				errs:        &errCont{},
+				recPartSize: 100,
+				minPartSize: 5,
 			},
 		},
 	}
-	bucket, err := client.NewBucket(ctx, "bucket", &BucketAttrs{Type: Private})
+	rec, min, err := client.PartSizes(ctx)
 	if err != nil {
 		t.Fatal(err)
 	}
-	o, _, err := writeFile(ctx, bucket, "file", 10, 10)
+	if rec != 100 || min != 5 {
+		t.Errorf("PartSizes(): got (%d, %d), want (100, 5)", rec, min)
+	}
+
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	r := o.NewReader(ctx)
-	// Read to EOF, and then read some more.
-	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+	w := bucket.Object("file").NewWriter(ctx)
+	if _, err := io.Copy(w, io.LimitReader(zReader{}, 250)); err != nil {
 		t.Fatal(err)
 	}
-	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+	if err := w.Close(); err != nil {
 		t.Fatal(err)
 	}
+	if w.csize != 100 {
+		t.Errorf("default chunk size: got %d, want 100", w.csize)
+	}
 }
 
-func TestReadWrite(t *testing.T) {
+func TestWriterAttrs(t *testing.T) {
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -642,50 +1187,119 @@ func TestReadWrite(t *testing.T) {
 			},
 		},
 	}
-
-	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		if err := bucket.Delete(ctx); err != nil {
-			t.Error(err)
-		}
-	}()
 
-	sobj, wsha, err := writeFile(ctx, bucket, smallFileName, 1e6+42, 1e8)
-	if err != nil {
-		t.Fatal(err)
+	for _, size := range []int64{10, 1e4} {
+		w := bucket.Object("file").NewWriter(ctx)
+		w.ChunkSize = 1e3
+		if _, err := w.Attrs(); err == nil {
+			t.Error("Attrs() before Close: expected an error")
+		}
+		if _, err := io.Copy(w, io.LimitReader(zReader{}, size)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		attrs, err := w.Attrs()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if attrs.Name != "file" || attrs.Size != size || attrs.Status != Uploaded {
+			t.Errorf("Attrs(): got %+v, want name %q and size %d", attrs, "file", size)
+		}
 	}
+}
 
-	defer func() {
-		if err := sobj.Delete(ctx); err != nil {
-			t.Error(err)
-		}
-	}()
+func TestLargeFileSHA1(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-	if err := readFile(ctx, sobj, wsha, 1e5, 10); err != nil {
-		t.Error(err)
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	lobj, wshaL, err := writeFile(ctx, bucket, largeFileName, 1e6-1e5, 1e4)
+	const size = 1e4
+	hsh := sha1.New()
+	io.Copy(hsh, io.LimitReader(&zReadSeeker{size: size}, size))
+	want := fmt.Sprintf("%x", hsh.Sum(nil))
+
+	w := bucket.Object("readfrom").NewWriter(ctx)
+	w.ChunkSize = 1e3
+	if _, err := w.ReadFrom(&zReadSeeker{size: size}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.info["large_file_sha1"]; got != want {
+		t.Errorf("large_file_sha1: got %q, want %q", got, want)
+	}
+	attrs, err := w.Attrs()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer func() {
-		if err := lobj.Delete(ctx); err != nil {
-			t.Error(err)
-		}
-	}()
+	if attrs.SHA1 != want {
+		t.Errorf("ReadFrom: Attrs().SHA1: got %q, want %q", attrs.SHA1, want)
+	}
 
-	if err := readFile(ctx, lobj, wshaL, 1e7, 10); err != nil {
-		t.Error(err)
+	w = bucket.Object("write").NewWriter(ctx)
+	w.ChunkSize = 1e3
+	if _, err := io.Copy(w, io.LimitReader(&zReadSeeker{size: size}, size)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	attrs, err = w.Attrs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.SHA1 != want {
+		t.Errorf("Write: Attrs().SHA1: got %q, want %q", attrs.SHA1, want)
+	}
+
+	w = bucket.Object("optout").NewWriter(ctx, WithoutLargeFileSHA1())
+	w.ChunkSize = 1e3
+	if _, err := w.ReadFrom(&zReadSeeker{size: size}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.info["large_file_sha1"]; ok {
+		t.Error("WithoutLargeFileSHA1: large_file_sha1 was set")
 	}
 }
 
-func TestLargeFileCancellation(t *testing.T) {
+// countingReadSeeker counts the bytes read from it.
+type countingReadSeeker struct {
+	*zReadSeeker
+	n int64
+}
+
+func (c *countingReadSeeker) Read(p []byte) (int, error) {
+	n, err := c.zReadSeeker.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+	return n, err
+}
+
+func TestWithSHA1(t *testing.T) {
 	ctx := context.Background()
-	ctx, cancel := context.WithCancel(ctx)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
 	client := &Client{
@@ -696,38 +1310,134 @@ func TestLargeFileCancellation(t *testing.T) {
 			},
 		},
 	}
-
-	b, err := client.NewBucket(ctx, bucketName, nil)
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
 	if err != nil {
 		t.Fatal(err)
 	}
-	var called bool
-	w := b.Object("foo").NewWriter(ctx, WithCancelOnError(func() context.Context { return context.Background() }, func(err error) {
-		called = true
+
+	const size = 1e4
+	hsh := sha1.New()
+	io.Copy(hsh, io.LimitReader(&zReadSeeker{size: size}, size))
+	want := fmt.Sprintf("%x", hsh.Sum(nil))
+	wrong := strings.Repeat("0", 40)
+	copyAll := func(w *Writer) error {
+		_, err := io.Copy(w, io.LimitReader(&zReadSeeker{size: size}, size))
+		return err
+	}
+	readFrom := func(w *Writer) error {
+		_, err := w.ReadFrom(&zReadSeeker{size: size})
+		return err
+	}
+	writeABC := func(w *Writer) error {
+		_, err := w.Write([]byte("abc"))
+		return err
+	}
+
+	table := []struct {
+		name  string
+		sha   string
+		write func(*Writer) error
+		large bool
+		fail  bool
+	}{
+		{
+			name:  "write",
+			sha:   want,
+			write: copyAll,
+		},
+		{
+			name:  "write wrong",
+			sha:   wrong,
+			write: copyAll,
+			fail:  true,
+		},
+		{
+			name:  "readfrom",
+			sha:   strings.ToUpper(want),
+			write: readFrom,
+		},
+		{
+			name:  "readfrom wrong",
+			sha:   wrong,
+			write: readFrom,
+			fail:  true,
+		},
+		{
+			name:  "large readfrom",
+			sha:   want,
+			write: readFrom,
+			large: true,
+		},
+		{
+			name:  "large write",
+			sha:   want,
+			write: copyAll,
+			large: true,
+		},
+		{
+			name:  "malformed",
+			sha:   "abc",
+			write: writeABC,
+			fail:  true,
+		},
+		{
+			name:  "not hex",
+			sha:   strings.Repeat("g", 40),
+			write: writeABC,
+			fail:  true,
+		},
+	}
+	for _, e := range table {
+		w := bucket.Object(e.name).NewWriter(ctx, WithSHA1(e.sha))
+		if e.large {
+			w.ChunkSize = 1e3
+		}
+		err := e.write(w)
+		if cerr := w.Close(); err == nil {
+			err = cerr
+		}
+		if e.fail {
+			if err == nil {
+				t.Errorf("%s: got no error", e.name)
+			}
+			continue
+		}
 		if err != nil {
-			t.Errorf("expected no error, got %v", err)
+			t.Errorf("%s: %v", e.name, err)
+			continue
 		}
-	}))
-	w.ChunkSize = 10
-
-	for i := 0; i < 10; i++ {
-		r := io.LimitReader(zReader{}, 20)
-		if _, err := io.Copy(w, r); err != nil && err != context.Canceled {
-			t.Errorf("Copy: %v", err)
+		if got := w.SHA1(); got != want {
+			t.Errorf("%s: SHA1(): got %q, want %q", e.name, got, want)
+		}
+		if got, ok := w.info["large_file_sha1"]; e.large && got != want || !e.large && ok {
+			t.Errorf("%s: large_file_sha1: got %q", e.name, got)
 		}
-		cancel()
 	}
 
-	if err := w.Close(); err != context.Canceled {
-		t.Errorf("expected cancelled context; got %v", err)
+	// A large file with a known SHA1 is read only once.
+	rs := &countingReadSeeker{zReadSeeker: &zReadSeeker{size: size}}
+	w := bucket.Object("once").NewWriter(ctx, WithSHA1(want))
+	w.ChunkSize = 1e3
+	if _, err := w.ReadFrom(rs); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt64(&rs.n); n != size {
+		t.Errorf("ReadFrom with WithSHA1: read %d bytes, want %d", n, int64(size))
 	}
 
-	if !called {
-		t.Errorf("error callback not called")
+	// WriteFrom streams small objects without a trailing hash.
+	if err := bucket.Object("writefrom").WriteFrom(ctx, io.LimitReader(&zReadSeeker{size: size}, size), size, WithSHA1(want)); err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Object("writefrom wrong").WriteFrom(ctx, io.LimitReader(&zReadSeeker{size: size}, size), size, WithSHA1(wrong)); err == nil {
+		t.Error("WriteFrom with the wrong SHA1: got no error")
 	}
 }
 
-func TestReadRangeReturnsRight(t *testing.T) {
+func TestWriteFrom(t *testing.T) {
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -740,7 +1450,6 @@ func TestReadRangeReturnsRight(t *testing.T) {
 			},
 		},
 	}
-
 	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
 	if err != nil {
 		t.Fatal(err)
@@ -751,23 +1460,46 @@ func TestReadRangeReturnsRight(t *testing.T) {
 		}
 	}()
 
-	obj, _, err := writeFile(ctx, bucket, "file", 1e6+42, 1e8)
-	if err != nil {
-		t.Fatal(err)
-	}
-	r := obj.NewRangeReader(ctx, 200, 1400)
-	r.ChunkSize = 1000
-
-	i, err := io.Copy(ioutil.Discard, r)
-	if err != nil {
-		t.Error(err)
+	table := []struct {
+		name string
+		size int64
+		seek bool
+	}{
+		{name: "small", size: 1e3},
+		{name: "small-seeker", size: 1e3, seek: true},
+		{name: "large", size: 2e6},
 	}
-	if i != 1400 {
-		t.Errorf("NewRangeReader(_, 200, 1400): want 1400, got %d", i)
+	for _, e := range table {
+		data := make([]byte, e.size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		var r io.Reader = bytes.NewReader(data)
+		if !e.seek {
+			r = struct{ io.Reader }{r}
+		}
+		obj := bucket.Object(e.name)
+		if err := obj.WriteFrom(ctx, r, e.size, func(w *Writer) { w.ChunkSize = 1e6 }); err != nil {
+			t.Errorf("%s: WriteFrom: %v", e.name, err)
+			continue
+		}
+		rdr := obj.NewReader(ctx)
+		got, err := ioutil.ReadAll(rdr)
+		rdr.Close()
+		if err != nil {
+			t.Errorf("%s: read: %v", e.name, err)
+			continue
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("%s: round trip mismatch: got %d bytes, want %d", e.name, len(got), len(data))
+		}
+		if err := obj.Delete(ctx); err != nil {
+			t.Error(err)
+		}
 	}
 }
 
-func TestWriterReturnsError(t *testing.T) {
+func TestTags(t *testing.T) {
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
@@ -776,141 +1508,3396 @@ func TestWriterReturnsError(t *testing.T) {
 		backend: &beRoot{
 			b2i: &testRoot{
 				bucketMap: make(map[string]map[string]string),
-				errs: &errCont{
-					errMap: map[string]map[int]error{
-						"uploadPart": {
-							0: testError{},
-							1: testError{},
-							2: testError{},
-							3: testError{},
-							4: testError{},
-							5: testError{},
-							6: testError{},
-						},
-					},
-				},
+				errs:      &errCont{},
 			},
 		},
 	}
-
 	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
 	if err != nil {
 		t.Fatal(err)
 	}
-	w := bucket.Object("test").NewWriter(ctx)
-	r := io.LimitReader(zReader{}, 1e7)
-	w.ChunkSize = 1e4
-	w.ConcurrentUploads = 4
-	if _, err := io.Copy(w, r); err == nil {
-		t.Fatalf("io.Copy: should have returned an error")
-	}
-}
+	defer func() {
+		if err := bucket.Delete(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
 
-func TestFileBuffer(t *testing.T) {
-	r := io.LimitReader(zReader{}, 1e8)
-	w, err := newFileBuffer("")
+	tags := Tags{
+		"env":           "prod",
+		"Owner Name":    "Zoë",
+		"a_b/c+d":       "100% ok",
+		"日本":            "語",
+		"MiXeD-case-01": "",
+	}
+	info, err := tags.Info()
 	if err != nil {
 		t.Fatal(err)
 	}
-	defer w.Close()
-	if _, err := io.Copy(w, r); err != nil {
-		t.Fatal(err)
+	if err := ValidateInfo(info); err != nil {
+		t.Errorf("ValidateInfo(%v): %v", info, err)
 	}
-	bReader, err := w.Reader()
-	if err != nil {
+
+	obj := bucket.Object("tagged")
+	w := obj.NewWriter(ctx, WithAttrsOption(&Attrs{Info: map[string]string{"other": "value"}}), WithTags(tags))
+	if _, err := io.Copy(w, strings.NewReader("tagged data")); err != nil {
 		t.Fatal(err)
 	}
-	hsh := sha1.New()
-	if _, err := io.Copy(hsh, bReader); err != nil {
+	if err := w.Close(); err != nil {
 		t.Fatal(err)
 	}
-	hshText := fmt.Sprintf("%x", hsh.Sum(nil))
-	if hshText != w.Hash() {
-		t.Errorf("hashes are not equal: bufferWriter is %q, read buffer is %q", w.Hash(), hshText)
+	defer obj.Delete(ctx)
+
+	// The test backend doesn't store info, so check what was sent.
+	attrs := &Attrs{Info: w.info}
+	if attrs.Info["other"] != "value" {
+		t.Errorf("non-tag info lost: %v", attrs.Info)
+	}
+	got := attrs.Tags()
+	if len(got) != len(tags) {
+		t.Errorf("Tags(): got %v, want %v", got, tags)
+	}
+	for k, v := range tags {
+		if got[k] != v {
+			t.Errorf("Tags()[%q]: got %q, want %q", k, got[k], v)
+		}
 	}
-}
 
-func TestNonBuffer(t *testing.T) {
-	table := []struct {
-		str  string
-		off  int64
-		len  int64
-		want string
-	}{
-		{
-			str:  "a string",
-			off:  0,
-			len:  3,
-			want: "a s",
-		},
-		{
-			str:  "a string",
-			off:  3,
-			len:  1,
-			want: "t",
-		},
-		{
-			str:  "a string",
-			off:  3,
-			len:  5,
-			want: "tring",
-		},
+	// B2 may return keys in lower case.
+	lower := &Attrs{Info: make(map[string]string)}
+	for k, v := range info {
+		lower.Info[strings.ToLower(k)] = v
+	}
+	if got := lower.Tags(); got["Owner Name"] != "Zoë" {
+		t.Errorf("lower-cased Tags(): got %v", got)
 	}
 
-	for _, e := range table {
-		nb := newNonBuffer(strings.NewReader(e.str), e.off, e.len)
-		want := fmt.Sprintf("%s%x", e.want, sha1.Sum([]byte(e.str[int(e.off):int(e.off+e.len)])))
-		r, err := nb.Reader()
-		if err != nil {
-			t.Error(err)
-			continue
+	bad := []Tags{
+		{strings.Repeat("x", 50): "too long"},
+		{"invalid": "\xff"},
+		{"0": "", "1": "", "2": "", "3": "", "4": "", "5": "", "6": "", "7": "", "8": "", "9": "", "10": ""},
+	}
+	for _, tg := range bad {
+		if _, err := tg.Info(); err == nil {
+			t.Errorf("%v.Info(): got no error", tg)
 		}
-		got, err := ioutil.ReadAll(r)
+	}
+	w = bucket.Object("untagged").NewWriter(ctx, WithTags(bad[0]))
+	if _, err := w.Write([]byte("x")); err == nil {
+		t.Error("Write with invalid tags: got no error")
+	}
+	w.Close()
+
+	badInfo := []map[string]string{
+		{"has space": "x"},
+		{"ünicode": "x"},
+		{"": "x"},
+		{"ok": "\xff"},
+	}
+	for _, i := range badInfo {
+		if err := ValidateInfo(i); err == nil {
+			t.Errorf("ValidateInfo(%v): got no error", i)
+		}
+	}
+}
+
+func FuzzTags(f *testing.F) {
+	f.Add("color", "blue")
+	f.Add("Mixed Case/Name", "café")
+	f.Add("_5f", "")
+	f.Add("\x00\xff", "\xff")
+	f.Fuzz(func(t *testing.T, name, value string) {
+		// Arbitrary keys, as B2 might return them, must never panic.
+		unescapeInfoKey(name)
+		ValidateInfo(map[string]string{name: value})
+
+		tags := Tags{name: value}
+		info, err := tags.Info()
 		if err != nil {
-			t.Errorf("ioutil.ReadAll(%#v): %v", e, err)
-			continue
+			return
 		}
-		if want != string(got) {
-			t.Errorf("ioutil.ReadAll(%#v): got %q, want %q", e, string(got), want)
+		if err := ValidateInfo(info); err != nil {
+			t.Fatalf("ValidateInfo(%v) = %v for encoded tags %v", info, err, tags)
 		}
-	}
+		a := &Attrs{Info: info}
+		if got := a.Tags(); !reflect.DeepEqual(got, tags) {
+			t.Fatalf("Tags(): got %v, want %v", got, tags)
+		}
+	})
 }
 
-func writeFile(ctx context.Context, bucket *Bucket, name string, size int64, csize int) (*Object, string, error) {
-	r := io.LimitReader(zReader{}, size)
-	o := bucket.Object(name)
-	f := o.NewWriter(ctx)
-	h := sha1.New()
-	w := io.MultiWriter(f, h)
-	f.ConcurrentUploads = 5
-	f.ChunkSize = csize
-	n, err := io.Copy(w, r)
-	if err != nil {
-		return nil, "", err
+func TestParts(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{
+		b2i: &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs:      &errCont{},
+		},
 	}
-	if n != size {
-		return nil, "", fmt.Errorf("io.Copy(): wrote %d bytes; wanted %d bytes", n, size)
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	pf := &testPartsFile{testFile: testFile{n: "unfinished"}}
+	for i := 1; i <= 2500; i++ {
+		pf.parts = append(pf.parts, &testFilePart{
+			n: i,
+			s: int64(i) * 10,
+			h: fmt.Sprintf("%040d", i),
+			t: start.Add(time.Duration(i) * time.Second),
+		})
+	}
+	obj := &Object{
+		name: "unfinished",
+		f:    &beFile{b2file: pf, ri: root},
+	}
+
+	iter := obj.Parts(ctx)
+	var n int
+	for iter.Next() {
+		n++
+		p := iter.Part()
+		want := pf.parts[n-1]
+		if p.Number != want.n || p.Size != want.s || p.SHA1 != want.h || !p.UploadTimestamp.Equal(want.t) {
+			t.Errorf("part %d: got %+v, want %+v", n, p, want)
+		}
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != len(pf.parts) {
+		t.Errorf("got %d parts, want %d", n, len(pf.parts))
+	}
+	if pf.calls != 3 {
+		t.Errorf("listParts called %d times, want 3", pf.calls)
+	}
+
+	empty := &Object{
+		name: "empty",
+		f:    &beFile{b2file: &testPartsFile{testFile: testFile{n: "empty"}}, ri: root},
+	}
+	iter = empty.Parts(ctx)
+	if iter.Next() {
+		t.Errorf("empty file: got part %+v", iter.Part())
+	}
+	if err := iter.Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestResumeSelection(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := bucket.b.(*beBucket).b2bucket.(*testBucket)
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	table := []struct {
+		desc    string
+		ids     []string
+		opts    []WriterOption
+		want    string // the ID of the resumed file, if any
+		wantErr bool
+	}{
+		{desc: "single", ids: []string{"a"}, want: "a"},
+		{desc: "none", ids: []string{}},
+		{desc: "ambiguous", ids: []string{"a", "b"}, wantErr: true},
+		{desc: "by id", ids: []string{"a", "b", "c"}, opts: []WriterOption{ResumeFileID("b")}, want: "b"},
+		{desc: "missing id", ids: []string{"a", "b"}, opts: []WriterOption{ResumeFileID("z")}, wantErr: true},
+		{desc: "newest", ids: []string{"a", "c", "b"}, opts: []WriterOption{ResumeNewest()}, want: "c"},
+	}
+	for _, e := range table {
+		files := make(map[string]*testPartsFile)
+		tb.unfinished = []b2FileInterface{}
+		for _, id := range e.ids {
+			// Later letters were started later.
+			f := &testPartsFile{
+				testFile: testFile{n: "resumed", t: start.Add(time.Duration(id[0]) * time.Minute), files: tb.files},
+				fid:      id,
+			}
+			files[id] = f
+			tb.unfinished = append(tb.unfinished, f)
+		}
+		w := bucket.Object("resumed").NewWriter(ctx, e.opts...)
+		w.Resume = true
+		w.ChunkSize = 1e5
+		_, err := io.Copy(w, io.LimitReader(zReader{}, 3e5))
+		// Close reports the writer's first error.
+		if cerr := w.Close(); cerr != nil {
+			err = cerr
+		}
+		if (err != nil) != e.wantErr {
+			t.Errorf("%s: got err %v, want error %v", e.desc, err, e.wantErr)
+		}
+		if e.desc == "ambiguous" {
+			if _, ok := err.(*AmbiguousResumeError); !ok {
+				t.Errorf("%s: got %T, want *AmbiguousResumeError", e.desc, err)
+			} else if !strings.Contains(err.Error(), "a (started") || !strings.Contains(err.Error(), "b (started") {
+				t.Errorf("%s: error %q does not list candidates", e.desc, err)
+			}
+		}
+		for id, f := range files {
+			if f.resumed != (id == e.want) {
+				t.Errorf("%s: file %s resumed: %v, want %v", e.desc, id, f.resumed, id == e.want)
+			}
+		}
+		delete(tb.files, "resumed")
+	}
+}
+
+func TestStaleUploadURLs(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	clk := &testClock{now: time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)}
+	root := &beRoot{
+		b2i: &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs:      &errCont{},
+		},
+		options: clientOptions{clock: clk},
+	}
+	reader := func() readResetter {
+		mb := newMemoryBuffer()
+		mb.Write([]byte("some data"))
+		r, err := mb.Reader()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	table := []struct {
+		age  time.Duration
+		want int
+	}{
+		{age: time.Minute, want: 0},
+		{age: 22 * time.Hour, want: 0},
+		{age: 23*time.Hour + time.Minute, want: 1},
+	}
+	for _, e := range table {
+		tu := &testURL{files: make(map[string]string)}
+		u := &beURL{b2url: tu, ri: root, born: clk.Now().Add(-e.age)}
+		if _, err := u.uploadFile(ctx, reader(), 9, "file", "", "", nil); err != nil {
+			t.Fatal(err)
+		}
+		if tu.reloads != e.want {
+			t.Errorf("upload URL aged %v: got %d reloads, want %d", e.age, tu.reloads, e.want)
+		}
+		if clk.Now().Sub(u.born) > uploadURLMaxAge {
+			t.Errorf("upload URL aged %v: still stale after upload", e.age)
+		}
+
+		tc := &testFileChunk{parts: make(map[int][]byte), errs: &errCont{}}
+		c := &beFileChunk{b2fileChunk: tc, ri: root, born: clk.Now().Add(-e.age)}
+		if _, err := c.uploadPart(ctx, reader(), "", 9, 1); err != nil {
+			t.Fatal(err)
+		}
+		if tc.reloads != e.want {
+			t.Errorf("upload part URL aged %v: got %d reloads, want %d", e.age, tc.reloads, e.want)
+		}
+	}
+}
+
+func TestReaderDoubleClose(t *testing.T) {
+	ctx := context.Background()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, _, err := writeFile(ctx, bucket, "file", 10, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := o.NewReader(ctx)
+	// Read to EOF, and then read some more.
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadWrite(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := bucket.Delete(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	sobj, wsha, err := writeFile(ctx, bucket, smallFileName, 1e6+42, 1e8)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if err := sobj.Delete(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := readFile(ctx, sobj, wsha, 1e5, 10); err != nil {
+		t.Error(err)
+	}
+
+	lobj, wshaL, err := writeFile(ctx, bucket, largeFileName, 1e6-1e5, 1e4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := lobj.Delete(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	if err := readFile(ctx, lobj, wshaL, 1e7, 10); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLargeFileCancellation(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+
+	b, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var called bool
+	w := b.Object("foo").NewWriter(ctx, WithCancelOnError(func() context.Context { return context.Background() }, func(err error) {
+		called = true
+		if err != nil {
+			t.Errorf("expected no error, got %v", err)
+		}
+	}))
+	w.ChunkSize = 10
+
+	for i := 0; i < 10; i++ {
+		r := io.LimitReader(zReader{}, 20)
+		if _, err := io.Copy(w, r); err != nil && err != context.Canceled {
+			t.Errorf("Copy: %v", err)
+		}
+		cancel()
+	}
+
+	if err := w.Close(); err != context.Canceled {
+		t.Errorf("expected cancelled context; got %v", err)
+	}
+
+	if !called {
+		t.Errorf("error callback not called")
+	}
+}
+
+func TestReadRangeReturnsRight(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := bucket.Delete(ctx); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	obj, _, err := writeFile(ctx, bucket, "file", 1e6+42, 1e8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := obj.NewRangeReader(ctx, 200, 1400)
+	r.ChunkSize = 1000
+
+	i, err := io.Copy(ioutil.Discard, r)
+	if err != nil {
+		t.Error(err)
+	}
+	if i != 1400 {
+		t.Errorf("NewRangeReader(_, 200, 1400): want 1400, got %d", i)
+	}
+}
+
+func TestWriterReturnsError(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs: &errCont{
+					errMap: map[string]map[int]error{
+						"uploadPart": {
+							0: testError{},
+							1: testError{},
+							2: testError{},
+							3: testError{},
+							4: testError{},
+							5: testError{},
+							6: testError{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bucket.Object("test").NewWriter(ctx)
+	r := io.LimitReader(zReader{}, 1e7)
+	w.ChunkSize = 1e4
+	w.ConcurrentUploads = 4
+	if _, err := io.Copy(w, r); err == nil {
+		t.Fatalf("io.Copy: should have returned an error")
+	}
+}
+
+func TestFileBuffer(t *testing.T) {
+	r := io.LimitReader(zReader{}, 1e8)
+	w, err := newFileBuffer("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := io.Copy(w, r); err != nil {
+		t.Fatal(err)
+	}
+	bReader, err := w.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	hsh := sha1.New()
+	if _, err := io.Copy(hsh, bReader); err != nil {
+		t.Fatal(err)
+	}
+	hshText := fmt.Sprintf("%x", hsh.Sum(nil))
+	if hshText != w.Hash() {
+		t.Errorf("hashes are not equal: bufferWriter is %q, read buffer is %q", w.Hash(), hshText)
+	}
+}
+
+func TestNonBuffer(t *testing.T) {
+	table := []struct {
+		str  string
+		off  int64
+		len  int64
+		want string
+	}{
+		{
+			str:  "a string",
+			off:  0,
+			len:  3,
+			want: "a s",
+		},
+		{
+			str:  "a string",
+			off:  3,
+			len:  1,
+			want: "t",
+		},
+		{
+			str:  "a string",
+			off:  3,
+			len:  5,
+			want: "tring",
+		},
+	}
+
+	for _, e := range table {
+		nb := newNonBuffer(strings.NewReader(e.str), e.off, e.len)
+		want := fmt.Sprintf("%s%x", e.want, sha1.Sum([]byte(e.str[int(e.off):int(e.off+e.len)])))
+		r, err := nb.Reader()
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Errorf("ioutil.ReadAll(%#v): %v", e, err)
+			continue
+		}
+		if want != string(got) {
+			t.Errorf("ioutil.ReadAll(%#v): got %q, want %q", e, string(got), want)
+		}
+	}
+}
+
+func writeFile(ctx context.Context, bucket *Bucket, name string, size int64, csize int) (*Object, string, error) {
+	r := io.LimitReader(zReader{}, size)
+	o := bucket.Object(name)
+	f := o.NewWriter(ctx)
+	h := sha1.New()
+	w := io.MultiWriter(f, h)
+	f.ConcurrentUploads = 5
+	f.ChunkSize = csize
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return nil, "", err
+	}
+	if n != size {
+		return nil, "", fmt.Errorf("io.Copy(): wrote %d bytes; wanted %d bytes", n, size)
 	}
 	if err := f.Close(); err != nil {
 		return nil, "", err
 	}
-	return o, fmt.Sprintf("%x", h.Sum(nil)), nil
+	return o, fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+func readFile(ctx context.Context, obj *Object, sha string, chunk, concur int) error {
+	r := obj.NewReader(ctx)
+	r.ChunkSize = chunk
+	r.ConcurrentDownloads = concur
+	h := sha1.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	if err := r.Close(); err != nil {
+		return err
+	}
+	rsha := fmt.Sprintf("%x", h.Sum(nil))
+	if sha != rsha {
+		return fmt.Errorf("bad hash: got %s, want %s", rsha, sha)
+	}
+	return nil
+}
+
+func sha1Sum(b []byte) []byte {
+	h := sha1.Sum(b)
+	return h[:]
+}
+
+type testLockedFile struct {
+	testFile
+	fi *testFileInfo
+}
+
+func (t *testLockedFile) getFileInfo(context.Context) (b2FileInfoInterface, error) {
+	return t.fi, nil
+}
+
+func TestAttrsProtection(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{
+		b2i: &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs:      &errCont{},
+		},
+	}
+	until := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	lf := &testLockedFile{
+		testFile: testFile{n: "locked"},
+		fi: &testFileInfo{
+			name: "locked",
+			ret:  Retention{Mode: "compliance", Until: until},
+			hold: true,
+			sse:  Encryption{Mode: "SSE-B2", Algorithm: "AES256"},
+		},
+	}
+	obj := &Object{
+		name: "locked",
+		f:    &beFile{b2file: lf, ri: root},
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Retention.Mode != "compliance" || !attrs.Retention.Until.Equal(until) {
+		t.Errorf("Retention: got %+v, want compliance until %v", attrs.Retention, until)
+	}
+	if !attrs.LegalHold {
+		t.Error("LegalHold: got false, want true")
+	}
+	if want := (Encryption{Mode: "SSE-B2", Algorithm: "AES256"}); attrs.Encryption != want {
+		t.Errorf("Encryption: got %+v, want %+v", attrs.Encryption, want)
+	}
+}
+
+func TestReaderChunkRetry(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	want := strings.Repeat("abcdefghij", 100)
+	table := []struct {
+		errs    map[int]error
+		wantErr bool
+	}{
+		{
+			errs: map[int]error{
+				0: testError{retry: true},
+				1: io.ErrUnexpectedEOF,
+			},
+		},
+		{
+			errs: map[int]error{
+				0: testError{backoff: time.Second},
+				1: testError{retry: true},
+				2: testError{retry: true},
+				3: testError{retry: true},
+				4: testError{retry: true},
+			},
+			wantErr: true,
+		},
+	}
+	for i, e := range table {
+		clk := &testClock{}
+		root := &testRoot{
+			bucketMap: map[string]map[string]string{
+				bucketName: {smallFileName: want},
+			},
+			errs: &errCont{
+				errMap: map[string]map[int]error{"readBody": e.errs},
+			},
+		}
+		client := &Client{backend: &beRoot{b2i: root, options: clientOptions{clock: clk}}}
+		bucket, err := client.Bucket(ctx, bucketName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := bucket.Object(smallFileName).NewReader(ctx)
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		calls := clk.calls()
+		if e.wantErr {
+			if err == nil {
+				t.Errorf("%d: ReadAll succeeded, want error", i)
+			}
+			if len(calls) != maxChunkAttempts-1 {
+				t.Errorf("%d: waited %d times, want %d", i, len(calls), maxChunkAttempts-1)
+			}
+			if len(calls) > 0 && calls[0] != time.Second {
+				t.Errorf("%d: first wait %v, want 1s", i, calls[0])
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%d: ReadAll: %v", i, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%d: got %d bytes, want %d", i, len(got), len(want))
+		}
+		if len(calls) != len(e.errs) {
+			t.Errorf("%d: waited %d times, want %d", i, len(calls), len(e.errs))
+		}
+	}
+}
+
+func TestReaderResumesRange(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	want := strings.Repeat("0123456789", 100)
+	root := &beRoot{
+		b2i: &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs:      &errCont{},
+		},
+		options: clientOptions{clock: &testClock{}},
+	}
+	tb := &testBucket{
+		n:     bucketName,
+		files: map[string]string{smallFileName: want},
+		errs: &errCont{
+			errMap: map[string]map[int]error{
+				"readBody": {
+					0: io.ErrUnexpectedEOF,
+					1: testError{retry: true},
+				},
+			},
+		},
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+	r := bucket.Object(smallFileName).NewReader(ctx)
+	r.ChunkSize = 800
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	// The first chunk fails after 400 bytes, and then after 200 more; each
+	// retry should ask only for what remains.
+	wantRanges := [][2]int64{{0, 800}, {400, 400}, {600, 200}, {800, 800}}
+	gmux.Lock()
+	defer gmux.Unlock()
+	if len(tb.ranges) < len(wantRanges) {
+		t.Fatalf("got ranges %v, want %v", tb.ranges, wantRanges)
+	}
+	for i, rng := range wantRanges {
+		if tb.ranges[i] != rng {
+			t.Errorf("download %d: got range %v, want %v", i, tb.ranges[i], rng)
+		}
+	}
+}
+
+func TestReaderByID(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	want := "listed objects are read by id"
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: map[string]map[string]string{
+					bucketName: {smallFileName: want},
+				},
+				errs: &errCont{},
+			},
+		},
+	}
+	bucket, err := client.Bucket(ctx, bucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	iter := bucket.List(ctx, ListPageSize(10))
+	if !iter.Next() {
+		t.Fatalf("no objects listed: %v", iter.Err())
+	}
+	obj := iter.Object()
+	r := obj.NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	tf := obj.f.(*beFile).b2file.(*testFile)
+	if tf.byID == 0 {
+		t.Error("listed object was not downloaded by ID")
+	}
+
+	// Objects named directly have no known ID, and are read by name.
+	r = bucket.Object(smallFileName).NewReader(ctx)
+	got, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("by name: got %q, want %q", got, want)
+	}
+}
+
+func TestWriterUploadedObject(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pinned *Object
+	w := bucket.Object(smallFileName).NewWriter(ctx, WithUploadedObject(func(o *Object) { pinned = o }))
+	if _, err := io.WriteString(w, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if pinned == nil {
+		t.Fatal("WithUploadedObject callback not called")
+	}
+
+	// Overwrite the name; the pinned object should still read the first upload.
+	w = bucket.Object(smallFileName).NewWriter(ctx)
+	if _, err := io.WriteString(w, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := pinned.NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "first" {
+		t.Errorf("pinned object: got %q, want %q", got, "first")
+	}
+	r = bucket.Object(smallFileName).NewReader(ctx)
+	got, err = ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "second" {
+		t.Errorf("by name: got %q, want %q", got, "second")
+	}
+}
+
+func TestListDirs(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: map[string]map[string]string{
+					bucketName: {
+						"a":         "a",
+						"dir/x":     "x",
+						"dir/y":     "y",
+						"dir/sub/z": "z",
+					},
+				},
+				errs: &errCont{},
+			},
+		},
+	}
+	bucket, err := client.Bucket(ctx, bucketName)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(iter *ObjectIterator) ([]string, []*Dir) {
+		var names []string
+		var dirs []*Dir
+		for iter.Next() {
+			obj := iter.Object()
+			d := iter.Dir()
+			if obj.IsDir() != (d != nil) {
+				t.Errorf("%s: IsDir is %v, but Dir is %v", obj.Name(), obj.IsDir(), d)
+			}
+			if d != nil {
+				names = append(names, d.Name+" (dir)")
+				dirs = append(dirs, d)
+				continue
+			}
+			names = append(names, obj.Name())
+		}
+		if err := iter.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return names, dirs
+	}
+
+	names, dirs := list(bucket.List(ctx, ListDelimiter("/"), ListPageSize(10)))
+	if want := []string{"a", "dir/ (dir)"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Fatalf("top level: got %v, want %v", names, want)
+	}
+	names, _ = list(dirs[0].List(ctx))
+	if want := []string{"dir/sub/ (dir)", "dir/x", "dir/y"}; fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("dir/: got %v, want %v", names, want)
+	}
+
+	iter := bucket.List(ctx, ListDelimiter("/"), ListPageSize(10), ListPrefix("dir/"))
+	for iter.Next() {
+		obj := iter.Object()
+		if !obj.IsDir() {
+			continue
+		}
+		if err := obj.Delete(ctx); err == nil {
+			t.Errorf("%s: Delete succeeded on a folder", obj.Name())
+		}
+		if _, err := ioutil.ReadAll(obj.NewReader(ctx)); err == nil {
+			t.Errorf("%s: read succeeded on a folder", obj.Name())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStandardInfoKeys(t *testing.T) {
+	expires := time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+	in := &Attrs{
+		ContentDisposition: `attachment; filename="report.pdf"`,
+		CacheControl:       "max-age=3600",
+		Expires:            expires,
+		Info:               map[string]string{"color": "blue"},
+	}
+	w := (&Writer{}).withAttrs(in)
+	want := map[string]string{
+		"color":                  "blue",
+		"b2-content-disposition": `attachment; filename="report.pdf"`,
+		"b2-cache-control":       "max-age=3600",
+		"b2-expires":             "Sat, 01 Jun 2030 12:00:00 GMT",
+	}
+	if fmt.Sprint(w.info) != fmt.Sprint(want) {
+		t.Errorf("info: got %v, want %v", w.info, want)
+	}
+
+	out, err := attrsFromInfo(&testFileInfo{info: w.info})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.ContentDisposition != in.ContentDisposition || out.CacheControl != in.CacheControl || !out.Expires.Equal(expires) {
+		t.Errorf("got %+v, want %+v", out, in)
+	}
+	if fmt.Sprint(out.Info) != fmt.Sprint(in.Info) {
+		t.Errorf("Info: got %v, want %v", out.Info, in.Info)
+	}
+
+	// Malformed dates are left alone.
+	out, err = attrsFromInfo(&testFileInfo{info: map[string]string{"b2-expires": "soon"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Expires.IsZero() || out.Info["b2-expires"] != "soon" {
+		t.Errorf("malformed expiry: got %v and %v", out.Expires, out.Info)
+	}
+}
+
+func TestRawInfo(t *testing.T) {
+	stored := map[string]string{
+		"color":                    "blue",
+		"src_last_modified_millis": "1000",
+		"b2-content-language":      "fr",
+		"b2-cache-control":         "no-cache",
+	}
+	attrs, err := attrsFromInfo(&testFileInfo{info: stored})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(attrs.RawInfo) != fmt.Sprint(stored) {
+		t.Errorf("RawInfo: got %v, want %v", attrs.RawInfo, stored)
+	}
+
+	// Reserved keys survive even when Info is rebuilt from scratch.
+	attrs.Info = map[string]string{"color": "red"}
+	attrs.CacheControl = "max-age=60"
+	w := (&Writer{}).withAttrs(attrs)
+	want := map[string]string{
+		"color":                    "red",
+		"src_last_modified_millis": "1000",
+		"b2-content-language":      "fr",
+		"b2-cache-control":         "max-age=60",
+	}
+	if fmt.Sprint(w.info) != fmt.Sprint(want) {
+		t.Errorf("info: got %v, want %v", w.info, want)
+	}
+
+	// Keys with fields of their own are cleared with the field.
+	attrs.CacheControl = ""
+	w = (&Writer{}).withAttrs(attrs)
+	delete(want, "b2-cache-control")
+	if fmt.Sprint(w.info) != fmt.Sprint(want) {
+		t.Errorf("info with CacheControl cleared: got %v, want %v", w.info, want)
+	}
+
+	ctx := context.Background()
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := make(map[string]string)
+	for i := 0; i < maxInfoKeys; i++ {
+		info[fmt.Sprintf("k%d", i)] = "v"
+	}
+	w = bucket.Object("full").NewWriter(ctx, WithAttrsOption(&Attrs{Info: info, CacheControl: "no-cache"}))
+	w.Write([]byte("data"))
+	err = w.Close()
+	var ierr *InfoLimitError
+	if !errors.As(err, &ierr) || ierr.Keys != maxInfoKeys+1 {
+		t.Errorf("too many info keys: got %v, want an InfoLimitError for %d keys", err, maxInfoKeys+1)
+	}
+}
+
+type rtFunc func(*http.Request) (*http.Response, error)
+
+func (f rtFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestProxyTransport(t *testing.T) {
+	var got, auth string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.URL.String()
+		auth = r.Header.Get("Proxy-Authorization")
+	}))
+	defer proxy.Close()
+
+	u, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.User = url.UserPassword("user", "pass")
+	o := clientOptions{}
+	ProxyURL(u)(&o)
+	rt, err := proxyTransport(o.transport, o.proxy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest("GET", "http://pod-000-1000-00.backblaze.com/b2api/v1/b2_upload_file", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got != req.URL.String() {
+		t.Errorf("proxy got request for %q, want %q", got, req.URL)
+	}
+	if auth == "" {
+		t.Errorf("proxy got no credentials")
+	}
+	if http.DefaultTransport.(*http.Transport).Proxy == nil {
+		t.Errorf("default transport was modified")
+	}
+
+	var other rtFunc = func(*http.Request) (*http.Response, error) { return nil, nil }
+	if _, err := proxyTransport(other, o.proxy); err == nil {
+		t.Errorf("proxyTransport on a non-http.Transport: got nil error")
+	}
+}
+
+func TestKeepAlive(t *testing.T) {
+	c, err := newClient(clientOptions{}, []ClientOption{KeepAlive(8, time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr, ok := c.opts.transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("transport: got %T, want *http.Transport", c.opts.transport)
+	}
+	if tr.MaxIdleConnsPerHost != 8 {
+		t.Errorf("MaxIdleConnsPerHost: got %d, want 8", tr.MaxIdleConnsPerHost)
+	}
+	if tr.IdleConnTimeout != time.Minute {
+		t.Errorf("IdleConnTimeout: got %v, want 1m", tr.IdleConnTimeout)
+	}
+	if def := http.DefaultTransport.(*http.Transport); def.MaxIdleConnsPerHost == 8 {
+		t.Errorf("default transport was modified")
+	}
+
+	var other rtFunc = func(*http.Request) (*http.Response, error) { return nil, nil }
+	if _, err := newClient(clientOptions{}, []ClientOption{Transport(other), KeepAlive(8, 0)}); err == nil {
+		t.Errorf("KeepAlive on a non-http.Transport: got nil error")
+	}
+}
+
+func TestTransferSHA1(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := strings.Repeat("abcdefghij", 100)
+	want := fmt.Sprintf("%x", sha1.Sum([]byte(data)))
+	table := []struct {
+		name  string
+		write func(w *Writer) error
+		want  string
+	}{
+		{
+			name: "small write",
+			write: func(w *Writer) error {
+				_, err := io.WriteString(w, data)
+				return err
+			},
+			want: want,
+		},
+		{
+			name: "large write",
+			write: func(w *Writer) error {
+				w.ChunkSize = 300
+				_, err := io.WriteString(w, data)
+				return err
+			},
+			want: want,
+		},
+		{
+			name: "large write with file buffer",
+			write: func(w *Writer) error {
+				w.ChunkSize = 300
+				w.UseFileBuffer = true
+				w.ConcurrentUploads = 3
+				_, err := io.WriteString(w, data)
+				return err
+			},
+			want: want,
+		},
+		{
+			name: "large read from",
+			write: func(w *Writer) error {
+				w.ChunkSize = 300
+				_, err := w.ReadFrom(strings.NewReader(data))
+				return err
+			},
+			want: want,
+		},
+		{
+			name: "large read from without file sha1",
+			write: func(w *Writer) error {
+				w.ChunkSize = 300
+				WithoutLargeFileSHA1()(w)
+				_, err := w.ReadFrom(strings.NewReader(data))
+				return err
+			},
+		},
+	}
+	for _, e := range table {
+		w := bucket.Object(e.name).NewWriter(ctx)
+		if err := e.write(w); err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		if got := w.SHA1(); got != "" {
+			t.Errorf("%s: SHA1 before Close: got %q, want empty", e.name, got)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		if got := w.SHA1(); got != e.want {
+			t.Errorf("%s: Writer.SHA1: got %q, want %q", e.name, got, e.want)
+		}
+
+		r := bucket.Object(e.name).NewReader(ctx)
+		if _, err := io.Copy(ioutil.Discard, r); err != nil {
+			t.Fatalf("%s: %v", e.name, err)
+		}
+		r.Close()
+		if got := r.SHA1(); got != want {
+			t.Errorf("%s: Reader.SHA1: got %q, want %q", e.name, got, want)
+		}
+	}
+}
+
+func TestRelocate(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Photos/a", "Photos/b", "Photos/2018/c", "Photosynthesis", "other"} {
+		if _, _, err := writeFile(ctx, bucket, name, 10, 1e8); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := bucket.Relocate(ctx, "Photos/", "Photos/", RelocateConcurrency(2)); err == nil {
+		t.Error("Relocate to the same prefix: got nil error")
+	}
+	if err := bucket.Relocate(ctx, "Photos/", "photos/", RelocateConcurrency(2), RelocateDeleteSources()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	iter := bucket.List(ctx, ListPageSize(100))
+	for iter.Next() {
+		got = append(got, iter.Object().Name())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	want := []string{"Photosynthesis", "other", "photos/2018/c", "photos/a", "photos/b"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("after Relocate: got %v, want %v", got, want)
+	}
+}
+
+func TestListSince(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{
+		b2i: &testRoot{
+			bucketMap: make(map[string]map[string]string),
+			errs:      &errCont{},
+		},
+	}
+	start := time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC)
+	tb := &testBucket{
+		n: bucketName,
+		versions: []b2FileInterface{
+			&testFile{n: "a", t: start, a: "upload"},
+			&testFile{n: "a", t: start.Add(2 * time.Hour), a: "hide"},
+			&testFile{n: "b", t: start.Add(time.Hour), a: "upload"},
+			&testFile{n: "c", t: start.Add(3 * time.Hour), a: "upload"},
+		},
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+
+	iter := bucket.List(ctx, ListSince(start.Add(time.Hour)))
+	var got []string
+	for iter.Next() {
+		attrs, err := iter.Object().Attrs(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s:%v", iter.Object().Name(), attrs.Status == Hider))
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a:true", "c:false"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ListSince: got %v, want %v", got, want)
+	}
+	if got, want := iter.Watermark(), start.Add(3*time.Hour); !got.Equal(want) {
+		t.Errorf("Watermark: got %v, want %v", got, want)
+	}
+}
+
+func TestWriterStats(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs: &errCont{
+					errMap: map[string]map[int]error{
+						"uploadPart": {0: testError{reupload: true}},
+					},
+				},
+			},
+			options: clientOptions{clock: &testClock{}},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, &BucketAttrs{Type: Private})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bucket.Object(largeFileName).NewWriter(ctx)
+	w.ChunkSize = 300
+	if _, err := io.WriteString(w, strings.Repeat("0123456789", 100)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	st := w.Stats()
+	if len(st.Parts) != 4 {
+		t.Fatalf("got %d parts, want 4", len(st.Parts))
+	}
+	for i, p := range st.Parts {
+		if p.Number != i+1 {
+			t.Errorf("part %d: got number %d", i, p.Number)
+		}
+	}
+	if st.Bytes != 1000 {
+		t.Errorf("Bytes: got %d, want 1000", st.Bytes)
+	}
+	if st.Retries != 1 {
+		t.Errorf("Retries: got %d, want 1", st.Retries)
+	}
+	// The test clock only moves when the writer sleeps before its retry.
+	if st.Bandwidth <= 0 {
+		t.Errorf("Bandwidth: got %v, want > 0", st.Bandwidth)
+	}
+
+	rec := httptest.NewRecorder()
+	client.addWriter(w)
+	client.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if !strings.Contains(rec.Body.String(), "4 parts, 1000 bytes, 1 retries") {
+		t.Errorf("status page does not include writer stats:\n%s", rec.Body.String())
+	}
+}
+
+func TestBucketCache(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: map[string]map[string]string{
+			"a": {},
+			"b": {},
+		},
+		errs: &errCont{},
+	}
+	clk := &testClock{}
+	client := &Client{
+		backend: &beRoot{
+			b2i:     root,
+			options: clientOptions{clock: clk},
+		},
+		opts: clientOptions{bucketTTL: time.Minute},
+	}
+
+	bs, err := client.ListBuckets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bs) != 2 {
+		t.Fatalf("ListBuckets: got %d buckets, want 2", len(bs))
+	}
+	for _, name := range []string{"a", "b"} {
+		if _, err := client.Bucket(ctx, name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := client.ListBuckets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if root.lists != 1 {
+		t.Errorf("after cached lookups: got %d listings, want 1", root.lists)
+	}
+
+	c, err := client.NewBucket(ctx, "c", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Bucket(ctx, "c"); err != nil {
+		t.Fatal(err)
+	}
+	if bs, _ := client.ListBuckets(ctx); len(bs) != 3 {
+		t.Errorf("after NewBucket: got %d buckets, want 3", len(bs))
+	}
+	if err := c.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	delete(root.bucketMap, "c")
+	if _, err := client.Bucket(ctx, "c"); !IsNotExist(err) {
+		t.Errorf("Bucket after Delete: got %v, want not found", err)
+	}
+	lists := root.lists
+
+	if _, err := client.Bucket(WithoutBucketCache(ctx), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if root.lists != lists+1 {
+		t.Errorf("WithoutBucketCache: got %d listings, want %d", root.lists, lists+1)
+	}
+
+	clk.now = clk.now.Add(time.Minute)
+	if _, err := client.ListBuckets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if root.lists != lists+2 {
+		t.Errorf("after expiry: got %d listings, want %d", root.lists, lists+2)
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	newClient := func() *Client {
+		return &Client{
+			backend: &beRoot{
+				b2i: &testRoot{
+					bucketMap: make(map[string]map[string]string),
+					errs:      &errCont{},
+				},
+			},
+		}
+	}
+
+	// Close waits for outstanding writers.
+	client := newClient()
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bucket.Object(smallFileName).NewWriter(ctx)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	closed := make(chan error)
+	go func() { closed <- client.Close(ctx) }()
+	select {
+	case err := <-closed:
+		t.Fatalf("Close returned %v before the writer was closed", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	w2 := bucket.Object("other").NewWriter(ctx)
+	if _, err := io.WriteString(w2, "hello"); err != ErrClientClosed {
+		t.Errorf("Write during Close: got %v, want %v", err, ErrClientClosed)
+	}
+	if err := w2.Close(); err != ErrClientClosed {
+		t.Errorf("Writer.Close during Close: got %v, want %v", err, ErrClientClosed)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if _, err := client.Bucket(ctx, bucketName); err != ErrClientClosed {
+		t.Errorf("Bucket after Close: got %v, want %v", err, ErrClientClosed)
+	}
+	if err := bucket.Object(smallFileName).Delete(ctx); err != ErrClientClosed {
+		t.Errorf("Delete after Close: got %v, want %v", err, ErrClientClosed)
+	}
+
+	// Close cancels outstanding writers when its context is done.
+	client = newClient()
+	bucket, err = client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = bucket.Object(smallFileName).NewWriter(ctx)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	cctx, ccancel := context.WithCancel(ctx)
+	ccancel()
+	if err := client.Close(cctx); err != context.Canceled {
+		t.Errorf("Close: got %v, want %v", err, context.Canceled)
+	}
+	if err := w.Close(); err != ErrClientClosed {
+		t.Errorf("Writer.Close after Close: got %v, want %v", err, ErrClientClosed)
+	}
+}
+
+func TestDefaultOptions(t *testing.T) {
+	ctx := context.Background()
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	for _, opt := range []ClientOption{
+		WithDefaultWriterOptions(UploadConcurrency(3), UploadChunkSize(1e6)),
+		WithDefaultWriterOptions(UploadFileBuffer("/scratch")),
+		WithDefaultReaderOptions(DownloadConcurrency(4), DownloadChunkSize(1e5)),
+	} {
+		opt(&client.opts)
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := bucket.Object(smallFileName)
+
+	w := obj.NewWriter(ctx, UploadConcurrency(5))
+	if w.ConcurrentUploads != 5 || w.ChunkSize != 1e6 || !w.UseFileBuffer || w.FileBufferDir != "/scratch" {
+		t.Errorf("writer: got ConcurrentUploads %d, ChunkSize %d, UseFileBuffer %v, FileBufferDir %q", w.ConcurrentUploads, w.ChunkSize, w.UseFileBuffer, w.FileBufferDir)
+	}
+	r := obj.NewReader(ctx, DownloadChunkSize(2e5))
+	defer r.Close()
+	if r.ConcurrentDownloads != 4 || r.ChunkSize != 2e5 {
+		t.Errorf("reader: got ConcurrentDownloads %d, ChunkSize %d", r.ConcurrentDownloads, r.ChunkSize)
+	}
+}
+
+func TestZeroByteReadWrite(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const emptySHA1 = "da39a3ee5e6b4b0d3255bfef95601890afd80709"
+
+	obj := bucket.Object("empty")
+	w := obj.NewWriter(ctx)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := w.SHA1(); got != emptySHA1 {
+		t.Errorf("Writer.SHA1: got %q, want %q", got, emptySHA1)
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Size != 0 {
+		t.Errorf("Attrs: got size %d, want 0", attrs.Size)
+	}
+
+	r := obj.NewReader(ctx)
+	defer r.Close()
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Read: got %q, want nothing", got)
+	}
+	if err, ok := r.Verify(); err != nil || !ok {
+		t.Errorf("Verify: got (%v, %v), want (nil, true)", err, ok)
+	}
+	if got := r.SHA1(); got != emptySHA1 {
+		t.Errorf("Reader.SHA1: got %q, want %q", got, emptySHA1)
+	}
+
+	// An empty range needs no request at all.
+	bucket, err = client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tb := bucket.b.(*beBucket).b2bucket.(*testBucket)
+	n := len(tb.ranges)
+	rr := bucket.Object("empty").NewRangeReader(ctx, 5, 0)
+	if _, err := rr.Read(make([]byte, 10)); err != io.EOF {
+		t.Errorf("empty range: got %v, want EOF", err)
+	}
+	if len(tb.ranges) != n {
+		t.Errorf("empty range: got %d downloads, want none", len(tb.ranges)-n)
+	}
+}
+
+func TestReadNthNewest(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{b2i: &testRoot{}}
+	body := func(s string) *string { return &s }
+	tb := &testBucket{
+		n:    bucketName,
+		errs: &errCont{},
+		versions: []b2FileInterface{
+			&testFile{n: "a", a: "hide"},
+			&testFile{n: "a", a: "upload", body: body("third")},
+			&testFile{n: "a", a: "upload", body: body("second")},
+			&testFile{n: "a", a: "upload", body: body("first")},
+			&testFile{n: "ab", a: "upload", body: body("other")},
+		},
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+	for n, want := range []string{"third", "second", "first"} {
+		r := bucket.Object("a").NewReader(ctx, ReadNthNewest(n))
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("ReadNthNewest(%d): %v", n, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("ReadNthNewest(%d): got %q, want %q", n, got, want)
+		}
+	}
+	r := bucket.Object("a").NewReader(ctx, ReadNthNewest(3))
+	defer r.Close()
+	if _, err := ioutil.ReadAll(r); !IsNotExist(err) {
+		t.Errorf("ReadNthNewest(3): got %v, want not found", err)
+	}
+}
+
+type panicBuffer struct{ writeBuffer }
+
+func (panicBuffer) Reader() (readResetter, error) { panic("boom") }
+
+func TestWriterPanic(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := bucket.Object(largeFileName).NewWriter(ctx)
+	w.ChunkSize = 100
+	w.ConcurrentUploads = 2
+	w.newBuffer = func() (writeBuffer, error) { return panicBuffer{newMemoryBuffer()}, nil }
+	io.WriteString(w, strings.Repeat("x", 1000))
+	err = w.Close()
+	perr, ok := err.(*PanicError)
+	if !ok {
+		t.Fatalf("Close: got %v, want a *PanicError", err)
+	}
+	if perr.Value != "boom" || len(perr.Stack) == 0 {
+		t.Errorf("got panic %v with %d bytes of stack", perr.Value, len(perr.Stack))
+	}
+}
+
+func TestCloseSemantics(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs: &errCont{
+					errMap: map[string]map[int]error{
+						"uploadPart": {0: testError{}},
+					},
+				},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A writer whose part fails reports the failure from every Close.
+	w := bucket.Object(largeFileName).NewWriter(ctx)
+	w.ChunkSize = 100
+	for i := 0; i < 10 && err == nil; i++ {
+		_, err = io.WriteString(w, strings.Repeat("x", 100))
+	}
+	first := w.Close()
+	if _, ok := first.(testError); !ok {
+		t.Fatalf("Close: got %v, want the part's error", first)
+	}
+	if err := w.Close(); err != first {
+		t.Errorf("second Close: got %v, want %v", err, first)
+	}
+
+	// A writer that succeeds refuses writes after Close.
+	w = bucket.Object(smallFileName).NewWriter(ctx)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("second Close: got %v, want nil", err)
+	}
+	if _, err := io.WriteString(w, "more"); err != errWriterClosed {
+		t.Errorf("Write after Close: got %v, want %v", err, errWriterClosed)
+	}
+
+	r := bucket.Object(smallFileName).NewReader(ctx)
+	if _, err := ioutil.ReadAll(r); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := r.Close(); err != nil {
+			t.Errorf("Reader.Close %d: got %v, want nil", i, err)
+		}
+	}
+	if _, err := r.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read after EOF and Close: got %v, want EOF", err)
+	}
+	r = bucket.Object(smallFileName).NewReader(ctx)
+	r.Close()
+	if _, err := r.Read(make([]byte, 1)); err != errReaderClosed {
+		t.Errorf("Read after Close: got %v, want %v", err, errReaderClosed)
+	}
+}
+
+func TestCapExceeded(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	code := "transaction_cap_exceeded"
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v1/b2_authorize_account":
+			fmt.Fprintf(w, `{"accountId": "id", "authorizationToken": "token", "apiUrl": %q, "downloadUrl": %q}`, srv.URL, srv.URL)
+		case "/b2api/v1/b2_list_buckets":
+			w.Header().Set("X-Bz-Request-Id", "capped")
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprintf(w, `{"status": 403, "code": %q, "message": "Cap exceeded"}`, code)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ctx, "id", "key", APIBase(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.ListBuckets(ctx)
+	var cerr *CapExceededError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("ListBuckets: got %v, want a *CapExceededError", err)
+	}
+	if cerr.Cap != TransactionCap {
+		t.Errorf("Cap: got %q, want %q", cerr.Cap, TransactionCap)
+	}
+	if got := ResponseHeader(err).Get("X-Bz-Request-Id"); got != "capped" {
+		t.Errorf("ResponseHeader: got request ID %q, want %q", got, "capped")
+	}
+
+	// The bare code is what B2 sends for uploads over the storage cap.
+	code = "cap_exceeded"
+	_, err = client.ListBuckets(ctx)
+	if !errors.As(err, &cerr) || cerr.Cap != StorageCap {
+		t.Errorf("cap_exceeded: got %v, want a *CapExceededError for %q", err, StorageCap)
+	}
+}
+
+func TestMissingCapability(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v1/b2_authorize_account":
+			fmt.Fprintf(w, `{"accountId": "id", "authorizationToken": "token", "apiUrl": %q, "downloadUrl": %q}`, srv.URL, srv.URL)
+		case "/b2api/v1/b2_list_buckets":
+			fmt.Fprint(w, `{"buckets": [{"bucketId": "id1", "bucketName": "one"}]}`)
+		default:
+			w.Header().Set("X-Bz-Request-Id", "denied")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"status": 401, "code": "unauthorized", "message": "unauthorized"}`)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ctx, "id", "key", APIBase(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.Bucket(ctx, "one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = client.ListKeys(ctx, 10, "")
+	var merr *MissingCapabilityError
+	if !errors.As(err, &merr) {
+		t.Fatalf("ListKeys: got %v, want a *MissingCapabilityError", err)
+	}
+	if merr.Capability != "listKeys" {
+		t.Errorf("ListKeys: got capability %q, want listKeys", merr.Capability)
+	}
+	if got := ResponseHeader(err).Get("X-Bz-Request-Id"); got != "denied" {
+		t.Errorf("ResponseHeader: got request ID %q, want %q", got, "denied")
+	}
+	if err := bucket.Delete(ctx); !errors.As(err, &merr) || merr.Capability != "deleteBuckets" {
+		t.Errorf("Delete: got %v, want missing deleteBuckets", err)
+	}
+
+	if _, err := NewClient(ctx, "id", "key", APIBase(srv.URL+"/nowhere")); errors.As(err, &merr) {
+		t.Errorf("NewClient with bad credentials: got %v", err)
+	}
+}
+
+func TestListEmptyPages(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{b2i: &testRoot{}}
+	tb := &testBucket{
+		n:     bucketName,
+		errs:  &errCont{},
+		files: map[string]string{"a": "", "b": "", "c": ""},
+		empty: 2,
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+	var got []string
+	iter := bucket.List(ctx, ListPageSize(2))
+	for iter.Next() {
+		got = append(got, iter.Object().Name())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// A continuation that never moves is an error, not an endless loop.
+	tb.empty = -1
+	iter = bucket.List(ctx, ListPageSize(2))
+	for iter.Next() {
+		t.Errorf("got %s, want nothing", iter.Object().Name())
+	}
+	if iter.Err() == nil {
+		t.Errorf("stuck listing: got nil error")
+	}
+}
+
+func TestAdjacentNames(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{b2i: &testRoot{}}
+	files := map[string]string{
+		"fo":      "1",
+		"foo":     "22",
+		"foo0":    "333",
+		"foo/bar": "4444",
+	}
+	body := func(s string) *string { return &s }
+	tb := &testBucket{
+		n:     bucketName,
+		errs:  &errCont{},
+		files: files,
+		versions: []b2FileInterface{
+			&testFile{n: "foo", a: "upload", body: body("22")},
+			&testFile{n: "foo/bar", a: "upload", body: body("4444")},
+			&testFile{n: "foo0", a: "upload", body: body("333")},
+		},
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+	for name, want := range files {
+		attrs, err := bucket.Object(name).Attrs(ctx)
+		if err != nil {
+			t.Errorf("%s: Attrs: %v", name, err)
+			continue
+		}
+		if attrs.Name != name {
+			t.Errorf("%s: Attrs: got name %q", name, attrs.Name)
+		}
+		r := bucket.Object(name).NewReader(ctx)
+		got, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Errorf("%s: Read: %v", name, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("%s: Read: got %q, want %q", name, got, want)
+		}
+	}
+	// Only "foo" itself has an earlier version to find, not "foo/bar" or
+	// "foo0", which sort after it.
+	r := bucket.Object("foo").NewReader(ctx, ReadNthNewest(1))
+	defer r.Close()
+	if _, err := ioutil.ReadAll(r); !IsNotExist(err) {
+		t.Errorf("ReadNthNewest(1): got %v, want not found", err)
+	}
+}
+
+func TestClientBase(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var auths int
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v1/b2_authorize_account":
+			auths++
+			fmt.Fprintf(w, `{"accountId": "id", "authorizationToken": "token", "apiUrl": %q, "downloadUrl": %q}`, srv.URL, srv.URL)
+		case "/b2api/v1/b2_list_buckets":
+			if r.Header.Get("Authorization") != "token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				fmt.Fprint(w, `{"status": 401, "code": "unauthorized", "message": "bad token"}`)
+				return
+			}
+			fmt.Fprint(w, `{"buckets": [{"bucketId": "id1", "bucketName": "one"}]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ctx, "id", "key", APIBase(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := client.Base()
+	if b == nil {
+		t.Fatal("Base: got nil")
+	}
+	buckets, err := b.ListBuckets(ctx, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 1 || buckets[0].Name != "one" {
+		t.Errorf("ListBuckets: got %v, want bucket one", buckets)
+	}
+	if auths != 1 {
+		t.Errorf("got %d authorizations, want 1", auths)
+	}
+
+	fake := &Client{backend: &beRoot{b2i: &testRoot{}}}
+	if fake.Base() != nil {
+		t.Error("Base of a test client: got non-nil")
+	}
+}
+
+func TestObjectByID(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &beRoot{b2i: &testRoot{}}
+	tb := &testBucket{
+		n:     bucketName,
+		errs:  &errCont{},
+		files: map[string]string{"foo": "contents"},
+	}
+	bucket := &Bucket{
+		b: &beBucket{b2bucket: tb, ri: root},
+		r: root,
+		c: &Client{backend: root},
+	}
+	obj, err := bucket.ObjectByID(ctx, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Name() != "foo" || obj.ID() != "foo" {
+		t.Errorf("ObjectByID: got name %q, id %q", obj.Name(), obj.ID())
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Size != int64(len("contents")) {
+		t.Errorf("Attrs: got size %d, want %d", attrs.Size, len("contents"))
+	}
+	r := obj.NewReader(ctx, ReadVersion(obj.ID()))
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil || string(got) != "contents" {
+		t.Errorf("NewReader: got %q, %v", got, err)
+	}
+	if err := obj.Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := tb.files["foo"]; ok {
+		t.Error("Delete: version still exists")
+	}
+}
+
+func TestAccountInfo(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"accountId": "acct", "authorizationToken": "token", "apiUrl": %q, "downloadUrl": "https://f000.example.com",
+			"recommendedPartSize": 100000000, "absoluteMinimumPartSize": 5000000,
+			"allowed": {"capabilities": ["listBuckets", "readFiles"], "bucketId": "bid", "namePrefix": "logs/"}}`, srv.URL)
+	}))
+	defer srv.Close()
+
+	client, err := NewClient(ctx, "id", "key", APIBase(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &AccountInfo{
+		AccountID:               "acct",
+		Capabilities:            []string{"listBuckets", "readFiles"},
+		BucketID:                "bid",
+		Prefix:                  "logs/",
+		APIURL:                  srv.URL,
+		DownloadURL:             "https://f000.example.com",
+		RecommendedPartSize:     1e8,
+		AbsoluteMinimumPartSize: 5e6,
+	}
+	if got := client.AccountInfo(); !reflect.DeepEqual(got, want) {
+		t.Errorf("AccountInfo: got %+v, want %+v", got, want)
+	}
+}
+
+func TestNotificationHandler(t *testing.T) {
+	const secret = "sekrit"
+	body := `{"events": [{
+		"accountId": "acct", "bucketId": "bid", "bucketName": "bucket",
+		"eventId": "ev1", "eventTimestamp": 1684793309123,
+		"eventType": "b2:ObjectCreated:Upload", "eventVersion": 1,
+		"matchedRuleName": "rule", "objectName": "a/b.txt",
+		"objectSize": 42, "objectVersionId": "4_zid"
+	}]}`
+	sign := func(secret, body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "v1=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var got []NotificationEvent
+	var fail error
+	h := &NotificationHandler{
+		SigningSecret: secret,
+		Handle: func(_ context.Context, es []NotificationEvent) error {
+			got = append(got, es...)
+			return fail
+		},
+	}
+
+	table := []struct {
+		method, body, sig string
+		fail              error
+		want              int
+	}{
+		{method: "POST", body: body, sig: sign(secret, body), want: 200},
+		{method: "POST", body: body, sig: "v2=abc, " + sign(secret, body), want: 200},
+		{method: "POST", body: body, sig: sign("wrong", body), want: 401},
+		{method: "POST", body: body, want: 401},
+		{method: "POST", body: body + " ", sig: sign(secret, body), want: 401},
+		{method: "POST", body: "{", sig: sign(secret, "{"), want: 400},
+		{method: "POST", body: body, sig: sign(secret, body), fail: errors.New("busy"), want: 500},
+		{method: "GET", want: 405},
+	}
+	for i, e := range table {
+		got, fail = nil, e.fail
+		req := httptest.NewRequest(e.method, "/notify", strings.NewReader(e.body))
+		if e.sig != "" {
+			req.Header.Set(NotificationSignatureHeader, e.sig)
+		}
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, req)
+		if rw.Code != e.want {
+			t.Errorf("%d: got status %d, want %d", i, rw.Code, e.want)
+		}
+		if e.fail != nil && strings.Contains(rw.Body.String(), e.fail.Error()) {
+			t.Errorf("%d: response %q reveals the error", i, rw.Body.String())
+		}
+		if e.want == 200 && len(got) != 1 {
+			t.Errorf("%d: got %d events, want 1", i, len(got))
+		}
+	}
+
+	got = nil
+	req := httptest.NewRequest("POST", "/notify", strings.NewReader(body))
+	req.Header.Set(NotificationSignatureHeader, sign(secret, body))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	want := NotificationEvent{
+		AccountID:       "acct",
+		BucketID:        "bid",
+		BucketName:      "bucket",
+		EventID:         "ev1",
+		EventTime:       time.Unix(1684793309, 123e6),
+		EventType:       "b2:ObjectCreated:Upload",
+		EventVersion:    1,
+		MatchedRuleName: "rule",
+		ObjectName:      "a/b.txt",
+		ObjectSize:      42,
+		ObjectVersionID: "4_zid",
+	}
+	if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+		t.Errorf("got events %+v, want %+v", got, want)
+	}
+	if got[0].IsTest() {
+		t.Error("IsTest: got true for an upload")
+	}
+
+	// Without a secret, deliveries are rejected, unless checks are skipped.
+	h.SigningSecret = ""
+	got = nil
+	req = httptest.NewRequest("POST", "/notify", strings.NewReader(body))
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	if rw.Code != 500 || len(got) != 0 {
+		t.Errorf("delivery without a secret: got status %d and %d events, want 500 and none", rw.Code, len(got))
+	}
+	h.InsecureSkipVerify = true
+	req = httptest.NewRequest("POST", "/notify", strings.NewReader(body))
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, req)
+	if rw.Code != 200 || len(got) != 1 {
+		t.Errorf("unsigned delivery with InsecureSkipVerify: got status %d and %d events", rw.Code, len(got))
+	}
+}
+
+func TestPublishManifest(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := bucket.PublishManifest(ctx, "set/MANIFEST", []ManifestFile{
+		{Name: "set/a", Body: strings.NewReader("aaa")},
+		{Name: "set/b", Body: strings.NewReader("bb")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := bucket.ReadManifest(ctx, "set/MANIFEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Manifest{Files: []ManifestEntry{
+		{Name: "set/a", FileID: "set/a", Size: 3, SHA1: fmt.Sprintf("%x", sha1.Sum([]byte("aaa")))},
+		{Name: "set/b", FileID: "set/b", Size: 2, SHA1: fmt.Sprintf("%x", sha1.Sum([]byte("bb")))},
+	}}
+	if !reflect.DeepEqual(m, want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("manifest: got %+v and %+v, want %+v", m, got, want)
+	}
+
+	broken := errors.New("broken")
+	_, err = bucket.PublishManifest(ctx, "bad/MANIFEST", []ManifestFile{
+		{Name: "bad/a", Body: strings.NewReader("aaa")},
+		{Name: "bad/b", Body: errReader{broken}},
+	})
+	if err != broken {
+		t.Errorf("failed publish: got error %v, want %v", err, broken)
+	}
+	gmux.Lock()
+	defer gmux.Unlock()
+	for _, name := range []string{"bad/a", "bad/b", "bad/MANIFEST"} {
+		if _, ok := root.bucketMap[bucketName][name]; ok {
+			t.Errorf("%s exists after a failed publish", name)
+		}
+	}
+}
+
+func TestConcatenate(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap:   make(map[string]map[string]string),
+		errs:        &errCont{},
+		minPartSize: 3,
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := root.bucketMap[bucketName]
+	files["log/1"] = "aaaa"
+	files["log/2"] = "bbb"
+	files["log/3"] = "c"
+
+	table := []struct {
+		srcs []string
+		want string
+		fail bool
+	}{
+		{srcs: []string{"log/1", "log/2", "log/3"}, want: "aaaabbbc"},
+		{srcs: []string{"log/3", "log/1"}, fail: true},
+		{srcs: []string{"log/2"}, want: "bbb"},
+	}
+	for i, e := range table {
+		var srcs []*Object
+		for _, name := range e.srcs {
+			srcs = append(srcs, bucket.Object(name))
+		}
+		dst := fmt.Sprintf("compacted/%d", i)
+		_, err := bucket.Concatenate(ctx, dst, srcs...)
+		if e.fail {
+			if err == nil {
+				t.Errorf("Concatenate(%v): got no error", e.srcs)
+			}
+			if _, ok := files[dst]; ok {
+				t.Errorf("Concatenate(%v): %s exists after failure", e.srcs, dst)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Concatenate(%v): %v", e.srcs, err)
+			continue
+		}
+		if got := files[dst]; got != e.want {
+			t.Errorf("Concatenate(%v): got %q, want %q", e.srcs, got, e.want)
+		}
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := root.bucketMap[bucketName]
+	files["huge"] = "0123456789"
+
+	table := []struct {
+		offset, length int64
+		want           string
+		fail           bool
+	}{
+		{offset: 2, length: 3, want: "234"},
+		{offset: 7, length: -1, want: "789"},
+		{offset: 8, length: 10, want: "89"},
+		{offset: 11, length: 1, fail: true},
+		{offset: 10, length: -1, fail: true},
+	}
+	for i, e := range table {
+		dst := fmt.Sprintf("slice/%d", i)
+		_, err := bucket.Object("huge").CopyRange(ctx, dst, e.offset, e.length)
+		if e.fail {
+			if err == nil {
+				t.Errorf("CopyRange(%d, %d): got no error", e.offset, e.length)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("CopyRange(%d, %d): %v", e.offset, e.length, err)
+			continue
+		}
+		if got := files[dst]; got != e.want {
+			t.Errorf("CopyRange(%d, %d): got %q, want %q", e.offset, e.length, got, e.want)
+		}
+	}
+}
+
+type partRecorder struct {
+	beLargeFileInterface
+	parts [][3]int64
+}
+
+func (p *partRecorder) copyPart(_ context.Context, _ string, index int, offset, size int64) (int64, error) {
+	p.parts = append(p.parts, [3]int64{int64(index), offset, size})
+	return size, nil
+}
+
+func (p *partRecorder) finishLargeFile(context.Context) (beFileInterface, error) { return nil, nil }
+
+func TestCopyPartsSplitsLargeSpans(t *testing.T) {
+	src := &Object{f: &beFile{b2file: &testFile{n: "src"}}}
+	lf := &partRecorder{}
+	spans := []span{
+		{src: src, offset: 1e9, size: 12e9},
+		{src: src, size: 5},
+	}
+	if _, err := copyParts(context.Background(), lf, spans); err != nil {
+		t.Fatal(err)
+	}
+	want := [][3]int64{
+		{1, 1e9, 4e9},
+		{2, 5e9, 4e9},
+		{3, 9e9, 4e9},
+		{4, 0, 5},
+	}
+	if !reflect.DeepEqual(lf.parts, want) {
+		t.Errorf("parts: got %v, want %v", lf.parts, want)
+	}
+}
+
+func TestMetadataStore(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := root.bucketMap[bucketName]
+	files["doc"] = "hello"
+	ms := &MetadataStore{Bucket: bucket}
+
+	got, err := ms.Get(ctx, "doc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Get without sidecar: got %v, want nothing", got)
+	}
+
+	want := make(map[string]string)
+	for i := 0; i < 20; i++ {
+		want[fmt.Sprintf("key-%d", i)] = strings.Repeat("v", 1000)
+	}
+	if err := ms.Set(ctx, "doc", want); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["doc.meta"]; !ok {
+		t.Fatal("Set did not write doc.meta")
+	}
+	if err := ms.Update(ctx, "doc", func(info map[string]string) error {
+		delete(info, "key-0")
+		info["extra"] = "yes"
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	delete(want, "key-0")
+	want["extra"] = "yes"
+	if got, err := ms.Get(ctx, "doc"); err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("Get after Update: got %d keys (%v), want %d keys", len(got), err, len(want))
+	}
+
+	broken := errors.New("broken")
+	if err := ms.Update(ctx, "doc", func(map[string]string) error { return broken }); err != broken {
+		t.Errorf("failed Update: got %v, want %v", err, broken)
+	}
+
+	// A sidecar for another version of the object is ignored.
+	files["doc.meta"] = `{"fileId":"older","info":{"stale":"true"}}`
+	if got, err := ms.Get(ctx, "doc"); err != nil || len(got) != 0 {
+		t.Errorf("Get with stale sidecar: got %v, %v; want nothing", got, err)
+	}
+
+	if err := ms.Delete(ctx, "doc"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := files["doc.meta"]; ok {
+		t.Error("Delete left doc.meta")
+	}
+	if _, ok := files["doc"]; !ok {
+		t.Error("Delete removed doc")
+	}
+	if err := ms.Set(ctx, "missing", want); !IsNotExist(err) {
+		t.Errorf("Set on missing object: got %v, want not-exist error", err)
+	}
+}
+
+func TestListBudgetAndResume(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	clk := &testClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root, options: clientOptions{clock: clk}}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		root.bucketMap[bucketName][name] = name
+	}
+
+	list := func(n int, opts ...ListOption) ([]string, string) {
+		iter := bucket.List(ctx, append(opts, ListPageSize(2), ListBudget(60))...)
+		var got []string
+		for len(got) != n && iter.Next() {
+			got = append(got, iter.Object().Name())
+		}
+		if err := iter.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return got, iter.Cursor()
+	}
+
+	got, cur := list(3)
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("first listing: got %v, want %v", got, want)
+	}
+	if want := []time.Duration{time.Minute}; !reflect.DeepEqual(clk.calls(), want) {
+		t.Errorf("first listing: waited %v, want %v", clk.calls(), want)
+	}
+
+	got, cur = list(-1, ListResume(cur))
+	if want := []string{"d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("resumed listing: got %v, want %v", got, want)
+	}
+	// The resumed listing waits out the first one's budget before its only
+	// page.
+	if want := []time.Duration{time.Minute, time.Minute}; !reflect.DeepEqual(clk.calls(), want) {
+		t.Errorf("resumed listing: waited %v, want %v", clk.calls(), want)
+	}
+
+	if got, _ := list(-1, ListResume(cur)); len(got) != 0 {
+		t.Errorf("listing resumed at end: got %v, want nothing", got)
+	}
+
+	iter := bucket.List(ctx, ListResume("not a cursor"))
+	if iter.Next() || iter.Err() == nil {
+		t.Error("listing with a bad cursor: got no error")
+	}
+}
+
+func TestListSharded(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := root.bucketMap[bucketName]
+	for i := 0; i < 2000; i++ {
+		files[fmt.Sprintf("logs/%d/%05d", i%7, i)] = ""
+	}
+	files["logs"] = ""
+	files["other"] = ""
+
+	var mu sync.Mutex
+	seen := make(map[string]int)
+	if err := bucket.ListSharded(ctx, 8, func(o *Object) error {
+		mu.Lock()
+		defer mu.Unlock()
+		seen[o.Name()]++
+		return nil
+	}, ListPrefix("logs/"), ListPageSize(10)); err != nil {
+		t.Fatal(err)
+	}
+	if len(seen) != 2000 {
+		t.Errorf("got %d objects, want 2000", len(seen))
+	}
+	for name, n := range seen {
+		if !strings.HasPrefix(name, "logs/") || n != 1 {
+			t.Errorf("%s: listed %d times", name, n)
+		}
+	}
+
+	broken := errors.New("broken")
+	if err := bucket.ListSharded(ctx, 4, func(*Object) error { return broken }); err != broken {
+		t.Errorf("failing callback: got %v, want %v", err, broken)
+	}
+	if err := bucket.ListSharded(ctx, 4, func(*Object) error { return nil }, ListHidden()); err == nil {
+		t.Error("ListSharded with ListHidden: got no error")
+	}
+}
+
+func TestMidpoint(t *testing.T) {
+	table := []struct {
+		lo, hi, prefix string
+		ok             bool
+	}{
+		{lo: "a", hi: "c", ok: true},
+		{lo: "a", hi: "b", ok: true},
+		{lo: "logs/1", hi: "", prefix: "logs/", ok: true},
+		{lo: "logs/~~~", hi: "", prefix: "logs/", ok: true},
+		{lo: "logs/é", hi: "", prefix: "logs/"},
+		{lo: "b", hi: "b"},
+		{lo: "b", hi: "b "},
+	}
+	for _, e := range table {
+		mid, ok := midpoint(e.lo, e.hi, e.prefix)
+		if ok != e.ok {
+			t.Errorf("midpoint(%q, %q, %q): got %q, %v; want ok=%v", e.lo, e.hi, e.prefix, mid, ok, e.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if mid <= e.lo || (e.hi != "" && mid >= e.hi) || !strings.HasPrefix(mid, e.prefix) {
+			t.Errorf("midpoint(%q, %q, %q): %q is out of range", e.lo, e.hi, e.prefix, mid)
+		}
+	}
+}
+
+func TestBucketTypes(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+
+	table := []struct {
+		bucket string
+		attrs  *BucketAttrs
+		want   BucketType
+		fail   bool
+	}{
+		{bucket: "default", want: Private},
+		{bucket: "unset", attrs: &BucketAttrs{}, want: Private},
+		{bucket: "public", attrs: &BucketAttrs{Type: Public}, want: Public},
+		{bucket: "snapshot", attrs: &BucketAttrs{Type: Snapshot}, want: Snapshot},
+		{bucket: "future", attrs: &BucketAttrs{Type: "allFuture"}, want: "allFuture"},
+		{bucket: "malformed", attrs: &BucketAttrs{Type: "all private"}, fail: true},
+	}
+	for _, e := range table {
+		bucket, err := client.NewBucket(ctx, e.bucket, e.attrs)
+		if e.fail {
+			if err == nil {
+				t.Errorf("NewBucket(%s): got no error", e.bucket)
+			}
+			if _, ok := root.bucketMap[e.bucket]; ok {
+				t.Errorf("NewBucket(%s): bucket was created", e.bucket)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewBucket(%s): %v", e.bucket, err)
+			continue
+		}
+		if got := bucket.b.btype(); got != e.want {
+			t.Errorf("NewBucket(%s): got type %q, want %q", e.bucket, got, e.want)
+		}
+		if err := bucket.Update(ctx, &BucketAttrs{Type: "all-public"}); err == nil {
+			t.Errorf("Update(%s) with a malformed type: got no error", e.bucket)
+		}
+	}
+
+	if BucketType("allPublic") != Public || !Public.Known() || UnknownType.Known() || BucketType("future").Known() {
+		t.Error("BucketType.Known: wrong answer")
+	}
+}
+
+func TestBucketInfoLimits(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+
+	many := make(map[string]string)
+	for i := 0; i < 11; i++ {
+		many[fmt.Sprintf("key%d", i)] = "v"
+	}
+	if _, err := client.NewBucket(ctx, "many", &BucketAttrs{Info: many}); !errors.Is(err, ErrInfoTooLarge) {
+		t.Errorf("NewBucket with 11 info keys: got %v, want ErrInfoTooLarge", err)
+	}
+	if _, ok := root.bucketMap["many"]; ok {
+		t.Errorf("NewBucket with 11 info keys: bucket was created")
+	}
+
+	bucket, err := client.NewBucket(ctx, "bucket", &BucketAttrs{Info: map[string]string{"a": "b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	big := map[string]string{"group": strings.Repeat("x", 7000)}
+	err = bucket.Update(ctx, &BucketAttrs{Info: big})
+	var e *InfoTooLargeError
+	if !errors.As(err, &e) {
+		t.Fatalf("Update with 7005 bytes of info: got %v, want *InfoTooLargeError", err)
+	}
+	want := InfoTooLargeError{Keys: 1, Size: 7005, MaxKeys: 10, MaxSize: 7000}
+	if *e != want {
+		t.Errorf("Update with 7005 bytes of info: got %+v, want %+v", *e, want)
+	}
+	if !strings.Contains(e.Error(), "(5 bytes over the limit)") {
+		t.Errorf("Error: got %q, want it to say how far over the limit", e.Error())
+	}
+	if err := ValidateBucketInfo(map[string]string{"group": strings.Repeat("x", 6995)}); err != nil {
+		t.Errorf("ValidateBucketInfo with 7000 bytes: %v", err)
+	}
+	if keys, size := BucketInfoRoom(map[string]string{"group": "xyz"}); keys != 9 || size != 6992 {
+		t.Errorf("BucketInfoRoom: got %d keys and %d bytes, want 9 and 6992", keys, size)
+	}
+}
+
+func TestUsage(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	clk := &testClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root, options: clientOptions{clock: clk}}}
+	for _, name := range []string{"b", "a"} {
+		if _, err := client.NewBucket(ctx, name, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root.bucketMap["a"]["x"] = "12345"
+	root.bucketMap["a"]["y"] = "123"
+
+	got, err := client.Usage(ctx, UsageConcurrency(2), UsageMaxAge(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*BucketUsage{
+		{Bucket: "a", Objects: 2, Bytes: 8, Versions: 2, StoredBytes: 8, Computed: clk.now},
+		{Bucket: "b", Computed: clk.now},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Usage: got %+v, want %+v", got, want)
+	}
+
+	// Within the maximum age, the cached usage is returned.
+	root.bucketMap["a"]["z"] = "1"
+	clk.now = clk.now.Add(time.Minute)
+	if got, err := client.Usage(ctx, UsageMaxAge(time.Hour)); err != nil || got[0].Objects != 2 {
+		t.Errorf("cached Usage: got %+v, %v; want 2 objects", got[0], err)
+	}
+	if got, err := client.Usage(ctx); err != nil || got[0].Objects != 3 {
+		t.Errorf("fresh Usage: got %+v, %v; want 3 objects", got[0], err)
+	}
+
+	tb := &testBucket{
+		n: "versioned",
+		versions: []b2FileInterface{
+			&testFile{n: "a", s: 10, a: "upload"},
+			&testFile{n: "a", s: 20, a: "upload"},
+			&testFile{n: "b", a: "hide"},
+			&testFile{n: "b", s: 5, a: "upload"},
+			&testFile{n: "c", a: "start"},
+		},
+		unfinished: []b2FileInterface{&testFile{n: "c"}},
+	}
+	br := &beRoot{b2i: &testRoot{}, options: clientOptions{clock: clk}}
+	bucket := &Bucket{b: &beBucket{b2bucket: tb, ri: br}, r: br}
+	u, err := bucket.Usage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantU := &BucketUsage{
+		Bucket:               "versioned",
+		Objects:              1,
+		Bytes:                10,
+		Versions:             3,
+		StoredBytes:          35,
+		UnfinishedLargeFiles: 1,
+		Computed:             clk.now,
+	}
+	if !reflect.DeepEqual(u, wantU) {
+		t.Errorf("Bucket.Usage: got %+v, want %+v", u, wantU)
+	}
+}
+
+func TestBytesUploaded(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	broken := errors.New("broken")
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs: &errCont{
+					errMap: map[string]map[int]error{
+						"uploadPart": {2: broken},
+					},
+				},
+			},
+			options: clientOptions{clock: &testClock{}},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := bucket.Object(smallFileName).NewWriter(ctx)
+	if _, err := io.WriteString(w, "hello"); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.BytesUploaded(); n != 0 {
+		t.Errorf("small file before Close: got %d bytes uploaded, want 0", n)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := w.BytesUploaded(); n != 5 {
+		t.Errorf("small file after Close: got %d bytes uploaded, want 5", n)
+	}
+
+	w = bucket.Object(largeFileName).NewWriter(ctx)
+	w.ChunkSize = 300
+	w.ConcurrentUploads = 1
+	n, err := io.WriteString(w, strings.Repeat("0123456789", 100))
+	if err == nil {
+		err = w.Close()
+	}
+	if err != broken {
+		t.Fatalf("large file: got error %v, want %v", err, broken)
+	}
+	if up := w.BytesUploaded(); up != 600 || int64(n) < up {
+		t.Errorf("large file: wrote %d, got %d bytes uploaded, want 600", n, up)
+	}
 }
 
-func readFile(ctx context.Context, obj *Object, sha string, chunk, concur int) error {
-	r := obj.NewReader(ctx)
-	r.ChunkSize = chunk
-	r.ConcurrentDownloads = concur
-	h := sha1.New()
-	if _, err := io.Copy(h, r); err != nil {
-		return err
+func TestPause(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Repeat("0123456789", 100)
+
+	w := bucket.Object(largeFileName).NewWriter(ctx)
+	w.ChunkSize = 300
+	w.ConcurrentUploads = 2
+	w.Pause()
+	if !w.Paused() {
+		t.Error("writer is not paused")
+	}
+	done := make(chan error)
+	go func() {
+		if _, err := io.WriteString(w, data); err != nil {
+			done <- err
+			return
+		}
+		done <- w.Close()
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("paused writer finished: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := len(w.Stats().Parts); n != 0 {
+		t.Errorf("paused writer uploaded %d parts", n)
+	}
+	w.Unpause()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := root.bucketMap[bucketName][largeFileName]; got != data {
+		t.Errorf("after unpause: got %d bytes, want %d", len(got), len(data))
+	}
+
+	r := bucket.Object(largeFileName).NewReader(ctx, DownloadChunkSize(100), DownloadConcurrency(2))
+	defer r.Close()
+	r.Pause()
+	got := make(chan string)
+	go func() {
+		buf := &bytes.Buffer{}
+		io.Copy(buf, r)
+		got <- buf.String()
+	}()
+	select {
+	case <-got:
+		t.Fatal("paused reader finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := len(r.status().Progress); n != 0 {
+		t.Errorf("paused reader downloaded %d chunks", n)
+	}
+	r.Unpause()
+	if s := <-got; s != data {
+		t.Errorf("after unpause: read %d bytes, want %d", len(s), len(data))
+	}
+}
+
+func TestSpillToDisk(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	data := strings.Repeat("0123456789", 100)
+
+	w := bucket.Object(largeFileName).NewWriter(ctx, SpillToDisk(dir, 600))
+	w.ChunkSize = 300
+	w.ConcurrentUploads = 1
+	// With the writer paused, no thread takes chunks, so all that Write can
+	// do without waiting is spill them.
+	w.Pause()
+	wrote := make(chan error)
+	go func() {
+		_, err := io.WriteString(w, data)
+		wrote <- err
+	}()
+	select {
+	case err := <-wrote:
+		t.Fatalf("Write returned %v; it should wait once the limit is reached", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	spilled, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(spilled) != 2 {
+		t.Errorf("got %d spilled chunks, want 2", len(spilled))
+	}
+	w.Unpause()
+	if err := <-wrote; err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.bucketMap[bucketName][largeFileName]; got != data {
+		t.Errorf("got %d bytes, want %d", len(got), len(data))
+	}
+	if left, _ := ioutil.ReadDir(dir); len(left) != 0 {
+		t.Errorf("%d spill files left behind", len(left))
+	}
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	tb := &testBucket{
+		n: "verify",
+		files: map[string]string{
+			"a": "good",
+			"b": "corrupt",
+			"c": "no hash",
+			"d": "a large object that is only sampled",
+			"e": "another sampled object",
+		},
+		sums: map[string]string{
+			"b": fmt.Sprintf("%x", sha1.Sum([]byte("something else"))),
+			"c": "none",
+		},
+	}
+	br := &beRoot{b2i: &testRoot{}}
+	client := &Client{backend: br}
+	bucket := &Bucket{b: &beBucket{b2bucket: tb, ri: br}, r: br, c: client}
+
+	var progress []*VerifyReport
+	rep, err := bucket.Verify(ctx, "", VerifyConcurrency(3), VerifySample(5), VerifyProgress(func(r *VerifyReport) {
+		progress = append(progress, r)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Objects != 5 || rep.Verified != 1 || rep.Unverifiable != 1 || rep.Sampled != 2 || len(rep.Failures) != 1 {
+		t.Errorf("Verify: got %+v", rep)
+	}
+	if len(rep.Failures) == 1 && (rep.Failures[0].Name != "b" || rep.Failures[0].Got == rep.Failures[0].Want) {
+		t.Errorf("Verify: got failure %v, want a bad hash for b", rep.Failures[0])
+	}
+	if len(progress) != 1 || progress[0].Cursor != rep.Cursor {
+		t.Errorf("Verify: got %d progress reports, want 1 with the final cursor", len(progress))
+	}
+
+	// Resuming from the end verifies nothing more.
+	rep, err = bucket.Verify(ctx, "", VerifyResume(rep.Cursor))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Objects != 0 {
+		t.Errorf("resumed Verify: got %d objects, want 0", rep.Objects)
+	}
+	if _, err := bucket.Verify(ctx, "", VerifyResume("garbage!")); err == nil {
+		t.Error("Verify with a bad cursor: got nil error")
+	}
+}
+
+func TestExportTar(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	files := map[string]string{
+		"dir/a":   "the first object",
+		"dir/b/c": "the second object",
+		"dir/d":   "",
+		"other":   "not exported",
+	}
+	tb := &testBucket{n: "export", files: files}
+	br := &beRoot{b2i: &testRoot{}}
+	client := &Client{backend: br}
+	bucket := &Bucket{b: &beBucket{b2bucket: tb, ri: br}, r: br, c: client}
+
+	buf := &bytes.Buffer{}
+	if err := bucket.ExportTar(ctx, buf, "dir/", TarConcurrency(2), TarReaderOptions(DownloadChunkSize(5))); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hdr.Size != int64(len(body)) {
+			t.Errorf("%s: header size %d, got %d bytes", hdr.Name, hdr.Size, len(body))
+		}
+		got[hdr.Name] = string(body)
+	}
+	delete(files, "other")
+	if !reflect.DeepEqual(got, files) {
+		t.Errorf("ExportTar: got %v, want %v", got, files)
+	}
+
+	if err := bucket.ExportTar(ctx, ioutil.Discard, "dir/", TarConcurrency(3)); err != nil {
+		t.Errorf("ExportTar to a discarding writer: %v", err)
+	}
+	cctx, ccancel := context.WithCancel(ctx)
+	ccancel()
+	if err := bucket.ExportTar(cctx, ioutil.Discard, ""); err == nil {
+		t.Error("ExportTar with a cancelled context: got nil error")
+	}
+}
+
+func TestImportTar(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	client := &Client{
+		backend: &beRoot{
+			b2i: &testRoot{
+				bucketMap: make(map[string]map[string]string),
+				errs:      &errCont{},
+			},
+		},
+	}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	entries := []struct {
+		hdr  tar.Header
+		body string
+	}{
+		{tar.Header{Name: "./dir/", Typeflag: tar.TypeDir}, ""},
+		{tar.Header{Name: "./dir/small", Typeflag: tar.TypeReg}, "small"},
+		{tar.Header{Name: "dir/large", Typeflag: tar.TypeReg}, "larger than the buffer"},
+		{tar.Header{Name: "dir/link", Typeflag: tar.TypeSymlink, Linkname: "small"}, ""},
+		{tar.Header{Name: "empty", Typeflag: tar.TypeReg}, ""},
+	}
+	for _, e := range entries {
+		hdr := e.hdr
+		hdr.Size = int64(len(e.body))
+		hdr.ModTime = time.Unix(1e9, 0)
+		if err := tw.WriteHeader(&hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, e.body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bucket.ImportTar(ctx, buf, "imported/", TarConcurrency(2), TarBufferSize(10)); err != nil {
+		t.Fatal(err)
+	}
+	files := bucket.b.(*beBucket).b2bucket.(*testBucket).files
+	want := map[string]string{
+		"imported/dir/small": "small",
+		"imported/dir/large": "larger than the buffer",
+		"imported/empty":     "",
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("ImportTar: got %v, want %v", files, want)
+	}
+
+	if err := bucket.ImportTar(ctx, strings.NewReader(strings.Repeat("not a tar archive ", 100)), ""); err == nil {
+		t.Error("ImportTar of garbage: got nil error")
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	table := []struct {
+		name string
+		ok   bool
+	}{
+		{name: "a", ok: true},
+		{name: "dir/sub/file.txt", ok: true},
+		{name: "名前/ファイル", ok: true},
+		{name: strings.Repeat("a", MaxNameLength), ok: true},
+		{name: strings.Repeat("a", MaxNameLength+1)},
+		{name: strings.Repeat("é", MaxNameLength/2+1)},
+		{name: ""},
+		{name: "/a"},
+		{name: "a/"},
+		{name: "a//b"},
+		{name: "a\\b"},
+		{name: "a\nb"},
+		{name: "a\x7fb"},
+		{name: "a\xffb"},
+	}
+	for _, e := range table {
+		err := ValidateName(e.name)
+		if (err == nil) != e.ok {
+			t.Errorf("ValidateName(%q): got %v, want ok=%v", e.name, err, e.ok)
+		}
+		if _, isName := err.(*NameError); err != nil && !isName {
+			t.Errorf("ValidateName(%q): got %T, want *NameError", e.name, err)
+		}
+	}
+}
+
+func TestInvalidNames(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := bucket.Object("bad//name").NewWriter(ctx)
+	if _, err := w.Write([]byte("data")); err == nil {
+		t.Error("Write: got nil error")
+	} else if _, ok := err.(*NameError); !ok {
+		t.Errorf("Write: got %v, want a *NameError", err)
+	}
+	w.Close()
+
+	_, err = bucket.PublishManifest(ctx, "MANIFEST", []ManifestFile{
+		{Name: "good", Body: strings.NewReader("good")},
+		{Name: "bad\\name", Body: strings.NewReader("bad")},
+	})
+	if _, ok := err.(*NameError); !ok {
+		t.Errorf("PublishManifest: got %v, want a *NameError", err)
+	}
+	if len(root.bucketMap[bucketName]) != 0 {
+		t.Errorf("got %d objects, want none", len(root.bucketMap[bucketName]))
+	}
+}
+
+func TestDownloadToFile(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	data := strings.Repeat("0123456789", 25)
+	tb := &testBucket{
+		n:    "download",
+		errs: &errCont{},
+		files: map[string]string{
+			"obj":   data,
+			"empty": "",
+			"bad":   "corrupt",
+		},
+		sums: map[string]string{
+			"bad": fmt.Sprintf("%x", sha1.Sum([]byte("something else"))),
+		},
+	}
+	br := &beRoot{b2i: &testRoot{}}
+	client := &Client{backend: br}
+	bucket := &Bucket{b: &beBucket{b2bucket: tb, ri: br}, r: br, c: client}
+	dir := t.TempDir()
+
+	for _, p := range []Prealloc{PreallocSparse, PreallocFull, PreallocNone} {
+		path := filepath.Join(dir, "obj")
+		if err := bucket.Object("obj").DownloadToFile(ctx, path, FileChunkSize(30), FileConcurrency(3), FileBufferSize(7), FilePrealloc(p), FileSync(SyncChunk), FileVerify()); err != nil {
+			t.Fatalf("prealloc %d: %v", p, err)
+		}
+		got, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != data {
+			t.Errorf("prealloc %d: got %q, want %q", p, got, data)
+		}
+	}
+
+	// A chunk that fails partway is resumed where it stopped.
+	tb.errs.errMap = map[string]map[int]error{"readBody": {1: testError{retry: true}}}
+	path := filepath.Join(dir, "resumed")
+	if err := bucket.Object("obj").DownloadToFile(ctx, path, FileChunkSize(100), FileConcurrency(1)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != data {
+		t.Errorf("resumed: got %q, %v; want %q", got, err, data)
+	}
+	tb.errs.errMap = nil
+
+	path = filepath.Join(dir, "empty")
+	if err := bucket.Object("empty").DownloadToFile(ctx, path); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Size() != 0 {
+		t.Errorf("empty: got %v, %v", fi, err)
+	}
+
+	path = filepath.Join(dir, "bad")
+	if err := bucket.Object("bad").DownloadToFile(ctx, path, FileVerify()); err == nil {
+		t.Error("bad: got nil error")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("bad: file was not removed: %v", err)
+	}
+
+	// Atomic downloads replace the file only when they succeed.
+	path = filepath.Join(dir, "atomic")
+	if err := ioutil.WriteFile(path, []byte("old"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Object("bad").DownloadToFile(ctx, path, FileAtomic()); err == nil {
+		t.Error("atomic bad: got nil error")
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != "old" {
+		t.Errorf("atomic bad: got %q, %v; want the old file", got, err)
+	}
+	if err := bucket.Object("obj").DownloadToFile(ctx, path, FileAtomic(), FileChunkSize(100)); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ioutil.ReadFile(path); err != nil || string(got) != data {
+		t.Errorf("atomic: got %q, %v; want %q", got, err, data)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0600 {
+		t.Errorf("atomic: got %v, %v; want mode 0600", fi.Mode(), err)
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), "b2tmp") {
+			t.Errorf("temporary file %s was left behind", e.Name())
+		}
+	}
+}
+
+// shortWriter accepts at most n bytes.
+type shortWriter struct {
+	bytes.Buffer
+	n int
+}
+
+func (s *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > s.n {
+		p = p[:s.n]
+	}
+	s.n -= len(p)
+	return s.Buffer.Write(p)
+}
+
+func TestCopyFastPaths(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	root := &testRoot{
+		bucketMap: make(map[string]map[string]string),
+		errs:      &errCont{},
+	}
+	client := &Client{backend: &beRoot{b2i: root}}
+	bucket, err := client.NewBucket(ctx, bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Repeat("0123456789", 10)
+
+	// ReadFrom uploads a seekable reader from its current offset.
+	br := bytes.NewReader([]byte(data))
+	if _, err := br.Seek(25, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	w := bucket.Object("offset").NewWriter(ctx)
+	if n, err := w.ReadFrom(br); err != nil || n != 75 {
+		t.Errorf("ReadFrom: got %d, %v; want 75, nil", n, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.bucketMap[bucketName]["offset"]; got != data[25:] {
+		t.Errorf("ReadFrom at offset: got %q, want %q", got, data[25:])
+	}
+
+	// A pipe cannot seek, and is buffered instead.
+	pr, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		pw.Write([]byte(data))
+		pw.Close()
+	}()
+	w = bucket.Object("pipe").NewWriter(ctx)
+	if n, err := w.ReadFrom(pr); err != nil || n != int64(len(data)) {
+		t.Errorf("ReadFrom(pipe): got %d, %v; want %d, nil", n, err, len(data))
+	}
+	pr.Close()
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := root.bucketMap[bucketName]["pipe"]; got != data {
+		t.Errorf("ReadFrom(pipe): got %q, want %q", got, data)
+	}
+
+	// WriteTo writes whole chunks, and picks up where Read left off.
+	r := bucket.Object("pipe").NewReader(ctx, DownloadChunkSize(30))
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	var got bytes.Buffer
+	if n, err := r.WriteTo(&got); err != nil || n != 90 {
+		t.Errorf("WriteTo: got %d, %v; want 90, nil", n, err)
+	}
+	if got.String() != data[10:] {
+		t.Errorf("WriteTo: got %q, want %q", got.String(), data[10:])
 	}
 	if err := r.Close(); err != nil {
-		return err
+		t.Error(err)
 	}
-	rsha := fmt.Sprintf("%x", h.Sum(nil))
-	if sha != rsha {
-		return fmt.Errorf("bad hash: got %s, want %s", rsha, sha)
+	if err, ok := r.Verify(); err != nil || !ok {
+		t.Errorf("Verify after WriteTo: got %v, %v", err, ok)
 	}
-	return nil
+
+	r = bucket.Object("pipe").NewReader(ctx, DownloadChunkSize(30))
+	sw := &shortWriter{n: 45}
+	if n, err := r.WriteTo(sw); err != io.ErrShortWrite || n != 45 {
+		t.Errorf("WriteTo short: got %d, %v; want 45, %v", n, err, io.ErrShortWrite)
+	}
+	r.Close()
 }
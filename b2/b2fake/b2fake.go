@@ -0,0 +1,1323 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package b2fake provides an in-memory fake of the B2 service, for the unit
+// tests of programs that use package b2.
+//
+// A Server speaks B2's HTTP API on a local port, so that a b2.Client made with
+// its Client method exercises the same code, including retries and
+// reauthorization, that it would against B2.  Everything is kept in memory,
+// and the server is deterministic: file IDs are assigned in sequence, and
+// upload times come from a clock that moves only when versions are created.
+// Errors can be injected into any API call with Fail and ExpireTokens, and
+// clients made with b2.ExpireSomeAuthTokens find their tokens expiring
+// regularly.
+//
+// The fake supports the calls that package b2 makes for buckets and objects,
+// including large files and server-side copies, and application keys and
+// download authorizations, which expire by the wall clock.  It does not
+// enforce capabilities, key restrictions, caps, lifecycle rules, or object
+// lock.
+package b2fake
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/internal/b2types"
+)
+
+const apiPrefix = "/b2api/v1/"
+
+// Options configure a Server.
+type Options struct {
+	// Account and Key are the credentials the server accepts.  The defaults
+	// are "account" and "key".
+	Account string
+	Key     string
+
+	// PartSize and MinimumPartSize are the recommended and absolute minimum
+	// large file part sizes the server reports, and MinimumPartSize is
+	// enforced for every part but the last.  The defaults are B2's, 100MB and
+	// 5MB; tests of large files can make them much smaller.
+	PartSize        int
+	MinimumPartSize int
+
+	// Start is the upload time of the first version created.  Each version
+	// after it is uploaded a millisecond later.  The default is midnight UTC
+	// on January 1, 2000.
+	Start time.Time
+}
+
+// A Server is a fake B2 service.  It is safe for concurrent use.
+type Server struct {
+	// URL is the server's API base, for use with b2.APIBase.
+	URL string
+
+	opts Options
+	srv  *httptest.Server
+
+	mu      sync.Mutex
+	now     time.Time
+	ids     int
+	tokens  map[string]bool
+	expired map[string]bool
+	buckets map[string]*bucket // by ID
+	files   map[string]*file   // every version, by ID
+	faults  map[string][]*Error
+	calls   map[string]int
+	keys    map[string]*b2types.Key // by ID
+	uses    map[string]int          // calls made with each token in test mode
+	grants  map[string]grant        // download authorization tokens
+}
+
+// A grant is what a download authorization token allows.
+type grant struct {
+	bucketID string
+	prefix   string
+	expires  time.Time
+}
+
+type bucket struct {
+	id       string
+	name     string
+	btype    string
+	info     map[string]string
+	rules    []b2types.LifecycleRule
+	extra    map[string]json.RawMessage // settings the fake does not know
+	revision int
+}
+
+type file struct {
+	id       string
+	bucketID string
+	name     string
+	ctype    string
+	sha1     string
+	info     map[string]string
+	action   string // "upload", "hide", or "start"
+	data     []byte
+	stamp    time.Time
+	parts    map[int]part // for unfinished large files
+}
+
+type part struct {
+	data  []byte
+	sha1  string
+	stamp time.Time
+}
+
+// New starts a fake B2 server.  Callers should Close it when finished.
+func New(opts Options) *Server {
+	if opts.Account == "" {
+		opts.Account = "account"
+	}
+	if opts.Key == "" {
+		opts.Key = "key"
+	}
+	if opts.PartSize == 0 {
+		opts.PartSize = 1e8
+	}
+	if opts.MinimumPartSize == 0 {
+		opts.MinimumPartSize = 5e6
+	}
+	if opts.Start.IsZero() {
+		opts.Start = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	s := &Server{
+		opts:    opts,
+		now:     opts.Start.Add(-time.Millisecond),
+		tokens:  make(map[string]bool),
+		expired: make(map[string]bool),
+		buckets: make(map[string]*bucket),
+		files:   make(map[string]*file),
+		faults:  make(map[string][]*Error),
+		calls:   make(map[string]int),
+		keys:    make(map[string]*b2types.Key),
+		uses:    make(map[string]int),
+		grants:  make(map[string]grant),
+	}
+	s.srv = httptest.NewServer(s)
+	s.URL = s.srv.URL
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Client returns a client authorized with the server.  Options are applied
+// after the one that directs the client to the server.
+func (s *Server) Client(ctx context.Context, opts ...b2.ClientOption) (*b2.Client, error) {
+	opts = append([]b2.ClientOption{b2.APIBase(s.URL)}, opts...)
+	return b2.NewClient(ctx, s.opts.Account, s.opts.Key, opts...)
+}
+
+// An Error is a failure returned by the server, as B2 would report it.
+type Error struct {
+	// Status is the HTTP status, and Code the B2 error code, such as
+	// "service_unavailable".
+	Status int
+	Code   string
+
+	// Message is the error message.  If empty, one is made up.
+	Message string
+
+	// RetryAfter, if set, is sent as the Retry-After header, in seconds.
+	RetryAfter int
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%d %s", e.Status, e.Code)
+}
+
+// Fail causes the next n calls to the B2 API method, such as
+// "b2_upload_file", to fail with err.  Failures queued for the same method are
+// returned in the order they were queued.
+func (s *Server) Fail(method string, n int, err *Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.faults[method] = append(s.faults[method], err)
+	}
+}
+
+// ExpireTokens expires every authorization token the server has issued, so
+// that clients must reauthorize.
+func (s *Server) ExpireTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for t := range s.tokens {
+		s.expired[t] = true
+	}
+	s.tokens = make(map[string]bool)
+}
+
+// Calls returns the number of calls made to the B2 API method, such as
+// "b2_list_file_names", including calls that failed.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+func notFound(format string, args ...interface{}) error {
+	return &Error{Status: 404, Code: "not_found", Message: fmt.Sprintf(format, args...)}
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &Error{Status: 400, Code: "bad_request", Message: fmt.Sprintf(format, args...)}
+}
+
+func writeError(rw http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Status: 400, Code: "bad_request", Message: err.Error()}
+	}
+	if e.RetryAfter > 0 {
+		rw.Header().Set("Retry-After", strconv.Itoa(e.RetryAfter))
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(e.Status)
+	json.NewEncoder(rw).Encode(b2types.ErrorMessage{
+		Status: e.Status,
+		Code:   e.Code,
+		Msg:    e.Error(),
+	})
+}
+
+func writeJSON(rw http.ResponseWriter, v interface{}) {
+	rw.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(rw).Encode(v)
+}
+
+// ServeHTTP serves the B2 API.
+func (s *Server) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	method, arg := s.route(r)
+	if err := s.check(method, r); err != nil {
+		writeError(rw, err)
+		return
+	}
+	switch method {
+	case "b2_authorize_account":
+		writeJSON(rw, s.authorize(r))
+	case "b2_upload_file":
+		s.serveUpload(rw, r, arg)
+	case "b2_upload_part":
+		s.servePart(rw, r, arg)
+	case "b2_download_file_by_id":
+		s.serveDownload(rw, r, "", arg)
+	case "b2_download_file_by_name":
+		i := strings.Index(arg, "/")
+		s.serveDownload(rw, r, arg[:i], arg[i+1:])
+	default:
+		h, ok := s.handlers()[method]
+		if !ok {
+			writeError(rw, badRequest("b2fake: %s is not supported", method))
+			return
+		}
+		req := h.req()
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(rw, badRequest("%v", err))
+			return
+		}
+		s.mu.Lock()
+		resp, err := h.call(req)
+		s.mu.Unlock()
+		if err != nil {
+			writeError(rw, err)
+			return
+		}
+		writeJSON(rw, resp)
+	}
+}
+
+// route returns the API method a request is for, and the rest of its path.
+func (s *Server) route(r *http.Request) (string, string) {
+	if strings.HasPrefix(r.URL.Path, "/file/") {
+		return "b2_download_file_by_name", strings.TrimPrefix(r.URL.Path, "/file/")
+	}
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	if i := strings.Index(path, "/"); i >= 0 {
+		return path[:i], path[i+1:]
+	}
+	if path == "b2_download_file_by_id" {
+		return path, r.URL.Query().Get("fileId")
+	}
+	return path, ""
+}
+
+// check records the call, and returns any injected failure, or an error if
+// the request is not authorized.
+func (s *Server) check(method string, r *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+	if errs := s.faults[method]; len(errs) > 0 {
+		s.faults[method] = errs[1:]
+		return errs[0]
+	}
+	auth := r.Header.Get("Authorization")
+	if method == "b2_authorize_account" {
+		want := "Basic " + base64.StdEncoding.EncodeToString([]byte(s.opts.Account+":"+s.opts.Key))
+		if auth != want && s.appKey(auth) == nil {
+			return &Error{Status: 401, Code: "bad_auth_token", Message: "invalid account or key"}
+		}
+		return nil
+	}
+	if g, ok := s.grants[auth]; ok {
+		return s.checkGrant(g, method, r)
+	}
+	if s.expired[auth] {
+		return &Error{Status: 401, Code: "expired_auth_token", Message: "authorization token has expired"}
+	}
+	if !s.tokens[auth] {
+		return &Error{Status: 401, Code: "bad_auth_token", Message: "invalid authorization token"}
+	}
+	if testMode(r, "expire_some_account_authorization_tokens") {
+		s.uses[auth]++
+		if s.uses[auth] >= testTokenUses {
+			s.expired[auth] = true
+			delete(s.tokens, auth)
+			return &Error{Status: 401, Code: "expired_auth_token", Message: "authorization token has expired"}
+		}
+	}
+	return nil
+}
+
+// checkGrant returns an error unless the download authorization g allows r.
+func (s *Server) checkGrant(g grant, method string, r *http.Request) error {
+	if time.Now().After(g.expires) {
+		return &Error{Status: 401, Code: "expired_auth_token", Message: "download authorization has expired"}
+	}
+	if method != "b2_download_file_by_name" {
+		return &Error{Status: 401, Code: "unauthorized", Message: "download authorization allows only downloads by name"}
+	}
+	_, arg := s.route(r)
+	i := strings.Index(arg, "/")
+	if i < 0 {
+		return notFound("%s: file not found", arg)
+	}
+	name, err := url.PathUnescape(arg[i+1:])
+	if err != nil {
+		return badRequest("%v", err)
+	}
+	b, ok := s.buckets[g.bucketID]
+	if !ok || b.name != arg[:i] || !strings.HasPrefix(name, g.prefix) {
+		return &Error{Status: 401, Code: "unauthorized", Message: "download authorization does not cover " + arg}
+	}
+	return nil
+}
+
+// testTokenUses is how many calls a token is good for, including the one that
+// fails, when clients ask for tokens to be expired with the
+// expire_some_account_authorization_tokens test mode.  B2 expires them at
+// random; the fake does so in turn, so that tests are repeatable.
+const testTokenUses = 20
+
+func testMode(r *http.Request, mode string) bool {
+	for _, m := range r.Header["X-Bz-Test-Mode"] {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// appKey returns the unexpired application key that the Basic authorization
+// auth names, or nil if there is none.
+func (s *Server) appKey(auth string) *b2types.Key {
+	creds, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return nil
+	}
+	i := strings.Index(string(creds), ":")
+	if i < 0 {
+		return nil
+	}
+	k, ok := s.keys[string(creds[:i])]
+	if !ok || k.Secret != string(creds[i+1:]) {
+		return nil
+	}
+	if k.Expires > 0 && millis(time.Now()) >= k.Expires {
+		return nil
+	}
+	return k
+}
+
+func (s *Server) authorize(r *http.Request) *b2types.AuthorizeAccountResponse {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids++
+	token := fmt.Sprintf("fake_token_%d", s.ids)
+	s.tokens[token] = true
+	if k := s.appKey(r.Header.Get("Authorization")); k != nil {
+		return &b2types.AuthorizeAccountResponse{
+			AccountID:      s.opts.Account,
+			AuthToken:      token,
+			URI:            s.URL,
+			DownloadURI:    s.URL,
+			MinPartSize:    s.opts.PartSize,
+			PartSize:       s.opts.PartSize,
+			AbsMinPartSize: s.opts.MinimumPartSize,
+			Allowed: b2types.Allowance{
+				Capabilities: k.Capabilities,
+				Bucket:       k.BucketID,
+				Prefix:       k.Prefix,
+			},
+		}
+	}
+	return &b2types.AuthorizeAccountResponse{
+		AccountID:      s.opts.Account,
+		AuthToken:      token,
+		URI:            s.URL,
+		DownloadURI:    s.URL,
+		MinPartSize:    s.opts.PartSize,
+		PartSize:       s.opts.PartSize,
+		AbsMinPartSize: s.opts.MinimumPartSize,
+		Allowed: b2types.Allowance{
+			Capabilities: []string{
+				"listBuckets", "writeBuckets", "deleteBuckets",
+				"listFiles", "readFiles", "shareFiles", "writeFiles", "deleteFiles",
+			},
+		},
+	}
+}
+
+// newID returns a new, unique file ID.  IDs sort in the order they were
+// issued.
+func (s *Server) newID() string {
+	s.ids++
+	return fmt.Sprintf("fake_file_%08d", s.ids)
+}
+
+// tick returns the upload time of a new version.
+func (s *Server) tick() time.Time {
+	s.now = s.now.Add(time.Millisecond)
+	return s.now
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / 1e6
+}
+
+type apiCall struct {
+	req  func() interface{}
+	call func(interface{}) (interface{}, error)
+}
+
+// handlers returns the handlers for the JSON API calls.  Each is called with
+// s.mu held.
+func (s *Server) handlers() map[string]apiCall {
+	return map[string]apiCall{
+		"b2_list_buckets": {
+			req:  func() interface{} { return &b2types.ListBucketsRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.listBuckets(r.(*b2types.ListBucketsRequest)) },
+		},
+		"b2_create_bucket": {
+			req:  func() interface{} { return &b2types.CreateBucketRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.createBucket(r.(*b2types.CreateBucketRequest)) },
+		},
+		"b2_update_bucket": {
+			req:  func() interface{} { return &b2types.UpdateBucketRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.updateBucket(r.(*b2types.UpdateBucketRequest)) },
+		},
+		"b2_delete_bucket": {
+			req:  func() interface{} { return &b2types.DeleteBucketRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.deleteBucket(r.(*b2types.DeleteBucketRequest)) },
+		},
+		"b2_get_upload_url": {
+			req:  func() interface{} { return &b2types.GetUploadURLRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.getUploadURL(r.(*b2types.GetUploadURLRequest)) },
+		},
+		"b2_start_large_file": {
+			req:  func() interface{} { return &b2types.StartLargeFileRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.startLargeFile(r.(*b2types.StartLargeFileRequest)) },
+		},
+		"b2_get_upload_part_url": {
+			req: func() interface{} { return &b2types.GetUploadPartURLRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.getUploadPartURL(r.(*b2types.GetUploadPartURLRequest))
+			},
+		},
+		"b2_copy_part": {
+			req:  func() interface{} { return &b2types.CopyPartRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.copyPart(r.(*b2types.CopyPartRequest)) },
+		},
+		"b2_finish_large_file": {
+			req: func() interface{} { return &b2types.FinishLargeFileRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.finishLargeFile(r.(*b2types.FinishLargeFileRequest))
+			},
+		},
+		"b2_cancel_large_file": {
+			req: func() interface{} { return &b2types.CancelLargeFileRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.cancelLargeFile(r.(*b2types.CancelLargeFileRequest))
+			},
+		},
+		"b2_list_parts": {
+			req:  func() interface{} { return &b2types.ListPartsRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.listParts(r.(*b2types.ListPartsRequest)) },
+		},
+		"b2_list_unfinished_large_files": {
+			req: func() interface{} { return &b2types.ListUnfinishedLargeFilesRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.listUnfinished(r.(*b2types.ListUnfinishedLargeFilesRequest))
+			},
+		},
+		"b2_list_file_names": {
+			req:  func() interface{} { return &b2types.ListFileNamesRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.listFileNames(r.(*b2types.ListFileNamesRequest)) },
+		},
+		"b2_list_file_versions": {
+			req: func() interface{} { return &b2types.ListFileVersionsRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.listFileVersions(r.(*b2types.ListFileVersionsRequest))
+			},
+		},
+		"b2_get_file_info": {
+			req:  func() interface{} { return &b2types.GetFileInfoRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.getFileInfo(r.(*b2types.GetFileInfoRequest)) },
+		},
+		"b2_delete_file_version": {
+			req: func() interface{} { return &b2types.DeleteFileVersionRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				return s.deleteFileVersion(r.(*b2types.DeleteFileVersionRequest))
+			},
+		},
+		"b2_hide_file": {
+			req:  func() interface{} { return &b2types.HideFileRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.hideFile(r.(*b2types.HideFileRequest)) },
+		},
+		"b2_copy_file": {
+			req:  func() interface{} { return &b2types.CopyFileRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.copyFile(r.(*b2types.CopyFileRequest)) },
+		},
+		"b2_create_key": {
+			req:  func() interface{} { return &b2types.CreateKeyRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.createKey(r.(*b2types.CreateKeyRequest)) },
+		},
+		"b2_delete_key": {
+			req:  func() interface{} { return &b2types.DeleteKeyRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.deleteKey(r.(*b2types.DeleteKeyRequest)) },
+		},
+		"b2_list_keys": {
+			req:  func() interface{} { return &b2types.ListKeysRequest{} },
+			call: func(r interface{}) (interface{}, error) { return s.listKeys(r.(*b2types.ListKeysRequest)) },
+		},
+		"b2_get_download_authorization": {
+			req: func() interface{} { return &b2types.GetDownloadAuthorizationRequest{} },
+			call: func(r interface{}) (interface{}, error) {
+				req := r.(*b2types.GetDownloadAuthorizationRequest)
+				s.ids++
+				token := fmt.Sprintf("fake_download_%d", s.ids)
+				s.grants[token] = grant{
+					bucketID: req.BucketID,
+					prefix:   req.Prefix,
+					expires:  time.Now().Add(time.Duration(req.Valid) * time.Second),
+				}
+				return &b2types.GetDownloadAuthorizationResponse{
+					BucketID: req.BucketID,
+					Prefix:   req.Prefix,
+					Token:    token,
+				}, nil
+			},
+		},
+	}
+}
+
+func (b *bucket) response() b2types.CreateBucketResponse {
+	return b2types.CreateBucketResponse{
+		BucketID:       b.id,
+		Name:           b.name,
+		Type:           b.btype,
+		Info:           b.info,
+		LifecycleRules: b.rules,
+		Revision:       b.revision,
+		Extra:          b.extra,
+	}
+}
+
+func (s *Server) bucket(id string) (*bucket, error) {
+	b, ok := s.buckets[id]
+	if !ok {
+		return nil, badRequest("Bucket %s does not exist", id)
+	}
+	return b, nil
+}
+
+func (s *Server) listBuckets(req *b2types.ListBucketsRequest) (interface{}, error) {
+	resp := &b2types.ListBucketsResponse{Buckets: []b2types.CreateBucketResponse{}}
+	for _, b := range s.buckets {
+		if (req.Name != "" && b.name != req.Name) || (req.Bucket != "" && b.id != req.Bucket) {
+			continue
+		}
+		resp.Buckets = append(resp.Buckets, b.response())
+	}
+	sort.Slice(resp.Buckets, func(i, j int) bool { return resp.Buckets[i].Name < resp.Buckets[j].Name })
+	return resp, nil
+}
+
+func (s *Server) createBucket(req *b2types.CreateBucketRequest) (interface{}, error) {
+	for _, b := range s.buckets {
+		if b.name == req.Name {
+			return nil, &Error{Status: 400, Code: "duplicate_bucket_name", Message: "Bucket name is already in use."}
+		}
+	}
+	s.ids++
+	b := &bucket{
+		id:       fmt.Sprintf("fake_bucket_%d", s.ids),
+		name:     req.Name,
+		btype:    req.Type,
+		info:     req.Info,
+		rules:    req.LifecycleRules,
+		revision: 1,
+	}
+	s.buckets[b.id] = b
+	return b.response(), nil
+}
+
+func (s *Server) updateBucket(req *b2types.UpdateBucketRequest) (interface{}, error) {
+	b, err := s.bucket(req.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	if req.IfRevisionIs != 0 && req.IfRevisionIs != b.revision {
+		return nil, &Error{Status: 409, Code: "conflict", Message: "ifRevisionIs does not match"}
+	}
+	if req.Type != "" {
+		b.btype = req.Type
+	}
+	if req.Info != nil {
+		b.info = req.Info
+	}
+	if req.LifecycleRules != nil {
+		b.rules = req.LifecycleRules
+	}
+	for k, v := range req.Extra {
+		if b.extra == nil {
+			b.extra = make(map[string]json.RawMessage)
+		}
+		b.extra[k] = v
+	}
+	b.revision++
+	return b.response(), nil
+}
+
+func (s *Server) deleteBucket(req *b2types.DeleteBucketRequest) (interface{}, error) {
+	b, err := s.bucket(req.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range s.files {
+		if f.bucketID == b.id {
+			return nil, &Error{Status: 400, Code: "cannot_delete_non_empty_bucket", Message: "Cannot delete non-empty bucket"}
+		}
+	}
+	delete(s.buckets, b.id)
+	return b.response(), nil
+}
+
+func (s *Server) getUploadURL(req *b2types.GetUploadURLRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	return &b2types.GetUploadURLResponse{
+		URI:   s.URL + apiPrefix + "b2_upload_file/" + req.BucketID,
+		Token: s.currentToken(),
+	}, nil
+}
+
+// currentToken returns a token that is valid now.  Upload URLs share the
+// account's tokens, so that ExpireTokens expires them too.
+func (s *Server) currentToken() string {
+	var ts []string
+	for t := range s.tokens {
+		ts = append(ts, t)
+	}
+	sort.Strings(ts)
+	if len(ts) == 0 {
+		return ""
+	}
+	return ts[len(ts)-1]
+}
+
+func (s *Server) createKey(req *b2types.CreateKeyRequest) (interface{}, error) {
+	if req.Name == "" {
+		return nil, badRequest("keyName is required")
+	}
+	if req.BucketID != "" {
+		if _, err := s.bucket(req.BucketID); err != nil {
+			return nil, err
+		}
+	} else if req.Prefix != "" {
+		return nil, badRequest("namePrefix requires bucketId")
+	}
+	s.ids++
+	k := &b2types.Key{
+		ID:           fmt.Sprintf("fake_key_%08d", s.ids),
+		Secret:       fmt.Sprintf("fake_secret_%08d", s.ids),
+		AccountID:    s.opts.Account,
+		Capabilities: req.Capabilities,
+		Name:         req.Name,
+		BucketID:     req.BucketID,
+		Prefix:       req.Prefix,
+	}
+	if req.Valid > 0 {
+		k.Expires = millis(time.Now().Add(time.Duration(req.Valid) * time.Second))
+	}
+	s.keys[k.ID] = k
+	resp := b2types.CreateKeyResponse(*k)
+	return &resp, nil
+}
+
+func (s *Server) deleteKey(req *b2types.DeleteKeyRequest) (interface{}, error) {
+	k, ok := s.keys[req.KeyID]
+	if !ok {
+		return nil, badRequest("key %s does not exist", req.KeyID)
+	}
+	delete(s.keys, req.KeyID)
+	resp := b2types.DeleteKeyResponse(*k)
+	resp.Secret = ""
+	return &resp, nil
+}
+
+func (s *Server) listKeys(req *b2types.ListKeysRequest) (interface{}, error) {
+	var ids []string
+	for id := range s.keys {
+		if id >= req.Next {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	max := req.Max
+	if max <= 0 {
+		max = 100
+	}
+	resp := &b2types.ListKeysResponse{Keys: []b2types.Key{}}
+	for i, id := range ids {
+		if i == max {
+			resp.Next = id
+			break
+		}
+		k := *s.keys[id]
+		k.Secret = ""
+		resp.Keys = append(resp.Keys, k)
+	}
+	return resp, nil
+}
+
+func (f *file) response() b2types.GetFileInfoResponse {
+	sha := f.sha1
+	if f.action != "upload" {
+		sha = "none"
+	}
+	return b2types.GetFileInfoResponse{
+		FileID:      f.id,
+		Name:        f.name,
+		BucketID:    f.bucketID,
+		Size:        int64(len(f.data)),
+		SHA1:        sha,
+		ContentType: f.ctype,
+		Info:        f.info,
+		Action:      f.action,
+		Timestamp:   millis(f.stamp),
+	}
+}
+
+// body reads the upload in r, checking it against the hash in the
+// X-Bz-Content-Sha1 header, which may be "hex_digits_at_end".  It returns the
+// body and its hash.
+func body(r *http.Request) ([]byte, string, error) {
+	data, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return nil, "", err
+	}
+	want := r.Header.Get("X-Bz-Content-Sha1")
+	if want == "hex_digits_at_end" {
+		if len(data) < 40 {
+			return nil, "", badRequest("body is too short for a trailing hash")
+		}
+		want = string(data[len(data)-40:])
+		data = data[:len(data)-40]
+	}
+	got := fmt.Sprintf("%x", sha1.Sum(data))
+	if want != "do_not_verify" && !strings.EqualFold(want, got) {
+		return nil, "", &Error{Status: 400, Code: "bad_request", Message: fmt.Sprintf("sha1 did not match data received: got %s, want %s", got, want)}
+	}
+	return data, got, nil
+}
+
+func info(h http.Header) (map[string]string, error) {
+	m := make(map[string]string)
+	for k := range h {
+		if !strings.HasPrefix(k, "X-Bz-Info-") {
+			continue
+		}
+		v, err := url.QueryUnescape(h.Get(k))
+		if err != nil {
+			return nil, err
+		}
+		m[strings.ToLower(strings.TrimPrefix(k, "X-Bz-Info-"))] = v
+	}
+	return m, nil
+}
+
+func (s *Server) serveUpload(rw http.ResponseWriter, r *http.Request, bucketID string) {
+	data, sum, err := body(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	name, err := url.QueryUnescape(r.Header.Get("X-Bz-File-Name"))
+	if err != nil {
+		writeError(rw, badRequest("%v", err))
+		return
+	}
+	m, err := info(r.Header)
+	if err != nil {
+		writeError(rw, badRequest("%v", err))
+		return
+	}
+	s.mu.Lock()
+	if _, err := s.bucket(bucketID); err != nil {
+		s.mu.Unlock()
+		writeError(rw, err)
+		return
+	}
+	f := &file{
+		id:       s.newID(),
+		bucketID: bucketID,
+		name:     name,
+		ctype:    r.Header.Get("Content-Type"),
+		sha1:     sum,
+		info:     m,
+		action:   "upload",
+		data:     data,
+		stamp:    s.tick(),
+	}
+	s.files[f.id] = f
+	resp := f.response()
+	s.mu.Unlock()
+	writeJSON(rw, resp)
+}
+
+func (s *Server) startLargeFile(req *b2types.StartLargeFileRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	f := &file{
+		id:       s.newID(),
+		bucketID: req.BucketID,
+		name:     req.Name,
+		ctype:    req.ContentType,
+		info:     req.Info,
+		action:   "start",
+		stamp:    s.tick(),
+		parts:    make(map[int]part),
+	}
+	s.files[f.id] = f
+	return &b2types.StartLargeFileResponse{ID: f.id}, nil
+}
+
+func (s *Server) largeFile(id string) (*file, error) {
+	f, ok := s.files[id]
+	if !ok || f.action != "start" {
+		return nil, badRequest("no unfinished large file with ID %s", id)
+	}
+	return f, nil
+}
+
+func (s *Server) getUploadPartURL(req *b2types.GetUploadPartURLRequest) (interface{}, error) {
+	if _, err := s.largeFile(req.ID); err != nil {
+		return nil, err
+	}
+	return &b2types.GetUploadPartURLResponse{
+		ID:    req.ID,
+		URL:   s.URL + apiPrefix + "b2_upload_part/" + req.ID,
+		Token: s.currentToken(),
+	}, nil
+}
+
+func (s *Server) servePart(rw http.ResponseWriter, r *http.Request, id string) {
+	data, sum, err := body(r)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	n, err := strconv.Atoi(r.Header.Get("X-Bz-Part-Number"))
+	if err != nil || n < 1 || n > 10000 {
+		writeError(rw, badRequest("bad part number %q", r.Header.Get("X-Bz-Part-Number")))
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f, err := s.largeFile(id)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	f.parts[n] = part{data: data, sha1: sum, stamp: s.now}
+	writeJSON(rw, &b2types.UploadPartResponse{ID: id, Number: n, Size: int64(len(data)), SHA1: sum})
+}
+
+// byteRange parses a range of the form "bytes=a-b", or "bytes=a-".  It
+// returns the whole of data if rng is empty.
+func byteRange(rng string, data []byte) ([]byte, bool, error) {
+	if rng == "" {
+		return data, false, nil
+	}
+	if !strings.HasPrefix(rng, "bytes=") || !strings.Contains(rng, "-") {
+		return nil, false, badRequest("bad range %q", rng)
+	}
+	parts := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, false, badRequest("bad range %q", rng)
+	}
+	end := int64(len(data)) - 1
+	if parts[1] != "" {
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil {
+			return nil, false, badRequest("bad range %q", rng)
+		}
+	}
+	if start >= int64(len(data)) || end < start {
+		return nil, false, &Error{Status: 416, Code: "range_not_satisfiable", Message: fmt.Sprintf("range %q is not satisfiable", rng)}
+	}
+	if end >= int64(len(data)) {
+		end = int64(len(data)) - 1
+	}
+	return data[start : end+1], true, nil
+}
+
+func (s *Server) copyPart(req *b2types.CopyPartRequest) (interface{}, error) {
+	src, ok := s.files[req.SourceID]
+	if !ok || src.action != "upload" {
+		return nil, badRequest("no file with ID %s", req.SourceID)
+	}
+	f, err := s.largeFile(req.LargeFileID)
+	if err != nil {
+		return nil, err
+	}
+	data, _, err := byteRange(req.Range, src.data)
+	if err != nil {
+		return nil, err
+	}
+	sum := fmt.Sprintf("%x", sha1.Sum(data))
+	f.parts[req.Number] = part{data: data, sha1: sum, stamp: s.now}
+	return &b2types.CopyPartResponse{ID: f.id, Number: req.Number, Size: int64(len(data)), SHA1: sum}, nil
+}
+
+func (s *Server) finishLargeFile(req *b2types.FinishLargeFileRequest) (interface{}, error) {
+	f, err := s.largeFile(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.Hashes) != len(f.parts) {
+		return nil, badRequest("got %d part hashes for %d parts", len(req.Hashes), len(f.parts))
+	}
+	if len(f.parts) < 2 {
+		return nil, badRequest("large files must have at least two parts")
+	}
+	var data []byte
+	for i, sum := range req.Hashes {
+		p, ok := f.parts[i+1]
+		if !ok {
+			return nil, badRequest("part %d is missing", i+1)
+		}
+		if !strings.EqualFold(p.sha1, sum) {
+			return nil, badRequest("part %d: sha1 %s does not match %s", i+1, sum, p.sha1)
+		}
+		if i+1 < len(req.Hashes) && len(p.data) < s.opts.MinimumPartSize {
+			return nil, badRequest("part %d is smaller than the minimum part size", i+1)
+		}
+		data = append(data, p.data...)
+	}
+	f.data = data
+	f.sha1 = "none" // as B2 reports for large files
+	f.parts = nil
+	f.action = "upload"
+	f.stamp = s.tick()
+	return f.response(), nil
+}
+
+func (s *Server) cancelLargeFile(req *b2types.CancelLargeFileRequest) (interface{}, error) {
+	f, err := s.largeFile(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	delete(s.files, f.id)
+	return &struct {
+		ID       string `json:"fileId"`
+		Name     string `json:"fileName"`
+		BucketID string `json:"bucketId"`
+	}{f.id, f.name, f.bucketID}, nil
+}
+
+func (s *Server) listParts(req *b2types.ListPartsRequest) (interface{}, error) {
+	f, err := s.largeFile(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	count := req.Count
+	if count <= 0 {
+		count = 100
+	}
+	var ns []int
+	for n := range f.parts {
+		if n >= req.Start {
+			ns = append(ns, n)
+		}
+	}
+	sort.Ints(ns)
+	resp := &b2types.ListPartsResponse{}
+	for i, n := range ns {
+		if i == count {
+			resp.Next = n
+			break
+		}
+		p := f.parts[n]
+		resp.Parts = append(resp.Parts, struct {
+			ID        string `json:"fileId"`
+			Number    int    `json:"partNumber"`
+			SHA1      string `json:"contentSha1"`
+			Size      int64  `json:"contentLength"`
+			Timestamp int64  `json:"uploadTimestamp"`
+		}{f.id, n, p.sha1, int64(len(p.data)), millis(p.stamp)})
+	}
+	return resp, nil
+}
+
+func (s *Server) listUnfinished(req *b2types.ListUnfinishedLargeFilesRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	count := req.Count
+	if count <= 0 {
+		count = 100
+	}
+	var fs []*file
+	for _, f := range s.files {
+		if f.bucketID == req.BucketID && f.action == "start" && f.id >= req.Continuation {
+			fs = append(fs, f)
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool { return fs[i].id < fs[j].id })
+	resp := &b2types.ListUnfinishedLargeFilesResponse{Files: []b2types.GetFileInfoResponse{}}
+	for i, f := range fs {
+		if i == count {
+			resp.Continuation = f.id
+			break
+		}
+		resp.Files = append(resp.Files, f.response())
+	}
+	return resp, nil
+}
+
+// versions returns every version in the bucket whose name begins with
+// prefix, sorted as B2 lists them: by name, then newest first.
+func (s *Server) versions(bucketID, prefix string) []*file {
+	var fs []*file
+	for _, f := range s.files {
+		if f.bucketID == bucketID && strings.HasPrefix(f.name, prefix) {
+			fs = append(fs, f)
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		if fs[i].name != fs[j].name {
+			return fs[i].name < fs[j].name
+		}
+		if !fs[i].stamp.Equal(fs[j].stamp) {
+			return fs[i].stamp.After(fs[j].stamp)
+		}
+		return fs[i].id > fs[j].id
+	})
+	return fs
+}
+
+// collapse replaces the files whose names contain delimiter after prefix
+// with a single folder entry for each distinct folder.
+func collapse(fs []*file, prefix, delimiter string) []*file {
+	if delimiter == "" {
+		return fs
+	}
+	var out []*file
+	for _, f := range fs {
+		i := strings.Index(f.name[len(prefix):], delimiter)
+		if i < 0 {
+			out = append(out, f)
+			continue
+		}
+		name := f.name[:len(prefix)+i+len(delimiter)]
+		if n := len(out); n > 0 && out[n-1].action == "folder" && out[n-1].name == name {
+			continue
+		}
+		out = append(out, &file{name: name, action: "folder"})
+	}
+	return out
+}
+
+func listCount(n int) int {
+	if n <= 0 {
+		return 100
+	}
+	if n > 10000 {
+		return 10000
+	}
+	return n
+}
+
+func (s *Server) listFileNames(req *b2types.ListFileNamesRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	var current []*file
+	var last string
+	var seen bool
+	for _, f := range s.versions(req.BucketID, req.Prefix) {
+		// Only the newest version of each name counts; if it hides the
+		// name, the name is not listed.
+		if f.action == "start" || (f.name == last && seen) {
+			continue
+		}
+		last, seen = f.name, true
+		if f.action == "upload" {
+			current = append(current, f)
+		}
+	}
+	fs := collapse(current, req.Prefix, req.Delimiter)
+	count := listCount(req.Count)
+	resp := &b2types.ListFileNamesResponse{Files: []b2types.GetFileInfoResponse{}}
+	for _, f := range fs {
+		if f.name < req.Continuation {
+			continue
+		}
+		if len(resp.Files) == count {
+			resp.Continuation = f.name
+			break
+		}
+		resp.Files = append(resp.Files, f.response())
+	}
+	return resp, nil
+}
+
+func (s *Server) listFileVersions(req *b2types.ListFileVersionsRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	fs := collapse(s.versions(req.BucketID, req.Prefix), req.Prefix, req.Delimiter)
+	start := sort.Search(len(fs), func(i int) bool { return fs[i].name >= req.StartName })
+	if req.StartID != "" {
+		for i := start; i < len(fs) && fs[i].name == req.StartName; i++ {
+			if fs[i].id == req.StartID {
+				start = i
+				break
+			}
+		}
+	}
+	count := listCount(req.Count)
+	resp := &b2types.ListFileVersionsResponse{Files: []b2types.GetFileInfoResponse{}}
+	for _, f := range fs[start:] {
+		if len(resp.Files) == count {
+			resp.NextName, resp.NextID = f.name, f.id
+			break
+		}
+		resp.Files = append(resp.Files, f.response())
+	}
+	return resp, nil
+}
+
+func (s *Server) getFileInfo(req *b2types.GetFileInfoRequest) (interface{}, error) {
+	f, ok := s.files[req.ID]
+	if !ok {
+		return nil, notFound("no file with ID %s", req.ID)
+	}
+	return f.response(), nil
+}
+
+func (s *Server) deleteFileVersion(req *b2types.DeleteFileVersionRequest) (interface{}, error) {
+	f, ok := s.files[req.FileID]
+	if !ok || f.name != req.Name {
+		return nil, badRequest("File not present: %s %s", req.Name, req.FileID)
+	}
+	delete(s.files, f.id)
+	return &struct {
+		ID   string `json:"fileId"`
+		Name string `json:"fileName"`
+	}{f.id, f.name}, nil
+}
+
+func (s *Server) hideFile(req *b2types.HideFileRequest) (interface{}, error) {
+	if _, err := s.bucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	f := &file{
+		id:       s.newID(),
+		bucketID: req.BucketID,
+		name:     req.File,
+		action:   "hide",
+		stamp:    s.tick(),
+	}
+	s.files[f.id] = f
+	return &b2types.HideFileResponse{ID: f.id, Timestamp: millis(f.stamp), Action: f.action}, nil
+}
+
+func (s *Server) copyFile(req *b2types.CopyFileRequest) (interface{}, error) {
+	src, ok := s.files[req.SourceID]
+	if !ok || src.action != "upload" {
+		return nil, badRequest("no file with ID %s", req.SourceID)
+	}
+	dst := req.DestBucketID
+	if dst == "" {
+		dst = src.bucketID
+	}
+	if _, err := s.bucket(dst); err != nil {
+		return nil, err
+	}
+	data, _, err := byteRange(req.Range, src.data)
+	if err != nil {
+		return nil, err
+	}
+	f := &file{
+		id:       s.newID(),
+		bucketID: dst,
+		name:     req.Name,
+		ctype:    src.ctype,
+		sha1:     fmt.Sprintf("%x", sha1.Sum(data)),
+		info:     src.info,
+		action:   "upload",
+		data:     data,
+		stamp:    s.tick(),
+	}
+	if req.MetadataDirective == "REPLACE" {
+		f.ctype, f.info = req.ContentType, req.Info
+	}
+	s.files[f.id] = f
+	return f.response(), nil
+}
+
+func (s *Server) serveDownload(rw http.ResponseWriter, r *http.Request, bucketName, name string) {
+	s.mu.Lock()
+	var f *file
+	if bucketName == "" {
+		f = s.files[name]
+	} else {
+		f = s.byName(bucketName, name)
+	}
+	s.mu.Unlock()
+	if f == nil || f.action != "upload" {
+		writeError(rw, notFound("%s: file not found", name))
+		return
+	}
+	data, partial, err := byteRange(r.Header.Get("Range"), f.data)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	h := rw.Header()
+	h.Set("Content-Length", strconv.Itoa(len(data)))
+	h.Set("Content-Type", f.ctype)
+	h.Set("X-Bz-File-Id", f.id)
+	h.Set("X-Bz-File-Name", url.PathEscape(f.name))
+	h.Set("X-Bz-Upload-Timestamp", strconv.FormatInt(millis(f.stamp), 10))
+	h.Set("X-Bz-Content-Sha1", f.sha1)
+	for k, v := range f.info {
+		h.Set("X-Bz-Info-"+k, url.QueryEscape(v))
+	}
+	if partial {
+		rw.WriteHeader(http.StatusPartialContent)
+	}
+	if r.Method != "HEAD" {
+		rw.Write(data)
+	}
+}
+
+// byName returns the current version of the named file, which may be a hide
+// marker, or nil if there is none.
+func (s *Server) byName(bucketName, name string) *file {
+	unescaped, err := url.PathUnescape(name)
+	if err == nil {
+		name = unescaped
+	}
+	for _, b := range s.buckets {
+		if b.name != bucketName {
+			continue
+		}
+		for _, f := range s.versions(b.id, name) {
+			if f.name == name && f.action != "start" {
+				return f
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2fake
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+)
+
+func newBucket(t *testing.T, s *Server, opts ...b2.ClientOption) *b2.Bucket {
+	ctx := context.Background()
+	opts = append(opts, b2.RetryPolicy(b2.RetrySettings{Initial: time.Millisecond, Max: time.Millisecond}))
+	client, err := s.Client(ctx, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bucket
+}
+
+func write(t *testing.T, bucket *b2.Bucket, name string, data []byte, opts ...b2.WriterOption) {
+	w := bucket.Object(name).NewWriter(context.Background(), opts...)
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+func read(t *testing.T, bucket *b2.Bucket, name string) []byte {
+	r := bucket.Object(name).NewReader(context.Background())
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	return data
+}
+
+func list(t *testing.T, bucket *b2.Bucket, opts ...b2.ListOption) []string {
+	var names []string
+	iter := bucket.List(context.Background(), append([]b2.ListOption{b2.ListPageSize(2)}, opts...)...)
+	for iter.Next() {
+		names = append(names, iter.Object().Name())
+	}
+	if err := iter.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestReadWrite(t *testing.T) {
+	s := New(Options{PartSize: 1000, MinimumPartSize: 100})
+	defer s.Close()
+	bucket := newBucket(t, s)
+
+	small := []byte("hello, world")
+	write(t, bucket, "small", small)
+	if got := read(t, bucket, "small"); !bytes.Equal(got, small) {
+		t.Errorf("small: got %q, want %q", got, small)
+	}
+
+	large := bytes.Repeat([]byte("0123456789"), 350)
+	write(t, bucket, "large", large, b2.UploadChunkSize(1000))
+	if got := read(t, bucket, "large"); !bytes.Equal(got, large) {
+		t.Errorf("large: got %d bytes, want %d", len(got), len(large))
+	}
+	if n := s.Calls("b2_finish_large_file"); n != 1 {
+		t.Errorf("b2_finish_large_file: got %d calls, want 1", n)
+	}
+
+	r := bucket.Object("large").NewRangeReader(context.Background(), 995, 10)
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := large[995:1005]; !bytes.Equal(got, want) {
+		t.Errorf("range: got %q, want %q", got, want)
+	}
+}
+
+func TestList(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+	bucket := newBucket(t, s)
+	ctx := context.Background()
+
+	for _, name := range []string{"a", "b/1", "b/2", "c", "d"} {
+		write(t, bucket, name, []byte(name))
+	}
+	write(t, bucket, "c", []byte("c again"))
+	if err := bucket.Object("d").Hide(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := list(t, bucket), []string{"a", "b/1", "b/2", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List: got %v, want %v", got, want)
+	}
+	if got, want := list(t, bucket, b2.ListDelimiter("/")), []string{"a", "b/", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List(/): got %v, want %v", got, want)
+	}
+	if got, want := list(t, bucket, b2.ListHidden()), []string{"a", "b/1", "b/2", "c", "c", "d", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List(hidden): got %v, want %v", got, want)
+	}
+
+	// Versions are listed newest first.
+	if got := read(t, bucket, "c"); string(got) != "c again" {
+		t.Errorf("c: got %q, want %q", got, "c again")
+	}
+	attrs, err := bucket.Object("c").Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := time.Date(2000, 1, 1, 0, 0, 0, 5e6, time.UTC)
+	if !attrs.UploadTimestamp.Equal(want) {
+		t.Errorf("c: got upload time %v, want %v", attrs.UploadTimestamp, want)
+	}
+
+	if _, err := bucket.Object("d").Attrs(ctx); !b2.IsNotExist(err) {
+		t.Errorf("hidden d: got %v, want not exist", err)
+	}
+	if err := bucket.Object("a").Delete(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := list(t, bucket), []string{"b/1", "b/2", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("List after delete: got %v, want %v", got, want)
+	}
+}
+
+func TestFail(t *testing.T) {
+	s := New(Options{})
+	defer s.Close()
+	bucket := newBucket(t, s)
+
+	s.Fail("b2_upload_file", 2, &Error{Status: 503, Code: "service_unavailable"})
+	write(t, bucket, "retried", []byte("data"))
+	if n := s.Calls("b2_upload_file"); n != 3 {
+		t.Errorf("b2_upload_file: got %d calls, want 3", n)
+	}
+
+	s.ExpireTokens()
+	if got := read(t, bucket, "retried"); string(got) != "data" {
+		t.Errorf("after ExpireTokens: got %q, want %q", got, "data")
+	}
+	if n := s.Calls("b2_authorize_account"); n != 2 {
+		t.Errorf("b2_authorize_account: got %d calls, want 2", n)
+	}
+
+	s.Fail("b2_download_file_by_name", 1, &Error{Status: 400, Code: "bad_request"})
+	r := bucket.Object("retried").NewReader(context.Background())
+	if _, err := ioutil.ReadAll(r); err == nil {
+		t.Error("read with injected 400: got nil error")
+	}
+}
@@ -17,7 +17,8 @@ package b2
 import (
 	"context"
 	"io"
-	"math/rand"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,18 +29,33 @@ type beRootInterface interface {
 	reauth(error) bool
 	transient(error) bool
 	reupload(error) bool
+	partSizes() (int, int)
+	accountInfo() *AccountInfo
 	authorizeAccount(context.Context, string, string, clientOptions) error
 	reauthorizeAccount(context.Context) error
+	generation() uint64
+	reauthorizeSince(context.Context, uint64) error
 	createBucket(ctx context.Context, name, btype string, info map[string]string, rules []LifecycleRule) (beBucketInterface, error)
 	listBuckets(context.Context, string) ([]beBucketInterface, error)
 	createKey(context.Context, string, []string, time.Duration, string, string) (beKeyInterface, error)
 	listKeys(context.Context, int, string) ([]beKeyInterface, string, error)
+	clock() Clock
+	retry() RetrySettings
+	close()
+	closed() bool
 }
 
 type beRoot struct {
 	account, key string
 	b2i          b2RootInterface
 	options      clientOptions
+	shut         int32
+
+	// account, key, and options are set by authorizeAccount, when the client
+	// is made, and only read afterwards.  Reauthorizations are serialized by
+	// authMu, and each successful one increments gen.
+	authMu sync.Mutex
+	gen    uint64
 }
 
 type beBucketInterface interface {
@@ -54,7 +70,7 @@ type beBucketInterface interface {
 	listFileNames(context.Context, int, string, string, string) ([]beFileInterface, string, error)
 	listFileVersions(context.Context, int, string, string, string, string) ([]beFileInterface, string, string, error)
 	listUnfinishedLargeFiles(context.Context, int, string) ([]beFileInterface, string, error)
-	downloadFileByName(context.Context, string, int64, int64, bool) (beFileReaderInterface, error)
+	downloadFileByName(context.Context, string, string, int64, int64, bool) (beFileReaderInterface, error)
 	hideFile(context.Context, string) (beFileInterface, error)
 	getDownloadAuthorization(context.Context, string, time.Duration, string) (string, error)
 	baseURL() string
@@ -73,6 +89,7 @@ type beURLInterface interface {
 type beURL struct {
 	b2url b2URLInterface
 	ri    beRootInterface
+	born  time.Time // when b2url was obtained
 }
 
 type beFileInterface interface {
@@ -82,6 +99,8 @@ type beFileInterface interface {
 	timestamp() time.Time
 	status() string
 	deleteFileVersion(context.Context) error
+	copyFile(context.Context, string, int64, int64, string, map[string]string) (beFileInterface, error)
+	downloadFileByID(context.Context, int64, int64, bool) (beFileReaderInterface, error)
 	getFileInfo(context.Context) (beFileInfoInterface, error)
 	listParts(context.Context, int, int) ([]beFilePartInterface, int, error)
 	compileParts(int64, map[int]string) beLargeFileInterface
@@ -94,8 +113,10 @@ type beFile struct {
 }
 
 type beLargeFileInterface interface {
+	id() string
 	finishLargeFile(context.Context) (beFileInterface, error)
 	getUploadPartURL(context.Context) (beFileChunkInterface, error)
+	copyPart(context.Context, string, int, int64, int64) (int64, error)
 	cancel(context.Context) error
 }
 
@@ -112,6 +133,16 @@ type beFileChunkInterface interface {
 type beFileChunk struct {
 	b2fileChunk b2FileChunkInterface
 	ri          beRootInterface
+	born        time.Time // when b2fileChunk's URL was obtained
+}
+
+// B2 upload URLs are good for 24 hours.  URLs that have been held (whether
+// in use or idle) for longer than uploadURLMaxAge are reloaded before their
+// next use, rather than waiting for an upload to fail.
+var uploadURLMaxAge = 23 * time.Hour
+
+func stale(ri beRootInterface, born time.Time) bool {
+	return !born.IsZero() && ri.clock().Now().Sub(born) > uploadURLMaxAge
 }
 
 type beFileReaderInterface interface {
@@ -127,12 +158,16 @@ type beFileReader struct {
 
 type beFileInfoInterface interface {
 	stats() (string, string, int64, string, map[string]string, string, time.Time)
+	retention() Retention
+	legalHold() bool
+	encryption() Encryption
 }
 
 type beFilePartInterface interface {
 	number() int
 	sha1() string
 	size() int64
+	timestamp() time.Time
 }
 
 type beFilePart struct {
@@ -148,6 +183,9 @@ type beFileInfo struct {
 	info   map[string]string
 	status string
 	stamp  time.Time
+	ret    Retention
+	hold   bool
+	sse    Encryption
 }
 
 type beKeyInterface interface {
@@ -157,6 +195,8 @@ type beKeyInterface interface {
 	expires() time.Time
 	secret() string
 	id() string
+	bucketID() string
+	prefix() string
 }
 
 type beKey struct {
@@ -168,6 +208,21 @@ func (r *beRoot) backoff(err error) time.Duration { return r.b2i.backoff(err) }
 func (r *beRoot) reauth(err error) bool           { return r.b2i.reauth(err) }
 func (r *beRoot) reupload(err error) bool         { return r.b2i.reupload(err) }
 func (r *beRoot) transient(err error) bool        { return r.b2i.transient(err) }
+func (r *beRoot) partSizes() (int, int)           { return r.b2i.partSizes() }
+func (r *beRoot) accountInfo() *AccountInfo       { return r.b2i.accountInfo() }
+
+func (r *beRoot) clock() Clock {
+	if r.options.clock == nil {
+		return systemClock{}
+	}
+	return r.options.clock
+}
+
+func (r *beRoot) retry() RetrySettings { return r.options.retry.withDefaults() }
+
+// close causes every later request to fail with ErrClientClosed.
+func (r *beRoot) close()       { atomic.StoreInt32(&r.shut, 1) }
+func (r *beRoot) closed() bool { return atomic.LoadInt32(&r.shut) != 0 }
 
 func (r *beRoot) authorizeAccount(ctx context.Context, account, key string, c clientOptions) error {
 	f := func() error {
@@ -177,13 +232,42 @@ func (r *beRoot) authorizeAccount(ctx context.Context, account, key string, c cl
 		r.account = account
 		r.key = key
 		r.options = c
+		atomic.AddUint64(&r.gen, 1)
 		return nil
 	}
+	if c.clock != nil {
+		// Time retries of the first authorization with the new clock, too.
+		r.options.clock = c.clock
+	}
+	r.options.retry = c.retry
 	return withBackoff(ctx, r, f)
 }
 
 func (r *beRoot) reauthorizeAccount(ctx context.Context) error {
-	return r.authorizeAccount(ctx, r.account, r.key, r.options)
+	return r.reauthorizeSince(ctx, r.generation())
+}
+
+// generation identifies the current authorization.
+func (r *beRoot) generation() uint64 { return atomic.LoadUint64(&r.gen) }
+
+// reauthorizeSince reauthorizes the client, unless it has been reauthorized
+// since generation gen.  Requests that fail together because their token has
+// expired thus share a single new authorization, instead of each replacing
+// the token the others have just been given.
+func (r *beRoot) reauthorizeSince(ctx context.Context, gen uint64) error {
+	r.authMu.Lock()
+	defer r.authMu.Unlock()
+	if r.generation() != gen {
+		return nil
+	}
+	f := func() error {
+		return r.b2i.authorizeAccount(ctx, r.account, r.key, r.options)
+	}
+	if err := withBackoff(ctx, r, f); err != nil {
+		return err
+	}
+	atomic.AddUint64(&r.gen, 1)
+	return nil
 }
 
 func (r *beRoot) createBucket(ctx context.Context, name, btype string, info map[string]string, rules []LifecycleRule) (beBucketInterface, error) {
@@ -316,6 +400,7 @@ func (b *beBucket) getUploadURL(ctx context.Context) (beURLInterface, error) {
 			url = &beURL{
 				b2url: u,
 				ri:    b.ri,
+				born:  b.ri.clock().Now(),
 			}
 			return nil
 		}
@@ -428,11 +513,14 @@ func (b *beBucket) listUnfinishedLargeFiles(ctx context.Context, count int, cont
 	return files, cont, nil
 }
 
-func (b *beBucket) downloadFileByName(ctx context.Context, name string, offset, size int64, header bool) (beFileReaderInterface, error) {
+// downloadFileByName downloads name, authorized with token if it is not empty.
+// Reauthorizing the client cannot renew such a token, so requests made with
+// one are not retried after authorization errors.
+func (b *beBucket) downloadFileByName(ctx context.Context, name, token string, offset, size int64, header bool) (beFileReaderInterface, error) {
 	var reader beFileReaderInterface
 	f := func() error {
 		g := func() error {
-			fr, err := b.b2bucket.downloadFileByName(ctx, name, offset, size, header)
+			fr, err := b.b2bucket.downloadFileByName(ctx, name, token, offset, size, header)
 			if err != nil {
 				return err
 			}
@@ -442,6 +530,9 @@ func (b *beBucket) downloadFileByName(ctx context.Context, name string, offset,
 			}
 			return nil
 		}
+		if token != "" {
+			return g()
+		}
 		return withReauth(ctx, b.ri, g)
 	}
 	if err := withBackoff(ctx, b.ri, f); err != nil {
@@ -503,6 +594,9 @@ func (b *beBucket) file(id, name string) beFileInterface {
 }
 
 func (b *beURL) uploadFile(ctx context.Context, r readResetter, size int, name, ct, sha1 string, info map[string]string) (beFileInterface, error) {
+	if err := b.refresh(ctx); err != nil {
+		return nil, err
+	}
 	var file beFileInterface
 	f := func() error {
 		if err := r.Reset(); err != nil {
@@ -525,6 +619,24 @@ func (b *beURL) uploadFile(ctx context.Context, r readResetter, size int, name,
 	return file, nil
 }
 
+// refresh reloads the upload URL if it is about to expire.
+func (b *beURL) refresh(ctx context.Context) error {
+	if !stale(b.ri, b.born) {
+		return nil
+	}
+	f := func() error {
+		g := func() error {
+			return b.b2url.reload(ctx)
+		}
+		return withReauth(ctx, b.ri, g)
+	}
+	if err := withBackoff(ctx, b.ri, f); err != nil {
+		return err
+	}
+	b.born = b.ri.clock().Now()
+	return nil
+}
+
 func (b *beFile) deleteFileVersion(ctx context.Context) error {
 	f := func() error {
 		g := func() error {
@@ -535,6 +647,50 @@ func (b *beFile) deleteFileVersion(ctx context.Context) error {
 	return withBackoff(ctx, b.ri, f)
 }
 
+func (b *beFile) copyFile(ctx context.Context, name string, offset, size int64, ctype string, info map[string]string) (beFileInterface, error) {
+	var file beFileInterface
+	f := func() error {
+		g := func() error {
+			f, err := b.b2file.copyFile(ctx, name, offset, size, ctype, info)
+			if err != nil {
+				return err
+			}
+			file = &beFile{
+				b2file: f,
+				ri:     b.ri,
+			}
+			return nil
+		}
+		return withReauth(ctx, b.ri, g)
+	}
+	if err := withBackoff(ctx, b.ri, f); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+func (b *beFile) downloadFileByID(ctx context.Context, offset, size int64, header bool) (beFileReaderInterface, error) {
+	var reader beFileReaderInterface
+	f := func() error {
+		g := func() error {
+			fr, err := b.b2file.downloadFileByID(ctx, offset, size, header)
+			if err != nil {
+				return err
+			}
+			reader = &beFileReader{
+				b2fileReader: fr,
+				ri:           b.ri,
+			}
+			return nil
+		}
+		return withReauth(ctx, b.ri, g)
+	}
+	if err := withBackoff(ctx, b.ri, f); err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
 func (b *beFile) size() int64 {
 	return b.b2file.size()
 }
@@ -572,6 +728,9 @@ func (b *beFile) getFileInfo(ctx context.Context) (beFileInfoInterface, error) {
 				info:   info,
 				status: status,
 				stamp:  stamp,
+				ret:    fi.retention(),
+				hold:   fi.legalHold(),
+				sse:    fi.encryption(),
 			}
 			return nil
 		}
@@ -627,6 +786,7 @@ func (b *beLargeFile) getUploadPartURL(ctx context.Context) (beFileChunkInterfac
 			chunk = &beFileChunk{
 				b2fileChunk: fc,
 				ri:          b.ri,
+				born:        b.ri.clock().Now(),
 			}
 			return nil
 		}
@@ -638,6 +798,8 @@ func (b *beLargeFile) getUploadPartURL(ctx context.Context) (beFileChunkInterfac
 	return chunk, nil
 }
 
+func (b *beLargeFile) id() string { return b.b2largeFile.id() }
+
 func (b *beLargeFile) finishLargeFile(ctx context.Context) (beFileInterface, error) {
 	var file beFileInterface
 	f := func() error {
@@ -660,6 +822,25 @@ func (b *beLargeFile) finishLargeFile(ctx context.Context) (beFileInterface, err
 	return file, nil
 }
 
+func (b *beLargeFile) copyPart(ctx context.Context, src string, index int, offset, size int64) (int64, error) {
+	var n int64
+	f := func() error {
+		g := func() error {
+			i, err := b.b2largeFile.copyPart(ctx, src, index, offset, size)
+			if err != nil {
+				return err
+			}
+			n = i
+			return nil
+		}
+		return withReauth(ctx, b.ri, g)
+	}
+	if err := withBackoff(ctx, b.ri, f); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
 func (b *beLargeFile) cancel(ctx context.Context) error {
 	f := func() error {
 		g := func() error {
@@ -677,12 +858,21 @@ func (b *beFileChunk) reload(ctx context.Context) error {
 		}
 		return withReauth(ctx, b.ri, g)
 	}
-	return withBackoff(ctx, b.ri, f)
+	if err := withBackoff(ctx, b.ri, f); err != nil {
+		return err
+	}
+	b.born = b.ri.clock().Now()
+	return nil
 }
 
 func (b *beFileChunk) uploadPart(ctx context.Context, r readResetter, sha1 string, size, index int) (int, error) {
 	// no re-auth; pass it back up to the caller so they can get an new upload URI and token
 	// TODO: we should handle that here probably
+	if stale(b.ri, b.born) {
+		if err := b.reload(ctx); err != nil {
+			return 0, err
+		}
+	}
 	var i int
 	f := func() error {
 		if err := r.Reset(); err != nil {
@@ -719,9 +909,14 @@ func (b *beFileInfo) stats() (string, string, int64, string, map[string]string,
 	return b.name, b.sha, b.size, b.ct, b.info, b.status, b.stamp
 }
 
-func (b *beFilePart) number() int  { return b.b2filePart.number() }
-func (b *beFilePart) sha1() string { return b.b2filePart.sha1() }
-func (b *beFilePart) size() int64  { return b.b2filePart.size() }
+func (b *beFileInfo) retention() Retention   { return b.ret }
+func (b *beFileInfo) legalHold() bool        { return b.hold }
+func (b *beFileInfo) encryption() Encryption { return b.sse }
+
+func (b *beFilePart) number() int          { return b.b2filePart.number() }
+func (b *beFilePart) sha1() string         { return b.b2filePart.sha1() }
+func (b *beFilePart) size() int64          { return b.b2filePart.size() }
+func (b *beFilePart) timestamp() time.Time { return b.b2filePart.timestamp() }
 
 func (b *beKey) del(ctx context.Context) error { return b.k.del(ctx) }
 func (b *beKey) caps() []string                { return b.k.caps() }
@@ -729,51 +924,47 @@ func (b *beKey) name() string                  { return b.k.name() }
 func (b *beKey) expires() time.Time            { return b.k.expires() }
 func (b *beKey) secret() string                { return b.k.secret() }
 func (b *beKey) id() string                    { return b.k.id() }
-
-func jitter(d time.Duration) time.Duration {
-	f := float64(d)
-	f /= 50
-	f += f * (rand.Float64() - 0.5)
-	return time.Duration(f)
-}
-
-func getBackoff(d time.Duration) time.Duration {
-	if d > 30*time.Second {
-		return 30*time.Second + jitter(d)
-	}
-	return d*2 + jitter(d*2)
-}
-
-var after = time.After
+func (b *beKey) bucketID() string              { return b.k.bucketID() }
+func (b *beKey) prefix() string                { return b.k.prefix() }
 
 func withBackoff(ctx context.Context, ri beRootInterface, f func() error) error {
-	backoff := 500 * time.Millisecond
+	var backoff time.Duration
 	for {
+		if ri.closed() {
+			return ErrClientClosed
+		}
 		err := f()
 		if !ri.transient(err) {
-			return err
+			return missingCapability(capExceeded(err))
 		}
 		bo := ri.backoff(err)
 		if bo > 0 {
 			backoff = bo
 		} else {
-			backoff = getBackoff(backoff)
+			backoff = ri.retry().next(backoff)
 		}
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-after(backoff):
+		case <-ri.clock().After(backoff):
 		}
 	}
 }
 
+// maxReauths is how many times withReauth reauthorizes for a single call.
+// Tokens can expire again before a request that failed with the old one is
+// retried, when many are in flight at once.
+const maxReauths = 3
+
 func withReauth(ctx context.Context, ri beRootInterface, f func() error) error {
-	err := f()
-	if ri.reauth(err) {
-		if err := ri.reauthorizeAccount(ctx); err != nil {
+	for i := 0; ; i++ {
+		gen := ri.generation()
+		err := f()
+		if !ri.reauth(err) || i == maxReauths {
+			return err
+		}
+		if err := ri.reauthorizeSince(ctx, gen); err != nil {
 			return err
 		}
-		err = f()
 	}
-	return err
 }
@@ -16,6 +16,7 @@ package b2
 
 import (
 	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"time"
@@ -32,6 +33,8 @@ type b2RootInterface interface {
 	backoff(error) time.Duration
 	reauth(error) bool
 	reupload(error) bool
+	partSizes() (int, int)
+	accountInfo() *AccountInfo
 	createBucket(context.Context, string, string, map[string]string, []LifecycleRule) (b2BucketInterface, error)
 	listBuckets(context.Context, string) ([]b2BucketInterface, error)
 	createKey(context.Context, string, []string, time.Duration, string, string) (b2KeyInterface, error)
@@ -50,7 +53,7 @@ type b2BucketInterface interface {
 	listFileNames(context.Context, int, string, string, string) ([]b2FileInterface, string, error)
 	listFileVersions(context.Context, int, string, string, string, string) ([]b2FileInterface, string, string, error)
 	listUnfinishedLargeFiles(context.Context, int, string) ([]b2FileInterface, string, error)
-	downloadFileByName(context.Context, string, int64, int64, bool) (b2FileReaderInterface, error)
+	downloadFileByName(context.Context, string, string, int64, int64, bool) (b2FileReaderInterface, error)
 	hideFile(context.Context, string) (b2FileInterface, error)
 	getDownloadAuthorization(context.Context, string, time.Duration, string) (string, error)
 	baseURL() string
@@ -69,14 +72,18 @@ type b2FileInterface interface {
 	timestamp() time.Time
 	status() string
 	deleteFileVersion(context.Context) error
+	copyFile(context.Context, string, int64, int64, string, map[string]string) (b2FileInterface, error)
+	downloadFileByID(context.Context, int64, int64, bool) (b2FileReaderInterface, error)
 	getFileInfo(context.Context) (b2FileInfoInterface, error)
 	listParts(context.Context, int, int) ([]b2FilePartInterface, int, error)
 	compileParts(int64, map[int]string) b2LargeFileInterface
 }
 
 type b2LargeFileInterface interface {
+	id() string
 	finishLargeFile(context.Context) (b2FileInterface, error)
 	getUploadPartURL(context.Context) (b2FileChunkInterface, error)
+	copyPart(context.Context, string, int, int64, int64) (int64, error)
 	cancel(context.Context) error
 }
 
@@ -93,12 +100,16 @@ type b2FileReaderInterface interface {
 
 type b2FileInfoInterface interface {
 	stats() (string, string, int64, string, map[string]string, string, time.Time) // bleck
+	retention() Retention
+	legalHold() bool
+	encryption() Encryption
 }
 
 type b2FilePartInterface interface {
 	number() int
 	sha1() string
 	size() int64
+	timestamp() time.Time
 }
 
 type b2KeyInterface interface {
@@ -108,6 +119,8 @@ type b2KeyInterface interface {
 	expires() time.Time
 	secret() string
 	id() string
+	bucketID() string
+	prefix() string
 }
 
 type b2Root struct {
@@ -150,6 +163,42 @@ type b2Key struct {
 	b *base.Key
 }
 
+func withRequestID(ctx context.Context, id string) context.Context {
+	return base.WithRequestID(ctx, id)
+}
+
+func responseHeader(err error) http.Header {
+	return base.Header(err)
+}
+
+func errorCode(err error) (int, string) {
+	code, msgCode, _ := base.MsgCode(err)
+	return code, msgCode
+}
+
+func errorMethod(err error) string {
+	return base.Method(err)
+}
+
+// Base returns the authorized base.B2 that c uses, for calling B2 APIs that
+// this package does not wrap.  It is shared with c: when c reauthorizes, the
+// returned value is updated in place, and it must not be used once c is
+// closed.  Calls made through it are not retried, and the base package's API
+// is less stable than this one's.
+//
+// Base returns nil if c is not backed by a B2 account, as in tests.
+func (c *Client) Base() *base.B2 {
+	r, ok := c.backend.(*beRoot)
+	if !ok {
+		return nil
+	}
+	b, ok := r.b2i.(*b2Root)
+	if !ok {
+		return nil
+	}
+	return b.b
+}
+
 func (b *b2Root) authorizeAccount(ctx context.Context, account, key string, c clientOptions) error {
 	var aopts []base.AuthOption
 	ct := &clientTransport{client: c.client}
@@ -169,12 +218,41 @@ func (b *b2Root) authorizeAccount(ctx context.Context, account, key string, c cl
 	if c.apiBase != "" {
 		aopts = append(aopts, base.SetAPIBase(c.apiBase))
 	}
+	if c.noCompression {
+		aopts = append(aopts, base.DisableCompression())
+	}
+	if c.log != nil {
+		aopts = append(aopts, base.Logger(c.log))
+	}
+	if c.dumpDir != "" {
+		aopts = append(aopts, base.DumpFailures(c.dumpDir))
+	}
+	if c.faults != nil {
+		aopts = append(aopts, base.InjectFaults(c.faults.fault))
+	}
 	for _, agent := range c.userAgents {
 		aopts = append(aopts, base.UserAgent(agent))
 	}
-	nb, err := base.AuthorizeAccount(ctx, account, key, aopts...)
-	if err != nil {
-		return err
+	var nb *base.B2
+	if c.token != nil {
+		apiURL, downloadURL, token, err := c.token(ctx)
+		if err != nil {
+			return err
+		}
+		nb = base.FromToken(account, apiURL, downloadURL, token, aopts...)
+	} else {
+		if c.keys != nil {
+			id, secret, err := c.keys(ctx)
+			if err != nil {
+				return err
+			}
+			account, key = id, secret
+		}
+		var err error
+		nb, err = base.AuthorizeAccount(ctx, account, key, aopts...)
+		if err != nil {
+			return err
+		}
 	}
 	if b.b == nil {
 		b.b = nb
@@ -199,6 +277,30 @@ func (*b2Root) reupload(err error) bool {
 	return base.Action(err) == base.AttemptNewUpload
 }
 
+func (b *b2Root) accountInfo() *AccountInfo {
+	if b.b == nil {
+		return nil
+	}
+	ai := b.b.AccountInfo()
+	return &AccountInfo{
+		AccountID:               ai.AccountID,
+		Capabilities:            ai.Capabilities,
+		BucketID:                ai.BucketID,
+		Prefix:                  ai.Prefix,
+		APIURL:                  ai.APIURL,
+		DownloadURL:             ai.DownloadURL,
+		RecommendedPartSize:     ai.RecommendedPartSize,
+		AbsoluteMinimumPartSize: ai.AbsoluteMinimumPartSize,
+	}
+}
+
+func (b *b2Root) partSizes() (int, int) {
+	if b.b == nil {
+		return 0, 0
+	}
+	return b.b.PartSizes()
+}
+
 func (*b2Root) transient(err error) bool {
 	return base.Action(err) == base.Retry
 }
@@ -207,9 +309,11 @@ func (b *b2Root) createBucket(ctx context.Context, name, btype string, info map[
 	var baseRules []base.LifecycleRule
 	for _, rule := range rules {
 		baseRules = append(baseRules, base.LifecycleRule{
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
-			Prefix:                 rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			Extra:                       extraFields(rule.extra),
+			Prefix:                      rule.Prefix,
 		})
 	}
 	bucket, err := b.b.CreateBucket(ctx, name, btype, info, baseRules)
@@ -245,9 +349,11 @@ func (b *b2Bucket) updateBucket(ctx context.Context, attrs *BucketAttrs) error {
 		rules := []base.LifecycleRule{}
 		for _, rule := range attrs.LifecycleRules {
 			rules = append(rules, base.LifecycleRule{
-				DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-				DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
-				Prefix:                 rule.Prefix,
+				DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+				DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+				DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+				Extra:                       extraFields(rule.extra),
+				Prefix:                      rule.Prefix,
 			})
 		}
 		b.b.LifecycleRules = rules
@@ -298,13 +404,39 @@ func (b *b2Bucket) btype() string {
 	return b.b.Type
 }
 
+// extraJSON and extraFields convert the fields of a lifecycle rule that
+// blazer does not know between their base and b2 forms.
+func extraJSON(m map[string]json.RawMessage) string {
+	if len(m) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(m)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func extraFields(s string) map[string]json.RawMessage {
+	if s == "" {
+		return nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
 func (b *b2Bucket) attrs() *BucketAttrs {
 	var rules []LifecycleRule
 	for _, rule := range b.b.LifecycleRules {
 		rules = append(rules, LifecycleRule{
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
-			Prefix:                 rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			extra:                       extraJSON(rule.Extra),
+			Prefix:                      rule.Prefix,
 		})
 	}
 	return &BucketAttrs{
@@ -368,8 +500,14 @@ func (b *b2Bucket) listUnfinishedLargeFiles(ctx context.Context, count int, cont
 	return files, cont, nil
 }
 
-func (b *b2Bucket) downloadFileByName(ctx context.Context, name string, offset, size int64, header bool) (b2FileReaderInterface, error) {
-	fr, err := b.b.DownloadFileByName(ctx, name, offset, size, header)
+func (b *b2Bucket) downloadFileByName(ctx context.Context, name, token string, offset, size int64, header bool) (b2FileReaderInterface, error) {
+	var fr *base.FileReader
+	var err error
+	if token != "" {
+		fr, err = b.b.DownloadFileByNameWithToken(ctx, name, token, offset, size, header)
+	} else {
+		fr, err = b.b.DownloadFileByName(ctx, name, offset, size, header)
+	}
 	if err != nil {
 		code, _ := base.Code(err)
 		switch code {
@@ -417,6 +555,37 @@ func (b *b2File) deleteFileVersion(ctx context.Context) error {
 	return b.b.DeleteFileVersion(ctx)
 }
 
+// copyFile copies the file's metadata along with its data if info is nil, and
+// gives the copy ctype and info otherwise.
+func (b *b2File) copyFile(ctx context.Context, name string, offset, size int64, ctype string, info map[string]string) (b2FileInterface, error) {
+	var f *base.File
+	var err error
+	if info != nil {
+		f, err = b.b.CopyRangeWithInfo(ctx, name, offset, size, ctype, info)
+	} else {
+		f, err = b.b.CopyRange(ctx, name, offset, size)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b2File{f}, nil
+}
+
+func (b *b2File) downloadFileByID(ctx context.Context, offset, size int64, header bool) (b2FileReaderInterface, error) {
+	fr, err := b.b.DownloadFileByID(ctx, offset, size, header)
+	if err != nil {
+		code, _ := base.Code(err)
+		switch code {
+		case http.StatusRequestedRangeNotSatisfiable:
+			return nil, errNoMoreContent
+		case http.StatusNotFound:
+			return nil, b2err{err: err, notFoundErr: true}
+		}
+		return nil, err
+	}
+	return &b2FileReader{fr}, nil
+}
+
 func (b *b2File) name() string {
 	return b.b.Name
 }
@@ -443,6 +612,9 @@ func (b *b2File) getFileInfo(ctx context.Context) (b2FileInfoInterface, error) {
 	}
 	fi, err := b.b.GetFileInfo(ctx)
 	if err != nil {
+		if code, _ := base.Code(err); code == http.StatusNotFound {
+			return nil, b2err{err: err, notFoundErr: true}
+		}
 		return nil, err
 	}
 	return &b2FileInfo{fi}, nil
@@ -464,6 +636,8 @@ func (b *b2File) compileParts(size int64, seen map[int]string) b2LargeFileInterf
 	return &b2LargeFile{b.b.CompileParts(size, seen)}
 }
 
+func (b *b2LargeFile) id() string { return b.b.ID }
+
 func (b *b2LargeFile) finishLargeFile(ctx context.Context) (b2FileInterface, error) {
 	f, err := b.b.FinishLargeFile(ctx)
 	if err != nil {
@@ -480,6 +654,10 @@ func (b *b2LargeFile) getUploadPartURL(ctx context.Context) (b2FileChunkInterfac
 	return &b2FileChunk{c}, nil
 }
 
+func (b *b2LargeFile) copyPart(ctx context.Context, src string, index int, offset, size int64) (int64, error) {
+	return b.b.CopyPart(ctx, src, index, offset, size)
+}
+
 func (b *b2LargeFile) cancel(ctx context.Context) error {
 	return b.b.CancelLargeFile(ctx)
 }
@@ -510,9 +688,22 @@ func (b *b2FileInfo) stats() (string, string, int64, string, map[string]string,
 	return b.b.Name, b.b.SHA1, b.b.Size, b.b.ContentType, b.b.Info, b.b.Status, b.b.Timestamp
 }
 
-func (b *b2FilePart) number() int  { return b.b.Number }
-func (b *b2FilePart) sha1() string { return b.b.SHA1 }
-func (b *b2FilePart) size() int64  { return b.b.Size }
+func (b *b2FileInfo) retention() Retention {
+	return Retention{Mode: b.b.RetentionMode, Until: b.b.RetainUntil}
+}
+
+func (b *b2FileInfo) legalHold() bool {
+	return b.b.LegalHold == "on"
+}
+
+func (b *b2FileInfo) encryption() Encryption {
+	return Encryption{Mode: b.b.SSEMode, Algorithm: b.b.SSEAlgorithm}
+}
+
+func (b *b2FilePart) number() int          { return b.b.Number }
+func (b *b2FilePart) sha1() string         { return b.b.SHA1 }
+func (b *b2FilePart) size() int64          { return b.b.Size }
+func (b *b2FilePart) timestamp() time.Time { return b.b.Timestamp }
 
 func (b *b2Key) del(ctx context.Context) error { return b.b.Delete(ctx) }
 func (b *b2Key) caps() []string                { return b.b.Capabilities }
@@ -520,3 +711,5 @@ func (b *b2Key) name() string                  { return b.b.Name }
 func (b *b2Key) expires() time.Time            { return b.b.Expires }
 func (b *b2Key) secret() string                { return b.b.Secret }
 func (b *b2Key) id() string                    { return b.b.ID }
+func (b *b2Key) bucketID() string              { return b.b.BucketID }
+func (b *b2Key) prefix() string                { return b.b.Prefix }
@@ -0,0 +1,132 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BucketCacheTTL causes the client to remember the buckets it has seen for
+// the given duration, so that Bucket, NewBucket, and ListBuckets do not list
+// buckets with B2 on every call.  Buckets created or deleted through the
+// client update the cache immediately; changes made elsewhere are seen once
+// the cached entries expire.  The cache is disabled by default.
+func BucketCacheTTL(ttl time.Duration) ClientOption {
+	return func(o *clientOptions) {
+		o.bucketTTL = ttl
+	}
+}
+
+type noBucketCacheKey struct{}
+
+// WithoutBucketCache returns a context that causes Bucket, NewBucket, and
+// ListBuckets to ask B2 for the current buckets, rather than using the
+// client's bucket cache.  The cache is refreshed with the result.
+func WithoutBucketCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noBucketCacheKey{}, true)
+}
+
+type cachedBucket struct {
+	b  beBucketInterface
+	at time.Time
+}
+
+type bucketCache struct {
+	mu     sync.Mutex
+	m      map[string]cachedBucket
+	listed bool      // whether m has held every bucket
+	all    time.Time // when m last held every bucket
+}
+
+func (c *Client) cachingBuckets(ctx context.Context) bool {
+	if c.opts.bucketTTL <= 0 {
+		return false
+	}
+	bypass, _ := ctx.Value(noBucketCacheKey{}).(bool)
+	return !bypass
+}
+
+func (c *Client) fresh(at time.Time) bool {
+	return c.backend.clock().Now().Sub(at) < c.opts.bucketTTL
+}
+
+// cachedBucket returns the named bucket from the cache, if it is there and
+// has not expired.
+func (c *Client) cachedBucket(ctx context.Context, name string) (beBucketInterface, bool) {
+	if !c.cachingBuckets(ctx) {
+		return nil, false
+	}
+	c.buckets.mu.Lock()
+	defer c.buckets.mu.Unlock()
+	e, ok := c.buckets.m[name]
+	if !ok || !c.fresh(e.at) {
+		return nil, false
+	}
+	return e.b, true
+}
+
+// cachedBuckets returns every bucket, if the cache was filled by a listing
+// that has not expired.
+func (c *Client) cachedBuckets(ctx context.Context) ([]beBucketInterface, bool) {
+	if !c.cachingBuckets(ctx) {
+		return nil, false
+	}
+	c.buckets.mu.Lock()
+	defer c.buckets.mu.Unlock()
+	if !c.buckets.listed || !c.fresh(c.buckets.all) {
+		return nil, false
+	}
+	var names []string
+	for name := range c.buckets.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var bs []beBucketInterface
+	for _, name := range names {
+		bs = append(bs, c.buckets.m[name].b)
+	}
+	return bs, true
+}
+
+// cacheBuckets adds the given buckets to the cache.  If all is true, bs is
+// every bucket in the account, and the cache is replaced.
+func (c *Client) cacheBuckets(bs []beBucketInterface, all bool) {
+	if c.opts.bucketTTL <= 0 {
+		return
+	}
+	now := c.backend.clock().Now()
+	c.buckets.mu.Lock()
+	defer c.buckets.mu.Unlock()
+	if all || c.buckets.m == nil {
+		c.buckets.m = make(map[string]cachedBucket)
+	}
+	for _, b := range bs {
+		c.buckets.m[b.name()] = cachedBucket{b: b, at: now}
+	}
+	if all {
+		c.buckets.listed = true
+		c.buckets.all = now
+	}
+}
+
+// uncacheBucket removes the named bucket from the cache.
+func (c *Client) uncacheBucket(name string) {
+	c.buckets.mu.Lock()
+	defer c.buckets.mu.Unlock()
+	delete(c.buckets.m, name)
+}
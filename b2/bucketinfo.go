@@ -0,0 +1,97 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// B2 allows a bucket ten info keys, and, as with file info, 7000 bytes of
+// keys and values together.
+const (
+	maxBucketInfoKeys = 10
+	maxBucketInfoSize = 7000
+)
+
+// ErrInfoTooLarge is matched, with errors.Is, by the errors returned when a
+// bucket's info would exceed B2's limits.  Such errors are always
+// *InfoTooLargeError, which tells by how much.
+var ErrInfoTooLarge = errors.New("b2: bucket info is too large")
+
+// An InfoTooLargeError is returned by NewBucket and Bucket.Update, before any
+// request is sent, when the bucket info would have more keys or bytes than B2
+// allows.
+type InfoTooLargeError struct {
+	// Keys and Size are the number of keys, and of bytes of keys and values,
+	// that the info would have had.
+	Keys int
+	Size int
+
+	// MaxKeys and MaxSize are B2's limits.
+	MaxKeys int
+	MaxSize int
+}
+
+func (e *InfoTooLargeError) Error() string {
+	var over []string
+	if n := e.Keys - e.MaxKeys; n > 0 {
+		over = append(over, plural(n, "key"))
+	}
+	if n := e.Size - e.MaxSize; n > 0 {
+		over = append(over, plural(n, "byte"))
+	}
+	return fmt.Sprintf("b2: bucket info would have %d keys and %d bytes, but B2 allows %d keys and %d bytes (%s over the limit)",
+		e.Keys, e.Size, e.MaxKeys, e.MaxSize, strings.Join(over, " and "))
+}
+
+func plural(n int, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+// Is reports whether target is ErrInfoTooLarge.
+func (e *InfoTooLargeError) Is(target error) bool {
+	return target == ErrInfoTooLarge
+}
+
+// ValidateBucketInfo reports whether info fits within B2's limits for bucket
+// info.  Programs that add to bucket info over time can use BucketInfoRoom to
+// learn how much room remains before they run out.
+func ValidateBucketInfo(info map[string]string) error {
+	keys, size := BucketInfoRoom(info)
+	if keys < 0 || size < 0 {
+		return &InfoTooLargeError{
+			Keys:    maxBucketInfoKeys - keys,
+			Size:    maxBucketInfoSize - size,
+			MaxKeys: maxBucketInfoKeys,
+			MaxSize: maxBucketInfoSize,
+		}
+	}
+	return nil
+}
+
+// BucketInfoRoom returns how many more keys, and bytes of keys and values,
+// info could hold within B2's limits for bucket info.  Either is negative if
+// info is already over that limit.
+func BucketInfoRoom(info map[string]string) (keys, size int) {
+	for k, v := range info {
+		size += len(k) + len(v)
+	}
+	return maxBucketInfoKeys - len(info), maxBucketInfoSize - size
+}
@@ -45,10 +45,18 @@ type writeBuffer interface {
 	io.Writer
 	Len() int
 	Reader() (readResetter, error)
-	Hash() string // sha1 or whatever it is
+	Hash() string           // sha1 or whatever it is
+	Sum() string            // the sha1 of the data read from the buffer, after an upload
+	HashTo(io.Writer) error // writes the contents to a whole-file hash
 	Close() error
 }
 
+// A presummer is a writeBuffer that sends its SHA1 after its data, unless it
+// is told the SHA1 in advance, in which case its data is not hashed at all.
+type presummer interface {
+	presum(sha string)
+}
+
 // nonBuffer doesn't buffer anything, but passes values directly from the
 // source readseeker.  Many nonBuffers can point at different parts of the same
 // underlying source, and be accessed by multiple goroutines simultaneously.
@@ -64,18 +72,44 @@ type nonBuffer struct {
 	r    *io.SectionReader
 	size int
 	hsh  hash.Hash
+	sum  string // the SHA1, if given in advance
 
 	isEOF bool
 	buf   *strings.Reader
 }
 
-func (nb *nonBuffer) Len() int                      { return nb.size + 40 }
-func (nb *nonBuffer) Hash() string                  { return "hex_digits_at_end" }
 func (nb *nonBuffer) Close() error                  { return nil }
 func (nb *nonBuffer) Reader() (readResetter, error) { return nb, nil }
 func (nb *nonBuffer) Write([]byte) (int, error)     { return 0, errors.New("writes not supported") }
+func (nb *nonBuffer) HashTo(io.Writer) error        { return errors.New("hashing not supported") }
+
+func (nb *nonBuffer) presum(sha string) { nb.sum = sha }
+
+func (nb *nonBuffer) Len() int {
+	if nb.sum != "" {
+		return nb.size
+	}
+	return nb.size + 40
+}
+
+func (nb *nonBuffer) Hash() string {
+	if nb.sum != "" {
+		return nb.sum
+	}
+	return "hex_digits_at_end"
+}
+
+func (nb *nonBuffer) Sum() string {
+	if nb.sum != "" {
+		return nb.sum
+	}
+	return fmt.Sprintf("%x", nb.hsh.Sum(nil))
+}
 
 func (nb *nonBuffer) Read(p []byte) (int, error) {
+	if nb.sum != "" {
+		return nb.r.Read(p)
+	}
 	if nb.isEOF {
 		return nb.buf.Read(p)
 	}
@@ -95,10 +129,105 @@ func (nb *nonBuffer) Reset() error {
 	return err
 }
 
+// streamBuffer, like nonBuffer, passes data directly from its source, but the
+// source is an arbitrary io.Reader.  If the source is not also an io.Seeker,
+// it can only be read once, and so uploads from it cannot be retried.
+// Retries seek back to wherever the source was when the buffer was made,
+// which need not be its start.
+func newStreamBuffer(r io.Reader, size int64) writeBuffer {
+	sb := &streamBuffer{
+		r:     io.LimitReader(r, size),
+		src:   r,
+		size:  int(size),
+		hsh:   sha1.New(),
+		start: -1,
+	}
+	if rs, ok := r.(io.Seeker); ok {
+		if start, err := rs.Seek(0, io.SeekCurrent); err == nil {
+			sb.start = start
+		}
+	}
+	return sb
+}
+
+type streamBuffer struct {
+	r     io.Reader
+	src   io.Reader
+	size  int
+	start int64 // the source's offset when the buffer was made, or -1
+	hsh   hash.Hash
+	sum   string // the SHA1, if given in advance
+	used  bool
+
+	isEOF bool
+	buf   *strings.Reader
+}
+
+func (sb *streamBuffer) Close() error                  { return nil }
+func (sb *streamBuffer) Reader() (readResetter, error) { return sb, nil }
+func (sb *streamBuffer) Write([]byte) (int, error)     { return 0, errors.New("writes not supported") }
+func (sb *streamBuffer) HashTo(io.Writer) error        { return errors.New("hashing not supported") }
+
+func (sb *streamBuffer) presum(sha string) { sb.sum = sha }
+
+func (sb *streamBuffer) Len() int {
+	if sb.sum != "" {
+		return sb.size
+	}
+	return sb.size + 40
+}
+
+func (sb *streamBuffer) Hash() string {
+	if sb.sum != "" {
+		return sb.sum
+	}
+	return "hex_digits_at_end"
+}
+
+func (sb *streamBuffer) Sum() string {
+	if sb.sum != "" {
+		return sb.sum
+	}
+	return fmt.Sprintf("%x", sb.hsh.Sum(nil))
+}
+
+func (sb *streamBuffer) Read(p []byte) (int, error) {
+	sb.used = true
+	if sb.sum != "" {
+		return sb.r.Read(p)
+	}
+	if sb.isEOF {
+		return sb.buf.Read(p)
+	}
+	n, err := io.TeeReader(sb.r, sb.hsh).Read(p)
+	if err == io.EOF {
+		err = nil
+		sb.isEOF = true
+		sb.buf = strings.NewReader(fmt.Sprintf("%x", sb.hsh.Sum(nil)))
+	}
+	return n, err
+}
+
+func (sb *streamBuffer) Reset() error {
+	if !sb.used {
+		return nil
+	}
+	rs, ok := sb.src.(io.Seeker)
+	if !ok || sb.start < 0 {
+		return errors.New("b2: cannot retry an upload from a non-seekable reader")
+	}
+	if _, err := rs.Seek(sb.start, io.SeekStart); err != nil {
+		return err
+	}
+	sb.hsh.Reset()
+	sb.isEOF = false
+	sb.r = io.LimitReader(sb.src, int64(sb.size))
+	return nil
+}
+
 type memoryBuffer struct {
 	buf *bytes.Buffer
-	hsh hash.Hash
-	w   io.Writer
+	sha string
 	mux sync.Mutex
 }
 
@@ -110,18 +239,33 @@ func init() {
 }
 
 func newMemoryBuffer() *memoryBuffer {
-	mb := &memoryBuffer{
-		hsh: sha1.New(),
+	return &memoryBuffer{
+		buf: bufpool.Get().(*bytes.Buffer),
 	}
-	mb.buf = bufpool.Get().(*bytes.Buffer)
-	mb.w = io.MultiWriter(mb.hsh, mb.buf)
-	return mb
 }
 
-func (mb *memoryBuffer) Write(p []byte) (int, error)   { return mb.w.Write(p) }
+func (mb *memoryBuffer) Write(p []byte) (int, error)   { return mb.buf.Write(p) }
 func (mb *memoryBuffer) Len() int                      { return mb.buf.Len() }
 func (mb *memoryBuffer) Reader() (readResetter, error) { return newResetter(mb.buf.Bytes()), nil }
-func (mb *memoryBuffer) Hash() string                  { return fmt.Sprintf("%x", mb.hsh.Sum(nil)) }
+func (mb *memoryBuffer) Sum() string                   { return mb.Hash() }
+
+// Hash is computed when it is first needed, which for large files is by the
+// thread uploading the chunk, rather than on the Write path.
+func (mb *memoryBuffer) Hash() string {
+	mb.mux.Lock()
+	defer mb.mux.Unlock()
+	if mb.sha == "" {
+		mb.sha = fmt.Sprintf("%x", sha1.Sum(mb.buf.Bytes()))
+	}
+	return mb.sha
+}
+
+// HashTo may run alongside Hash and Reader, which also only read the buffer,
+// but not Write or Close.
+func (mb *memoryBuffer) HashTo(w io.Writer) error {
+	_, err := w.Write(mb.buf.Bytes())
+	return err
+}
 
 func (mb *memoryBuffer) Close() error {
 	mb.mux.Lock()
@@ -163,6 +307,19 @@ func (fb *fileBuffer) Write(p []byte) (int, error) {
 
 func (fb *fileBuffer) Len() int     { return fb.s }
 func (fb *fileBuffer) Hash() string { return fmt.Sprintf("%x", fb.hsh.Sum(nil)) }
+func (fb *fileBuffer) Sum() string  { return fb.Hash() }
+
+// HashTo reads the file through its own handle, so as not to disturb uploads
+// from it.
+func (fb *fileBuffer) HashTo(w io.Writer) error {
+	f, err := os.Open(fb.f.Name())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(w, f)
+	return err
+}
 
 func (fb *fileBuffer) Reader() (readResetter, error) {
 	if _, err := fb.f.Seek(0, 0); err != nil {
@@ -0,0 +1,171 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// maxPartSize is the largest part that b2_copy_part will copy.
+	maxPartSize = 5e9
+
+	// defaultMinPartSize is used when the account does not report its own
+	// absolute minimum part size.
+	defaultMinPartSize = 5e6
+)
+
+// Concatenate creates a new object named dst whose contents are the contents
+// of srcs, in order.  The data are copied server-side, part by part, and
+// never pass through the client, which makes it suitable for e.g. compacting
+// many small log segments into one.  The new object takes its content type
+// from the first source.
+//
+// B2 requires every part of a large file but the last to be at least the
+// account's absolute minimum part size (5MB), so every source but the last
+// must be at least that large.  A single source is simply copied.  If
+// Concatenate fails, the partially assembled file is cancelled.
+func (b *Bucket) Concatenate(ctx context.Context, dst string, srcs ...*Object) (*Object, error) {
+	if len(srcs) == 0 {
+		return nil, errors.New("b2: concatenate: no sources")
+	}
+	if err := ValidateName(dst); err != nil {
+		return nil, err
+	}
+	_, min := b.r.partSizes()
+	if min <= 0 {
+		min = defaultMinPartSize
+	}
+	spans := make([]span, len(srcs))
+	var ctype string
+	for i, src := range srcs {
+		attrs, err := src.Attrs(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			ctype = attrs.ContentType
+		}
+		if i < len(srcs)-1 && attrs.Size < int64(min) {
+			return nil, fmt.Errorf("b2: concatenate: %s: %d bytes is smaller than the minimum part size (%d)", src.name, attrs.Size, min)
+		}
+		spans[i] = span{src: src, size: attrs.Size}
+	}
+	return b.assemble(ctx, dst, ctype, nil, spans)
+}
+
+// CopyRange creates a new object named dst from length bytes of o, starting
+// at offset.  If length is negative, the rest of the object is copied.  Like
+// Concatenate, the data are copied server-side, so a slice of a very large
+// object can be extracted without downloading it.  The new object has the
+// content type and info of o, except that the whole-object hash that large
+// files record in large_file_sha1 is not copied to a part of o.
+func (o *Object) CopyRange(ctx context.Context, dst string, offset, length int64) (*Object, error) {
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 0 || offset > attrs.Size {
+		return nil, fmt.Errorf("b2: copy range: %s: offset %d out of bounds (size %d)", o.name, offset, attrs.Size)
+	}
+	if length < 0 || offset+length > attrs.Size {
+		length = attrs.Size - offset
+	}
+	if length == 0 {
+		return nil, fmt.Errorf("b2: copy range: %s: empty range", o.name)
+	}
+	var info map[string]string
+	if offset > 0 || length < attrs.Size {
+		// Copying o's metadata would copy its hash, which is not the hash
+		// of the range.
+		info = make(map[string]string)
+		for k, v := range attrs.RawInfo {
+			if k != infoLargeFileSHA1 {
+				info[k] = v
+			}
+		}
+	}
+	return o.b.assemble(ctx, dst, attrs.ContentType, info, []span{{src: o, offset: offset, size: length}})
+}
+
+// span is a range of bytes in an existing object.
+type span struct {
+	src          *Object
+	offset, size int64
+}
+
+// assemble creates dst from spans, with ctype and info.  A single span small
+// enough for b2_copy_file is copied directly, with its source's metadata if
+// info is nil; otherwise the spans are copied into a new large file, which is
+// cancelled on failure.
+func (b *Bucket) assemble(ctx context.Context, dst, ctype string, info map[string]string, spans []span) (*Object, error) {
+	if len(spans) == 1 && spans[0].size <= maxPartSize {
+		sp := spans[0]
+		f, err := sp.src.f.copyFile(ctx, dst, sp.offset, sp.size, ctype, info)
+		if err != nil {
+			return nil, err
+		}
+		return &Object{name: dst, f: f, b: b, resp: &objectResponse{}}, nil
+	}
+
+	lf, err := b.b.startLargeFile(ctx, dst, ctype, info)
+	if err != nil {
+		return nil, err
+	}
+	f, err := copyParts(ctx, lf, spans)
+	if err != nil {
+		cctx := ctx
+		if ctx.Err() != nil {
+			var cancel context.CancelFunc
+			cctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+		}
+		if cerr := lf.cancel(cctx); cerr != nil {
+			b.log().V(1).Infof("error cancelling large file %s: %v", dst, cerr)
+		}
+		return nil, err
+	}
+	return &Object{name: dst, f: f, b: b, resp: &objectResponse{}}, nil
+}
+
+// copyParts copies each span into lf, splitting those too large for a single
+// part into evenly sized pieces, and then finishes lf.
+func copyParts(ctx context.Context, lf beLargeFileInterface, spans []span) (beFileInterface, error) {
+	part := 1
+	for _, sp := range spans {
+		n := (sp.size + maxPartSize - 1) / maxPartSize
+		if n < 1 {
+			n = 1
+		}
+		chunk := (sp.size + n - 1) / n
+		for off := int64(0); off == 0 || off < sp.size; off += chunk {
+			size := chunk
+			if off+size > sp.size {
+				size = sp.size - off
+			}
+			if _, err := lf.copyPart(ctx, sp.src.ID(), part, sp.offset+off, size); err != nil {
+				return nil, err
+			}
+			part++
+			if chunk == 0 {
+				break
+			}
+		}
+	}
+	return lf.finishLargeFile(ctx)
+}
@@ -0,0 +1,96 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestCopyRangeOfLargeFile(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{PartSize: 1e3, MinimumPartSize: 1e3})
+	defer s.Close()
+
+	client, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := make([]byte, 5e3)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	src := bucket.Object("src")
+	// The source records the hash of all of its data in large_file_sha1.
+	w := src.NewWriter(ctx,
+		b2.WithAttrsOption(&b2.Attrs{ContentType: "text/plain", Info: map[string]string{"color": "blue"}}),
+		b2.WithSHA1(fmt.Sprintf("%x", sha1.Sum(data))))
+	w.ChunkSize = 1e3
+	if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if attrs, err := src.Attrs(ctx); err != nil || attrs.RawInfo["large_file_sha1"] == "" {
+		t.Fatalf("source: got %v, %v; want a large file with large_file_sha1", attrs, err)
+	}
+
+	slice, err := src.CopyRange(ctx, "slice", 1500, 2000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := data[1500:3500]
+	attrs, err := slice.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprintf("%x", sha1.Sum(want)); attrs.SHA1 != got {
+		t.Errorf("SHA1: got %q, want the slice's %q", attrs.SHA1, got)
+	}
+	if attrs.ContentType != "text/plain" || attrs.Info["color"] != "blue" {
+		t.Errorf("metadata: got %q and %v, want the source's", attrs.ContentType, attrs.Info)
+	}
+
+	r := bucket.Object("slice").NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("read: got %d bytes, want %d bytes of the source", len(got), len(want))
+	}
+	rep, err := bucket.Verify(ctx, "slice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Verified != 1 || len(rep.Failures) != 0 {
+		t.Errorf("Verify: got %d verified and failures %v, want the slice verified", rep.Verified, rep.Failures)
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// consistencyHeader is the header in which blazer expects B2 to return, and
+// accept, read-after-write consistency tokens.  B2 does not define it today;
+// it stays unexported until B2 documents one.
+const consistencyHeader = "X-Bz-Client-Consistency"
+
+// objectResponse holds what an Object has learned from its latest response.
+type objectResponse struct {
+	mu     sync.Mutex
+	header http.Header
+	token  string
+}
+
+func (or *objectResponse) record(h http.Header) {
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	or.header = h
+	if t := h.Get(consistencyHeader); t != "" {
+		or.token = t
+	}
+}
+
+// Header returns the HTTP headers of the latest response B2 sent to a request
+// made for o, by its methods or by its Readers and Writers, or nil if there
+// has been none.  It includes any headers blazer does not otherwise expose,
+// and is intended for advanced users.  When requests are concurrent, as with
+// large files, which response is the latest is arbitrary.
+func (o *Object) Header() http.Header {
+	or := o.resp
+	if or == nil {
+		return nil
+	}
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	return or.header.Clone()
+}
+
+// ConsistencyToken returns the latest consistency token B2 has returned for
+// o, or the empty string if there is none.  Later requests made for o send
+// the token back, so that they observe o's writes; to share it with other
+// Objects, clients, or processes, see WithConsistencyToken.
+func (o *Object) ConsistencyToken() string {
+	or := o.resp
+	if or == nil {
+		return ""
+	}
+	or.mu.Lock()
+	defer or.mu.Unlock()
+	return or.token
+}
+
+type consistencyKey struct{}
+type objectKey struct{}
+
+// WithConsistencyToken returns a context that sends token to B2 with every
+// request made with it, so that the requests observe the writes the token was
+// returned for.  A token given this way takes precedence over an Object's own.
+func WithConsistencyToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, consistencyKey{}, token)
+}
+
+// forObject returns a context that records the responses to the requests
+// made with it on o.
+func (o *Object) forObject(ctx context.Context) context.Context {
+	if o.resp == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, objectKey{}, o.resp)
+}
+
+// consistent returns r with the consistency token from its context, if any,
+// and the objectResponse to record its response in, if any.
+func consistent(r *http.Request) (*http.Request, *objectResponse) {
+	ctx := r.Context()
+	or, _ := ctx.Value(objectKey{}).(*objectResponse)
+	token, _ := ctx.Value(consistencyKey{}).(string)
+	if token == "" && or != nil {
+		or.mu.Lock()
+		token = or.token
+		or.mu.Unlock()
+	}
+	if token != "" {
+		r = r.Clone(ctx)
+		r.Header.Set(consistencyHeader, token)
+	}
+	return r, or
+}
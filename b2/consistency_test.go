@@ -0,0 +1,111 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+// consistencyHeader is the header in which blazer expects consistency tokens.
+const consistencyHeader = "X-Bz-Client-Consistency"
+
+// consistencyTransport adds a consistency token to upload responses, and
+// records the tokens sent with downloads.
+type consistencyTransport struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (ct *consistencyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	method := r.Header.Get("X-Blazer-Method")
+	if strings.HasPrefix(method, "b2_download_file") {
+		ct.mu.Lock()
+		ct.sent = append(ct.sent, r.Header.Get(consistencyHeader))
+		ct.mu.Unlock()
+	}
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err == nil && method == "b2_upload_file" {
+		resp.Header.Set(consistencyHeader, "token-1")
+	}
+	return resp, err
+}
+
+func TestConsistencyToken(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	ct := &consistencyTransport{}
+	client, err := s.Client(ctx, b2.Transport(ct))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := bucket.Object("obj")
+	if h := obj.Header(); h != nil {
+		t.Errorf("Header before any request: got %v, want nil", h)
+	}
+	w := obj.NewWriter(ctx)
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := obj.ConsistencyToken(); got != "token-1" {
+		t.Errorf("ConsistencyToken: got %q, want %q", got, "token-1")
+	}
+	if got := obj.Header().Get(consistencyHeader); got != "token-1" {
+		t.Errorf("Header: got token %q, want %q", got, "token-1")
+	}
+
+	read := func(ctx context.Context, o *b2.Object) {
+		r := o.NewReader(ctx)
+		defer r.Close()
+		if _, err := ioutil.ReadAll(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read(ctx, obj)
+	if h := obj.Header(); h == nil || h.Get(consistencyHeader) != "" {
+		t.Errorf("Header after read: got %v, want the download's headers", h)
+	}
+	read(ctx, bucket.Object("obj"))
+	read(b2.WithConsistencyToken(ctx, "token-2"), bucket.Object("obj"))
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	if len(ct.sent) != 6 {
+		t.Fatalf("got %d downloads, want 6: %q", len(ct.sent), ct.sent)
+	}
+	// Each read is two requests: one for the data, and one past the end.
+	want := []string{"token-1", "token-1", "", "", "token-2", "token-2"}
+	for i := range want {
+		if ct.sent[i] != want[i] {
+			t.Errorf("download %d: sent token %q, want %q", i, ct.sent[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"io"
+	"sync/atomic"
+)
+
+// A CallClass is the class by which B2 bills an API call.
+type CallClass int
+
+const (
+	// ClassA calls, such as uploads and deletions, are free.
+	ClassA CallClass = iota
+
+	// ClassB calls are downloads and b2_get_file_info.
+	ClassB
+
+	// ClassC calls are listings, copies, download authorizations, and
+	// bucket and key management.
+	ClassC
+)
+
+var callClasses = map[string]CallClass{
+	"b2_cancel_large_file":           ClassA,
+	"b2_delete_bucket":               ClassA,
+	"b2_delete_file_version":         ClassA,
+	"b2_delete_key":                  ClassA,
+	"b2_finish_large_file":           ClassA,
+	"b2_get_upload_part_url":         ClassA,
+	"b2_get_upload_url":              ClassA,
+	"b2_hide_file":                   ClassA,
+	"b2_start_large_file":            ClassA,
+	"b2_update_file_legal_hold":      ClassA,
+	"b2_update_file_retention":       ClassA,
+	"b2_upload_file":                 ClassA,
+	"b2_upload_part":                 ClassA,
+	"b2_download_file_by_id":         ClassB,
+	"b2_download_file_by_name":       ClassB,
+	"b2_get_file_info":               ClassB,
+	"b2_authorize_account":           ClassC,
+	"b2_copy_file":                   ClassC,
+	"b2_copy_part":                   ClassC,
+	"b2_create_bucket":               ClassC,
+	"b2_create_key":                  ClassC,
+	"b2_get_download_authorization":  ClassC,
+	"b2_list_buckets":                ClassC,
+	"b2_list_file_names":             ClassC,
+	"b2_list_file_versions":          ClassC,
+	"b2_list_keys":                   ClassC,
+	"b2_list_parts":                  ClassC,
+	"b2_list_unfinished_large_files": ClassC,
+	"b2_update_bucket":               ClassC,
+}
+
+// MethodClass returns the class by which B2 bills calls to method, such as
+// "b2_list_file_names".  Methods it does not know are reported as ClassC, the
+// most expensive.
+func MethodClass(method string) CallClass {
+	if c, ok := callClasses[method]; ok {
+		return c
+	}
+	return ClassC
+}
+
+// Pricing gives the prices, in US dollars, with which a CostEstimate is
+// computed.
+type Pricing struct {
+	// ClassA, ClassB, and ClassC are the prices of 1,000 calls of each
+	// class.
+	ClassA float64
+	ClassB float64
+	ClassC float64
+
+	// Download is the price of downloading a gigabyte (10^9 bytes).
+	Download float64
+}
+
+// DefaultPricing is B2's published pricing at the time of writing.  It does
+// not account for B2's daily free allowances, nor for download bandwidth that
+// is free because of the amount stored, and so overestimates the cost of
+// small jobs.  Programs can change it, or give a client other prices with
+// WithPricing.
+var DefaultPricing = Pricing{
+	ClassA:   0,
+	ClassB:   0.0004,
+	ClassC:   0.004,
+	Download: 0.01,
+}
+
+// WithPricing sets the prices the client's CostEstimate uses.  The default is
+// DefaultPricing.
+func WithPricing(p Pricing) ClientOption {
+	return func(o *clientOptions) {
+		o.pricing = &p
+	}
+}
+
+// A CostEstimate estimates what a client's use of B2 has cost.  It counts
+// the API calls the client has made, including those that failed, and the
+// bytes it has downloaded.  Storage is not included.
+type CostEstimate struct {
+	// ClassA, ClassB, and ClassC count the calls made of each class.
+	ClassA int64
+	ClassB int64
+	ClassC int64
+
+	// DownloadBytes counts the bytes of the bodies of download responses.
+	DownloadBytes int64
+
+	// Dollars is the estimated cost, in US dollars.
+	Dollars float64
+}
+
+// Cost returns the cost of the calls and downloads counted in e, at the
+// given prices.
+func (e *CostEstimate) Cost(p Pricing) float64 {
+	return float64(e.ClassA)/1000*p.ClassA +
+		float64(e.ClassB)/1000*p.ClassB +
+		float64(e.ClassC)/1000*p.ClassC +
+		float64(e.DownloadBytes)/1e9*p.Download
+}
+
+// CostEstimate estimates what the client's calls and downloads have cost
+// since it was created, at the prices set with WithPricing.  This allows
+// batch jobs to report their approximate spend.
+func (c *Client) CostEstimate() *CostEstimate {
+	e := &CostEstimate{
+		ClassA:        atomic.LoadInt64(&c.calls[ClassA]),
+		ClassB:        atomic.LoadInt64(&c.calls[ClassB]),
+		ClassC:        atomic.LoadInt64(&c.calls[ClassC]),
+		DownloadBytes: atomic.LoadInt64(&c.downloaded),
+	}
+	p := DefaultPricing
+	if c.opts.pricing != nil {
+		p = *c.opts.pricing
+	}
+	e.Dollars = e.Cost(p)
+	return e
+}
+
+// countingBody counts the bytes read from a download response into n.
+type countingBody struct {
+	io.ReadCloser
+	n *int64
+}
+
+func (cb countingBody) Read(p []byte) (int, error) {
+	n, err := cb.ReadCloser.Read(p)
+	atomic.AddInt64(cb.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,74 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestCostEstimate(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	prices := b2.Pricing{ClassA: 1, ClassB: 10, ClassC: 100, Download: 1e6}
+	client, err := s.Client(ctx, b2.WithPricing(prices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil) // list, create
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Repeat("x", 1000)
+	if err := bucket.Object("obj").WriteFrom(ctx, strings.NewReader(data), int64(len(data))); err != nil { // get url, upload
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		r := bucket.Object("obj").NewReader(ctx)
+		if _, err := ioutil.ReadAll(r); err != nil {
+			t.Fatal(err)
+		}
+		r.Close()
+	}
+
+	got := client.CostEstimate()
+	want := &b2.CostEstimate{
+		ClassA:        2,
+		ClassB:        4, // each read also asks for the bytes past the end
+		ClassC:        3, // including the authorization
+		DownloadBytes: 2000,
+	}
+	want.Dollars = 2*1e-3 + 4*1e-2 + 3*1e-1 + 2000*1e-3
+	if math.Abs(got.Dollars-want.Dollars) > 1e-9 {
+		t.Errorf("Dollars: got %v, want %v", got.Dollars, want.Dollars)
+	}
+	got.Dollars = want.Dollars
+	if *got != *want {
+		t.Errorf("CostEstimate: got %+v, want %+v", got, want)
+	}
+	for _, m := range []string{"b2_list_file_names", "b2_get_download_authorization"} {
+		if c := b2.MethodClass(m); c != b2.ClassC {
+			t.Errorf("%s: got class %v, want C", m, c)
+		}
+	}
+}
@@ -0,0 +1,368 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Prealloc says how DownloadToFile sets aside space for a file before
+// downloading it.
+type Prealloc int
+
+const (
+	// PreallocSparse extends the file to its full size without allocating
+	// any blocks, so that the file is sparse until its chunks are written.
+	// This is the default.
+	PreallocSparse Prealloc = iota
+
+	// PreallocFull allocates every block of the file before downloading, so
+	// that a download cannot fail partway for lack of space.  Where this is
+	// not supported (outside Linux, or on file systems without fallocate),
+	// the file is extended as with PreallocSparse instead.
+	PreallocFull
+
+	// PreallocNone leaves the file to grow as its chunks are written.
+	PreallocNone
+)
+
+// SyncPolicy says when DownloadToFile flushes a file to stable storage.
+type SyncPolicy int
+
+const (
+	// SyncEnd syncs the file once, after the last chunk is written, so that
+	// the file is durable when DownloadToFile returns.  This is the default.
+	SyncEnd SyncPolicy = iota
+
+	// SyncChunk syncs the file after each chunk is written, as well as at
+	// the end, which bounds how much is lost to a crash at the cost of
+	// throughput.
+	SyncChunk
+
+	// SyncNever leaves flushing to the operating system.
+	SyncNever
+)
+
+type fileOptions struct {
+	workers  int
+	csize    int64
+	buffer   int
+	prealloc Prealloc
+	sync     SyncPolicy
+	verify   bool
+	atomic   bool
+}
+
+// A FileOption alters the behavior of DownloadToFile.
+type FileOption func(*fileOptions)
+
+// FileConcurrency sets the number of chunks that are downloaded at once.  The
+// default is 4.
+func FileConcurrency(n int) FileOption {
+	return func(o *fileOptions) {
+		o.workers = n
+	}
+}
+
+// FileChunkSize sets the size of the ranges in which the object is
+// downloaded.  The default is 100MB.
+func FileChunkSize(n int64) FileOption {
+	return func(o *fileOptions) {
+		o.csize = n
+	}
+}
+
+// FileBufferSize sets the size of the buffer through which each chunk is
+// copied from the network to the file.  Nothing else is buffered, so memory
+// use is FileConcurrency times this, regardless of the chunk size.  The
+// default is 1MB.
+func FileBufferSize(n int) FileOption {
+	return func(o *fileOptions) {
+		o.buffer = n
+	}
+}
+
+// FilePrealloc sets how space is set aside for the file.
+func FilePrealloc(p Prealloc) FileOption {
+	return func(o *fileOptions) {
+		o.prealloc = p
+	}
+}
+
+// FileSync sets when the file is flushed to stable storage.
+func FileSync(p SyncPolicy) FileOption {
+	return func(o *fileOptions) {
+		o.sync = p
+	}
+}
+
+// FileVerify causes DownloadToFile to read the file back once it is written,
+// and compare its SHA1 hash with the one B2 recorded on upload (for large
+// files, the large_file_sha1 info key).  A mismatch is an error.  Objects for
+// which B2 has no hash are not checked.
+func FileVerify() FileOption {
+	return func(o *fileOptions) {
+		o.verify = true
+	}
+}
+
+// FileAtomic causes DownloadToFile to write to a temporary file in the same
+// directory, and to rename it into place only once it is complete and
+// verified as with FileVerify, so that no one ever observes a partial file at
+// the destination.  An existing file is replaced, keeping its permissions.
+// Objects for which B2 has no hash are checked only for their size.  If the
+// download fails, any existing file is left as it was.
+func FileAtomic() FileOption {
+	return func(o *fileOptions) {
+		o.atomic = true
+		o.verify = true
+	}
+}
+
+// DownloadToFile downloads the object to the file at path, creating it or
+// truncating it.  Chunks of the object are downloaded concurrently and
+// written directly to their places in the file, without being buffered in
+// memory or reassembled in order, which makes it suitable for restoring very
+// large objects.
+//
+// The version downloaded is the one that is current when DownloadToFile is
+// called.  If DownloadToFile fails, the file is removed, so that a file is
+// never left looking complete when it is not; but a crash may still leave a
+// partial file behind, which is sparse or zero-filled where chunks were not
+// yet written.  FileAtomic avoids this.
+func (o *Object) DownloadToFile(ctx context.Context, path string, opts ...FileOption) error {
+	fo := fileOptions{workers: 4, csize: 1e8, buffer: 1 << 20}
+	for _, opt := range opts {
+		opt(&fo)
+	}
+	if fo.workers < 1 {
+		fo.workers = 1
+	}
+	if fo.csize < 1 {
+		fo.csize = 1e8
+	}
+	if fo.buffer < 1 {
+		fo.buffer = 1 << 20
+	}
+	ctx = o.forObject(ctx)
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return err
+	}
+	if fo.atomic {
+		return o.downloadAtomic(ctx, path, attrs, fo)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0666)
+	if err != nil {
+		return err
+	}
+	if err := o.downloadTo(ctx, f, attrs, fo); err != nil {
+		f.Close()
+		os.Remove(path)
+		return err
+	}
+	return f.Close()
+}
+
+// downloadAtomic downloads the object to a temporary file beside path, and
+// renames it to path when it is done.
+func (o *Object) downloadAtomic(ctx context.Context, path string, attrs *Attrs, fo fileOptions) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	mode := os.FileMode(0644)
+	if fi, err := os.Stat(path); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	f, err := os.CreateTemp(dir, "."+base+".b2tmp-*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	err = o.downloadTo(ctx, f, attrs, fo)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if fo.sync != SyncNever {
+		// Make the rename durable too.  Not every system can sync a
+		// directory, so this is best effort.
+		if d, err := os.Open(dir); err == nil {
+			d.Sync()
+			d.Close()
+		}
+	}
+	return nil
+}
+
+func (o *Object) downloadTo(ctx context.Context, f *os.File, attrs *Attrs, fo fileOptions) error {
+	size := attrs.Size
+	switch fo.prealloc {
+	case PreallocFull:
+		if err := fallocate(f, size); err != nil {
+			if err := f.Truncate(size); err != nil {
+				return err
+			}
+		}
+	case PreallocSparse:
+		if err := f.Truncate(size); err != nil {
+			return err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+		want = attrs.SHA1
+	)
+	offsets := make(chan int64)
+	for i := 0; i < fo.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf := make([]byte, fo.buffer)
+			for off := range offsets {
+				n := fo.csize
+				if off+n > size {
+					n = size - off
+				}
+				sha, err := o.downloadChunk(ctx, f, off, n, buf)
+				if err == nil && fo.sync == SyncChunk {
+					err = f.Sync()
+				}
+				mu.Lock()
+				if len(want) != 40 && len(sha) == 40 {
+					want = sha
+				}
+				if err != nil && rerr == nil {
+					rerr = err
+					cancel()
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for off := int64(0); off < size; off += fo.csize {
+		select {
+		case offsets <- off:
+		case <-ctx.Done():
+		}
+	}
+	close(offsets)
+	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if fo.sync != SyncNever {
+		if err := f.Sync(); err != nil {
+			return err
+		}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() != size {
+		return fmt.Errorf("b2: %s: downloaded file has %d bytes, want %d", o.name, fi.Size(), size)
+	}
+	if fo.verify && len(want) == 40 {
+		hsh := sha1.New()
+		if _, err := copyContext(ctx, hsh, io.NewSectionReader(f, 0, size)); err != nil {
+			return err
+		}
+		if got := fmt.Sprintf("%x", hsh.Sum(nil)); got != want {
+			return fmt.Errorf("b2: %s: downloaded file has SHA1 %s, want %s", o.name, got, want)
+		}
+	}
+	return nil
+}
+
+// downloadChunk writes size bytes of the object, starting at offset, to the
+// same place in f.  Transfers that fail partway are resumed where they
+// stopped, as a Reader's are.  It returns the object's SHA1 hash, as reported
+// with the download.
+func (o *Object) downloadChunk(ctx context.Context, f *os.File, offset, size int64, buf []byte) (string, error) {
+	var attempts int
+	var wait time.Duration
+	var sha string
+	for size > 0 {
+		fr, err := o.f.downloadFileByID(ctx, offset, size, false)
+		if err == errNoMoreContent {
+			return "", fmt.Errorf("b2: %s: object is shorter than its reported size", o.name)
+		}
+		if err != nil {
+			return "", err
+		}
+		_, _, sha, _ = fr.stats()
+		w := &offsetWriter{f: f, off: offset}
+		n, err := io.CopyBuffer(w, io.LimitReader(fr, size), buf)
+		fr.Close()
+		offset += n
+		size -= n
+		if size == 0 || ctx.Err() != nil {
+			return sha, ctx.Err()
+		}
+		if err == nil {
+			err = io.ErrUnexpectedEOF
+		}
+		if w.err != nil {
+			// The file, not the network, failed; retrying won't help.
+			return "", w.err
+		}
+		attempts++
+		o.b.log().V(1).Infof("b2 download %s: %dB short at %d (attempt %d): %v", o.name, size, offset, attempts, err)
+		if err := retryChunk(ctx, o.b.r, attempts, &wait, err); err != nil {
+			return "", err
+		}
+	}
+	return sha, nil
+}
+
+// offsetWriter writes sequentially to f, starting at off.
+type offsetWriter struct {
+	f   *os.File
+	off int64
+	err error // the last error from f
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.off)
+	w.off += int64(n)
+	w.err = err
+	return n, err
+}
@@ -0,0 +1,77 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestReadWithToken(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	client, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"public/a", "secret/b"} {
+		if err := bucket.Object(name).WriteFrom(ctx, strings.NewReader(name), int64(len(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token, err := bucket.AuthToken(ctx, "public/", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := func(name string, opts ...b2.ReaderOption) (string, error) {
+		r := bucket.Object(name).NewReader(ctx, opts...)
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		return string(b), err
+	}
+
+	// The client's own tokens are not needed, or renewed.
+	s.ExpireTokens()
+	auths := s.Calls("b2_authorize_account")
+	got, err := read("public/a", b2.ReadWithToken(token))
+	if err != nil {
+		t.Fatalf("reading with the token: %v", err)
+	}
+	if got != "public/a" {
+		t.Errorf("got %q, want %q", got, "public/a")
+	}
+	if _, err := read("secret/b", b2.ReadWithToken(token)); err == nil {
+		t.Errorf("reading outside the token's prefix: got nil error")
+	}
+	if n := s.Calls("b2_authorize_account"); n != auths {
+		t.Errorf("b2_authorize_account: got %d calls, want %d", n, auths)
+	}
+
+	if _, err := read("public/a", b2.ReadWithToken(token), b2.ReadNthNewest(1)); err == nil {
+		t.Errorf("ReadWithToken with ReadNthNewest: got nil error")
+	}
+}
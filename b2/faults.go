@@ -0,0 +1,143 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"sync"
+
+	"github.com/kurin/blazer/base"
+)
+
+// A Fault is an error that Faults injects into a client's requests.  The
+// client handles it exactly as it would the same error from B2: it retries
+// transient errors, reauthorizes on expired tokens, gets new upload URLs, and
+// returns everything else to the caller.
+type Fault struct {
+	// Status is the HTTP status, such as 503, and Code the B2 error code,
+	// such as "service_unavailable".  A zero Status fails the request as
+	// though the connection had been lost.
+	Status int
+	Code   string
+
+	// Message is the error message.
+	Message string
+
+	// RetryAfter is the number of seconds B2 asks the client to wait before
+	// retrying, as with the Retry-After header.
+	RetryAfter int
+}
+
+// Some faults that B2 commonly returns.
+var (
+	FaultUnavailable  = Fault{Status: 503, Code: "service_unavailable", Message: "service unavailable"}
+	FaultTooMany      = Fault{Status: 429, Code: "too_many_requests", Message: "too many requests"}
+	FaultExpiredToken = Fault{Status: 401, Code: "expired_auth_token", Message: "authorization token has expired"}
+	FaultNotFound     = Fault{Status: 404, Code: "not_found", Message: "not found"}
+	FaultConnection   = Fault{Message: "connection reset by peer"}
+)
+
+// Faults injects errors into the requests of a client made with WithFaults,
+// so that code that uses blazer can test its handling of failures without a
+// custom transport or a fake service.  Faults are chosen by API method, such as
+// "b2_upload_file" or "b2_download_file_by_name", and by how many calls have
+// been made to that method.  A Faults is safe for concurrent use, and may be
+// changed while the client is in use.
+type Faults struct {
+	mu    sync.Mutex
+	calls map[string]int
+	next  map[string][]Fault
+	at    map[string]map[int]Fault
+}
+
+// Fail causes the next n calls to method to fail with f.  Faults queued for
+// the same method are returned in the order they were queued.
+func (fs *Faults) Fail(method string, n int, f Fault) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.next == nil {
+		fs.next = make(map[string][]Fault)
+	}
+	for i := 0; i < n; i++ {
+		fs.next[method] = append(fs.next[method], f)
+	}
+}
+
+// FailCall causes the call'th call to method, counting from one, to fail with
+// f.  Calls already made are counted, and retries count as calls.
+func (fs *Faults) FailCall(method string, call int, f Fault) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.at == nil {
+		fs.at = make(map[string]map[int]Fault)
+	}
+	if fs.at[method] == nil {
+		fs.at[method] = make(map[int]Fault)
+	}
+	fs.at[method][call] = f
+}
+
+// Calls returns the number of calls that have been made to method, including
+// those that failed.
+func (fs *Faults) Calls(method string) int {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.calls[method]
+}
+
+// Reset discards every fault not yet injected, and the call counts.
+func (fs *Faults) Reset() {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	fs.calls = nil
+	fs.next = nil
+	fs.at = nil
+}
+
+// fault counts a call to method, and returns the fault it should fail with,
+// if any.
+func (fs *Faults) fault(method string) *base.Fault {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.calls == nil {
+		fs.calls = make(map[string]int)
+	}
+	fs.calls[method]++
+	f, ok := fs.at[method][fs.calls[method]]
+	if ok {
+		delete(fs.at[method], fs.calls[method])
+	} else if q := fs.next[method]; len(q) > 0 {
+		f, ok = q[0], true
+		fs.next[method] = q[1:]
+	}
+	if !ok {
+		return nil
+	}
+	return &base.Fault{
+		Status:     f.Status,
+		Code:       f.Code,
+		Message:    f.Message,
+		RetryAfter: f.RetryAfter,
+	}
+}
+
+// WithFaults injects the faults in fs into the client's requests.  Unlike
+// FailSomeUploads and ExpireSomeAuthTokens, which ask B2 for failures, faults
+// are injected by the client itself, just before each request would be sent,
+// and so work with any service.
+func WithFaults(fs *Faults) ClientOption {
+	return func(c *clientOptions) {
+		c.faults = fs
+	}
+}
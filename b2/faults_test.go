@@ -0,0 +1,193 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestFaults(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	fs := &b2.Faults{}
+	client, err := s.Client(ctx, b2.WithFaults(fs), b2.RetryPolicy(b2.RetrySettings{
+		Initial:       time.Millisecond,
+		Max:           time.Millisecond,
+		UploadInitial: time.Millisecond,
+		UploadMax:     time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := bucket.Object("obj")
+
+	// Transient errors are retried.
+	fs.Fail("b2_upload_file", 2, b2.FaultUnavailable)
+	fs.FailCall("b2_get_upload_url", 2, b2.FaultTooMany)
+	w := obj.NewWriter(ctx)
+	if _, err := w.Write([]byte("data")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fs.Calls("b2_upload_file"), 3; got != want {
+		t.Errorf("b2_upload_file: got %d calls, want %d", got, want)
+	}
+	if got := s.Calls("b2_upload_file"); got != 1 {
+		t.Errorf("b2_upload_file: %d calls reached the service, want 1", got)
+	}
+
+	// Expired tokens cause the client to reauthorize.
+	fs.Fail("b2_download_file_by_id", 1, b2.FaultExpiredToken)
+	r := obj.NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if string(got) != "data" {
+		t.Errorf("read: got %q, want %q", got, "data")
+	}
+	if got, want := fs.Calls("b2_authorize_account"), 2; got != want {
+		t.Errorf("b2_authorize_account: got %d calls, want %d", got, want)
+	}
+
+	// Other errors are returned as they would be from B2.
+	fs.Fail("b2_download_file_by_id", 1, b2.FaultNotFound)
+	fs.Fail("b2_download_file_by_name", 1, b2.FaultNotFound)
+	r = obj.NewReader(ctx)
+	if _, err := ioutil.ReadAll(r); !b2.IsNotExist(err) {
+		t.Errorf("read: got %v, want a not-exist error", err)
+	}
+	r.Close()
+	fs.Fail("b2_hide_file", 1, b2.Fault{Status: 400, Code: "bad_request", Message: "no"})
+	if err := obj.Hide(ctx); err == nil {
+		t.Error("Hide: got nil error")
+	}
+
+	fs.Reset()
+	if got := fs.Calls("b2_upload_file"); got != 0 {
+		t.Errorf("after Reset: got %d calls, want 0", got)
+	}
+}
+
+func TestWriteFromRetryOffset(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	client, err := s.Client(ctx, b2.RetryPolicy(b2.RetrySettings{
+		UploadInitial: time.Millisecond,
+		UploadMax:     time.Millisecond,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A retried upload rereads the source from where it started, not from
+	// the beginning of the reader.
+	const header, body = "header:", "the body of the object"
+	r := bytes.NewReader([]byte(header + body))
+	if _, err := r.Seek(int64(len(header)), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	s.Fail("b2_upload_file", 1, &b2fake.Error{Status: 503, Code: "service_unavailable"})
+	obj := bucket.Object("obj")
+	if err := obj.WriteFrom(ctx, r, int64(len(body))); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Calls("b2_upload_file"); got != 2 {
+		t.Errorf("b2_upload_file: got %d calls, want 2", got)
+	}
+	or := obj.NewReader(ctx)
+	got, err := ioutil.ReadAll(or)
+	or.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != body {
+		t.Errorf("read: got %q, want %q", got, body)
+	}
+}
+
+func TestDownloadByNameFallback(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	fs := &b2.Faults{}
+	client, err := s.Client(ctx, b2.WithFaults(fs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := bucket.Object("obj")
+	if err := obj.WriteFrom(ctx, strings.NewReader("first"), 5); err != nil {
+		t.Fatal(err)
+	}
+	read := func() (string, error) {
+		r := obj.NewReader(ctx)
+		defer r.Close()
+		b, err := ioutil.ReadAll(r)
+		return string(b), err
+	}
+
+	// A failed download by ID is made by name, when the name still refers to
+	// the same version.
+	fs.Fail("b2_download_file_by_id", 1, b2.FaultNotFound)
+	got, err := read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "first" {
+		t.Errorf("read: got %q, want %q", got, "first")
+	}
+	if n := fs.Calls("b2_download_file_by_name"); n != 1 {
+		t.Errorf("b2_download_file_by_name: got %d calls, want 1", n)
+	}
+
+	// Once the name refers to another version, the pinned version's own
+	// error is returned instead.
+	if err := bucket.Object("obj").WriteFrom(ctx, strings.NewReader("second"), 6); err != nil {
+		t.Fatal(err)
+	}
+	fs.Fail("b2_download_file_by_id", 1, b2.FaultNotFound)
+	if got, err := read(); !b2.IsNotExist(err) {
+		t.Errorf("read after replacement: got %q, %v; want a not-exist error", got, err)
+	}
+}
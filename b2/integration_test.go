@@ -932,6 +932,23 @@ func TestZeroByteObject(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	obj := bucket.Object(smallFileName)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.Size != 0 {
+		t.Errorf("Attrs: got size %d, want 0", attrs.Size)
+	}
+	r := obj.NewReader(ctx)
+	defer r.Close()
+	if _, err := io.Copy(ioutil.Discard, r); err != nil {
+		t.Fatal(err)
+	}
+	if err, ok := r.Verify(); err != nil || !ok {
+		t.Errorf("Verify: got (%v, %v), want (nil, true)", err, ok)
+	}
+
 	if err := bucket.Object(smallFileName).Delete(ctx); err != nil {
 		t.Fatal(err)
 	}
@@ -16,8 +16,12 @@ package b2
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"io"
 	"sync"
+	"time"
 )
 
 // List returns an iterator for selecting objects in a bucket.  The default
@@ -56,11 +60,26 @@ type ObjectIterator struct {
 	init   sync.Once
 	l      lister
 	count  int
+	mark   time.Time
+	stalls int       // consecutive empty pages that did not advance the cursor
+	last   time.Time // when the last page was requested, for ListBudget
 }
 
 type lister func(context.Context, int, *cursor) ([]*Object, *cursor, error)
 
 func (o *ObjectIterator) page(ctx context.Context) error {
+	if o.opts.budget > 0 {
+		clk := o.bucket.r.clock()
+		gap := time.Hour / time.Duration(o.opts.budget)
+		if wait := o.last.Add(gap).Sub(clk.Now()); !o.last.IsZero() && wait > 0 {
+			select {
+			case <-clk.After(wait):
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		o.last = clk.Now()
+	}
 	if o.opts.locker != nil {
 		o.opts.locker.Lock()
 		defer o.opts.locker.Unlock()
@@ -75,6 +94,16 @@ func (o *ObjectIterator) page(ctx context.Context) error {
 		}
 		return err
 	}
+	if err == nil && len(objs) == 0 && sameCursor(c, o.c) {
+		// B2 sometimes returns an empty page whose continuation is where we
+		// started.  Ask again, but not forever.
+		o.stalls++
+		if o.stalls > maxListStalls {
+			return fmt.Errorf("b2: listing made no progress after %d pages", o.stalls)
+		}
+	} else {
+		o.stalls = 0
+	}
 	o.c = c
 	o.objs = objs
 	o.idx = 0
@@ -84,11 +113,55 @@ func (o *ObjectIterator) page(ctx context.Context) error {
 	return nil
 }
 
+const maxListStalls = 3
+
+func sameCursor(a, b *cursor) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.name == b.name && a.id == b.id
+}
+
+// endOfList returns io.EOF if the page is the last, and nil otherwise.  Pages
+// may be empty without being the last.
+func endOfList(next *cursor) error {
+	if next == nil {
+		return io.EOF
+	}
+	return nil
+}
+
 // Next advances the iterator to the next object.  It should be called before
 // any calls to Object().  If Next returns true, then the next call to Object()
 // will be valid.  Once Next returns false, it is important to check the return
 // value of Err().
 func (o *ObjectIterator) Next() bool {
+	o.start()
+	if o.err != nil {
+		return false
+	}
+	if o.ctx.Err() != nil {
+		o.err = o.ctx.Err()
+		return false
+	}
+	for o.idx >= len(o.objs) {
+		if o.final {
+			o.err = io.EOF
+			return false
+		}
+		if err := o.page(o.ctx); err != nil {
+			o.err = err
+			return false
+		}
+	}
+	o.idx++
+	if t := o.objs[o.idx-1].f.timestamp(); t.After(o.mark) {
+		o.mark = t
+	}
+	return true
+}
+
+func (o *ObjectIterator) start() {
 	o.init.Do(func() {
 		o.count = o.opts.pageSize
 		if o.count < 0 || o.count > 1000 {
@@ -100,6 +173,8 @@ func (o *ObjectIterator) Next() bool {
 			if o.count > 100 {
 				o.count = 100
 			}
+		case !o.opts.since.IsZero():
+			o.l = o.bucket.listChanges(o.opts.since)
 		case o.opts.hidden:
 			o.l = o.bucket.listObjects
 		default:
@@ -109,27 +184,61 @@ func (o *ObjectIterator) Next() bool {
 			prefix:    o.opts.prefix,
 			delimiter: o.opts.delimiter,
 		}
-	})
-	if o.err != nil {
-		return false
-	}
-	if o.ctx.Err() != nil {
-		o.err = o.ctx.Err()
-		return false
-	}
-	if o.idx >= len(o.objs) {
-		if o.final {
-			o.err = io.EOF
-			return false
+		o.err = o.opts.resumeErr
+		if r := o.opts.resume; r != nil {
+			o.c.name = r.Name
+			o.c.id = r.ID
+			o.final = r.Done
+			o.last = r.Last
 		}
-		if err := o.page(o.ctx); err != nil {
-			o.err = err
-			return false
+	})
+}
+
+// listPosition is the decoded form of a cursor returned by
+// ObjectIterator.Cursor.
+type listPosition struct {
+	Name string    `json:"name,omitempty"`
+	ID   string    `json:"id,omitempty"`
+	Done bool      `json:"done,omitempty"`
+	Last time.Time `json:"last"`
+}
+
+// Cursor returns an opaque string that records how far the iteration has
+// progressed.  It can be saved, and passed to ListResume, with the same
+// options, to continue the listing later, even in another process, from the
+// object after the last one returned by Object.
+func (o *ObjectIterator) Cursor() string {
+	o.start()
+	pos := listPosition{Last: o.last}
+	switch {
+	case o.idx < len(o.objs):
+		next := o.objs[o.idx]
+		switch {
+		case o.opts.unfinished:
+			pos.Name = next.ID()
+		case o.opts.hidden || !o.opts.since.IsZero():
+			pos.Name, pos.ID = next.name, next.ID()
+		default:
+			pos.Name = next.name
 		}
-		return o.Next()
+	case o.final:
+		pos.Done = true
+	default:
+		pos.Name, pos.ID = o.c.name, o.c.id
 	}
-	o.idx++
-	return true
+	bs, err := json.Marshal(pos)
+	if err != nil {
+		// Marshalling strings, a bool, and a time cannot fail.
+		panic(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(bs)
+}
+
+// Watermark returns the latest upload timestamp of the objects returned so
+// far.  Once a listing made with ListSince is complete, this can be passed to
+// ListSince to list only the changes since.
+func (o *ObjectIterator) Watermark() time.Time {
+	return o.mark
 }
 
 // Object returns the current object.
@@ -137,6 +246,45 @@ func (o *ObjectIterator) Object() *Object {
 	return o.objs[o.idx-1]
 }
 
+// Dir returns the current entry as a Dir, if it is a "folder" entry, and nil
+// if it is an object.  Folder entries are only returned when ListDelimiter is
+// used.
+func (o *ObjectIterator) Dir() *Dir {
+	obj := o.Object()
+	if !obj.IsDir() {
+		return nil
+	}
+	return &Dir{
+		Name: obj.name,
+		b:    o.bucket,
+		opts: o.opts,
+	}
+}
+
+// A Dir is a "folder" entry from a listing made with ListDelimiter: the common
+// prefix, up to and including the delimiter, of one or more objects.
+type Dir struct {
+	Name string
+
+	b    *Bucket
+	opts objectIteratorOptions
+}
+
+// List lists the entries in the directory, with the same delimiter as the
+// listing that returned it.  This can be used to walk a bucket as a tree.
+func (d *Dir) List(ctx context.Context, opts ...ListOption) *ObjectIterator {
+	o := &ObjectIterator{
+		bucket: d.b,
+		ctx:    ctx,
+		opts:   d.opts,
+	}
+	o.opts.prefix = d.Name
+	for _, opt := range opts {
+		opt(&o.opts)
+	}
+	return o
+}
+
 // Err returns the current error or nil.  If Next() returns false and Err() is
 // nil, then all objects have been seen.
 func (o *ObjectIterator) Err() error {
@@ -153,6 +301,10 @@ type objectIteratorOptions struct {
 	delimiter  string
 	pageSize   int
 	locker     sync.Locker
+	since      time.Time
+	budget     int
+	resume     *listPosition
+	resumeErr  error
 }
 
 // A ListOption alters the default behavor of List.
@@ -173,6 +325,20 @@ func ListUnfinished() ListOption {
 	}
 }
 
+// ListSince will list only the changes made after t: every version, including
+// hide markers, uploaded after t.  This lets incremental backup tools find new,
+// changed, and hidden objects without comparing whole listings; check
+// Attrs.Status for Hider to recognize hidden objects.  Versions that were
+// deleted outright are not listed, as B2 keeps no record of them.
+//
+// B2 lists versions by name, not by time, so this still reads the listing of
+// every version; it saves callers the work of filtering it.
+func ListSince(t time.Time) ListOption {
+	return func(o *objectIteratorOptions) {
+		o.since = t
+	}
+}
+
 // ListPrefix will restrict the output to objects whose names begin with
 // prefix.
 func ListPrefix(pfx string) ListOption {
@@ -191,8 +357,9 @@ func ListPrefix(pfx string) ListOption {
 // Note that objects returned that end in the delimiter may not be actual
 // objects, e.g. you cannot read from (or write to, or delete) an object
 // "foo/", both because no actual object exists and because B2 disallows object
-// names that end with "/".  If you want to ensure that all objects returned
-// are actual objects, leave this unset.
+// names that end with "/".  Use Object.IsDir or ObjectIterator.Dir to tell
+// these entries apart.  If you want to ensure that all objects returned are
+// actual objects, leave this unset.
 func ListDelimiter(delimiter string) ListOption {
 	return func(o *objectIteratorOptions) {
 		o.delimiter = delimiter
@@ -208,6 +375,39 @@ func ListPageSize(count int) ListOption {
 	}
 }
 
+// ListBudget limits the iterator to the given number of listing calls per
+// hour, waiting between pages as needed.  B2 bills listing as a class C
+// transaction, and caps them on accounts with spending limits; a budget keeps
+// long scans of large buckets under such caps.  With a small ListPageSize, a
+// budget also bounds the rate of objects listed.
+func ListBudget(callsPerHour int) ListOption {
+	return func(o *objectIteratorOptions) {
+		o.budget = callsPerHour
+	}
+}
+
+// ListResume continues a listing from a cursor returned by
+// ObjectIterator.Cursor.  The other options must be the same as those of the
+// listing that returned the cursor.  When used with ListBudget, the resumed
+// listing also waits out whatever remained of the budget when the cursor was
+// taken.
+func ListResume(cursor string) ListOption {
+	return func(o *objectIteratorOptions) {
+		o.resume, o.resumeErr = nil, nil
+		bs, err := base64.RawURLEncoding.DecodeString(cursor)
+		if err != nil {
+			o.resumeErr = fmt.Errorf("b2: invalid list cursor: %v", err)
+			return
+		}
+		pos := &listPosition{}
+		if err := json.Unmarshal(bs, pos); err != nil {
+			o.resumeErr = fmt.Errorf("b2: invalid list cursor: %v", err)
+			return
+		}
+		o.resume = pos
+	}
+}
+
 // ListLocker passes the iterator a lock which will be held during network
 // round-trips.
 func ListLocker(l sync.Locker) ListOption {
@@ -261,13 +461,23 @@ func (b *Bucket) listObjects(ctx context.Context, count int, c *cursor) ([]*Obje
 			name: f.name(),
 			f:    f,
 			b:    b,
+			resp: &objectResponse{},
 		})
 	}
-	var rtnErr error
-	if len(objects) == 0 || next == nil {
-		rtnErr = io.EOF
+	return objects, next, endOfList(next)
+}
+
+func (b *Bucket) listChanges(since time.Time) lister {
+	return func(ctx context.Context, count int, c *cursor) ([]*Object, *cursor, error) {
+		objs, next, err := b.listObjects(ctx, count, c)
+		var changed []*Object
+		for _, o := range objs {
+			if o.f.timestamp().After(since) {
+				changed = append(changed, o)
+			}
+		}
+		return changed, next, err
 	}
-	return objects, next, rtnErr
 }
 
 func (b *Bucket) listCurrentObjects(ctx context.Context, count int, c *cursor) ([]*Object, *cursor, error) {
@@ -292,13 +502,10 @@ func (b *Bucket) listCurrentObjects(ctx context.Context, count int, c *cursor) (
 			name: f.name(),
 			f:    f,
 			b:    b,
+			resp: &objectResponse{},
 		})
 	}
-	var rtnErr error
-	if len(objects) == 0 || next == nil {
-		rtnErr = io.EOF
-	}
-	return objects, next, rtnErr
+	return objects, next, endOfList(next)
 }
 
 func (b *Bucket) listUnfinishedLargeFiles(ctx context.Context, count int, c *cursor) ([]*Object, *cursor, error) {
@@ -321,11 +528,105 @@ func (b *Bucket) listUnfinishedLargeFiles(ctx context.Context, count int, c *cur
 			name: f.name(),
 			f:    f,
 			b:    b,
+			resp: &objectResponse{},
 		})
 	}
-	var rtnErr error
-	if len(objects) == 0 || next == nil {
-		rtnErr = io.EOF
+	return objects, next, endOfList(next)
+}
+
+// A Part describes one uploaded part of an unfinished large file.
+type Part struct {
+	Number          int
+	Size            int64
+	SHA1            string
+	UploadTimestamp time.Time
+}
+
+// Parts returns an iterator over the parts that have been uploaded for an
+// unfinished large file, in order of part number.  The object should be one
+// returned by listing with ListUnfinished; B2 does not list the parts of
+// finished files.
+//
+// Like ObjectIterator, it is intended to be called in a loop:
+//  iter := obj.Parts(ctx)
+//  for iter.Next() {
+//    part := iter.Part()
+//    // act on part
+//  }
+//  if err := iter.Err(); err != nil {
+//    // handle err
+//  }
+func (o *Object) Parts(ctx context.Context) *PartIterator {
+	return &PartIterator{
+		o:    o,
+		ctx:  ctx,
+		next: 1,
+	}
+}
+
+// PartIterator iterates over the parts of an unfinished large file.
+type PartIterator struct {
+	o     *Object
+	ctx   context.Context
+	next  int
+	final bool
+	err   error
+	idx   int
+	parts []beFilePartInterface
+}
+
+// Next advances the iterator to the next part.  It should be called before
+// any calls to Part().  Once Next returns false, it is important to check the
+// return value of Err().
+func (p *PartIterator) Next() bool {
+	if p.err != nil {
+		return false
+	}
+	if p.ctx.Err() != nil {
+		p.err = p.ctx.Err()
+		return false
+	}
+	for p.idx >= len(p.parts) {
+		if p.final {
+			p.err = io.EOF
+			return false
+		}
+		if err := p.o.ensure(p.ctx); err != nil {
+			p.err = err
+			return false
+		}
+		parts, next, err := p.o.f.listParts(p.ctx, p.next, 1000)
+		if err != nil {
+			p.err = err
+			return false
+		}
+		p.parts = parts
+		p.idx = 0
+		p.next = next
+		if next == 0 || len(parts) == 0 {
+			p.final = true
+		}
+	}
+	p.idx++
+	return true
+}
+
+// Part returns the current part.
+func (p *PartIterator) Part() *Part {
+	fp := p.parts[p.idx-1]
+	return &Part{
+		Number:          fp.number(),
+		Size:            fp.size(),
+		SHA1:            fp.sha1(),
+		UploadTimestamp: fp.timestamp(),
+	}
+}
+
+// Err returns the current error or nil.  If Next() returns false and Err() is
+// nil, then all parts have been seen.
+func (p *PartIterator) Err() error {
+	if p.err == io.EOF {
+		return nil
 	}
-	return objects, next, rtnErr
+	return p.err
 }
@@ -55,6 +55,7 @@ type keyOptions struct {
 	caps     []string
 	prefix   string
 	lifetime time.Duration
+	grace    time.Duration
 }
 
 // KeyOption specifies desired properties for application keys.
@@ -89,6 +90,16 @@ func Prefix(prefix string) KeyOption {
 	}
 }
 
+// GracePeriod sets how long Rotate waits before it deletes the key it
+// replaces, so that programs still using the old key can switch to the new
+// one.  The default is one hour.  If d is zero, the old key is deleted at
+// once.
+func GracePeriod(d time.Duration) KeyOption {
+	return func(k *keyOptions) {
+		k.grace = d
+	}
+}
+
 // CreateKey creates a global application key that is valid for all buckets in
 // this project.  The key's secret will only be accessible on the object
 // returned from this call.
@@ -154,3 +165,47 @@ func (b *Bucket) CreateKey(ctx context.Context, name string, opts ...KeyOption)
 		k: ki,
 	}, nil
 }
+
+// Rotate creates a new key with k's name, capabilities, bucket, and prefix,
+// and schedules k's deletion after the GracePeriod.  The new key's secret is
+// available only from the returned Key.  Its lifetime is that given with
+// Lifetime, or unlimited; other options are ignored.
+//
+// The deletion is made in the background by k's client; failures are logged.
+// If the client is closed or the program exits first, the deletion is
+// abandoned and k is left in place, to be deleted with Key.Delete.  A grace
+// period of zero deletes k before Rotate returns; if that fails, Rotate
+// returns the new key along with the error.
+func (k *Key) Rotate(ctx context.Context, opts ...KeyOption) (*Key, error) {
+	ko := keyOptions{grace: time.Hour}
+	for _, o := range opts {
+		o(&ko)
+	}
+	ki, err := k.c.backend.createKey(ctx, k.k.name(), k.k.caps(), ko.lifetime, k.k.bucketID(), k.k.prefix())
+	if err != nil {
+		return nil, err
+	}
+	nk := &Key{
+		c: k.c,
+		k: ki,
+	}
+	if ko.grace <= 0 {
+		return nk, k.Delete(ctx)
+	}
+	bg := k.c.bg
+	if bg == nil {
+		bg = context.Background()
+	}
+	go func() {
+		select {
+		case <-k.c.backend.clock().After(ko.grace):
+		case <-bg.Done():
+			k.c.opts.log.V(1).Infof("b2: client closed; not deleting rotated key %s", k.ID())
+			return
+		}
+		if err := k.Delete(bg); err != nil {
+			k.c.opts.log.V(1).Infof("b2: deleting rotated key %s: %v", k.ID(), err)
+		}
+	}()
+	return nk, nil
+}
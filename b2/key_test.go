@@ -0,0 +1,116 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+// graceClock fires waits for the grace period only when told to.
+type graceClock struct {
+	grace time.Duration
+	fire  chan time.Time
+}
+
+func (c *graceClock) Now() time.Time { return time.Now() }
+
+func (c *graceClock) After(d time.Duration) <-chan time.Time {
+	if d == c.grace {
+		return c.fire
+	}
+	return time.After(d)
+}
+
+func TestKeyRotate(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	clk := &graceClock{grace: 10 * time.Minute, fire: make(chan time.Time)}
+	client, err := s.Client(ctx, b2.WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := bucket.CreateKey(ctx, "worker", b2.Capabilities("readFiles", "listFiles"), b2.Prefix("logs/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nk, err := old.Rotate(ctx, b2.GracePeriod(clk.grace))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nk.ID() == old.ID() || nk.Secret() == "" {
+		t.Errorf("Rotate: got key %q with secret %q, want a new key with a secret", nk.ID(), nk.Secret())
+	}
+	if nk.Name() != "worker" || !reflect.DeepEqual(nk.Capabilities(), old.Capabilities()) {
+		t.Errorf("Rotate: got %q with %q, want worker with %q", nk.Name(), nk.Capabilities(), old.Capabilities())
+	}
+	if got := keyNames(t, client); len(got) != 2 {
+		t.Errorf("keys during the grace period: got %q, want two", got)
+	}
+
+	clk.fire <- time.Now()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(keyNames(t, client)) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("old key was not deleted after the grace period")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	keys, _, _ := client.ListKeys(ctx, 10, "")
+	if len(keys) != 1 || keys[0].ID() != nk.ID() {
+		t.Errorf("keys after the grace period: want only %s", nk.ID())
+	}
+
+	// Without a grace period, the old key is gone at once.
+	newest, err := nk.Rotate(ctx, b2.GracePeriod(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, _, _ = client.ListKeys(ctx, 10, "")
+	if len(keys) != 1 || keys[0].ID() != newest.ID() {
+		t.Errorf("keys after rotating with no grace period: want only %s", newest.ID())
+	}
+
+	// Closing the client abandons deletions still waiting for their grace
+	// period.
+	if _, err := newest.Rotate(ctx, b2.GracePeriod(clk.grace)); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case clk.fire <- time.Now():
+	case <-time.After(100 * time.Millisecond):
+	}
+	other, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := keyNames(t, other); len(got) != 2 {
+		t.Errorf("keys after Close: got %q, want two", got)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestLifecycleRules(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	client, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", &b2.BucketAttrs{
+		LifecycleRules: []b2.LifecycleRule{{Prefix: "logs/", DaysUnfinishedUntilCanceled: 7}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs, err := bucket.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs.LifecycleRules) != 1 || attrs.LifecycleRules[0].DaysUnfinishedUntilCanceled != 7 {
+		t.Fatalf("LifecycleRules: got %+v, want one rule canceling after 7 days", attrs.LifecycleRules)
+	}
+
+	// Give the rule a field that blazer does not know.
+	bs, err := client.Base().ListBuckets(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := map[string]interface{}{
+		"accountId": client.AccountInfo().AccountID,
+		"bucketId":  bs[0].ID,
+		"lifecycleRules": []map[string]interface{}{{
+			"fileNamePrefix": "logs/",
+			"daysFromStartingToCancelingUnfinishedLargeFiles": 7,
+			"daysFromTheFuture": 9,
+		}},
+	}
+	if err := client.Base().Call(ctx, "b2_update_bucket", req, nil); err != nil {
+		t.Fatal(err)
+	}
+	future := func() interface{} {
+		t.Helper()
+		var resp struct {
+			Buckets []struct {
+				Rules []map[string]interface{} `json:"lifecycleRules"`
+			} `json:"buckets"`
+		}
+		req := map[string]string{"accountId": client.AccountInfo().AccountID, "bucketName": "bucket"}
+		if err := client.Base().Call(ctx, "b2_list_buckets", req, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if len(resp.Buckets) != 1 || len(resp.Buckets[0].Rules) != 1 {
+			t.Fatalf("got %+v, want one bucket with one rule", resp)
+		}
+		return resp.Buckets[0].Rules[0]["daysFromTheFuture"]
+	}
+
+	// Updating other attributes leaves the rules alone.
+	bucket, err = client.Bucket(b2.WithoutBucketCache(ctx), "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Update(ctx, &b2.BucketAttrs{Info: map[string]string{"k": "v"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := future(); got != 9.0 {
+		t.Errorf("after updating the info: got daysFromTheFuture %v, want 9", got)
+	}
+
+	// So does writing back the rules that were read.
+	attrs, err = bucket.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs.LifecycleRules[0].DaysHiddenUntilDeleted = 30
+	if err := bucket.Update(ctx, &b2.BucketAttrs{LifecycleRules: attrs.LifecycleRules}); err != nil {
+		t.Fatal(err)
+	}
+	if got := future(); got != 9.0 {
+		t.Errorf("after updating the rules: got daysFromTheFuture %v, want 9", got)
+	}
+	attrs, err = bucket.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := b2.LifecycleRule{Prefix: "logs/", DaysHiddenUntilDeleted: 30, DaysUnfinishedUntilCanceled: 7}
+	if got := attrs.LifecycleRules[0]; got.Prefix != want.Prefix || got.DaysHiddenUntilDeleted != 30 || got.DaysUnfinishedUntilCanceled != 7 {
+		t.Errorf("rule: got %+v, want %+v", got, want)
+	}
+}
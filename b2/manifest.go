@@ -0,0 +1,144 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// A ManifestFile is an object to upload with PublishManifest.
+type ManifestFile struct {
+	Name string
+	Body io.Reader
+
+	// Options are applied to the object's Writer.
+	Options []WriterOption
+}
+
+// A Manifest lists the objects published together by PublishManifest.
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// A ManifestEntry identifies one version of an object in a Manifest.  Because
+// it names the version by file ID, it continues to refer to the same data
+// after the object is overwritten.
+type ManifestEntry struct {
+	Name   string `json:"name"`
+	FileID string `json:"fileId"`
+	Size   int64  `json:"size"`
+	SHA1   string `json:"sha1,omitempty"`
+}
+
+// PublishManifest uploads files, and then uploads a Manifest listing them, as
+// JSON, to the object named manifest.  Readers who find the objects through
+// the manifest see all of them or, before the manifest is written, none of
+// them.
+//
+// Every name is checked with ValidateName before anything is uploaded.  If
+// any upload fails, or the manifest cannot be written, the versions already
+// uploaded are deleted, and the first error is returned.  If some cannot be
+// deleted, the error says how many.  Earlier versions of
+// the objects, and any earlier manifest, are left as they were.  If ctx is
+// done, the deletions are made with a fresh context that times out after a
+// minute.
+func (b *Bucket) PublishManifest(ctx context.Context, manifest string, files []ManifestFile) (*Manifest, error) {
+	for _, f := range files {
+		if err := ValidateName(f.Name); err != nil {
+			return nil, err
+		}
+	}
+	if err := ValidateName(manifest); err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	var uploaded []*Object
+	err := func() error {
+		for _, f := range files {
+			w := b.Object(f.Name).NewWriter(ctx, f.Options...)
+			n, err := copyContext(ctx, w, f.Body)
+			if err != nil {
+				// Fail the writer, so that Close does not upload what was read.
+				w.setErr(err)
+				w.Close()
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			uploaded = append(uploaded, w.o)
+			m.Files = append(m.Files, ManifestEntry{
+				Name:   f.Name,
+				FileID: w.o.ID(),
+				Size:   n,
+				SHA1:   w.SHA1(),
+			})
+		}
+		bs, err := json.Marshal(m)
+		if err != nil {
+			return err
+		}
+		w := b.Object(manifest).NewWriter(ctx, WithAttrsOption(&Attrs{ContentType: "application/json"}))
+		if _, err := w.Write(bs); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}()
+	if err == nil {
+		return m, nil
+	}
+
+	rctx := ctx
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		rctx, cancel = context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+	}
+	var left int
+	var derr error
+	for _, o := range uploaded {
+		if err := o.Delete(rctx); err != nil {
+			left++
+			if derr == nil {
+				derr = err
+			}
+		}
+	}
+	if derr != nil {
+		return nil, fmt.Errorf("b2: publish %s: %w (and %d uploaded objects could not be deleted: %v)", manifest, err, left, derr)
+	}
+	return nil, err
+}
+
+// ReadManifest reads a Manifest written by PublishManifest.
+func (b *Bucket) ReadManifest(ctx context.Context, name string) (*Manifest, error) {
+	r := b.Object(name).NewReader(ctx)
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	if _, err := copyContext(ctx, buf, r); err != nil {
+		return nil, err
+	}
+	m := &Manifest{}
+	if err := json.Unmarshal(buf.Bytes(), m); err != nil {
+		return nil, fmt.Errorf("b2: manifest %s: %v", name, err)
+	}
+	return m, nil
+}
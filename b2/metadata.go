@@ -0,0 +1,164 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// DefaultMetadataSuffix is the suffix a MetadataStore appends to an object's
+// name to find its sidecar, if none is set.
+const DefaultMetadataSuffix = ".meta"
+
+// A MetadataStore keeps metadata for objects that will not fit in B2 file
+// info, which is limited to ten keys and a few kilobytes of headers.  The
+// metadata for each object is stored as JSON in a sidecar object, whose name
+// is the object's name with Suffix appended.  Sidecars are ordinary objects,
+// and so appear in listings.
+//
+// Each sidecar records the file ID of the object version it describes.  When
+// the object is overwritten, its old sidecar no longer applies, and is
+// ignored.  A sidecar is replaced in a single upload, so readers see either
+// the old metadata or the new, never a mixture; however, B2 has no
+// conditional writes, so concurrent updates to the same object's metadata
+// are last-writer-wins.
+type MetadataStore struct {
+	Bucket *Bucket
+
+	// Suffix is appended to an object's name to name its sidecar.  If empty,
+	// DefaultMetadataSuffix is used.
+	Suffix string
+}
+
+type sidecar struct {
+	FileID string            `json:"fileId"`
+	Info   map[string]string `json:"info"`
+}
+
+func (m *MetadataStore) sidecarName(name string) string {
+	if m.Suffix == "" {
+		return name + DefaultMetadataSuffix
+	}
+	return name + m.Suffix
+}
+
+// Get returns the metadata for the named object: its file info, overlaid
+// with the contents of its sidecar, if it has a current one.
+func (m *MetadataStore) Get(ctx context.Context, name string) (map[string]string, error) {
+	o := m.Bucket.Object(name)
+	attrs, err := o.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := make(map[string]string)
+	for k, v := range attrs.Info {
+		info[k] = v
+	}
+	extra, err := m.read(ctx, name, o.ID())
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		info[k] = v
+	}
+	return info, nil
+}
+
+// Set replaces the sidecar metadata for the named object, which must exist,
+// with info.  The object's own file info is unchanged.
+func (m *MetadataStore) Set(ctx context.Context, name string, info map[string]string) error {
+	o := m.Bucket.Object(name)
+	if err := o.ensure(ctx); err != nil {
+		return err
+	}
+	return m.write(ctx, name, o.ID(), info)
+}
+
+// Update reads the sidecar metadata for the named object, passes it to f,
+// which may modify it, and writes it back.  If f returns an error, nothing is
+// written and the error is returned.
+func (m *MetadataStore) Update(ctx context.Context, name string, f func(map[string]string) error) error {
+	o := m.Bucket.Object(name)
+	if err := o.ensure(ctx); err != nil {
+		return err
+	}
+	info, err := m.read(ctx, name, o.ID())
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		info = make(map[string]string)
+	}
+	if err := f(info); err != nil {
+		return err
+	}
+	return m.write(ctx, name, o.ID(), info)
+}
+
+// Delete deletes every version of the named object's sidecar.  The object
+// itself is not deleted.
+func (m *MetadataStore) Delete(ctx context.Context, name string) error {
+	sc := m.sidecarName(name)
+	iter := m.Bucket.List(ctx, ListHidden(), ListPrefix(sc), ListPageSize(100))
+	for iter.Next() {
+		obj := iter.Object()
+		if obj.Name() != sc {
+			continue
+		}
+		if err := obj.Delete(ctx); err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// read returns the sidecar info for the given version of the named object,
+// or nil if there is no sidecar or it describes another version.
+func (m *MetadataStore) read(ctx context.Context, name, id string) (map[string]string, error) {
+	sc := m.sidecarName(name)
+	r := m.Bucket.Object(sc).NewReader(ctx)
+	defer r.Close()
+	buf := &bytes.Buffer{}
+	if _, err := copyContext(ctx, buf, r); err != nil {
+		if IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	s := &sidecar{}
+	if err := json.Unmarshal(buf.Bytes(), s); err != nil {
+		return nil, fmt.Errorf("b2: metadata %s: %v", sc, err)
+	}
+	if s.FileID != id {
+		return nil, nil
+	}
+	return s.Info, nil
+}
+
+func (m *MetadataStore) write(ctx context.Context, name, id string, info map[string]string) error {
+	bs, err := json.Marshal(&sidecar{FileID: id, Info: info})
+	if err != nil {
+		return err
+	}
+	w := m.Bucket.Object(m.sidecarName(name)).NewWriter(ctx, WithAttrsOption(&Attrs{ContentType: "application/json"}))
+	if _, err := w.Write(bs); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,59 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import "time"
+
+// A ModTimeSource chooses where an object's modification time comes from.
+// Tools that upload to B2 conventionally record the source file's
+// modification time in the src_last_modified_millis info key, which Attrs
+// reports as LastModified, but not every tool does, so buckets written by a
+// mix of tools may have only upload times for some objects.
+type ModTimeSource int
+
+const (
+	// ModTimeInfoOrUpload uses LastModified, or the upload time for objects
+	// without it.  This is the default.
+	ModTimeInfoOrUpload ModTimeSource = iota
+
+	// ModTimeInfo uses only LastModified.  Objects without it have no
+	// modification time.
+	ModTimeInfo
+
+	// ModTimeUpload uses only the upload time, ignoring LastModified.  Helpers
+	// that upload objects with this source do not record LastModified.
+	ModTimeUpload
+)
+
+// ModTime returns the object's modification time, taken from src.  It is the
+// zero time if src has none to give.
+func (a *Attrs) ModTime(src ModTimeSource) time.Time {
+	switch src {
+	case ModTimeInfo:
+		return a.LastModified
+	case ModTimeUpload:
+		return a.UploadTimestamp
+	}
+	if a.LastModified.IsZero() {
+		return a.UploadTimestamp
+	}
+	return a.LastModified
+}
+
+// saves reports whether objects uploaded by helpers with this source record
+// their modification times as LastModified.
+func (src ModTimeSource) saves() bool {
+	return src != ModTimeUpload
+}
@@ -60,7 +60,7 @@ type method struct {
 
 type methodCounter struct {
 	d time.Duration
-	w *window.Window
+	w *window.Of[[]method]
 }
 
 func (mc methodCounter) record(m method) {
@@ -68,28 +68,18 @@ func (mc methodCounter) record(m method) {
 }
 
 func (mc methodCounter) retrieve() MethodList {
-	ms := mc.w.Reduce()
-	return MethodList(ms.([]method))
+	return MethodList(mc.w.Reduce())
 }
 
 func newMethodCounter(d, res time.Duration) methodCounter {
-	r := func(i, j interface{}) interface{} {
-		a, ok := i.([]method)
-		if !ok {
-			a = nil
-		}
-		b, ok := j.([]method)
-		if !ok {
-			b = nil
-		}
-		for _, m := range b {
-			a = append(a, m)
-		}
-		return a
+	r := func(a, b []method) []method {
+		// Limit a's capacity so that append copies rather than writes into
+		// the window's own slices.
+		return append(a[:len(a):len(a)], b...)
 	}
 	return methodCounter{
 		d: d,
-		w: window.New(d, res, r),
+		w: window.NewOf(d, res, r),
 	}
 }
 
@@ -98,6 +88,9 @@ type WriterStatus struct {
 	// Progress is a slice of completion ratios.  The index of a ratio is its
 	// chunk id less one.
 	Progress []float64
+
+	// Stats describes the parts that have been uploaded.
+	Stats WriterStats
 }
 
 // ReaderStatus reports the status for each reader.
@@ -107,6 +100,28 @@ type ReaderStatus struct {
 	Progress []float64
 }
 
+// ResponseInfo describes a response received from B2.
+type ResponseInfo struct {
+	// Method is the B2 API method that was called.
+	Method string
+
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+
+	// Header holds the response's HTTP headers, including any upload
+	// timestamps or request identifiers sent by B2.
+	Header http.Header
+}
+
+// LastResponse returns information about the most recent response received
+// from B2, or nil if no requests have been made.  It is intended for
+// debugging.
+func (c *Client) LastResponse() *ResponseInfo {
+	c.slock.Lock()
+	defer c.slock.Unlock()
+	return c.lastResp
+}
+
 // Status returns information about the current state of the client.
 func (c *Client) Status() *StatusInfo {
 	c.slock.Lock()
@@ -150,15 +165,20 @@ func (si *StatusInfo) table() map[string]map[string]int {
 	return r
 }
 
-func (c *Client) addWriter(w *Writer) {
+func (c *Client) addWriter(w *Writer) error {
 	c.slock.Lock()
 	defer c.slock.Unlock()
 
+	if c.closing {
+		return ErrClientClosed
+	}
+
 	if c.sWriters == nil {
 		c.sWriters = make(map[string]*Writer)
 	}
 
 	c.sWriters[fmt.Sprintf("%s/%s", w.o.b.Name(), w.name)] = w
+	return nil
 }
 
 func (c *Client) removeWriter(w *Writer) {
@@ -169,18 +189,27 @@ func (c *Client) removeWriter(w *Writer) {
 		return
 	}
 
-	delete(c.sWriters, fmt.Sprintf("%s/%s", w.o.b.Name(), w.name))
+	key := fmt.Sprintf("%s/%s", w.o.b.Name(), w.name)
+	if c.sWriters[key] == w {
+		delete(c.sWriters, key)
+	}
+	c.signalIdle()
 }
 
-func (c *Client) addReader(r *Reader) {
+func (c *Client) addReader(r *Reader) error {
 	c.slock.Lock()
 	defer c.slock.Unlock()
 
+	if c.closing {
+		return ErrClientClosed
+	}
+
 	if c.sReaders == nil {
 		c.sReaders = make(map[string]*Reader)
 	}
 
 	c.sReaders[fmt.Sprintf("%s/%s", r.o.b.Name(), r.name)] = r
+	return nil
 }
 
 func (c *Client) removeReader(r *Reader) {
@@ -191,7 +220,20 @@ func (c *Client) removeReader(r *Reader) {
 		return
 	}
 
-	delete(c.sReaders, fmt.Sprintf("%s/%s", r.o.b.Name(), r.name))
+	key := fmt.Sprintf("%s/%s", r.o.b.Name(), r.name)
+	if c.sReaders[key] == r {
+		delete(c.sReaders, key)
+	}
+	c.signalIdle()
+}
+
+// signalIdle wakes Close once the last Reader or Writer is gone.  c.slock
+// must be held.
+func (c *Client) signalIdle() {
+	if c.idle != nil && len(c.sWriters) == 0 && len(c.sReaders) == 0 {
+		close(c.idle)
+		c.idle = nil
+	}
 }
 
 var (
@@ -0,0 +1,78 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxNameLength is the length, in bytes of UTF-8, of the longest object name
+// B2 accepts.
+const MaxNameLength = 1024
+
+// A NameError reports an object name that B2 would reject.
+type NameError struct {
+	Name   string
+	Reason string
+}
+
+func (e *NameError) Error() string {
+	name := e.Name
+	if len(name) > 64 {
+		name = name[:61] + "..."
+	}
+	return fmt.Sprintf("b2: invalid object name %q: %s", name, e.Reason)
+}
+
+// ValidateName checks that name is one B2 accepts: non-empty UTF-8 of no more
+// than MaxNameLength bytes, with no control characters, DEL, or backslashes,
+// that neither begins nor ends with "/" and has no empty segments ("//").  It
+// returns a *NameError if not.
+//
+// Writers, and helpers that create objects, validate names before uploading
+// anything, so that a bad name is reported immediately rather than by B2 once
+// the data has been sent.
+func ValidateName(name string) error {
+	bad := func(format string, args ...interface{}) error {
+		return &NameError{Name: name, Reason: fmt.Sprintf(format, args...)}
+	}
+	switch {
+	case name == "":
+		return bad("name is empty")
+	case len(name) > MaxNameLength:
+		return bad("name is %d bytes, longer than the limit of %d", len(name), MaxNameLength)
+	case !utf8.ValidString(name):
+		return bad("name is not valid UTF-8")
+	case strings.HasPrefix(name, "/"):
+		return bad("name begins with /")
+	case strings.HasSuffix(name, "/"):
+		return bad("name ends with /")
+	case strings.Contains(name, "//"):
+		return bad("name contains //")
+	}
+	for i, r := range name {
+		switch {
+		case r < 32:
+			return bad("control character %U at byte %d", r, i)
+		case r == 127:
+			return bad("DEL character at byte %d", i)
+		case r == '\\':
+			return bad("backslash at byte %d", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotificationSignatureHeader is the header in which B2 signs event
+// notification deliveries.
+const NotificationSignatureHeader = "X-Bz-Event-Notification-Signature"
+
+// maxNotificationBody bounds the size of a delivery that NotificationHandler
+// will read.
+const maxNotificationBody = 10 << 20
+
+// A NotificationEvent is a single event from a B2 event notification
+// delivery.
+type NotificationEvent struct {
+	AccountID       string
+	BucketID        string
+	BucketName      string
+	EventID         string
+	EventTime       time.Time
+	EventType       string // such as "b2:ObjectCreated:Upload"
+	EventVersion    int
+	MatchedRuleName string
+	ObjectName      string
+	ObjectSize      int64
+	ObjectVersionID string
+}
+
+// IsTest reports whether e was sent to test the notification rule, rather
+// than for a change to the bucket.
+func (e *NotificationEvent) IsTest() bool {
+	return e.EventType == "b2:TestEvent"
+}
+
+type notificationEvent struct {
+	AccountID       string `json:"accountId"`
+	BucketID        string `json:"bucketId"`
+	BucketName      string `json:"bucketName"`
+	EventID         string `json:"eventId"`
+	EventTimestamp  int64  `json:"eventTimestamp"`
+	EventType       string `json:"eventType"`
+	EventVersion    int    `json:"eventVersion"`
+	MatchedRuleName string `json:"matchedRuleName"`
+	ObjectName      string `json:"objectName"`
+	ObjectSize      int64  `json:"objectSize"`
+	ObjectVersionID string `json:"objectVersionId"`
+}
+
+type notificationDelivery struct {
+	Events []notificationEvent `json:"events"`
+}
+
+// NotificationHandler is an http.Handler that receives B2 event notification
+// deliveries.  It checks each delivery's signature, decodes its events, and
+// passes them to Handle.
+//
+// B2 retries deliveries that fail, and may deliver an event more than once;
+// Handle should use EventID to recognize events it has already seen.
+type NotificationHandler struct {
+	// SigningSecret is the signing secret of the notification rule.  If it is
+	// empty, every delivery is rejected, unless InsecureSkipVerify is set.
+	SigningSecret string
+
+	// InsecureSkipVerify, if set, accepts deliveries without checking their
+	// signatures, so that anyone who can reach the handler can forge them.
+	// It is intended for tests.
+	InsecureSkipVerify bool
+
+	// Handle is called with the events of each delivery.  If it returns an
+	// error, the delivery fails with a 500, so that B2 will retry it.  The
+	// error is not sent in the response.
+	Handle func(context.Context, []NotificationEvent) error
+}
+
+// ServeHTTP satisfies the http.Handler interface.
+func (h *NotificationHandler) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	if req.Method != "POST" {
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(rw, req.Body, maxNotificationBody))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !h.InsecureSkipVerify {
+		if h.SigningSecret == "" {
+			http.Error(rw, "no signing secret is configured", http.StatusInternalServerError)
+			return
+		}
+		if !validNotificationSignature(h.SigningSecret, req.Header.Get(NotificationSignatureHeader), body) {
+			http.Error(rw, "bad signature", http.StatusUnauthorized)
+			return
+		}
+	}
+	var d notificationDelivery
+	if err := json.Unmarshal(body, &d); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events := make([]NotificationEvent, len(d.Events))
+	for i, e := range d.Events {
+		events[i] = NotificationEvent{
+			AccountID:       e.AccountID,
+			BucketID:        e.BucketID,
+			BucketName:      e.BucketName,
+			EventID:         e.EventID,
+			EventTime:       time.Unix(e.EventTimestamp/1e3, (e.EventTimestamp%1e3)*1e6),
+			EventType:       e.EventType,
+			EventVersion:    e.EventVersion,
+			MatchedRuleName: e.MatchedRuleName,
+			ObjectName:      e.ObjectName,
+			ObjectSize:      e.ObjectSize,
+			ObjectVersionID: e.ObjectVersionID,
+		}
+	}
+	if h.Handle != nil {
+		if err := h.Handle(req.Context(), events); err != nil {
+			http.Error(rw, "delivery failed", http.StatusInternalServerError)
+			return
+		}
+	}
+	rw.WriteHeader(http.StatusOK)
+}
+
+// validNotificationSignature reports whether sig, the value of the signature
+// header, holds a v1 signature of body: the hex-encoded HMAC-SHA256 of body,
+// keyed with secret.
+func validNotificationSignature(secret, sig string, body []byte) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	want := mac.Sum(nil)
+	for _, s := range strings.Split(sig, ",") {
+		s = strings.TrimSpace(s)
+		if !strings.HasPrefix(s, "v1=") {
+			continue
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(s, "v1="))
+		if err != nil {
+			continue
+		}
+		if hmac.Equal(got, want) {
+			return true
+		}
+	}
+	return false
+}
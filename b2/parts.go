@@ -0,0 +1,184 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// maxParts is the most parts a large file can have.
+const maxParts = 10000
+
+// A LargePartUploader uploads a large file one part at a time, with the caller
+// choosing each part's number and data.  Unlike a Writer, which reads an
+// object from start to end, it allows the parts of one object to be uploaded
+// in any order, and by many processes at once: one process starts the file,
+// and passes its ID to the others, which upload their parts with uploaders
+// from Object.LargePartUploader.  Once every part is uploaded, any of them
+// calls Finish.
+//
+// Parts are numbered from 1, and every part but the last must be at least
+// the absolute minimum part size; see Client.PartSizes.  A LargePartUploader
+// is safe for concurrent use.
+type LargePartUploader struct {
+	o  *Object
+	f  beFileInterface
+	lf beLargeFileInterface
+
+	mu   sync.Mutex
+	idle []beFileChunkInterface // upload URLs not in use
+}
+
+// StartLargeFile starts a large file for o, with the content type and info
+// from attrs, which may be nil, and returns an uploader for its parts.
+func (o *Object) StartLargeFile(ctx context.Context, attrs *Attrs) (*LargePartUploader, error) {
+	if err := ValidateName(o.name); err != nil {
+		return nil, err
+	}
+	if attrs == nil {
+		attrs = &Attrs{}
+	}
+	info, err := attrsInfo(attrs)
+	if err != nil {
+		return nil, err
+	}
+	ctype := attrs.ContentType
+	if ctype == "" {
+		ctype = "application/octet-stream"
+	}
+	lf, err := o.b.b.startLargeFile(ctx, o.name, ctype, info)
+	if err != nil {
+		return nil, err
+	}
+	return &LargePartUploader{o: o, f: o.b.b.file(lf.id(), o.name), lf: lf}, nil
+}
+
+// LargePartUploader returns an uploader for the parts of the unfinished large
+// file for o with the given ID, which may have been started by another
+// process.
+func (o *Object) LargePartUploader(id string) *LargePartUploader {
+	f := o.b.b.file(id, o.name)
+	return &LargePartUploader{o: o, f: f, lf: f.compileParts(0, nil)}
+}
+
+// ID returns the ID of the large file, which other processes pass to
+// Object.LargePartUploader.
+func (u *LargePartUploader) ID() string {
+	return u.f.id()
+}
+
+// UploadPart uploads the size bytes of r, from its start, as part number n,
+// replacing any part already uploaded with that number.  Failed uploads are
+// retried, reading r again.
+func (u *LargePartUploader) UploadPart(ctx context.Context, n int, r io.ReaderAt, size int64) error {
+	if n < 1 || n > maxParts {
+		return fmt.Errorf("b2: %s: part number %d is not between 1 and %d", u.o.name, n, maxParts)
+	}
+	hsh := sha1.New()
+	if _, err := copyContext(ctx, hsh, io.NewSectionReader(r, 0, size)); err != nil {
+		return err
+	}
+	sha := fmt.Sprintf("%x", hsh.Sum(nil))
+	rr := resetter{rs: io.NewSectionReader(r, 0, size)}
+
+	fc, err := u.getURL(ctx)
+	if err != nil {
+		return err
+	}
+	var sleep time.Duration
+	for {
+		_, err := fc.uploadPart(ctx, rr, sha, int(size), n)
+		if err == nil {
+			u.putURL(fc)
+			return nil
+		}
+		if !u.o.b.r.reupload(err) {
+			return err
+		}
+		u.o.b.log().V(1).Infof("b2 part uploader: %s: part %d: %v; retrying", u.o.name, n, err)
+		sleep = u.o.b.r.retry().nextUpload(sleep)
+		if err := sleepCtx(ctx, u.o.b.r.clock(), sleep); err != nil {
+			return err
+		}
+		if fc, err = u.lf.getUploadPartURL(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+func (u *LargePartUploader) getURL(ctx context.Context) (beFileChunkInterface, error) {
+	u.mu.Lock()
+	if n := len(u.idle); n > 0 {
+		fc := u.idle[n-1]
+		u.idle = u.idle[:n-1]
+		u.mu.Unlock()
+		return fc, nil
+	}
+	u.mu.Unlock()
+	return u.lf.getUploadPartURL(ctx)
+}
+
+func (u *LargePartUploader) putURL(fc beFileChunkInterface) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.idle = append(u.idle, fc)
+}
+
+// Finish assembles the large file from the parts B2 has, by whichever process
+// they were uploaded, and makes it the current version of the object.  The
+// parts must be numbered from 1 without gaps.
+func (u *LargePartUploader) Finish(ctx context.Context) error {
+	seen := make(map[int]string)
+	var size int64
+	next := 1
+	for {
+		parts, n, err := u.f.listParts(ctx, next, 1000)
+		if err != nil {
+			return err
+		}
+		for _, p := range parts {
+			seen[p.number()] = p.sha1()
+			size += p.size()
+		}
+		if len(parts) == 0 || n == 0 {
+			break
+		}
+		next = n
+	}
+	if len(seen) == 0 {
+		return fmt.Errorf("b2: %s: no parts were uploaded", u.o.name)
+	}
+	for i := 1; i <= len(seen); i++ {
+		if _, ok := seen[i]; !ok {
+			return fmt.Errorf("b2: %s: part %d was not uploaded", u.o.name, i)
+		}
+	}
+	f, err := u.f.compileParts(size, seen).finishLargeFile(ctx)
+	if err != nil {
+		return err
+	}
+	u.o.f = f
+	return nil
+}
+
+// Cancel abandons the large file, and deletes the parts uploaded for it.
+func (u *LargePartUploader) Cancel(ctx context.Context) error {
+	return u.lf.cancel(ctx)
+}
@@ -0,0 +1,112 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestLargePartUploader(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{PartSize: 1e3, MinimumPartSize: 1e3})
+	defer s.Close()
+
+	var buckets []*b2.Bucket
+	for i := 0; i < 2; i++ {
+		client, err := s.Client(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bucket, err := client.NewBucket(ctx, "bucket", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buckets = append(buckets, bucket)
+	}
+	parts := [][]byte{
+		bytes.Repeat([]byte("a"), 1e3),
+		bytes.Repeat([]byte("b"), 1e3),
+		bytes.Repeat([]byte("c"), 1e3),
+		[]byte("d"),
+	}
+
+	first, err := buckets[0].Object("obj").StartLargeFile(ctx, &b2.Attrs{ContentType: "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The parts are uploaded out of order, by two "processes" at once.
+	other := buckets[1].Object("obj").LargePartUploader(first.ID())
+	var wg sync.WaitGroup
+	for i, u := range []*b2.LargePartUploader{first, other} {
+		wg.Add(1)
+		go func(u *b2.LargePartUploader, nums []int) {
+			defer wg.Done()
+			for _, n := range nums {
+				p := parts[n-1]
+				if err := u.UploadPart(ctx, n, bytes.NewReader(p), int64(len(p))); err != nil {
+					t.Errorf("UploadPart(%d): %v", n, err)
+				}
+			}
+		}(u, [][]int{{4, 2}, {3, 1}}[i])
+	}
+	wg.Wait()
+
+	if err := other.Finish(ctx); err != nil {
+		t.Fatal(err)
+	}
+	r := buckets[0].Object("obj").NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Join(parts, nil); !bytes.Equal(got, want) {
+		t.Errorf("got %d bytes, want %d bytes of joined parts", len(got), len(want))
+	}
+	attrs, err := buckets[0].Object("obj").Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attrs.ContentType != "text/plain" {
+		t.Errorf("ContentType: got %q, want text/plain", attrs.ContentType)
+	}
+
+	gap, err := buckets[0].Object("gap").StartLargeFile(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{1, 3} {
+		if err := gap.UploadPart(ctx, n, bytes.NewReader(parts[0]), int64(len(parts[0]))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := gap.Finish(ctx); err == nil || !strings.Contains(err.Error(), "part 2") {
+		t.Errorf("Finish with a missing part: got %v, want an error about part 2", err)
+	}
+	if err := gap.UploadPart(ctx, 0, bytes.NewReader(nil), 0); err == nil {
+		t.Errorf("UploadPart(0): got nil error")
+	}
+	if err := gap.Cancel(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
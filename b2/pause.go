@@ -0,0 +1,90 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"sync"
+)
+
+// gate holds back a transfer's goroutines while the transfer is paused.
+type gate struct {
+	mu     sync.Mutex
+	paused chan struct{} // non-nil while paused; closed to unpause
+}
+
+func (g *gate) pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused == nil {
+		g.paused = make(chan struct{})
+	}
+}
+
+func (g *gate) unpause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.paused != nil {
+		close(g.paused)
+		g.paused = nil
+	}
+}
+
+func (g *gate) isPaused() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.paused != nil
+}
+
+// wait returns once the gate is open, or ctx is done.
+func (g *gate) wait(ctx context.Context) error {
+	g.mu.Lock()
+	ch := g.paused
+	g.mu.Unlock()
+	if ch == nil {
+		return nil
+	}
+	select {
+	case <-ch:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Pause stops the writer from starting to upload any more parts until Unpause
+// is called.  Parts already being sent are finished, and the large file is
+// not cancelled, so nothing is lost; once the writer's buffers are full,
+// Write and Close block until the writer is unpaused.  Cancelling the
+// writer's context still ends the upload.
+func (w *Writer) Pause() { w.gate.pause() }
+
+// Unpause continues an upload stopped by Pause.
+func (w *Writer) Unpause() { w.gate.unpause() }
+
+// Paused reports whether the writer is paused.
+func (w *Writer) Paused() bool { return w.gate.isPaused() }
+
+// Pause stops the reader from starting to download any more chunks until
+// Unpause is called.  Chunks already being fetched are finished; once they
+// have been read, Read blocks until the reader is unpaused.  Cancelling the
+// reader's context still ends the download.
+func (r *Reader) Pause() { r.gate.pause() }
+
+// Unpause continues a download stopped by Pause.
+func (r *Reader) Unpause() { r.gate.unpause() }
+
+// Paused reports whether the reader is paused.
+func (r *Reader) Paused() bool { return r.gate.isPaused() }
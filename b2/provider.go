@@ -0,0 +1,97 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+)
+
+// A Provider carries out the B2 API calls a client makes, in place of the B2
+// service.  Every call the client makes, for the API and for uploads and
+// downloads, is passed to Call.
+//
+// This allows advanced users to wrap the service a client talks to, to add
+// caching or accounting, or to replace it with a different storage service,
+// such as the fake in package b2fake.  Providers that wrap B2 itself can pass
+// calls on to RemoteProvider.
+type Provider interface {
+	// Call makes the B2 API call named method, such as "b2_list_buckets" or
+	// "b2_upload_file", with the request req, and returns the reply as B2
+	// would.  An error means the call could not be made at all; the client
+	// treats it as a network error, and retries.
+	Call(ctx context.Context, method string, req *http.Request) (*http.Response, error)
+}
+
+// ProviderFunc is an adapter that allows an ordinary function to be used as a
+// Provider.
+type ProviderFunc func(ctx context.Context, method string, req *http.Request) (*http.Response, error)
+
+// Call calls f(ctx, method, req).
+func (f ProviderFunc) Call(ctx context.Context, method string, req *http.Request) (*http.Response, error) {
+	return f(ctx, method, req)
+}
+
+// WithProvider sends every call the client makes to p, instead of over the
+// network.  WithProvider replaces any Transport.
+func WithProvider(p Provider) ClientOption {
+	return func(o *clientOptions) {
+		o.transport = providerTransport{p: p}
+	}
+}
+
+// RemoteProvider returns a Provider that sends calls on to their
+// destinations, normally B2, with rt, or with http.DefaultTransport if rt is
+// nil.
+func RemoteProvider(rt http.RoundTripper) Provider {
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	return ProviderFunc(func(ctx context.Context, _ string, req *http.Request) (*http.Response, error) {
+		return rt.RoundTrip(req.WithContext(ctx))
+	})
+}
+
+// HandlerProvider returns a Provider that serves calls with h, in process, as
+// an HTTP server would.  Each reply is held in memory until h returns, so it
+// suits small services such as the fake in package b2fake.
+func HandlerProvider(h http.Handler) Provider {
+	return ProviderFunc(func(ctx context.Context, _ string, req *http.Request) (*http.Response, error) {
+		if req.Body != nil {
+			defer req.Body.Close()
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req.WithContext(ctx))
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		resp := rec.Result()
+		resp.ContentLength = int64(rec.Body.Len())
+		resp.Body = ioutil.NopCloser(rec.Body)
+		resp.Request = req
+		return resp, nil
+	})
+}
+
+// providerTransport is a RoundTripper that makes calls with a Provider.
+type providerTransport struct {
+	p Provider
+}
+
+func (t providerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	return t.p.Call(r.Context(), r.Header.Get("X-Blazer-Method"), r)
+}
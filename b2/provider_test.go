@@ -0,0 +1,105 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestWithProvider(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	// Serve the client in process, with no address of its own.
+	client, err := b2.NewClient(ctx, "account", "key", b2.WithProvider(b2.HandlerProvider(s)), b2.APIBase("http://b2.invalid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := strings.Repeat("data", 1000)
+	if err := bucket.Object("obj").WriteFrom(ctx, strings.NewReader(data), int64(len(data))); err != nil {
+		t.Fatal(err)
+	}
+	r := bucket.Object("obj").NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != data {
+		t.Errorf("read %d bytes, want %d", len(got), len(data))
+	}
+
+	// Wrap the remote service to count calls.
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	remote := b2.RemoteProvider(nil)
+	counting := b2.ProviderFunc(func(ctx context.Context, method string, r *http.Request) (*http.Response, error) {
+		mu.Lock()
+		calls[method]++
+		mu.Unlock()
+		return remote.Call(ctx, method, r)
+	})
+	client, err = b2.NewClient(ctx, "account", "key", b2.WithProvider(counting), b2.APIBase(s.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.ListBuckets(ctx); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if calls["b2_authorize_account"] != 1 || calls["b2_list_buckets"] != 1 {
+		t.Errorf("calls: got %v, want one authorization and one listing", calls)
+	}
+	mu.Unlock()
+
+	// Network errors from the remote service are network errors to the
+	// client, and so are retried.
+	var attempts int
+	down := b2.RemoteProvider(rtFunc(func(*http.Request) (*http.Response, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+		return nil, syscall.ECONNREFUSED
+	}))
+	ctx, cancel := context.WithTimeout(ctx, 1500*time.Millisecond) // connection errors wait a second
+	defer cancel()
+	if _, err := b2.NewClient(ctx, "account", "key", b2.WithProvider(down), b2.APIBase(s.URL)); err == nil {
+		t.Error("NewClient with the service down: got no error")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if attempts < 2 {
+		t.Errorf("NewClient with the service down: made %d attempts, want retries", attempts)
+	}
+}
+
+type rtFunc func(*http.Request) (*http.Response, error)
+
+func (f rtFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
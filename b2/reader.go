@@ -24,11 +24,75 @@ import (
 	"io"
 	"sync"
 	"time"
+)
 
-	"github.com/kurin/blazer/internal/blog"
+var (
+	errNoMoreContent = errors.New("416: out of content")
+	errReaderClosed  = errors.New("b2: read from closed Reader")
 )
 
-var errNoMoreContent = errors.New("416: out of content")
+// A ReaderOption sets Reader-specific behavior.
+type ReaderOption func(*Reader)
+
+// DownloadConcurrency sets the reader's ConcurrentDownloads.
+func DownloadConcurrency(n int) ReaderOption {
+	return func(r *Reader) {
+		r.ConcurrentDownloads = n
+	}
+}
+
+// DownloadChunkSize sets the reader's ChunkSize.
+func DownloadChunkSize(n int) ReaderOption {
+	return func(r *Reader) {
+		r.ChunkSize = n
+	}
+}
+
+// ReadVersion causes the reader to download the version of the object with the
+// given file ID, even if it has since been hidden or overwritten.
+func ReadVersion(id string) ReaderOption {
+	return func(r *Reader) {
+		r.f = r.o.b.b.file(id, r.name)
+		r.nth = -1
+	}
+}
+
+// ReadWithToken causes the reader to authorize its downloads with token, a
+// download authorization token such as those returned by Bucket.AuthToken,
+// instead of with the client's own authorization.  The object is always
+// downloaded by name, as download authorization tokens allow, and so this
+// cannot be combined with ReadVersion or ReadNthNewest.
+//
+// This allows a service to hand readers to less-trusted components whose
+// downloads are confined to the token's bucket and prefix, and that fail once
+// the token expires rather than reauthorizing the client.
+func ReadWithToken(token string) ReaderOption {
+	return func(r *Reader) {
+		r.token = token
+	}
+}
+
+// ReadNthNewest causes the reader to download an earlier version of the
+// object: 0 is the newest, 1 the version it replaced, and so on.  Only
+// uploaded versions are counted, not hide markers, so ReadNthNewest(0) reads
+// the content of an object that has been hidden.  The version is found by
+// listing the object's versions when the reader is first read; if there are
+// not enough, Read returns an error for which IsNotExist is true.
+func ReadNthNewest(n int) ReaderOption {
+	return func(r *Reader) {
+		r.nth = n
+	}
+}
+
+// WithDefaultReaderOptions returns a ClientOption that will apply the given
+// ReaderOptions to every Reader.  Options passed to NewReader or
+// NewRangeReader, and fields set on the Reader before it is first read, take
+// precedence.
+func WithDefaultReaderOptions(opts ...ReaderOption) ClientOption {
+	return func(c *clientOptions) {
+		c.readerOpts = append(c.readerOpts, opts...)
+	}
+}
 
 // Reader reads files from B2.
 type Reader struct {
@@ -45,6 +109,7 @@ type Reader struct {
 	ctx        context.Context
 	cancel     context.CancelFunc // cancels ctx
 	o          *Object
+	f          beFileInterface // if set, download this version by ID
 	name       string
 	offset     int64 // the start of the file
 	length     int64 // the length to read, or -1
@@ -58,15 +123,22 @@ type Reader struct {
 	vrfy       hash.Hash
 	readOffEnd bool
 	sha1       string
+	nth        int    // if >= 0, download the nth newest version
+	token      string // if set, authorizes downloads by name instead
 
-	rmux  sync.Mutex // guards rcond
+	rmux  sync.Mutex // guards rcond, chunks, sha1, and readOffEnd
 	rcond *sync.Cond
 
 	emux sync.RWMutex // guards err, believe it or not
 	err  error
 
+	closed   sync.Once
+	closeErr error
+
 	smux sync.Mutex
 	smap map[int]*meteredReader
+
+	gate gate // shut while paused
 }
 
 type rchunk struct {
@@ -74,11 +146,21 @@ type rchunk struct {
 	final bool
 }
 
-// Close frees resources associated with the download.
+// Close frees resources associated with the download, stopping any chunks
+// that are still downloading.  It returns the first error the reader
+// encountered, other than io.EOF, so that a failed download is reported even
+// if the caller stopped reading before Read returned it.  Calling Close again
+// returns the same error, and Read after Close fails.
 func (r *Reader) Close() error {
-	r.cancel()
-	r.o.b.c.removeReader(r)
-	return nil
+	r.closed.Do(func() {
+		if err := r.getErr(); err != io.EOF {
+			r.closeErr = err
+		}
+		r.setErr(errReaderClosed)
+		r.cancel()
+		r.o.b.c.removeReader(r)
+	})
+	return r.closeErr
 }
 
 func (r *Reader) setErr(err error) {
@@ -107,6 +189,9 @@ func (r *Reader) getErr() error {
 func (r *Reader) thread() {
 	go func() {
 		for {
+			if err := r.gate.wait(r.ctx); err != nil {
+				return
+			}
 			var buf *rchunk
 			select {
 			case b, ok := <-r.chbuf:
@@ -130,14 +215,28 @@ func (r *Reader) thread() {
 				}
 				r.length -= size
 			}
-			var b backoff
+			var attempts int
+			var wait time.Duration
 		redo:
-			fr, err := r.o.b.b.downloadFileByName(r.ctx, r.name, offset, size, false)
+			fr, err := r.download(offset, size)
+			if err == errNoMoreContent && chunkID == 0 && r.offset == 0 {
+				// The object is empty, and so has no range to read.  Ask for
+				// the whole thing instead, to learn its hash.
+				fr, err = r.download(0, 0)
+				if err == nil {
+					_, _, sha1, _ := fr.stats()
+					r.rmux.Lock()
+					r.sha1 = sha1
+					r.rmux.Unlock()
+					fr.Close()
+					err = errNoMoreContent
+				}
+			}
 			if err == errNoMoreContent {
 				// this read generated a 416 so we are entirely past the end of the object
-				r.readOffEnd = true
 				buf.final = true
 				r.rmux.Lock()
+				r.readOffEnd = true
 				r.chunks[chunkID] = buf
 				r.rmux.Unlock()
 				r.rcond.Broadcast()
@@ -149,8 +248,10 @@ func (r *Reader) thread() {
 				return
 			}
 			rsize, _, sha1, _ := fr.stats()
-			if len(sha1) == 40 && r.sha1 != sha1 {
+			if len(sha1) == 40 {
+				r.rmux.Lock()
 				r.sha1 = sha1
+				r.rmux.Unlock()
 			}
 			mr := &meteredReader{r: noopResetter{fr}, size: int(rsize)}
 			r.smux.Lock()
@@ -161,15 +262,21 @@ func (r *Reader) thread() {
 			r.smux.Lock()
 			r.smap[chunkID] = nil
 			r.smux.Unlock()
-			if i < int64(rsize) || err == io.ErrUnexpectedEOF {
-				// Probably the network connection was closed early.  Retry.
-				blog.V(1).Infof("b2 reader %d: got %dB of %dB; retrying after %v", chunkID, i, rsize, b)
-				if err := b.wait(r.ctx); err != nil {
+			if (i < int64(rsize) || err != nil) && r.ctx.Err() == nil {
+				// Probably the network connection was closed early.  Keep what
+				// we have, and retry for the rest of the chunk.
+				if err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+				attempts++
+				r.o.b.log().V(1).Infof("b2 reader %d: got %dB of %dB (attempt %d): %v", chunkID, i, rsize, attempts, err)
+				if err := r.retryChunk(attempts, &wait, err); err != nil {
 					r.setErr(err)
 					r.rcond.Broadcast()
 					return
 				}
-				buf.Reset()
+				offset += i
+				size -= i
 				goto redo
 			}
 			if err != nil {
@@ -185,6 +292,64 @@ func (r *Reader) thread() {
 	}()
 }
 
+// download requests part of the object.  If the object's file ID was known
+// when the Reader was created, that version is downloaded by ID, so that a
+// concurrent overwrite cannot change the bytes being read; otherwise the
+// object is downloaded by name.
+//
+// If a download by ID is refused, or finds no such version, it is retried by
+// name, but the result is used only if it is the same version.  Any other
+// version would splice the bytes of two versions into one read, so a Reader
+// whose version has been deleted fails instead of reading its replacement.
+func (r *Reader) download(offset, size int64) (beFileReaderInterface, error) {
+	if r.token != "" || r.f == nil || r.f.id() == "" {
+		return r.o.b.b.downloadFileByName(r.ctx, r.name, r.token, offset, size, false)
+	}
+	fr, err := r.f.downloadFileByID(r.ctx, offset, size, false)
+	var merr *MissingCapabilityError
+	if err == nil || !IsNotExist(err) && !errors.As(err, &merr) {
+		return fr, err
+	}
+	nfr, nerr := r.o.b.b.downloadFileByName(r.ctx, r.name, "", offset, size, false)
+	if nerr != nil {
+		return nil, err
+	}
+	if nfr.id() != r.f.id() {
+		nfr.Close()
+		return nil, err
+	}
+	r.o.b.log().V(1).Infof("b2 reader: %s: downloaded by name after %v", r.name, err)
+	return nfr, nil
+}
+
+// maxChunkAttempts is the number of times a Reader will try to download a
+// chunk whose transfer fails partway through.
+const maxChunkAttempts = 5
+
+// retryChunk waits before a failed chunk is downloaded again, following the
+// same retry policy as the rest of the client.  It returns err if the chunk
+// has been tried too many times.
+func (r *Reader) retryChunk(attempts int, wait *time.Duration, err error) error {
+	return retryChunk(r.ctx, r.o.b.r, attempts, wait, err)
+}
+
+func retryChunk(ctx context.Context, ri beRootInterface, attempts int, wait *time.Duration, err error) error {
+	if attempts >= maxChunkAttempts {
+		return err
+	}
+	if bo := ri.backoff(err); bo > 0 {
+		*wait = bo
+	} else {
+		*wait = ri.retry().next(*wait)
+	}
+	select {
+	case <-ri.clock().After(*wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (r *Reader) curChunk() (*rchunk, error) {
 	ch := make(chan *rchunk)
 	go func() {
@@ -211,10 +376,25 @@ func (r *Reader) curChunk() (*rchunk, error) {
 }
 
 func (r *Reader) initFunc() {
+	if r.token != "" && (r.nth >= 0 || r.f != nil && r.f != r.o.f) {
+		r.setErr(errors.New("b2: ReadWithToken cannot be combined with ReadVersion or ReadNthNewest"))
+		return
+	}
+	if r.nth >= 0 {
+		f, err := r.o.nthVersion(r.ctx, r.nth)
+		if err != nil {
+			r.setErr(err)
+			return
+		}
+		r.f = f
+	}
 	r.smux.Lock()
 	r.smap = make(map[int]*meteredReader)
 	r.smux.Unlock()
-	r.o.b.c.addReader(r)
+	if err := r.o.b.c.addReader(r); err != nil {
+		r.setErr(err)
+		return
+	}
 	r.rcond = sync.NewCond(&r.rmux)
 	cr := r.ConcurrentDownloads
 	if cr < 1 {
@@ -237,6 +417,9 @@ func (r *Reader) Read(p []byte) (int, error) {
 		return 0, err
 	}
 	r.init.Do(r.initFunc)
+	if err := r.getErr(); err != nil {
+		return 0, err
+	}
 	chunk, err := r.curChunk()
 	if err != nil {
 		r.setErrNoCancel(err)
@@ -260,6 +443,52 @@ func (r *Reader) Read(p []byte) (int, error) {
 	return n, err
 }
 
+// WriteTo writes the rest of the object to w, returning the number of bytes
+// written and the first error other than io.EOF.  Each downloaded chunk is
+// written to w straight from the buffer it was downloaded into, saving the
+// copy through an intermediate buffer that Read requires; io.Copy uses
+// WriteTo automatically.  Reads and calls to WriteTo may be mixed.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for {
+		if err := r.getErr(); err != nil {
+			if err == io.EOF {
+				err = nil
+			}
+			return total, err
+		}
+		r.init.Do(r.initFunc)
+		if err := r.getErr(); err != nil {
+			return total, err
+		}
+		chunk, err := r.curChunk()
+		if err != nil {
+			r.setErrNoCancel(err)
+			return total, err
+		}
+		b := chunk.Bytes()
+		n, err := w.Write(b)
+		r.vrfy.Write(b[:n]) // Hash.Write never returns an error.
+		r.read += n
+		total += int64(n)
+		chunk.Next(n)
+		if err == nil && n < len(b) {
+			err = io.ErrShortWrite
+		}
+		if err != nil {
+			return total, err
+		}
+		if chunk.final {
+			close(r.chbuf)
+			r.setErrNoCancel(io.EOF)
+			return total, nil
+		}
+		r.chrid++
+		chunk.Reset()
+		r.chbuf <- chunk
+	}
+}
+
 func (r *Reader) status() *ReaderStatus {
 	r.smux.Lock()
 	defer r.smux.Unlock()
@@ -281,7 +510,12 @@ func (r *Reader) status() *ReaderStatus {
 // not read, or if the object was uploaded as a "large file" and thus the SHA1
 // hash was not sent), this returns (nil, false).
 func (r *Reader) Verify() (error, bool) {
+	if r.vrfy == nil {
+		return nil, false
+	}
 	got := fmt.Sprintf("%x", r.vrfy.Sum(nil))
+	r.rmux.Lock()
+	defer r.rmux.Unlock()
 	if r.sha1 == got {
 		return nil, true
 	}
@@ -296,6 +530,16 @@ func (r *Reader) Verify() (error, bool) {
 	return fmt.Errorf("bad hash: got %v, want %v", got, r.sha1), true
 }
 
+// SHA1 returns the hex-encoded SHA1 hash of everything read so far.  Once Read
+// has returned io.EOF on a reader for an entire object, this is the object's
+// hash, even for large files whose hash B2 does not know.
+func (r *Reader) SHA1() string {
+	if r.vrfy == nil {
+		return fmt.Sprintf("%x", sha1.New().Sum(nil))
+	}
+	return fmt.Sprintf("%x", r.vrfy.Sum(nil))
+}
+
 // strip a writer of any non-Write methods
 type onlyWriter struct{ w io.Writer }
 
@@ -325,24 +569,3 @@ type noopResetter struct {
 }
 
 func (noopResetter) Reset() error { return nil }
-
-type backoff time.Duration
-
-func (b *backoff) wait(ctx context.Context) error {
-	if *b == 0 {
-		*b = backoff(time.Millisecond)
-	}
-	select {
-	case <-time.After(time.Duration(*b)):
-		if time.Duration(*b) < time.Second*10 {
-			*b <<= 1
-		}
-		return nil
-	case <-ctx.Done():
-		return ctx.Err()
-	}
-}
-
-func (b backoff) String() string {
-	return time.Duration(b).String()
-}
@@ -0,0 +1,87 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"sync"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+// TestExpiringTokens transfers small and large files, concurrently, while the
+// server expires the client's tokens out from under them.  Run it with -race.
+func TestExpiringTokens(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{PartSize: 1e4, MinimumPartSize: 1e3})
+	defer s.Close()
+
+	client, err := s.Client(ctx, b2.ExpireSomeAuthTokens())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sizes := []int{10, 5e3, 3e4, 8e4}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		size := sizes[i%len(sizes)]
+		data := make([]byte, size)
+		rand.New(rand.NewSource(int64(i))).Read(data)
+		obj := bucket.Object(fmt.Sprintf("obj-%d", i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := obj.NewWriter(ctx)
+			w.ChunkSize = 1e4
+			w.ConcurrentUploads = 3
+			if _, err := io.Copy(w, bytes.NewReader(data)); err != nil {
+				t.Errorf("%s: write: %v", obj.Name(), err)
+				return
+			}
+			if err := w.Close(); err != nil {
+				t.Errorf("%s: close: %v", obj.Name(), err)
+				return
+			}
+			r := obj.NewReader(ctx)
+			r.ChunkSize = 7e3
+			r.ConcurrentDownloads = 3
+			got, err := ioutil.ReadAll(r)
+			r.Close()
+			if err != nil {
+				t.Errorf("%s: read: %v", obj.Name(), err)
+				return
+			}
+			if !bytes.Equal(got, data) {
+				t.Errorf("%s: read back %d bytes that differ from the %d written", obj.Name(), len(got), len(data))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := s.Calls("b2_authorize_account"); n < 3 {
+		t.Errorf("b2_authorize_account: got %d calls, want tokens to have expired more than once", n)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// maxCopySize is the largest object that b2_copy_file will copy.
+const maxCopySize = 5e9
+
+type relocateOptions struct {
+	workers       int
+	deleteSources bool
+}
+
+// A RelocateOption alters the behavior of Relocate.
+type RelocateOption func(*relocateOptions)
+
+// RelocateConcurrency sets the number of objects that Relocate copies at
+// once.  The default is 1.
+func RelocateConcurrency(n int) RelocateOption {
+	return func(o *relocateOptions) {
+		o.workers = n
+	}
+}
+
+// RelocateDeleteSources causes Relocate to delete each source object once it
+// has been copied, so that objects are moved rather than copied.
+func RelocateDeleteSources() RelocateOption {
+	return func(o *relocateOptions) {
+		o.deleteSources = true
+	}
+}
+
+// Relocate copies every current object whose name begins with srcPrefix to a
+// new object in the same bucket, with dstPrefix in place of srcPrefix.  This
+// can be used to rename objects in bulk, e.g. to change the case of a
+// "directory".
+//
+// Objects are copied server-side, with their content type and info, without
+// passing through the client.  Objects too large for B2 to copy (over 5GB)
+// are downloaded and uploaded again instead.  If dstPrefix itself begins with
+// srcPrefix, objects whose names already begin with dstPrefix are skipped.
+//
+// Relocate stops at the first error.  Objects relocated before the error are
+// left in place, and, with RelocateDeleteSources, their sources are gone.
+func (b *Bucket) Relocate(ctx context.Context, srcPrefix, dstPrefix string, opts ...RelocateOption) error {
+	if srcPrefix == dstPrefix {
+		return fmt.Errorf("b2: relocate %q: source and destination are the same", srcPrefix)
+	}
+	o := relocateOptions{workers: 1}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+	objs := make(chan *Object)
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range objs {
+				name := dstPrefix + strings.TrimPrefix(obj.name, srcPrefix)
+				if err := b.relocate(ctx, obj, name, o.deleteSources); err != nil {
+					mu.Lock()
+					if rerr == nil {
+						rerr = err
+						cancel()
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	skipDst := strings.HasPrefix(dstPrefix, srcPrefix)
+	iter := b.List(ctx, ListPrefix(srcPrefix), ListPageSize(1000))
+	for iter.Next() {
+		obj := iter.Object()
+		if skipDst && strings.HasPrefix(obj.name, dstPrefix) {
+			continue
+		}
+		select {
+		case objs <- obj:
+		case <-ctx.Done():
+		}
+	}
+	close(objs)
+	wg.Wait()
+	if rerr != nil {
+		return rerr
+	}
+	return iter.Err()
+}
+
+func (b *Bucket) relocate(ctx context.Context, o *Object, name string, del bool) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if err := o.ensure(ctx); err != nil {
+		return err
+	}
+	if o.f.size() <= maxCopySize {
+		if _, err := o.f.copyFile(ctx, name, 0, -1, "", nil); err != nil {
+			return err
+		}
+	} else {
+		attrs, err := o.Attrs(ctx)
+		if err != nil {
+			return err
+		}
+		r := o.NewReader(ctx)
+		defer r.Close()
+		w := b.Object(name).NewWriter(ctx, WithAttrsOption(attrs))
+		if _, err := copyContext(ctx, w, r); err != nil {
+			w.Close()
+			return err
+		}
+		if err := w.Close(); err != nil {
+			return err
+		}
+	}
+	if del {
+		return o.Delete(ctx)
+	}
+	return nil
+}
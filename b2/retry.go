@@ -0,0 +1,110 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"math/rand"
+	"time"
+)
+
+// RetrySettings describe how a client waits between attempts at a request
+// that failed with a transient error, such as a 503 or a dropped connection.
+// Unless B2 asks for a particular wait with a Retry-After header, the first
+// wait is Initial, and each wait after that is twice the last, up to Max.
+// Uploads that must be retried with a new upload URL wait in the same way,
+// from UploadInitial up to UploadMax.
+//
+// Every wait is lengthened by a random amount that averages Jitter times the
+// wait, so that clients that fail together do not retry together.
+type RetrySettings struct {
+	Initial       time.Duration
+	Max           time.Duration
+	UploadInitial time.Duration
+	UploadMax     time.Duration
+	Jitter        float64
+}
+
+// DefaultRetrySettings are the settings a client uses unless it is given
+// others with RetryPolicy.  They keep the waits blazer has always used: a
+// first wait of about a second, doubling to thirty seconds.
+var DefaultRetrySettings = RetrySettings{
+	Initial:       time.Second,
+	Max:           30 * time.Second,
+	UploadInitial: 15 * time.Millisecond,
+	UploadMax:     15 * time.Second,
+	Jitter:        1.0 / 50,
+}
+
+// RetryPolicy sets how the client waits between retries.  Fields of s that
+// are zero keep their defaults; a negative Jitter disables it.
+func RetryPolicy(s RetrySettings) ClientOption {
+	return func(o *clientOptions) {
+		o.retry = s
+	}
+}
+
+// RetrySettings returns the settings with which the client retries requests.
+func (c *Client) RetrySettings() RetrySettings {
+	return c.opts.retry.withDefaults()
+}
+
+func (s RetrySettings) withDefaults() RetrySettings {
+	d := DefaultRetrySettings
+	if s.Initial > 0 {
+		d.Initial = s.Initial
+	}
+	if s.Max > 0 {
+		d.Max = s.Max
+	}
+	if s.UploadInitial > 0 {
+		d.UploadInitial = s.UploadInitial
+	}
+	if s.UploadMax > 0 {
+		d.UploadMax = s.UploadMax
+	}
+	if s.Jitter < 0 {
+		d.Jitter = 0
+	} else if s.Jitter > 0 {
+		d.Jitter = s.Jitter
+	}
+	return d
+}
+
+func (s RetrySettings) jitter(d time.Duration) time.Duration {
+	f := float64(d) * s.Jitter
+	f += f * (rand.Float64() - 0.5)
+	return time.Duration(f)
+}
+
+// next returns the wait that follows d, or the first wait if d is zero.
+func (s RetrySettings) next(d time.Duration) time.Duration {
+	return s.grow(d, s.Initial, s.Max)
+}
+
+// nextUpload is next for uploads that need a new URL.
+func (s RetrySettings) nextUpload(d time.Duration) time.Duration {
+	return s.grow(d, s.UploadInitial, s.UploadMax)
+}
+
+func (s RetrySettings) grow(d, initial, max time.Duration) time.Duration {
+	if d <= 0 {
+		return initial + s.jitter(initial)
+	}
+	d *= 2
+	if d > max {
+		d = max
+	}
+	return d + s.jitter(d)
+}
@@ -0,0 +1,58 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestURLRotations(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{PartSize: 1e3, MinimumPartSize: 1e3})
+	defer s.Close()
+
+	client, err := s.Client(ctx, b2.KeepAlive(4, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// An upload host that is too busy must be given up for another.
+	s.Fail("b2_upload_part", 2, &b2fake.Error{Status: 503, Code: "service_unavailable"})
+	w := bucket.Object("obj").NewWriter(ctx)
+	w.ChunkSize = 1e3
+	w.ConcurrentUploads = 2
+	if _, err := io.Copy(w, strings.NewReader(strings.Repeat("x", 5e3))); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	st := w.Stats()
+	if st.URLRotations != 2 {
+		t.Errorf("URLRotations: got %d, want 2", st.URLRotations)
+	}
+	if st.Bytes != 5e3 {
+		t.Errorf("Bytes: got %d, want 5000", st.Bytes)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// scopedCapabilities are the capabilities of a scoped client's keys, unless
+// others are given: everything that can be done within a bucket.
+var scopedCapabilities = []string{
+	"listBuckets", "listFiles", "readFiles", "shareFiles", "writeFiles", "deleteFiles",
+}
+
+// scope mints the keys of a client returned by ScopedClient.
+type scope struct {
+	b    *Bucket
+	ko   keyOptions
+	mu   sync.Mutex
+	key  *Key
+	keys int // minted so far
+}
+
+// ScopedClient returns a new Client, with the same options as b's, that is
+// authorized with an application key restricted to b.  Programs can hand it
+// to subsystems that should have access to this bucket alone.  The key is
+// created with b's client, and so b's client must be able to write keys.
+//
+// The key has the capabilities given with Capabilities, plus "listBuckets",
+// which is needed to look b up; by default it may do anything within b.  It
+// is restricted to the prefix given with Prefix, if any, and expires after
+// the given Lifetime, one hour by default.  When the client's authorization
+// is rejected with less than a quarter of the key's lifetime left, a new key
+// is created in its place, so that the client remains usable.  Replaced keys
+// are left to expire; the current key is deleted when the client is closed.
+func (b *Bucket) ScopedClient(ctx context.Context, opts ...KeyOption) (*Client, error) {
+	var ko keyOptions
+	for _, o := range opts {
+		o(&ko)
+	}
+	if ko.lifetime <= 0 {
+		ko.lifetime = time.Hour
+	}
+	if len(ko.caps) == 0 {
+		ko.caps = scopedCapabilities
+	}
+	var list bool
+	for _, c := range ko.caps {
+		list = list || c == "listBuckets"
+	}
+	if !list {
+		ko.caps = append(append([]string(nil), ko.caps...), "listBuckets")
+	}
+	s := &scope{b: b, ko: ko}
+
+	base := b.c.opts
+	base.proxy = nil // already part of the transport
+	base.keepAlive = nil
+	base.token = nil
+	base.reauth = nil
+	base.keys = s.credentials
+	c, err := newClient(base, nil)
+	if err != nil {
+		return nil, err
+	}
+	c.scope = s
+	if err := c.backend.authorizeAccount(ctx, "", "", c.opts); err != nil {
+		s.close(ctx)
+		return nil, err
+	}
+	return c, nil
+}
+
+// credentials returns the ID and secret of the current key, creating a new
+// key if there is none, or if it is near its expiry.
+func (s *scope) credentials(ctx context.Context) (string, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil && s.b.r.clock().Now().Add(s.ko.lifetime/4).Before(s.key.Expires()) {
+		return s.key.ID(), s.key.Secret(), nil
+	}
+	s.keys++
+	name := fmt.Sprintf("%s-scoped-%d", s.b.Name(), s.keys)
+	ki, err := s.b.r.createKey(ctx, name, s.ko.caps, s.ko.lifetime, s.b.b.id(), s.ko.prefix)
+	if err != nil {
+		return "", "", err
+	}
+	s.key = &Key{c: s.b.c, k: ki}
+	return s.key.ID(), s.key.Secret(), nil
+}
+
+// close deletes the current key, if any.
+func (s *scope) close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key == nil {
+		return nil
+	}
+	err := s.key.Delete(ctx)
+	s.key = nil
+	return err
+}
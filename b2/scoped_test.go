@@ -0,0 +1,124 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+// skewClock is the system clock, moved forward by skew.
+type skewClock struct {
+	mu   sync.Mutex
+	skew time.Duration
+}
+
+func (c *skewClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Add(c.skew)
+}
+
+func (c *skewClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+func (c *skewClock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.skew += d
+}
+
+func keyNames(t *testing.T, client *b2.Client) []string {
+	keys, _, err := client.ListKeys(context.Background(), 100, "")
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, k := range keys {
+		names = append(names, k.Name())
+	}
+	return names
+}
+
+func TestScopedClient(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	clk := &skewClock{}
+	parent, err := s.Client(ctx, b2.WithClock(clk))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pb, err := parent.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	child, err := pb.ScopedClient(ctx, b2.Lifetime(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := child.AccountInfo().BucketID; got == "" {
+		t.Error("scoped key is not restricted to a bucket")
+	}
+	bucket, err := child.Bucket(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Object("obj").WriteFrom(ctx, strings.NewReader("data"), 4); err != nil {
+		t.Fatal(err)
+	}
+	read := func() {
+		t.Helper()
+		r := bucket.Object("obj").NewReader(ctx)
+		defer r.Close()
+		got, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != "data" {
+			t.Errorf("read: got %q, want %q", got, "data")
+		}
+	}
+
+	// A token that expires early is replaced with the same key.
+	s.ExpireTokens()
+	read()
+	if got := keyNames(t, parent); len(got) != 1 {
+		t.Errorf("keys after reauthorizing: got %q, want one", got)
+	}
+
+	// A key that is near its expiry is replaced.
+	clk.advance(50 * time.Minute)
+	s.ExpireTokens()
+	read()
+	if got := keyNames(t, parent); len(got) != 2 || got[0] != "bucket-scoped-1" || got[1] != "bucket-scoped-2" {
+		t.Errorf("keys after renewal: got %q, want bucket-scoped-1 and bucket-scoped-2", got)
+	}
+
+	if err := child.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := keyNames(t, parent); len(got) != 1 || got[0] != "bucket-scoped-1" {
+		t.Errorf("keys after Close: got %q, want bucket-scoped-1", got)
+	}
+}
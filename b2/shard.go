@@ -0,0 +1,249 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"sync"
+)
+
+// ListSharded lists the current objects in the bucket with several
+// concurrent listings, calling f for each object.  This is much faster than
+// List for full scans of buckets with millions of objects.
+//
+// The namespace is split into name ranges as the listing progresses: whenever
+// a worker is idle, a range still being listed is divided at the midpoint of
+// what remains of it, so dense parts of the namespace are split finely and
+// sparse ones hardly at all.  f is called
+// concurrently from up to workers goroutines, and objects are not passed to
+// it in order.  If f returns an error, the listing stops and ListSharded
+// returns that error.
+//
+// Only ListPrefix and ListPageSize may be given as options.
+func (b *Bucket) ListSharded(ctx context.Context, workers int, f func(*Object) error, opts ...ListOption) error {
+	var o objectIteratorOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.hidden || o.unfinished || o.delimiter != "" || !o.since.IsZero() || o.resume != nil || o.resumeErr != nil || o.budget != 0 {
+		return errors.New("b2: ListSharded supports only ListPrefix and ListPageSize")
+	}
+	count := o.pageSize
+	if count <= 0 || count > 1000 {
+		count = 1000
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	q := newShardQueue(workers)
+	q.put(nameRange{start: o.prefix})
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				r, ok := q.get()
+				if !ok {
+					return
+				}
+				if err := b.listRange(ctx, q, r, o.prefix, count, f); err != nil {
+					mu.Lock()
+					if rerr == nil {
+						rerr = err
+					}
+					mu.Unlock()
+					cancel()
+					q.stop()
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return rerr
+}
+
+// nameRange is the half-open range of object names [start, end).  An empty
+// end is unbounded.
+type nameRange struct {
+	start, end string
+}
+
+// listRange lists the objects in r, handing the upper part of the range back
+// to q whenever another worker is idle.
+func (b *Bucket) listRange(ctx context.Context, q *shardQueue, r nameRange, prefix string, count int, f func(*Object) error) error {
+	c := &cursor{prefix: prefix, name: r.start}
+	for {
+		objs, next, err := b.listCurrentObjects(ctx, count, c)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		for _, obj := range objs {
+			if r.end != "" && obj.name >= r.end {
+				return nil
+			}
+			if err := f(obj); err != nil {
+				return err
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		c = next
+		if q.hungry() {
+			if mid, ok := midpoint(c.name, r.end, prefix); ok {
+				q.put(nameRange{start: mid, end: r.end})
+				r.end = mid
+			}
+		}
+	}
+}
+
+// Split points are made only of printable ASCII, so that they are valid
+// object names and survive JSON encoding unchanged.
+const (
+	minSplitChar = ' '
+	maxSplitChar = '~'
+	splitBase    = maxSplitChar - minSplitChar + 1
+)
+
+// midpoint returns a name roughly halfway between lo and hi, and reports
+// whether it lies strictly between them.  An empty hi stands for the last
+// possible name beginning with prefix.
+func midpoint(lo, hi, prefix string) (string, bool) {
+	n := len(lo)
+	if len(hi) > n {
+		n = len(hi)
+	}
+	n++
+	unbounded := hi == ""
+	if unbounded {
+		hi = prefix + strings.Repeat(string(rune(maxSplitChar)), n-len(prefix))
+	}
+	sum := new(big.Int).Add(splitDigits(lo, n), splitDigits(hi, n))
+	sum.Rsh(sum, 1)
+
+	digits := make([]byte, n)
+	base := big.NewInt(splitBase)
+	d := new(big.Int)
+	for i := n - 1; i >= 0; i-- {
+		sum.DivMod(sum, base, d)
+		digits[i] = byte(d.Int64()) + minSplitChar
+	}
+	mid := string(digits)
+	if trimmed := strings.TrimRight(mid, string(rune(minSplitChar))); trimmed > lo {
+		mid = trimmed
+	}
+	if mid <= lo || (!unbounded && mid >= hi) || !strings.HasPrefix(mid, prefix) {
+		return "", false
+	}
+	return mid, true
+}
+
+// splitDigits reads the first n bytes of s, padded on the right, as a number
+// in base splitBase.  Bytes outside the printable range are clamped to it.
+func splitDigits(s string, n int) *big.Int {
+	v := new(big.Int)
+	base := big.NewInt(splitBase)
+	for i := 0; i < n; i++ {
+		c := byte(minSplitChar)
+		if i < len(s) {
+			c = s[i]
+		}
+		if c < minSplitChar {
+			c = minSplitChar
+		}
+		if c > maxSplitChar {
+			c = maxSplitChar
+		}
+		v.Mul(v, base)
+		v.Add(v, big.NewInt(int64(c-minSplitChar)))
+	}
+	return v
+}
+
+// shardQueue hands name ranges to a fixed number of workers, and ends the
+// listing when every worker is idle and no ranges remain.
+type shardQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	ranges  []nameRange
+	workers int
+	idle    int
+	done    bool
+}
+
+func newShardQueue(workers int) *shardQueue {
+	q := &shardQueue{workers: workers}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+func (q *shardQueue) put(r nameRange) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.ranges = append(q.ranges, r)
+	q.cond.Signal()
+}
+
+// get returns the next range to list, waiting for one if necessary.  It
+// returns false when the listing is over.
+func (q *shardQueue) get() (nameRange, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.idle++
+	for len(q.ranges) == 0 && !q.done {
+		if q.idle == q.workers {
+			q.done = true
+			q.cond.Broadcast()
+			break
+		}
+		q.cond.Wait()
+	}
+	if q.done {
+		return nameRange{}, false
+	}
+	q.idle--
+	r := q.ranges[0]
+	q.ranges = q.ranges[1:]
+	return r, true
+}
+
+// hungry reports whether a worker is waiting for a range.
+func (q *shardQueue) hungry() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.idle > 0 && len(q.ranges) == 0 && !q.done
+}
+
+func (q *shardQueue) stop() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.done = true
+	q.cond.Broadcast()
+}
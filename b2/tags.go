@@ -0,0 +1,169 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tagPrefix     = "tag-"
+	maxInfoKeys   = 10
+	maxInfoKeyLen = 50
+)
+
+// Tags are arbitrary labels attached to an object.  They are stored in the
+// object's Info, under keys derived from the tag names.
+//
+// B2 restricts Info keys to letters, digits, "-", and "_", and does not
+// preserve their case; tag names have no such restriction, and are escaped as
+// necessary.  Because each tag occupies one of the ten available Info keys,
+// and because the escaped key must be no longer than 50 characters, not
+// every set of tags can be stored.
+type Tags map[string]string
+
+// Info returns the Info entries that encode t.
+func (t Tags) Info() (map[string]string, error) {
+	info := make(map[string]string)
+	for k, v := range t {
+		key := tagPrefix + escapeInfoKey(k)
+		if len(key) > maxInfoKeyLen {
+			return nil, fmt.Errorf("b2: tag %q: encoded name %q is longer than %d characters", k, key, maxInfoKeyLen)
+		}
+		if !utf8.ValidString(v) {
+			return nil, fmt.Errorf("b2: tag %q: value is not valid UTF-8", k)
+		}
+		info[key] = v
+	}
+	if len(info) > maxInfoKeys {
+		return nil, &InfoLimitError{Keys: len(info)}
+	}
+	return info, nil
+}
+
+// An InfoLimitError is returned when more info keys would be stored with an
+// object than B2 allows.
+type InfoLimitError struct {
+	// Keys is the number of keys that would have been stored.
+	Keys int
+}
+
+func (e *InfoLimitError) Error() string {
+	return fmt.Sprintf("b2: %d info keys would be stored, but at most %d can be", e.Keys, maxInfoKeys)
+}
+
+// Tags returns the tags stored in the object's Info.
+func (a *Attrs) Tags() Tags {
+	t := make(Tags)
+	for k, v := range a.Info {
+		if !strings.HasPrefix(k, tagPrefix) {
+			continue
+		}
+		name, err := unescapeInfoKey(strings.TrimPrefix(k, tagPrefix))
+		if err != nil {
+			continue
+		}
+		t[name] = v
+	}
+	return t
+}
+
+// WithTags stores the given tags with the object, alongside any other Info.
+// If the tags cannot be stored, the writer will return an error.  Because
+// WithAttrsOption replaces the writer's Info, WithTags must follow it.
+func WithTags(t Tags) WriterOption {
+	return func(w *Writer) {
+		info, err := t.Info()
+		if err == nil && len(info)+len(w.info) > maxInfoKeys {
+			err = &InfoLimitError{Keys: len(info) + len(w.info)}
+		}
+		if err != nil {
+			w.setErr(err)
+			return
+		}
+		if w.info == nil {
+			w.info = make(map[string]string)
+		}
+		for k, v := range info {
+			w.info[k] = v
+		}
+	}
+}
+
+// ValidateInfo reports whether info can be stored with an object.  Invalid
+// info otherwise causes an error only when the upload is attempted.
+func ValidateInfo(info map[string]string) error {
+	if len(info) > maxInfoKeys {
+		return &InfoLimitError{Keys: len(info)}
+	}
+	for k, v := range info {
+		if k == "" || len(k) > maxInfoKeyLen {
+			return fmt.Errorf("b2: info key %q must be between 1 and %d characters", k, maxInfoKeyLen)
+		}
+		for _, r := range k {
+			if !isInfoKeyRune(r) {
+				return fmt.Errorf("b2: info key %q contains invalid character %q", k, r)
+			}
+		}
+		if !utf8.ValidString(v) {
+			return fmt.Errorf("b2: info value for %q is not valid UTF-8", k)
+		}
+	}
+	return nil
+}
+
+func isInfoKeyRune(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_'
+}
+
+// escapeInfoKey encodes s using only characters that B2 preserves in Info
+// keys.  Lower case letters, digits, and "-" are kept; all other bytes are
+// written as "_" followed by two hex digits.
+func escapeInfoKey(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-' {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "_%02x", c)
+	}
+	return b.String()
+}
+
+func unescapeInfoKey(s string) (string, error) {
+	var b strings.Builder
+	s = strings.ToLower(s)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '_' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+3 > len(s) {
+			return "", fmt.Errorf("b2: bad escape in info key %q", s)
+		}
+		n, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("b2: bad escape in info key %q", s)
+		}
+		b.WriteByte(byte(n))
+		i += 2
+	}
+	return b.String(), nil
+}
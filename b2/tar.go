@@ -0,0 +1,303 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+type tarOptions struct {
+	workers    int
+	buffer     int64
+	mtime      ModTimeSource
+	readerOpts []ReaderOption
+	writerOpts []WriterOption
+}
+
+// A TarOption alters the behavior of ExportTar and ImportTar.
+type TarOption func(*tarOptions)
+
+// TarConcurrency sets the number of objects that are downloaded or uploaded at
+// once.  Each download is buffered in memory up to the limit set by its
+// Reader's ChunkSize and ConcurrentDownloads, and each upload up to the limit
+// set by TarBufferSize.  The default is 4.
+func TarConcurrency(n int) TarOption {
+	return func(o *tarOptions) {
+		o.workers = n
+	}
+}
+
+// TarReaderOptions sets options for the Readers that download each object,
+// such as DownloadConcurrency for large objects.
+func TarReaderOptions(opts ...ReaderOption) TarOption {
+	return func(o *tarOptions) {
+		o.readerOpts = append(o.readerOpts, opts...)
+	}
+}
+
+// TarWriterOptions sets options for the Writers that upload each entry.
+func TarWriterOptions(opts ...WriterOption) TarOption {
+	return func(o *tarOptions) {
+		o.writerOpts = append(o.writerOpts, opts...)
+	}
+}
+
+// TarBufferSize sets the size of the largest entry that ImportTar reads into
+// memory, so that it can be uploaded while the archive is read further.
+// Larger entries are uploaded as they are read, one at a time.  The default is
+// 10MB.
+func TarBufferSize(n int64) TarOption {
+	return func(o *tarOptions) {
+		o.buffer = n
+	}
+}
+
+// TarModTime sets where the modification times of archive entries come from.
+// ExportTar gives each entry the object's modification time according to src,
+// and ImportTar saves each entry's modification time as LastModified unless
+// src is ModTimeUpload.  The default is ModTimeInfoOrUpload.
+func TarModTime(src ModTimeSource) TarOption {
+	return func(o *tarOptions) {
+		o.mtime = src
+	}
+}
+
+// ExportTar writes every current object whose name begins with prefix to w, as
+// a tar archive.  Each entry is named for its object, and has the object's
+// size and modification time (see TarModTime).
+//
+// Objects are downloaded ahead of the one being written, so that the archive
+// is produced at the speed of several downloads, but nothing is written to
+// disk.  To compress the archive, wrap w in a compressor such as gzip.Writer.
+// ExportTar does not close w.
+func (b *Bucket) ExportTar(ctx context.Context, w io.Writer, prefix string, opts ...TarOption) error {
+	o := tarOptions{workers: 4, buffer: 1e7}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Entries wait in queue with their downloads under way.  Together with
+	// the one being written, no more than o.workers are downloaded at once.
+	queue := make(chan *tarEntry, o.workers-1)
+	var listErr error
+	go func() {
+		defer close(queue)
+		iter := b.List(ctx, ListPrefix(prefix), ListPageSize(1000))
+		for iter.Next() {
+			e := &tarEntry{obj: iter.Object(), ready: make(chan struct{})}
+			select {
+			case queue <- e:
+				go e.start(ctx, o.readerOpts, o.mtime)
+			case <-ctx.Done():
+				return
+			}
+		}
+		listErr = iter.Err()
+	}()
+
+	tw := tar.NewWriter(w)
+	for e := range queue {
+		if err := e.write(ctx, tw); err != nil {
+			cancel()
+			for e := range queue {
+				e.close()
+			}
+			return err
+		}
+	}
+	if listErr != nil {
+		return listErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// A tarEntry is an object on its way into an archive.
+type tarEntry struct {
+	obj   *Object
+	attrs *Attrs
+	mtime time.Time
+	r     *Reader
+	err   error
+	ready chan struct{} // closed once attrs, mtime, r, and err are set
+}
+
+// start begins downloading the entry's object.
+func (e *tarEntry) start(ctx context.Context, opts []ReaderOption, src ModTimeSource) {
+	defer close(e.ready)
+	e.attrs, e.err = e.obj.Attrs(ctx)
+	if e.err != nil {
+		return
+	}
+	e.mtime = e.attrs.ModTime(src)
+	e.r = e.obj.NewReader(ctx, append([]ReaderOption{ReadVersion(e.obj.ID())}, opts...)...)
+	// An empty read starts the download, and waits for the first chunk.
+	if _, err := e.r.Read(nil); err != nil && err != io.EOF {
+		e.err = err
+	}
+}
+
+func (e *tarEntry) write(ctx context.Context, tw *tar.Writer) error {
+	<-e.ready
+	defer e.close()
+	if e.err != nil {
+		return e.err
+	}
+	hdr := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     e.obj.Name(),
+		Size:     e.attrs.Size,
+		Mode:     0644,
+		ModTime:  e.mtime,
+	}
+	if err := tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	n, err := copyContext(ctx, tw, e.r)
+	if err != nil {
+		return err
+	}
+	if n != e.attrs.Size {
+		return fmt.Errorf("b2: %s: read %d bytes, want %d", e.obj.Name(), n, e.attrs.Size)
+	}
+	return e.r.Close()
+}
+
+func (e *tarEntry) close() {
+	<-e.ready
+	if e.r != nil {
+		e.r.Close()
+	}
+}
+
+// ImportTar uploads each regular file in the tar archive read from r as an
+// object, named for the entry with prefix prepended and any leading "./"
+// removed.  The entry's modification time is saved as the object's
+// LastModified, unless TarModTime is ModTimeUpload.  Directories, links, and
+// other special entries are skipped.
+//
+// Entries no larger than TarBufferSize are uploaded concurrently, so memory
+// use is bounded by TarConcurrency times TarBufferSize, plus the buffers of
+// the Writer uploading the current larger entry, if any.  ImportTar stops at
+// the first error; objects uploaded before it are left in place.
+func (b *Bucket) ImportTar(ctx context.Context, r io.Reader, prefix string, opts ...TarOption) error {
+	o := tarOptions{workers: 4, buffer: 1e7}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+	fail := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if rerr == nil {
+			rerr = err
+			cancel()
+		}
+	}
+	slots := make(chan struct{}, o.workers)
+	tr := tar.NewReader(r)
+	for ctx.Err() == nil {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			fail(err)
+			break
+		}
+		if !hdr.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		obj := b.Object(prefix + strings.TrimPrefix(hdr.Name, "./"))
+		if err := ValidateName(obj.name); err != nil {
+			fail(err)
+			break
+		}
+		var wopts []WriterOption
+		if o.mtime.saves() {
+			wopts = append(wopts, WithAttrsOption(&Attrs{LastModified: hdr.ModTime}))
+		}
+		wopts = append(wopts, o.writerOpts...)
+
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			continue
+		}
+		if hdr.Size > o.buffer {
+			err := importStream(ctx, obj, tr, wopts)
+			<-slots
+			if err != nil {
+				fail(err)
+			}
+			continue
+		}
+		buf := make([]byte, hdr.Size)
+		if _, err := io.ReadFull(tr, buf); err != nil {
+			<-slots
+			fail(err)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := obj.WriteFrom(ctx, bytes.NewReader(buf), int64(len(buf)), wopts...); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	mu.Lock()
+	defer mu.Unlock()
+	if rerr != nil {
+		return rerr
+	}
+	return ctx.Err()
+}
+
+// importStream uploads the rest of r to obj.
+func importStream(ctx context.Context, obj *Object, r io.Reader, opts []WriterOption) error {
+	w := obj.NewWriter(ctx, opts...)
+	if _, err := copyContext(ctx, w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,81 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"errors"
+)
+
+// A TokenFunc returns a new authorization token, and the API and download
+// URLs it is good for, as b2_authorize_account would.  It is typically
+// implemented by asking a central credential service.
+type TokenFunc func(ctx context.Context) (apiURL, downloadURL, token string, err error)
+
+// ReauthFunc sets the function that a client made with NewClientFromToken
+// calls for a new token when its token expires.
+func ReauthFunc(f TokenFunc) ClientOption {
+	return func(o *clientOptions) {
+		o.reauth = f
+	}
+}
+
+// TokenAccount sets the ID of the account that the tokens given to a client
+// made with NewClientFromToken were issued for.  A token does not reveal its
+// account, and B2 requires it to list and create buckets, so clients that
+// call Bucket, ListBuckets, or NewBucket need it.
+func TokenAccount(accountID string) ClientOption {
+	return func(o *clientOptions) {
+		o.tokenAccount = accountID
+	}
+}
+
+// ErrNoReauth is returned by clients made with NewClientFromToken without a
+// ReauthFunc, once their token has expired.
+var ErrNoReauth = errors.New("b2: authorization token expired, and the client has no ReauthFunc")
+
+// NewClientFromToken creates and returns a new Client that uses an
+// authorization token obtained elsewhere, so that programs that use it never
+// see the application key.  apiURL and downloadURL are the URLs returned with
+// the token.  When the token expires, the client gets another from the
+// function given with ReauthFunc; if token is empty, its first token comes
+// from that function too.
+//
+// Since only b2_authorize_account reports them, the client's AccountInfo
+// lacks the key's capabilities and restrictions, and its part sizes are B2's
+// usual 100MB and 5MB.  See also TokenAccount.
+func NewClientFromToken(ctx context.Context, apiURL, downloadURL, token string, opts ...ClientOption) (*Client, error) {
+	c, err := newClient(clientOptions{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	reauth := c.opts.reauth
+	first := token != ""
+	// The first call is made here, before the client is shared.
+	c.opts.token = func(ctx context.Context) (string, string, string, error) {
+		if first {
+			first = false
+			return apiURL, downloadURL, token, nil
+		}
+		if reauth == nil {
+			return "", "", "", ErrNoReauth
+		}
+		return reauth(ctx)
+	}
+	if err := c.backend.authorizeAccount(ctx, c.opts.tokenAccount, "", c.opts); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,101 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func TestNewClientFromToken(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{})
+	defer s.Close()
+
+	// The credential service holds the key, and hands out tokens.
+	var issued int
+	issue := func(ctx context.Context) (string, string, string, error) {
+		central, err := s.Client(ctx)
+		if err != nil {
+			return "", "", "", err
+		}
+		issued++
+		info := central.AccountInfo()
+		return info.APIURL, info.DownloadURL, central.Base().AuthToken(), nil
+	}
+	central, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := central.NewBucket(ctx, "bucket", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	apiURL, downloadURL, token, err := issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worker, err := b2.NewClientFromToken(ctx, apiURL, downloadURL, token, b2.TokenAccount("account"), b2.ReauthFunc(issue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := worker.Bucket(ctx, "bucket")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bucket.Object("obj").WriteFrom(ctx, strings.NewReader("data"), 4); err != nil {
+		t.Fatal(err)
+	}
+
+	s.ExpireTokens()
+	r := bucket.Object("obj").NewReader(ctx)
+	got, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "data" {
+		t.Errorf("read after expiry: got %q, want %q", got, "data")
+	}
+	if issued != 2 {
+		t.Errorf("tokens issued: got %d, want 2", issued)
+	}
+	if rec, _, err := worker.PartSizes(ctx); err != nil || rec != 1e8 {
+		t.Errorf("PartSizes: got %d, %v; want 100000000, nil", rec, err)
+	}
+
+	// Without a ReauthFunc, an expired token is fatal.
+	apiURL, downloadURL, token, err = issue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stuck, err := b2.NewClientFromToken(ctx, apiURL, downloadURL, token, b2.TokenAccount("account"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stuck.Bucket(ctx, "bucket"); err != nil {
+		t.Fatal(err)
+	}
+	s.ExpireTokens()
+	if _, err := stuck.ListBuckets(ctx); !errors.Is(err, b2.ErrNoReauth) {
+		t.Errorf("ListBuckets with an expired token: got %v, want ErrNoReauth", err)
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// BucketUsage summarizes what a bucket holds.
+type BucketUsage struct {
+	Bucket string
+
+	// Objects and Bytes count the current, unhidden objects in the bucket.
+	Objects int64
+	Bytes   int64
+
+	// Versions and StoredBytes count every stored version, including hidden
+	// and superseded ones.  StoredBytes is what B2 bills for, less the parts
+	// of unfinished large files.
+	Versions    int64
+	StoredBytes int64
+
+	// UnfinishedLargeFiles counts large files that were started but neither
+	// finished nor cancelled.
+	UnfinishedLargeFiles int64
+
+	// Computed is when the bucket was listed.
+	Computed time.Time
+}
+
+type usageOptions struct {
+	workers int
+	maxAge  time.Duration
+}
+
+// A UsageOption alters the behavior of Usage.
+type UsageOption func(*usageOptions)
+
+// UsageConcurrency sets the number of buckets that Usage lists at once.  The
+// default is 4.
+func UsageConcurrency(n int) UsageOption {
+	return func(o *usageOptions) {
+		o.workers = n
+	}
+}
+
+// UsageMaxAge allows Usage to return a bucket's usage as computed by an
+// earlier call, if it is no older than d, instead of listing the bucket again.
+func UsageMaxAge(d time.Duration) UsageOption {
+	return func(o *usageOptions) {
+		o.maxAge = d
+	}
+}
+
+type usageCache struct {
+	mu sync.Mutex
+	m  map[string]*BucketUsage
+}
+
+// Usage reports the usage of every bucket in the account, sorted by bucket
+// name.  It lists every version in each bucket, which is one class C
+// transaction per thousand versions, so use UsageMaxAge to avoid repeating
+// the work for frequent reports.
+func (c *Client) Usage(ctx context.Context, opts ...UsageOption) ([]*BucketUsage, error) {
+	o := usageOptions{workers: 4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	buckets, err := c.ListBuckets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	usage := make([]*BucketUsage, len(buckets))
+	ch := make(chan int)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		rerr error
+	)
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range ch {
+				u, err := c.bucketUsage(ctx, buckets[i], o.maxAge)
+				if err != nil {
+					mu.Lock()
+					if rerr == nil {
+						rerr = err
+					}
+					mu.Unlock()
+					cancel()
+					continue
+				}
+				usage[i] = u
+			}
+		}()
+	}
+	for i := range buckets {
+		ch <- i
+	}
+	close(ch)
+	wg.Wait()
+	if rerr != nil {
+		return nil, rerr
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Bucket < usage[j].Bucket })
+	return usage, nil
+}
+
+// bucketUsage returns the usage for b, from the cache if an entry no older
+// than maxAge is there.
+func (c *Client) bucketUsage(ctx context.Context, b *Bucket, maxAge time.Duration) (*BucketUsage, error) {
+	now := c.backend.clock().Now()
+	c.usage.mu.Lock()
+	u, ok := c.usage.m[b.Name()]
+	c.usage.mu.Unlock()
+	if ok && maxAge > 0 && now.Sub(u.Computed) <= maxAge {
+		cp := *u
+		return &cp, nil
+	}
+	u, err := b.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.usage.mu.Lock()
+	if c.usage.m == nil {
+		c.usage.m = make(map[string]*BucketUsage)
+	}
+	cp := *u
+	c.usage.m[b.Name()] = &cp
+	c.usage.mu.Unlock()
+	return u, nil
+}
+
+// Usage reports the bucket's usage.  It lists every version in the bucket,
+// and every unfinished large file.
+func (b *Bucket) Usage(ctx context.Context) (*BucketUsage, error) {
+	u := &BucketUsage{
+		Bucket:   b.Name(),
+		Computed: b.r.clock().Now(),
+	}
+	var last string
+	iter := b.List(ctx, ListHidden(), ListPageSize(1000))
+	for iter.Next() {
+		f := iter.Object().f
+		name := f.name()
+		// Versions are listed newest first, so the first of each name
+		// decides whether there is a current object.
+		first := name != last
+		last = name
+		if f.status() != "upload" {
+			continue
+		}
+		u.Versions++
+		u.StoredBytes += f.size()
+		if first {
+			u.Objects++
+			u.Bytes += f.size()
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	iter = b.List(ctx, ListUnfinished(), ListPageSize(100))
+	for iter.Next() {
+		u.UnfinishedLargeFiles++
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
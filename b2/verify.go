@@ -0,0 +1,267 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// VerifyReport summarizes a call to Verify.
+type VerifyReport struct {
+	// Objects counts the objects that were examined.  Objects deleted after
+	// they were listed are counted, but otherwise skipped.
+	Objects int64
+
+	// Verified counts the objects that were read in full and whose hash
+	// matched the one B2 recorded on upload.
+	Verified int64
+
+	// Unverifiable counts the objects that were read in full but for which
+	// B2 has no hash, such as large files uploaded without large_file_sha1.
+	Unverifiable int64
+
+	// Sampled counts the objects that were only partly read, because they
+	// were larger than the limit set with VerifySample.
+	Sampled int64
+
+	// Bytes is the number of bytes downloaded.
+	Bytes int64
+
+	// Failures lists the objects that could not be read, or whose hashes did
+	// not match.
+	Failures []VerifyFailure
+
+	// Cursor records how far verification has progressed.  Passing it to
+	// VerifyResume continues from the first object not yet examined.
+	Cursor string
+}
+
+// A VerifyFailure is an object that failed verification.
+type VerifyFailure struct {
+	Name string
+	ID   string
+
+	// Want is the hash B2 recorded on upload, and Got the hash of what was
+	// downloaded.  Both are empty if Err is set.
+	Want string
+	Got  string
+
+	// Err is the error reading the object, if it could not be read.
+	Err error
+}
+
+func (f VerifyFailure) String() string {
+	if f.Err != nil {
+		return fmt.Sprintf("%s (%s): %v", f.Name, f.ID, f.Err)
+	}
+	return fmt.Sprintf("%s (%s): bad hash: got %s, want %s", f.Name, f.ID, f.Got, f.Want)
+}
+
+type verifyOptions struct {
+	workers  int
+	sample   int64
+	resume   string
+	progress func(*VerifyReport)
+}
+
+// A VerifyOption alters the behavior of Verify.
+type VerifyOption func(*verifyOptions)
+
+// VerifyConcurrency sets the number of objects that Verify reads at once.
+// The default is 4.
+func VerifyConcurrency(n int) VerifyOption {
+	return func(o *verifyOptions) {
+		o.workers = n
+	}
+}
+
+// VerifySample limits how much of each object Verify downloads.  Objects
+// larger than 2n bytes are not read in full; instead their first and last n
+// bytes are read.  This catches objects that cannot be read, or that are
+// shorter than B2 reports, but not corruption, since no hash can be checked.
+func VerifySample(n int64) VerifyOption {
+	return func(o *verifyOptions) {
+		o.sample = n
+	}
+}
+
+// VerifyResume continues verification from a cursor taken from an earlier
+// VerifyReport.  The prefix must be the same.
+func VerifyResume(cursor string) VerifyOption {
+	return func(o *verifyOptions) {
+		o.resume = cursor
+	}
+}
+
+// VerifyProgress causes Verify to call f with the report so far after each
+// page of objects, so that a long verification can be saved and resumed.
+func VerifyProgress(f func(*VerifyReport)) VerifyOption {
+	return func(o *verifyOptions) {
+		o.progress = f
+	}
+}
+
+// verifyBatch is the number of objects verified between progress reports.
+const verifyBatch = 1000
+
+// Verify checks the integrity of every current object whose name begins with
+// prefix, by downloading it and comparing its SHA1 hash with the one that B2
+// recorded on upload (for large files, the large_file_sha1 info key).
+// Corrupt or unreadable objects are listed in the report's Failures; Verify
+// returns an error only if the listing fails or ctx is done, in which case the
+// report so far is returned along with the error.
+//
+// Verify downloads every byte of every object, except as limited by
+// VerifySample, which is billed as download bandwidth.
+func (b *Bucket) Verify(ctx context.Context, prefix string, opts ...VerifyOption) (*VerifyReport, error) {
+	o := verifyOptions{workers: 4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers < 1 {
+		o.workers = 1
+	}
+	lopts := []ListOption{ListPrefix(prefix), ListPageSize(verifyBatch)}
+	if o.resume != "" {
+		lopts = append(lopts, ListResume(o.resume))
+	}
+	rep := &VerifyReport{}
+	iter := b.List(ctx, lopts...)
+	var batch []*Object
+	for {
+		more := iter.Next()
+		if more {
+			batch = append(batch, iter.Object())
+			if len(batch) < verifyBatch {
+				continue
+			}
+		}
+		b.verifyObjects(ctx, batch, o, rep)
+		batch = batch[:0]
+		if err := ctx.Err(); err != nil {
+			return rep, err
+		}
+		if iter.Err() != nil {
+			break
+		}
+		rep.Cursor = iter.Cursor()
+		if o.progress != nil {
+			cp := *rep
+			cp.Failures = append([]VerifyFailure(nil), rep.Failures...)
+			o.progress(&cp)
+		}
+		if !more {
+			break
+		}
+	}
+	return rep, iter.Err()
+}
+
+// verifyObjects verifies objs concurrently, adding the results to rep.
+func (b *Bucket) verifyObjects(ctx context.Context, objs []*Object, o verifyOptions, rep *VerifyReport) {
+	var (
+		wg sync.WaitGroup
+		mu sync.Mutex
+	)
+	ch := make(chan *Object)
+	for i := 0; i < o.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for obj := range ch {
+				var r VerifyReport
+				verifyObject(ctx, obj, o.sample, &r)
+				mu.Lock()
+				rep.Objects++
+				rep.Verified += r.Verified
+				rep.Unverifiable += r.Unverifiable
+				rep.Sampled += r.Sampled
+				rep.Bytes += r.Bytes
+				rep.Failures = append(rep.Failures, r.Failures...)
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, obj := range objs {
+		select {
+		case ch <- obj:
+		case <-ctx.Done():
+		}
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// verifyObject reads obj, or samples it if it is larger than twice sample,
+// and records the outcome in r.
+func verifyObject(ctx context.Context, obj *Object, sample int64, r *VerifyReport) {
+	fail := func(err error) {
+		if IsNotExist(err) || ctx.Err() != nil {
+			return
+		}
+		r.Failures = append(r.Failures, VerifyFailure{Name: obj.name, ID: obj.ID(), Err: err})
+	}
+	size := obj.f.size()
+	if sample > 0 && size > 2*sample {
+		for _, off := range []int64{0, size - sample} {
+			n, err := readRange(ctx, obj, off, sample)
+			r.Bytes += n
+			if err == nil && n != sample {
+				err = fmt.Errorf("b2: short read: got %d bytes at offset %d, want %d", n, off, sample)
+			}
+			if err != nil {
+				fail(err)
+				return
+			}
+		}
+		r.Sampled++
+		return
+	}
+
+	rd := obj.NewReader(ctx, ReadVersion(obj.ID()))
+	n, err := copyContext(ctx, discard{}, rd)
+	r.Bytes += n
+	if cerr := rd.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	rd.rmux.Lock()
+	want := strings.TrimPrefix(rd.sha1, "unverified:")
+	rd.rmux.Unlock()
+	if len(want) != 40 {
+		r.Unverifiable++
+		return
+	}
+	if got := rd.SHA1(); got != want {
+		r.Failures = append(r.Failures, VerifyFailure{Name: obj.name, ID: obj.ID(), Want: want, Got: got})
+		return
+	}
+	r.Verified++
+}
+
+func readRange(ctx context.Context, obj *Object, offset, length int64) (int64, error) {
+	rd := obj.NewRangeReader(ctx, offset, length, ReadVersion(obj.ID()))
+	n, err := copyContext(ctx, discard{}, rd)
+	if cerr := rd.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
@@ -0,0 +1,160 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// An EventType is the kind of change that an Event reports.
+type EventType int
+
+const (
+	// ObjectCreated reports a new version of an object.
+	ObjectCreated EventType = iota + 1
+	// ObjectHidden reports a hide marker, which hides an object.
+	ObjectHidden
+	// ObjectDeleted reports that a version or hide marker was deleted.
+	ObjectDeleted
+)
+
+func (t EventType) String() string {
+	switch t {
+	case ObjectCreated:
+		return "created"
+	case ObjectHidden:
+		return "hidden"
+	case ObjectDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// An Event is a change to a bucket, seen by Watch.
+type Event struct {
+	Type EventType
+
+	// Name and ID identify the version that changed.
+	Name string
+	ID   string
+
+	// Time is the upload time of created versions and hide markers.  For
+	// deletions, which B2 does not record, it is when the deletion was seen.
+	Time time.Time
+
+	// Object is the new version or hide marker, or nil for deletions.
+	Object *Object
+
+	// Err, if set, is an error listing the bucket; the other fields are unset.
+	// Watch keeps polling after errors.
+	Err error
+}
+
+// Watch polls the bucket for changes to the objects whose names begin with
+// prefix, and sends an Event for each version created, hidden, or deleted
+// since the watch began.  The bucket is listed once every interval, or once a
+// minute if interval is not positive; every poll reads the listing of every
+// version under prefix, so intervals should be chosen with the size of the
+// listing in mind.  Changes that come and go between two polls are not seen.
+//
+// The returned channel is closed once ctx is done.  Callers must receive from
+// it promptly, as polling waits for each event to be received.
+func (b *Bucket) Watch(ctx context.Context, prefix string, interval time.Duration) <-chan Event {
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	ch := make(chan Event)
+	go func() {
+		defer close(ch)
+		var seen map[string]*Object
+		for {
+			now, err := b.versions(ctx, prefix)
+			var events []Event
+			switch {
+			case err != nil:
+				if ctx.Err() != nil {
+					return
+				}
+				events = []Event{{Err: err}}
+			case seen != nil:
+				events = b.changes(seen, now)
+				seen = now
+			default:
+				seen = now
+			}
+			for _, e := range events {
+				select {
+				case ch <- e:
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-b.r.clock().After(interval):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// versions returns every finished version and hide marker under prefix, by
+// file ID.
+func (b *Bucket) versions(ctx context.Context, prefix string) (map[string]*Object, error) {
+	vs := make(map[string]*Object)
+	iter := b.List(ctx, ListHidden(), ListPrefix(prefix))
+	for iter.Next() {
+		o := iter.Object()
+		switch o.f.status() {
+		case "upload", "hide":
+			vs[o.f.id()] = o
+		}
+	}
+	return vs, iter.Err()
+}
+
+// changes returns the events that turn the versions in old into those in cur,
+// oldest first, followed by deletions.
+func (b *Bucket) changes(old, cur map[string]*Object) []Event {
+	var events []Event
+	for id, o := range cur {
+		if _, ok := old[id]; ok {
+			continue
+		}
+		e := Event{Type: ObjectCreated, Name: o.name, ID: id, Time: o.f.timestamp(), Object: o}
+		if o.f.status() == "hide" {
+			e.Type = ObjectHidden
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].Time.Equal(events[j].Time) {
+			return events[i].Time.Before(events[j].Time)
+		}
+		return events[i].Name < events[j].Name
+	})
+	var deleted []Event
+	now := b.r.clock().Now()
+	for id, o := range old {
+		if _, ok := cur[id]; !ok {
+			deleted = append(deleted, Event{Type: ObjectDeleted, Name: o.name, ID: id, Time: now})
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i].Name < deleted[j].Name })
+	return append(events, deleted...)
+}
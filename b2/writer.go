@@ -16,14 +16,19 @@ package b2
 
 import (
 	"context"
+	"crypto/sha1"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"net/http"
+	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/kurin/blazer/internal/blog"
 )
 
 // Writer writes data into Backblaze.  It automatically switches to the large
@@ -41,14 +46,17 @@ type Writer struct {
 
 	// Resume an upload.  If true, and the upload is a large file, and a file of
 	// the same name was started but not finished, then assume that we are
-	// resuming that file, and don't upload duplicate chunks.
+	// resuming that file, and don't upload duplicate chunks.  If more than one
+	// such file exists, the writer fails with an *AmbiguousResumeError, unless
+	// one is selected with ResumeFileID or ResumeNewest.
 	Resume bool
 
 	// ChunkSize is the size, in bytes, of each individual part, when writing
 	// large files, and also when determining whether to upload a file normally
-	// or when to split it into parts.  The default is 100M (1e8)  The minimum is
-	// 5M (5e6); values less than this are not an error, but will fail.  The
-	// maximum is 5GB (5e9).
+	// or when to split it into parts.  The default is the recommended part size
+	// reported by B2 (see Client.PartSizes), or 100M (1e8) if that is
+	// unavailable.  The minimum is 5M (5e6); values less than this are not an
+	// error, but will fail.  The maximum is 5GB (5e9).
 	ChunkSize int
 
 	// UseFileBuffer controls whether to use an in-memory buffer (the default) or
@@ -62,19 +70,30 @@ type Writer struct {
 
 	contentType string
 	info        map[string]string
+	noFileSHA1  bool
+	fileSHA1    hash.Hash     // hashes the entire object
+	hashed      chan struct{} // closed when the chunks sent so far are in fileSHA1
+	unhashed    bool          // data bypasses Write, and so fileSHA1
+	knownSHA1   string        // the entire object's hash, if known
+	givenSHA1   string        // the entire object's hash, from WithSHA1
+	resumeID    string
+	resumeNew   bool
 
 	csize       int
 	ctx         context.Context
 	cancel      context.CancelFunc // cancels ctx
 	ctxf        func() context.Context
 	errf        func(error)
+	onObject    func(*Object)
 	ready       chan chunk
 	cdone       chan struct{}
+	stopped     bool // whether cdone has been closed
 	wg          sync.WaitGroup
 	start       sync.Once
 	once        sync.Once
 	done        sync.Once
 	file        beLargeFileInterface
+	fin         beFileInterface
 	seen        map[int]string
 	everStarted bool
 	newBuffer   func() (writeBuffer, error)
@@ -85,16 +104,42 @@ type Writer struct {
 	cidx int
 	w    writeBuffer
 
-	emux sync.RWMutex
-	err  error
+	emux   sync.RWMutex
+	err    error
+	closed bool
+
+	gate gate // shut while paused
+
+	spillDir   string         // if set, chunks that would block are spilled here
+	spillLimit int64          // the most bytes to spill at once, or 0 for no limit
+	spilling   sync.WaitGroup // spilled chunks not yet handed to a thread
+	spillMux   sync.Mutex
+	spilled    int64 // bytes spilled and not yet handed to a thread
 
-	smux sync.RWMutex
-	smap map[int]*meteredReader
+	smux     sync.RWMutex
+	smap     map[int]*meteredReader
+	parts    []PartStats
+	uploaded int64 // payload bytes B2 has acknowledged
+	rotated  int   // upload URLs abandoned for new ones
 }
 
 type chunk struct {
-	id  int
-	buf writeBuffer
+	id     int
+	buf    writeBuffer
+	hashed chan struct{} // closed once buf is in the whole-file hash
+}
+
+// wait returns once the chunk's buffer has been hashed.
+func (c chunk) wait() {
+	if c.hashed != nil {
+		<-c.hashed
+	}
+}
+
+// close releases the chunk's buffer once it has been hashed.
+func (c chunk) close() error {
+	c.wait()
+	return c.buf.Close()
 }
 
 func (w *Writer) setErr(err error) {
@@ -106,7 +151,7 @@ func (w *Writer) setErr(err error) {
 	if w.err != nil {
 		return
 	}
-	blog.V(1).Infof("error writing %s: %v", w.name, err)
+	w.o.b.log().V(1).Infof("error writing %s: %v", w.name, err)
 	w.err = err
 	w.cancel()
 	if w.ctxf == nil {
@@ -124,12 +169,99 @@ func (w *Writer) getErr() error {
 	return w.err
 }
 
+var errWriterClosed = errors.New("b2: write to closed Writer")
+
+func (w *Writer) setClosed() {
+	w.emux.Lock()
+	defer w.emux.Unlock()
+	w.closed = true
+}
+
+func (w *Writer) isClosed() bool {
+	w.emux.RLock()
+	defer w.emux.RUnlock()
+	return w.closed
+}
+
+// stopThreads ends the upload threads, if they were started, and waits for
+// them to return.
+func (w *Writer) stopThreads() {
+	if w.cdone == nil || w.stopped {
+		return
+	}
+	w.stopped = true
+	// See https://github.com/kurin/blazer/issues/60 for why we use a special
+	// channel for this.
+	close(w.cdone)
+	w.wg.Wait()
+}
+
 func (w *Writer) registerChunk(id int, r *meteredReader) {
 	w.smux.Lock()
 	w.smap[id] = r
 	w.smux.Unlock()
 }
 
+// recordPart records the successful upload of a part, of which n bytes were
+// the object's data.  Every attempt to send the part resets its reader, so the
+// resets count the attempts.
+func (w *Writer) recordPart(id int, n int, mr *meteredReader, began time.Time) {
+	retries := mr.attempts() - 1
+	if retries < 0 {
+		retries = 0
+	}
+	w.smux.Lock()
+	defer w.smux.Unlock()
+	w.uploaded += int64(n)
+	w.parts = append(w.parts, PartStats{
+		Number:   id,
+		Size:     mr.size,
+		Began:    began,
+		Duration: w.o.b.r.clock().Now().Sub(began),
+		Retries:  retries,
+	})
+}
+
+// rotate counts an upload URL that was given up for a new one.
+func (w *Writer) rotate() {
+	w.smux.Lock()
+	defer w.smux.Unlock()
+	w.rotated++
+}
+
+// addUploaded counts n bytes of data that B2 already has, such as a chunk
+// skipped when resuming.
+func (w *Writer) addUploaded(n int) {
+	w.smux.Lock()
+	defer w.smux.Unlock()
+	w.uploaded += int64(n)
+}
+
+// payload returns the number of bytes of the object's data in buf, which may
+// be followed by a trailing hash.
+func payload(buf writeBuffer) int {
+	if buf.Hash() == "hex_digits_at_end" {
+		return buf.Len() - 40
+	}
+	return buf.Len()
+}
+
+// BytesUploaded returns the number of bytes of the object that B2 has
+// acknowledged.  For large files this is the total size of the parts B2 has
+// accepted, including those skipped because they were already uploaded,
+// while for other objects it is zero until the object is written.
+//
+// Unlike the counts returned by Write, which include data that is only
+// buffered, these bytes are durably stored: the parts of a large file whose
+// upload fails are kept by B2 until the file is cancelled, and are not sent
+// again when the upload is resumed (see Writer.Resume).  Parts are uploaded
+// concurrently, so the acknowledged bytes need not be a prefix of the object.
+func (w *Writer) BytesUploaded() int64 {
+	w.smux.RLock()
+	defer w.smux.RUnlock()
+	return w.uploaded
+}
+
 func (w *Writer) completeChunk(id int) {
 	w.smux.Lock()
 	w.smap[id] = nil
@@ -138,19 +270,41 @@ func (w *Writer) completeChunk(id int) {
 
 var gid int32
 
-func sleepCtx(ctx context.Context, d time.Duration) error {
+func sleepCtx(ctx context.Context, c Clock, d time.Duration) error {
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(d):
+	case <-c.After(d):
 		return nil
 	}
 }
 
+// A PanicError is returned by a Writer when one of its goroutines panics.
+// The panic fails the upload rather than the program.
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // the panicking goroutine's stack
+}
+
+func (p *PanicError) Error() string {
+	return fmt.Sprintf("b2: writer goroutine panicked: %v", p.Value)
+}
+
+// contain must be deferred by each of the writer's goroutines.  It turns a
+// panic into the writer's error, which cancels the writer's context, so that
+// the remaining goroutines, and Write and Close, do not wait forever on the
+// goroutine that died.
+func (w *Writer) contain() {
+	if v := recover(); v != nil {
+		w.setErr(&PanicError{Value: v, Stack: debug.Stack()})
+	}
+}
+
 func (w *Writer) thread() {
 	w.wg.Add(1)
 	go func() {
 		defer w.wg.Done()
+		defer w.contain()
 		id := atomic.AddInt32(&gid, 1)
 		fc, err := w.file.getUploadPartURL(w.ctx)
 		if err != nil {
@@ -158,23 +312,29 @@ func (w *Writer) thread() {
 			return
 		}
 		for {
+			if err := w.gate.wait(w.ctx); err != nil {
+				return
+			}
 			var cnk chunk
 			select {
 			case cnk = <-w.ready:
 			case <-w.cdone:
 				return
+			case <-w.ctx.Done():
+				return
 			}
 			if sha, ok := w.seen[cnk.id]; ok {
 				if sha != cnk.buf.Hash() {
 					w.setErr(errors.New("resumable upload was requested, but chunks don't match"))
 					return
 				}
-				cnk.buf.Close()
+				w.addUploaded(payload(cnk.buf))
+				cnk.close()
 				w.completeChunk(cnk.id)
-				blog.V(2).Infof("skipping chunk %d", cnk.id)
+				w.o.b.log().V(2).Infof("skipping chunk %d", cnk.id)
 				continue
 			}
-			blog.V(2).Infof("thread %d handling chunk %d", id, cnk.id)
+			w.o.b.log().V(2).Infof("thread %d handling chunk %d", id, cnk.id)
 			r, err := cnk.buf.Reader()
 			if err != nil {
 				w.setErr(err)
@@ -182,39 +342,40 @@ func (w *Writer) thread() {
 			}
 			mr := &meteredReader{r: r, size: cnk.buf.Len()}
 			w.registerChunk(cnk.id, mr)
-			sleep := time.Millisecond * 15
+			var sleep time.Duration
+			began := w.o.b.r.clock().Now()
 		redo:
 			n, err := fc.uploadPart(w.ctx, mr, cnk.buf.Hash(), cnk.buf.Len(), cnk.id)
 			if n != cnk.buf.Len() || err != nil {
 				if w.o.b.r.reupload(err) {
-					if err := sleepCtx(w.ctx, sleep); err != nil {
+					sleep = w.o.b.r.retry().nextUpload(sleep)
+					if err := sleepCtx(w.ctx, w.o.b.r.clock(), sleep); err != nil {
 						w.setErr(err)
 						w.completeChunk(cnk.id)
-						cnk.buf.Close() // TODO: log error
-					}
-					sleep *= 2
-					if sleep > time.Second*15 {
-						sleep = time.Second * 15
+						cnk.close() // TODO: log error
+						return
 					}
-					blog.V(1).Infof("b2 writer: wrote %d of %d: error: %v; retrying", n, cnk.buf.Len(), err)
+					w.o.b.log().V(1).Infof("b2 writer: wrote %d of %d: error: %v; retrying", n, cnk.buf.Len(), err)
 					f, err := w.file.getUploadPartURL(w.ctx)
 					if err != nil {
 						w.setErr(err)
 						w.completeChunk(cnk.id)
-						cnk.buf.Close() // TODO: log error
+						cnk.close() // TODO: log error
 						return
 					}
 					fc = f
+					w.rotate()
 					goto redo
 				}
 				w.setErr(err)
 				w.completeChunk(cnk.id)
-				cnk.buf.Close() // TODO: log error
+				cnk.close() // TODO: log error
 				return
 			}
+			w.recordPart(cnk.id, payload(cnk.buf), mr, began)
 			w.completeChunk(cnk.id)
-			cnk.buf.Close() // TODO: log error
-			blog.V(2).Infof("chunk %d handled", cnk.id)
+			cnk.close() // TODO: log error
+			w.o.b.log().V(2).Infof("chunk %d handled", cnk.id)
 		}
 	}()
 }
@@ -225,8 +386,28 @@ func (w *Writer) init() {
 		w.smux.Lock()
 		w.smap = make(map[int]*meteredReader)
 		w.smux.Unlock()
-		w.o.b.c.addWriter(w)
+		if err := ValidateName(w.name); err != nil {
+			w.setErr(err)
+			return
+		}
+		if err := w.o.b.c.addWriter(w); err != nil {
+			w.setErr(err)
+			return
+		}
+		w.fileSHA1 = sha1.New()
+		if w.givenSHA1 != "" {
+			sha, err := checkSHA1(w.givenSHA1)
+			if err != nil {
+				w.setErr(err)
+				return
+			}
+			w.givenSHA1 = sha
+			w.knownSHA1 = sha
+		}
 		w.csize = w.ChunkSize
+		if w.csize == 0 {
+			w.csize, _ = w.o.b.r.partSizes()
+		}
 		if w.csize == 0 {
 			w.csize = 1e8
 		}
@@ -245,11 +426,15 @@ func (w *Writer) init() {
 	})
 }
 
-// Write satisfies the io.Writer interface.
+// Write satisfies the io.Writer interface.  The count it returns includes
+// data that has only been buffered; see BytesUploaded for how much B2 has.
 func (w *Writer) Write(p []byte) (int, error) {
 	if len(p) == 0 {
 		return 0, nil
 	}
+	if w.isClosed() {
+		return 0, errWriterClosed
+	}
 	w.init()
 	if err := w.getErr(); err != nil {
 		return 0, err
@@ -291,7 +476,12 @@ func (w *Writer) simpleWriteFile() error {
 	// This defer needs to be in a func() so that we put whatever the value of ue
 	// is at function exit.
 	defer func() { w.o.b.urlPool.put(ue) }()
-	sha1 := w.w.Hash()
+	sha1 := w.givenSHA1
+	if sha1 == "" {
+		sha1 = w.w.Hash()
+	} else if p, ok := w.w.(presummer); ok {
+		p.presum(sha1)
+	}
 	ctype := w.contentType
 	if ctype == "" {
 		ctype = "application/octet-stream"
@@ -300,24 +490,39 @@ func (w *Writer) simpleWriteFile() error {
 	if err != nil {
 		return err
 	}
+	if err := w.gate.wait(w.ctx); err != nil {
+		return err
+	}
 	mr := &meteredReader{r: r, size: w.w.Len()}
 	w.registerChunk(1, mr)
 	defer w.completeChunk(1)
+	var sleep time.Duration
+	began := w.o.b.r.clock().Now()
 redo:
 	f, err := ue.uploadFile(w.ctx, mr, int(w.w.Len()), w.name, ctype, sha1, w.info)
 	if err != nil {
 		if w.o.b.r.reupload(err) {
-			blog.V(2).Infof("b2 writer: %v; retrying", err)
+			w.o.b.log().V(2).Infof("b2 writer: %v; retrying", err)
+			sleep = w.o.b.r.retry().nextUpload(sleep)
+			if err := sleepCtx(w.ctx, w.o.b.r.clock(), sleep); err != nil {
+				return err
+			}
 			u, err := w.o.b.b.getUploadURL(w.ctx)
 			if err != nil {
 				return err
 			}
 			ue = u
+			w.rotate()
 			goto redo
 		}
 		return err
 	}
+	w.recordPart(1, payload(w.w), mr, began)
 	w.o.f = f
+	w.fin = f
+	if w.givenSHA1 == "" {
+		w.knownSHA1 = w.w.Sum()
+	}
 	return nil
 }
 
@@ -327,22 +532,16 @@ func (w *Writer) getLargeFile() (beLargeFileInterface, error) {
 		if ctype == "" {
 			ctype = "application/octet-stream"
 		}
-		return w.o.b.b.startLargeFile(w.ctx, w.name, ctype, w.info)
-	}
-	var got bool
-	iter := w.o.b.List(w.ctx, ListPrefix(w.name), ListUnfinished())
-	var fi beFileInterface
-	for iter.Next() {
-		obj := iter.Object()
-		if obj.Name() == w.name {
-			got = true
-			fi = obj.f
+		if w.givenSHA1 != "" {
+			w.recordLargeFileSHA1(w.givenSHA1)
 		}
+		return w.o.b.b.startLargeFile(w.ctx, w.name, ctype, w.info)
 	}
-	if iter.Err() != nil {
-		return nil, iter.Err()
+	fi, err := w.resumeCandidate()
+	if err != nil {
+		return nil, err
 	}
-	if !got {
+	if fi == nil {
 		w.Resume = false
 		return w.getLargeFile()
 	}
@@ -374,6 +573,61 @@ func (w *Writer) getLargeFile() (beLargeFileInterface, error) {
 	return fi.compileParts(size, seen), nil
 }
 
+// resumeCandidate returns the unfinished large file that the writer should
+// resume, or nil if there is none.
+func (w *Writer) resumeCandidate() (beFileInterface, error) {
+	var cands []*Object
+	iter := w.o.b.List(w.ctx, ListPrefix(w.name), ListUnfinished())
+	for iter.Next() {
+		obj := iter.Object()
+		if obj.Name() != w.name {
+			continue
+		}
+		if w.resumeID != "" && obj.ID() != w.resumeID {
+			continue
+		}
+		cands = append(cands, obj)
+	}
+	if iter.Err() != nil {
+		return nil, iter.Err()
+	}
+	switch {
+	case len(cands) == 0:
+		if w.resumeID != "" {
+			return nil, fmt.Errorf("b2: no unfinished large file %s with id %s", w.name, w.resumeID)
+		}
+		return nil, nil
+	case len(cands) == 1:
+		return cands[0].f, nil
+	case w.resumeNew:
+		newest := cands[0]
+		for _, c := range cands[1:] {
+			if c.f.timestamp().After(newest.f.timestamp()) {
+				newest = c
+			}
+		}
+		return newest.f, nil
+	}
+	return nil, &AmbiguousResumeError{Name: w.name, Candidates: cands}
+}
+
+// AmbiguousResumeError is returned by writers with Resume set when more than
+// one unfinished large file exists with the writer's name.  The candidates
+// can be inspected (for instance with Object.Parts), and one selected with
+// ResumeFileID.
+type AmbiguousResumeError struct {
+	Name       string
+	Candidates []*Object
+}
+
+func (e *AmbiguousResumeError) Error() string {
+	var ids []string
+	for _, c := range e.Candidates {
+		ids = append(ids, fmt.Sprintf("%s (started %v)", c.ID(), c.f.timestamp()))
+	}
+	return fmt.Sprintf("b2: %d unfinished large files named %s: %s", len(e.Candidates), e.Name, strings.Join(ids, ", "))
+}
+
 func (w *Writer) sendChunk() error {
 	var err error
 	w.once.Do(func() {
@@ -395,16 +649,37 @@ func (w *Writer) sendChunk() error {
 	if err != nil {
 		return err
 	}
+	cnk := chunk{
+		id:  w.cidx + 1,
+		buf: w.w,
+	}
+	if !w.unhashed && w.givenSHA1 == "" {
+		cnk.hashed = w.hashChunk(w.w)
+	}
+	// If the chunk isn't sent, the buffer stays with the writer, which may
+	// close it as soon as we return.
+	sent, err := w.trySpill(cnk)
+	if err != nil {
+		cnk.wait()
+		return err
+	}
+	if sent {
+		return w.nextBuffer()
+	}
 	select {
 	case <-w.cdone:
+		cnk.wait()
 		return nil
-	case w.ready <- chunk{
-		id:  w.cidx + 1,
-		buf: w.w,
-	}:
+	case w.ready <- cnk:
 	case <-w.ctx.Done():
+		cnk.wait()
 		return w.ctx.Err()
 	}
+	return w.nextBuffer()
+}
+
+// nextBuffer gives the writer a fresh buffer after a chunk has been sent.
+func (w *Writer) nextBuffer() error {
 	w.cidx++
 	v, err := w.newBuffer()
 	if err != nil {
@@ -414,9 +689,96 @@ func (w *Writer) sendChunk() error {
 	return nil
 }
 
+// trySpill hands cnk to an upload thread if one is free.  Otherwise, if the
+// writer spills to disk and has room, it copies cnk to a scratch file and
+// hands that to the next free thread in the background.  It reports whether
+// cnk was taken, in which case its buffer no longer belongs to the writer.
+func (w *Writer) trySpill(cnk chunk) (bool, error) {
+	if w.spillDir == "" {
+		return false, nil
+	}
+	select {
+	case w.ready <- cnk:
+		return true, nil
+	default:
+	}
+	if _, ok := cnk.buf.(*memoryBuffer); !ok {
+		return false, nil
+	}
+	size := int64(cnk.buf.Len())
+	w.spillMux.Lock()
+	if w.spillLimit > 0 && w.spilled+size > w.spillLimit {
+		w.spillMux.Unlock()
+		return false, nil
+	}
+	w.spilled += size
+	w.spillMux.Unlock()
+	unspill := func() {
+		w.spillMux.Lock()
+		w.spilled -= size
+		w.spillMux.Unlock()
+	}
+
+	fb, err := newFileBuffer(w.spillDir)
+	if err != nil {
+		unspill()
+		return false, err
+	}
+	r, err := cnk.buf.Reader()
+	if err == nil {
+		_, err = io.Copy(fb, r)
+	}
+	if err != nil {
+		fb.Close()
+		unspill()
+		return false, err
+	}
+	cnk.close() // TODO: log error
+	spilled := chunk{id: cnk.id, buf: fb}
+
+	w.wg.Add(1)
+	w.spilling.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer w.spilling.Done()
+		defer unspill()
+		select {
+		case w.ready <- spilled:
+		case <-w.cdone:
+			spilled.close()
+		case <-w.ctx.Done():
+			spilled.close()
+		}
+	}()
+	return true, nil
+}
+
+// hashChunk adds buf to the whole-file hash after the chunks sent before it,
+// so that hashing runs alongside writing and uploading instead of on the Write
+// path.  The returned channel is closed when it is done.
+func (w *Writer) hashChunk(buf writeBuffer) chan struct{} {
+	prev := w.hashed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer w.contain()
+		if prev != nil {
+			<-prev
+		}
+		if err := buf.HashTo(w.fileSHA1); err != nil {
+			w.setErr(err)
+		}
+	}()
+	w.hashed = done
+	return done
+}
+
 // ReadFrom reads all of r into w, returning the first error or no error if r
-// returns io.EOF.  If r is also an io.Seeker, ReadFrom will stream r directly
-// over the wire instead of buffering it locally.  This reduces memory usage.
+// returns io.EOF.  If r is also an io.Seeker, such as an *os.File or a
+// *bytes.Reader, ReadFrom will stream the rest of r, from its current offset,
+// directly over the wire instead of buffering it locally.  This reduces memory
+// usage, and lets objects smaller than the chunk size be uploaded without a
+// copy.  Files that cannot seek, such as pipes, are buffered as with Write.
 //
 // Do not issue multiple calls to ReadFrom, or mix ReadFrom and Write.  If you
 // have multiple readers you want to concatenate into the same B2 object, use
@@ -431,8 +793,14 @@ func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
 	if !ok || w.Resume {
 		return copyContext(w.ctx, w, r)
 	}
-	blog.V(2).Info("streaming without buffer")
-	size, err := rs.Seek(0, io.SeekEnd)
+	start, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		// Not really seekable, like a pipe.
+		return copyContext(w.ctx, w, r)
+	}
+	w.o.b.log().V(2).Info("streaming without buffer")
+	w.unhashed = true
+	end, err := rs.Seek(0, io.SeekEnd)
 	if err != nil {
 		return 0, err
 	}
@@ -442,6 +810,10 @@ func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
 	} else {
 		ra = enReaderAt(rs)
 	}
+	if start > 0 {
+		ra = io.NewSectionReader(ra, start, end-start)
+	}
+	size := end - start
 	var offset int64
 	var wrote int64
 	w.newBuffer = func() (writeBuffer, error) {
@@ -467,6 +839,9 @@ func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
 		// the magic happens on w.Close()
 		return size, nil
 	}
+	if err := w.setLargeFileSHA1(ra, size); err != nil {
+		return 0, err
+	}
 	for {
 		if err := w.sendChunk(); err != nil {
 			if err != io.EOF {
@@ -477,23 +852,103 @@ func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
 	}
 }
 
+// WriteFrom uploads exactly size bytes from r to the object.  If size is
+// smaller than the writer's chunk size, the data is streamed directly to B2
+// without being buffered locally, with its SHA1 computed on the fly and sent
+// at the end of the upload.  This saves both memory and latency for small and
+// medium objects.  Larger objects are written as with NewWriter.
+//
+// If r is not also an io.Seeker, failed uploads cannot be retried.  Options
+// are applied as with NewWriter.
+func (o *Object) WriteFrom(ctx context.Context, r io.Reader, size int64, opts ...WriterOption) error {
+	w := o.NewWriter(ctx, opts...)
+	w.init()
+	if size >= int64(w.csize) {
+		if _, err := copyContext(w.ctx, w, io.LimitReader(r, size)); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}
+	if w.w != nil {
+		w.w.Close()
+	}
+	w.w = newStreamBuffer(r, size)
+	return w.Close()
+}
+
+// setLargeFileSHA1 hashes the entire contents of ra so that the whole-file
+// SHA1 can be recorded under the large_file_sha1 info key, which has to be
+// known before the large file is started.
+func (w *Writer) setLargeFileSHA1(ra io.ReaderAt, size int64) error {
+	if w.noFileSHA1 || w.givenSHA1 != "" {
+		return nil
+	}
+	if _, ok := w.info[infoLargeFileSHA1]; ok || len(w.info) >= maxInfoKeys {
+		return nil
+	}
+	hsh := sha1.New()
+	if _, err := copyContext(w.ctx, hsh, io.NewSectionReader(ra, 0, size)); err != nil {
+		return err
+	}
+	w.knownSHA1 = fmt.Sprintf("%x", hsh.Sum(nil))
+	w.recordLargeFileSHA1(w.knownSHA1)
+	return nil
+}
+
+// recordLargeFileSHA1 stores sha under the large_file_sha1 info key, unless
+// the key is already set or there is no room for it.
+func (w *Writer) recordLargeFileSHA1(sha string) {
+	if _, ok := w.info[infoLargeFileSHA1]; ok || len(w.info) >= maxInfoKeys {
+		return
+	}
+	if w.info == nil {
+		w.info = make(map[string]string)
+	}
+	w.info[infoLargeFileSHA1] = sha
+}
+
+// checkSHA1 returns sha, lowercased, if it is a hex-encoded SHA1.
+func checkSHA1(sha string) (string, error) {
+	if len(sha) != 2*sha1.Size {
+		return "", fmt.Errorf("b2: SHA1 %q has %d digits, want %d", sha, len(sha), 2*sha1.Size)
+	}
+	if _, err := hex.DecodeString(sha); err != nil {
+		return "", fmt.Errorf("b2: SHA1 %q is not hex-encoded", sha)
+	}
+	return strings.ToLower(sha), nil
+}
+
 // Close satisfies the io.Closer interface.  It is critical to check the return
 // value of Close for all writers.
+//
+// Close returns the writer's first error, whether it came from Write, from a
+// part uploading in the background, or from Close itself.  If the writer has
+// already failed, Close uploads nothing more.  Either way, Close stops the
+// writer's goroutines and frees its buffers.  Calling Close again returns the
+// same error, and Write after Close fails.
 func (w *Writer) Close() error {
 	w.done.Do(func() {
-		if !w.everStarted {
-			w.init()
-			w.setErr(w.simpleWriteFile())
+		defer w.sendObject()
+		started := w.everStarted
+		w.init()
+		defer w.o.b.c.removeWriter(w)
+		defer w.setClosed()
+		if w.w == nil {
+			// init failed, and has set the error.
 			return
 		}
-		defer w.o.b.c.removeWriter(w)
 		defer func() {
 			if err := w.w.Close(); err != nil {
 				// this is non-fatal, but alarming
-				blog.V(1).Infof("close %s: %v", w.name, err)
+				w.o.b.log().V(1).Infof("close %s: %v", w.name, err)
 			}
 		}()
-		if w.cidx == 0 {
+		defer w.stopThreads()
+		if w.getErr() != nil {
+			return
+		}
+		if !started || w.cidx == 0 {
 			w.setErr(w.simpleWriteFile())
 			return
 		}
@@ -503,45 +958,195 @@ func (w *Writer) Close() error {
 				return
 			}
 		}
-		// See https://github.com/kurin/blazer/issues/60 for why we use a special
-		// channel for this.
-		close(w.cdone)
-		w.wg.Wait()
+		// Spilled chunks must reach a thread before the threads are stopped.
+		w.spilling.Wait()
+		w.stopThreads()
+		if w.getErr() != nil {
+			// A part failed to upload.
+			return
+		}
 		f, err := w.file.finishLargeFile(w.ctx)
 		if err != nil {
 			w.setErr(err)
 			return
 		}
 		w.o.f = f
+		w.fin = f
 	})
 	return w.getErr()
 }
 
+// sendObject passes an Object bound to the uploaded file to the callback given
+// by WithUploadedObject, if the upload succeeded.
+func (w *Writer) sendObject() {
+	if w.onObject == nil || w.fin == nil || w.getErr() != nil {
+		return
+	}
+	w.onObject(&Object{
+		name: w.name,
+		f:    w.fin,
+		b:    w.o.b,
+		resp: &objectResponse{},
+	})
+}
+
+// Attrs returns the attributes of the newly written object.  They are taken
+// from B2's reply to the upload, and so do not require another network round
+// trip.  Attrs returns an error if Close has not been called or did not
+// succeed.  The new object's ID is available from the Object's ID method.
+func (w *Writer) Attrs() (*Attrs, error) {
+	if err := w.getErr(); err != nil {
+		return nil, err
+	}
+	if w.fin == nil {
+		return nil, errors.New("b2: writer has not been closed")
+	}
+	fi, err := w.fin.getFileInfo(w.ctx)
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := attrsFromInfo(fi)
+	if err != nil {
+		return nil, err
+	}
+	if attrs.SHA1 == "" || attrs.SHA1 == "none" {
+		if sha := w.SHA1(); sha != "" {
+			attrs.SHA1 = sha
+		}
+	}
+	return attrs, nil
+}
+
+// SHA1 returns the hex-encoded SHA1 hash of the entire object, as computed
+// while it was uploaded or as given with WithSHA1, so that callers need not
+// hash their data separately.
+// It returns the empty string if Close has not been called or did not
+// succeed, and for large files written with ReadFrom and WithoutLargeFileSHA1,
+// which are never hashed as a whole.
+func (w *Writer) SHA1() string {
+	if w.getErr() != nil || w.fin == nil {
+		return ""
+	}
+	if w.hashed != nil {
+		<-w.hashed
+	}
+	switch {
+	case w.knownSHA1 != "":
+		return w.knownSHA1
+	case w.fileSHA1 != nil && !w.unhashed:
+		// Large files written with Write have a hash of everything written.
+		return fmt.Sprintf("%x", w.fileSHA1.Sum(nil))
+	}
+	return ""
+}
+
 func (w *Writer) withAttrs(attrs *Attrs) *Writer {
 	w.contentType = attrs.ContentType
-	w.info = make(map[string]string)
+	info, err := attrsInfo(attrs)
+	if err != nil {
+		w.setErr(err)
+		return w
+	}
+	w.info = info
+	return w
+}
+
+// attrsInfo returns the info keys in which attrs are saved on upload.
+func attrsInfo(attrs *Attrs) (map[string]string, error) {
+	info := make(map[string]string)
+	for k, v := range attrs.RawInfo {
+		switch k {
+		case infoContentDisposition, infoCacheControl, infoExpires:
+			// These are set from their fields, even when cleared.
+			continue
+		}
+		if strings.HasPrefix(k, "b2-") {
+			info[k] = v
+		}
+	}
 	for k, v := range attrs.Info {
-		w.info[k] = v
+		info[k] = v
 	}
-	if len(w.info) < 10 && attrs.SHA1 != "" {
-		w.info["large_file_sha1"] = attrs.SHA1
+	if attrs.ContentDisposition != "" {
+		info[infoContentDisposition] = attrs.ContentDisposition
 	}
-	if len(w.info) < 10 && !attrs.LastModified.IsZero() {
-		w.info["src_last_modified_millis"] = fmt.Sprintf("%d", attrs.LastModified.UnixNano()/1e6)
+	if attrs.CacheControl != "" {
+		info[infoCacheControl] = attrs.CacheControl
 	}
-	return w
+	if !attrs.Expires.IsZero() {
+		info[infoExpires] = attrs.Expires.UTC().Format(http.TimeFormat)
+	}
+	if len(info) > maxInfoKeys {
+		return nil, &InfoLimitError{Keys: len(info)}
+	}
+	// These are kept only if there is room.
+	if len(info) < maxInfoKeys && attrs.SHA1 != "" {
+		info[infoLargeFileSHA1] = attrs.SHA1
+	}
+	if len(info) < maxInfoKeys && !attrs.LastModified.IsZero() {
+		info[infoLastModified] = fmt.Sprintf("%d", attrs.LastModified.UnixNano()/1e6)
+	}
+	return info, nil
 }
 
 // A WriterOption sets Writer-specific behavior.
 type WriterOption func(*Writer)
 
-// WithAttrs attaches the given Attrs to the writer.
+// WithAttrs attaches the given Attrs to the writer.  If they need more info
+// keys than B2 allows, the writer fails with an *InfoLimitError.
 func WithAttrsOption(attrs *Attrs) WriterOption {
 	return func(w *Writer) {
 		w.withAttrs(attrs)
 	}
 }
 
+// ResumeFileID resumes the unfinished large file with the given ID.  Unlike
+// Resume, if no such file exists, the writer fails rather than starting a new
+// file.
+func ResumeFileID(id string) WriterOption {
+	return func(w *Writer) {
+		w.Resume = true
+		w.resumeID = id
+	}
+}
+
+// ResumeNewest resumes the most recently started of the unfinished large
+// files with the writer's name, if there are more than one.
+func ResumeNewest() WriterOption {
+	return func(w *Writer) {
+		w.Resume = true
+		w.resumeNew = true
+	}
+}
+
+// WithoutLargeFileSHA1 disables the automatic computation of the whole-file
+// SHA1 for large files.
+//
+// B2 reports the SHA1 of large files as "none"; by convention, the hash of the
+// entire file is instead stored in the large_file_sha1 info key, which Attrs
+// and Reader.Verify consult.  Because file info must be supplied when a large
+// file is started, the key can only be set automatically when the object's
+// contents are available in advance, i.e. when writing with ReadFrom from an
+// io.ReadSeeker; this requires reading the source twice.  For objects written
+// with Write, the whole-file hash is still reported by Writer.Attrs.
+func WithoutLargeFileSHA1() WriterOption {
+	return func(w *Writer) {
+		w.noFileSHA1 = true
+	}
+}
+
+// WithSHA1 gives the hex-encoded SHA1 of the entire object, when it is
+// already known, so that the writer need not compute it.  Objects uploaded in
+// one piece send it to B2, which rejects the upload if the data does not
+// match.  Large files record it under the large_file_sha1 info key without
+// the whole-file hashing pass that ReadFrom otherwise makes; their parts are
+// still hashed, as B2 requires.  A malformed hash fails the writer.
+func WithSHA1(sha string) WriterOption {
+	return func(w *Writer) {
+		w.givenSHA1 = sha
+	}
+}
+
 // WithCancelOnError requests the writer, if it has started a large file
 // upload, to call b2_cancel_large_file on any permanent error.  It calls ctxf
 // to obtain a context with which to cancel the file; this is to allow callers
@@ -554,23 +1159,82 @@ func WithCancelOnError(ctxf func() context.Context, errf func(error)) WriterOpti
 	}
 }
 
+// WithUploadedObject requests the writer, when it is closed successfully, to
+// call f with an Object bound to the exact file that was uploaded.  Readers
+// opened from that Object download the file by its ID, so they return the
+// bytes that this writer wrote even if the name is concurrently overwritten.
+// f is called before Close returns.
+func WithUploadedObject(f func(*Object)) WriterOption {
+	return func(w *Writer) {
+		w.onObject = f
+	}
+}
+
+// UploadConcurrency sets the writer's ConcurrentUploads.
+func UploadConcurrency(n int) WriterOption {
+	return func(w *Writer) {
+		w.ConcurrentUploads = n
+	}
+}
+
+// SpillToDisk lets the writer go on accepting data when every upload thread
+// is busy, as when the network is slower than the source, by copying chunks
+// that would otherwise make Write wait to scratch files in dir.  The spilled
+// chunks are uploaded, and their files removed, as threads become free.  At
+// most limit bytes are spilled at once; beyond that, Write waits as usual.  A
+// limit of 0 means no limit but the disk.  This suits sources, such as live
+// captures, that cannot be made to wait without losing data.  Chunks already
+// buffered on disk, with UseFileBuffer, are not copied.
+func SpillToDisk(dir string, limit int64) WriterOption {
+	return func(w *Writer) {
+		w.spillDir = dir
+		w.spillLimit = limit
+	}
+}
+
+// UploadChunkSize sets the writer's ChunkSize.
+func UploadChunkSize(n int) WriterOption {
+	return func(w *Writer) {
+		w.ChunkSize = n
+	}
+}
+
+// UploadFileBuffer causes the writer to buffer chunks in scratch files in dir,
+// rather than in memory, as with UseFileBuffer and FileBufferDir.
+func UploadFileBuffer(dir string) WriterOption {
+	return func(w *Writer) {
+		w.UseFileBuffer = true
+		w.FileBufferDir = dir
+	}
+}
+
 // DefaultWriterOptions returns a ClientOption that will apply the given
 // WriterOptions to every Writer.  These options can be overridden by passing
-// new options to NewWriter.
+// new options to NewWriter.  It replaces any defaults set earlier.
 func DefaultWriterOptions(opts ...WriterOption) ClientOption {
 	return func(c *clientOptions) {
 		c.writerOpts = opts
 	}
 }
 
-func (w *Writer) status() *WriterStatus {
-	w.smux.RLock()
-	defer w.smux.RUnlock()
+// WithDefaultWriterOptions is like DefaultWriterOptions, but adds to the
+// defaults rather than replacing them.  Options passed to NewWriter, and
+// fields set on the Writer before it is first written, take precedence.
+func WithDefaultWriterOptions(opts ...WriterOption) ClientOption {
+	return func(c *clientOptions) {
+		c.writerOpts = append(c.writerOpts, opts...)
+	}
+}
 
+func (w *Writer) status() *WriterStatus {
 	ws := &WriterStatus{
-		Progress: make([]float64, len(w.smap)),
+		Stats: w.Stats(),
 	}
 
+	w.smux.RLock()
+	defer w.smux.RUnlock()
+
+	ws.Progress = make([]float64, len(w.smap))
 	for i := 1; i <= len(w.smap); i++ {
 		ws.Progress[i-1] = w.smap[i].done()
 	}
@@ -578,11 +1242,83 @@ func (w *Writer) status() *WriterStatus {
 	return ws
 }
 
+// PartStats describes the upload of one part of an object.  Objects that are
+// not large files are uploaded as a single part.
+type PartStats struct {
+	Number   int
+	Size     int           // bytes sent
+	Began    time.Time     // when the first attempt began
+	Duration time.Duration // from the first attempt to the end of the last
+	Retries  int
+}
+
+// Bandwidth returns the rate, in bytes per second, at which the part was
+// uploaded, including the time spent on failed attempts.
+func (p PartStats) Bandwidth() float64 {
+	if p.Duration <= 0 {
+		return 0
+	}
+	return float64(p.Size) / p.Duration.Seconds()
+}
+
+// WriterStats describes the uploads a writer has completed.  Comparing the
+// writer's Bandwidth to that of its parts shows how much is gained by
+// uploading parts concurrently, which can inform the choice of
+// ConcurrentUploads.
+type WriterStats struct {
+	// Parts holds the parts uploaded so far, in order of part number.
+	Parts []PartStats
+
+	// Bytes is the total size of the uploaded parts.
+	Bytes int64
+
+	// Retries is the total number of retried part uploads.
+	Retries int
+
+	// URLRotations is the number of times an upload URL failed and was
+	// replaced with a new one, which is usually on a different host.  Each
+	// concurrent upload otherwise keeps its URL, and its connection, for the
+	// life of the writer.
+	URLRotations int
+
+	// Bandwidth is the rate, in bytes per second, at which the parts were
+	// uploaded, from the start of the first to the end of the last.
+	Bandwidth float64
+}
+
+// Stats returns statistics about the parts that the writer has uploaded.  It
+// may be called while the upload is in progress.
+func (w *Writer) Stats() WriterStats {
+	w.smux.RLock()
+	parts := append([]PartStats(nil), w.parts...)
+	rotated := w.rotated
+	w.smux.RUnlock()
+
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	ws := WriterStats{Parts: parts, URLRotations: rotated}
+	var first, last time.Time
+	for i, p := range parts {
+		ws.Bytes += int64(p.Size)
+		ws.Retries += p.Retries
+		if i == 0 || p.Began.Before(first) {
+			first = p.Began
+		}
+		if end := p.Began.Add(p.Duration); end.After(last) {
+			last = end
+		}
+	}
+	if d := last.Sub(first); d > 0 {
+		ws.Bandwidth = float64(ws.Bytes) / d.Seconds()
+	}
+	return ws
+}
+
 type meteredReader struct {
-	read int64
-	size int
-	r    readResetter
-	mux  sync.Mutex
+	read   int64
+	size   int
+	r      readResetter
+	mux    sync.Mutex
+	resets int
 }
 
 func (mr *meteredReader) Read(p []byte) (int, error) {
@@ -597,9 +1333,16 @@ func (mr *meteredReader) Reset() error {
 	mr.mux.Lock()
 	defer mr.mux.Unlock()
 	mr.read = 0
+	mr.resets++
 	return mr.r.Reset()
 }
 
+func (mr *meteredReader) attempts() int {
+	mr.mux.Lock()
+	defer mr.mux.Unlock()
+	return mr.resets
+}
+
 func (mr *meteredReader) done() float64 {
 	if mr == nil {
 		return 1
@@ -14,14 +14,11 @@
 
 // Package base provides a very low-level interface on top of the B2 v1 API.
 // It is not intended to be used directly.
-//
-// It currently lacks support for the following APIs:
-//
-// b2_download_file_by_id
 package base
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
 	"crypto/x509"
 	"encoding/base64"
@@ -30,7 +27,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"regexp"
+	"net/url"
 	"strconv"
 	"strings"
 	"sync"
@@ -52,25 +49,54 @@ type b2err struct {
 	retry   int
 	code    int
 	msgCode string
+	header  http.Header
 }
 
 func (e b2err) Error() string {
-	if e.method == "" {
+	if e.method == "" || e.code == 0 {
 		return fmt.Sprintf("b2 error: %s", e.msg)
 	}
 	return fmt.Sprintf("%s: %d: %s", e.method, e.code, e.msg)
 }
 
+// Header returns the HTTP response headers that accompanied the given error,
+// or nil if the error did not come from a B2 response.  This includes any
+// request identifiers B2 sends, which are useful in support requests.
+func Header(err error) http.Header {
+	e, ok := err.(b2err)
+	if !ok {
+		return nil
+	}
+	return e.header
+}
+
+// Method returns the B2 API call, such as "b2_list_buckets", that returned
+// the given error, or the empty string if the error did not come from B2.
+func Method(err error) string {
+	e, ok := err.(b2err)
+	if !ok {
+		return ""
+	}
+	return e.method
+}
+
 // Action checks an error and returns a recommended course of action.
 func Action(err error) ErrAction {
 	e, ok := err.(b2err)
 	if !ok {
 		return Punt
 	}
+	upload := e.method == "b2_upload_file" || e.method == "b2_upload_part"
+	if upload && (e.code == 0 || e.code == 408) {
+		// Upload hosts that time out or drop the connection should be
+		// abandoned, per B2's integration guidelines, even if they also
+		// suggested a time to retry.
+		return AttemptNewUpload
+	}
 	if e.retry > 0 {
 		return Retry
 	}
-	if e.code >= 500 && e.code < 600 && (e.method == "b2_upload_file" || e.method == "b2_upload_part") {
+	if e.code >= 500 && e.code < 600 && upload {
 		return AttemptNewUpload
 	}
 	switch e.code {
@@ -137,13 +163,13 @@ const (
 	Punt
 )
 
-func mkErr(resp *http.Response) error {
+func (o *b2Options) mkErr(resp *http.Response, args []byte, start time.Time) error {
 	data, err := ioutil.ReadAll(resp.Body)
 	var msgBody string
 	if err != nil {
 		msgBody = fmt.Sprintf("couldn't read message body: %v", err)
 	}
-	logResponse(resp, data)
+	o.logResponse(resp, data)
 	msg := &b2types.ErrorMessage{}
 	if err := json.Unmarshal(data, msg); err != nil {
 		if msgBody != "" {
@@ -154,22 +180,55 @@ func mkErr(resp *http.Response) error {
 		msgBody = msg.Msg
 	}
 	var retryAfter int
-	retry := resp.Header.Get("Retry-After")
-	if retry != "" {
-		r, err := strconv.ParseInt(retry, 10, 64)
+	if retry := resp.Header.Get("Retry-After"); retry != "" {
+		r, err := parseRetryAfter(retry, time.Now())
 		if err != nil {
-			r = 0
-			blog.V(1).Infof("couldn't parse retry-after header %q: %v", retry, err)
+			o.log.V(1).Infof("couldn't parse retry-after header %q: %v", retry, err)
 		}
-		retryAfter = int(r)
+		retryAfter = r
 	}
-	return b2err{
+	e := b2err{
 		msg:     msgBody,
 		retry:   retryAfter,
 		code:    resp.StatusCode,
 		msgCode: msg.Code,
 		method:  resp.Request.Header.Get("X-Blazer-Method"),
+		header:  resp.Header,
+	}
+	o.dump(resp.Request, args, resp, data, start, e)
+	return e
+}
+
+// maxRetryAfter bounds the wait a server can ask for, in seconds.
+const maxRetryAfter = 24 * 60 * 60
+
+// parseRetryAfter returns the number of seconds to wait given the value of a
+// Retry-After header, which is either a number of seconds or an HTTP date.
+// Dates in the past, and values that can't be parsed, mean no wait.
+func parseRetryAfter(v string, now time.Time) (int, error) {
+	v = strings.TrimSpace(v)
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		switch {
+		case secs < 0:
+			return 0, fmt.Errorf("negative delay %d", secs)
+		case secs > maxRetryAfter:
+			return maxRetryAfter, nil
+		}
+		return int(secs), nil
 	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, err
+	}
+	d := t.Sub(now)
+	if d <= 0 {
+		return 0, nil
+	}
+	secs := (d + time.Second - 1) / time.Second
+	if secs > maxRetryAfter {
+		return maxRetryAfter, nil
+	}
+	return int(secs), nil
 }
 
 // Backoff returns an appropriate amount of time to wait, given an error, if
@@ -184,45 +243,57 @@ func Backoff(err error) time.Duration {
 	return time.Duration(e.retry) * time.Second
 }
 
-func logRequest(req *http.Request, args []byte) {
-	if !blog.V(2) {
+func (o *b2Options) logRequest(req *http.Request, args []byte) {
+	v := o.log.V(2)
+	if !v.Enabled() || !o.sampled(req) {
 		return
 	}
 	var headers []string
-	for k, v := range req.Header {
-		if k == "Authorization" || k == "X-Blazer-Method" {
+	for k, vs := range req.Header {
+		if k == "X-Blazer-Method" {
 			continue
 		}
-		headers = append(headers, fmt.Sprintf("%s: %s", k, strings.Join(v, ",")))
+		headers = append(headers, fmt.Sprintf("%s: %s", k, o.log.Header(k, strings.Join(vs, ","))))
 	}
 	hstr := strings.Join(headers, ";")
 	method := req.Header.Get("X-Blazer-Method")
 	if args != nil {
-		blog.V(2).Infof(">> %s %v: %v headers: {%s} args: (%s)", method, req.Method, req.URL, hstr, string(args))
+		v.Infof(">> %s %v: %v headers: {%s} args: (%s)", method, req.Method, req.URL, hstr, o.log.Body(args))
 		return
 	}
-	blog.V(2).Infof(">> %s %v: %v {%s} (no args)", method, req.Method, req.URL, hstr)
+	v.Infof(">> %s %v: %v {%s} (no args)", method, req.Method, req.URL, hstr)
 }
 
-var authRegexp = regexp.MustCompile(`"authorizationToken": ".[^"]*"`)
-
-func logResponse(resp *http.Response, reply []byte) {
-	if !blog.V(2) {
+func (o *b2Options) logResponse(resp *http.Response, reply []byte) {
+	v := o.log.V(2)
+	if !v.Enabled() || !o.sampled(resp.Request) {
 		return
 	}
 	var headers []string
-	for k, v := range resp.Header {
-		headers = append(headers, fmt.Sprintf("%s: %s", k, strings.Join(v, ",")))
+	for k, vs := range resp.Header {
+		headers = append(headers, fmt.Sprintf("%s: %s", k, o.log.Header(k, strings.Join(vs, ","))))
 	}
 	hstr := strings.Join(headers, "; ")
 	method := resp.Request.Header.Get("X-Blazer-Method")
 	id := resp.Request.Header.Get("X-Blazer-Request-ID")
+	if cid := resp.Request.Header.Get("X-Blazer-Correlation-ID"); cid != "" {
+		id = fmt.Sprintf("%s/%s", cid, id)
+	}
 	if reply != nil {
-		safe := string(authRegexp.ReplaceAll(reply, []byte(`"authorizationToken": "[redacted]"`)))
-		blog.V(2).Infof("<< %s (%s) %s {%s} (%s)", method, id, resp.Status, hstr, safe)
+		v.Infof("<< %s (%s) %s {%s} (%s)", method, id, resp.Status, hstr, o.log.Body(reply))
 		return
 	}
-	blog.V(2).Infof("<< %s (%s) %s {%s} (no reply)", method, id, resp.Status, hstr)
+	v.Infof("<< %s (%s) %s {%s} (no reply)", method, id, resp.Status, hstr)
+}
+
+// sampled reports whether the exchange for req should be logged.  Requests
+// and their responses share an ID, so both or neither are logged.
+func (o *b2Options) sampled(req *http.Request) bool {
+	id, err := strconv.ParseInt(req.Header.Get("X-Blazer-Request-ID"), 10, 64)
+	if err != nil {
+		return true
+	}
+	return o.log.Sampled(id)
 }
 
 func millitime(t int64) time.Time {
@@ -236,6 +307,10 @@ type b2Options struct {
 	capExceeded     bool
 	apiBase         string
 	userAgent       string
+	noCompression   bool
+	log             *blog.Logger
+	dumpDir         string
+	faults          func(method string) *Fault
 }
 
 func (o *b2Options) addHeaders(req *http.Request) {
@@ -272,26 +347,85 @@ func (o *b2Options) getTransport() http.RoundTripper {
 	return o.transport
 }
 
-// B2 holds account information for Backblaze.
+// B2 holds account information for Backblaze.  It is safe for concurrent
+// use; Update may be called while other calls are in flight.
 type B2 struct {
+	mu sync.RWMutex
+	s  session
+}
+
+// session is what a B2 learns from b2_authorize_account.  Calls take a copy
+// of it when they start, so that the token and URLs they use all come from
+// the same authorization, even if Update is called before they finish.
+type session struct {
 	accountID   string
 	authToken   string
 	apiURI      string
 	downloadURI string
 	minPartSize int
+	absPartSize int
 	opts        *b2Options
 	bucket      string // restricted to this bucket if present
 	pfx         string // restricted to objects with this prefix if present
+	caps        []string
+}
+
+func (b *B2) session() session {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.s
 }
 
-// Update replaces the B2 object with a new one, in-place.
+// Update replaces the B2 object with a new one, in-place.  Calls already in
+// flight continue with the old authorization.
 func (b *B2) Update(n *B2) {
-	b.accountID = n.accountID
-	b.authToken = n.authToken
-	b.apiURI = n.apiURI
-	b.downloadURI = n.downloadURI
-	b.minPartSize = n.minPartSize
-	b.opts = n.opts
+	s := n.session()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.s = s
+}
+
+// AccountInfo describes the authorization behind a B2.
+type AccountInfo struct {
+	AccountID    string
+	Capabilities []string
+	BucketID     string // the key is restricted to this bucket, if set
+	Prefix       string // and to objects with this prefix, if set
+	APIURL       string
+	DownloadURL  string
+
+	RecommendedPartSize     int
+	AbsoluteMinimumPartSize int
+}
+
+// AccountInfo returns the details of b's authorization, as reported by
+// b2_authorize_account.
+func (b *B2) AccountInfo() *AccountInfo {
+	s := b.session()
+	return &AccountInfo{
+		AccountID:    s.accountID,
+		Capabilities: append([]string(nil), s.caps...),
+		BucketID:     s.bucket,
+		Prefix:       s.pfx,
+		APIURL:       s.apiURI,
+		DownloadURL:  s.downloadURI,
+
+		RecommendedPartSize:     s.minPartSize,
+		AbsoluteMinimumPartSize: s.absPartSize,
+	}
+}
+
+// AccountID returns the ID of the account that b is authorized for.  Many
+// calls made with Call need it.
+func (b *B2) AccountID() string {
+	return b.session().accountID
+}
+
+// PartSizes returns the recommended and absolute minimum large file part
+// sizes, in bytes, as reported by B2 at authorization time.
+func (b *B2) PartSizes() (recommended, absoluteMinimum int) {
+	s := b.session()
+	return s.minPartSize, s.absPartSize
 }
 
 type httpReply struct {
@@ -299,9 +433,16 @@ type httpReply struct {
 	err  error
 }
 
-func makeNetRequest(ctx context.Context, req *http.Request, rt http.RoundTripper) (*http.Response, error) {
+func (o *b2Options) makeNetRequest(ctx context.Context, req *http.Request) (*http.Response, error) {
+	if o.faults != nil {
+		method := req.Header.Get("X-Blazer-Method")
+		if f := o.faults(method); f != nil {
+			o.log.V(2).Infof(">> %s uri: %v injected fault: %d %s", method, req.URL, f.Status, f.Code)
+			return nil, f.err(method)
+		}
+	}
 	req = req.WithContext(ctx)
-	resp, err := rt.RoundTrip(req)
+	resp, err := o.getTransport().RoundTrip(req)
 	switch err {
 	case nil:
 		return resp, nil
@@ -309,14 +450,15 @@ func makeNetRequest(ctx context.Context, req *http.Request, rt http.RoundTripper
 		return nil, err
 	default:
 		method := req.Header.Get("X-Blazer-Method")
-		blog.V(2).Infof(">> %s uri: %v err: %v", method, req.URL, err)
+		o.log.V(2).Infof(">> %s uri: %v err: %v", method, req.URL, err)
 		switch err.(type) {
 		case x509.UnknownAuthorityError:
 			return nil, err
 		}
 		return nil, b2err{
-			msg:   err.Error(),
-			retry: 1,
+			msg:    err.Error(),
+			method: method,
+			retry:  1,
 		}
 	}
 }
@@ -377,6 +519,30 @@ func (k *keepFinalBytes) Read(p []byte) (int, error) {
 
 var reqID int64
 
+type requestIDKey struct{}
+
+// WithRequestID returns a context that attaches the given correlation ID to
+// every request made with it.  The ID is sent in the X-Blazer-Correlation-ID
+// header and is included in request and response logs, so that client logs
+// can be joined with server or proxy logs.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the correlation ID attached to ctx by WithRequestID, or
+// the empty string if there is none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func setRequestID(ctx context.Context, req *http.Request) {
+	req.Header.Set("X-Blazer-Request-ID", fmt.Sprintf("%d", atomic.AddInt64(&reqID, 1)))
+	if id := RequestID(ctx); id != "" {
+		req.Header.Set("X-Blazer-Correlation-ID", id)
+	}
+}
+
 func (o *b2Options) makeRequest(ctx context.Context, method, verb, uri string, b2req, b2resp interface{}, headers map[string]string, body *requestBody) error {
 	var args []byte
 	if b2req != nil {
@@ -396,22 +562,32 @@ func (o *b2Options) makeRequest(ctx context.Context, method, verb, uri string, b
 	}
 	req.ContentLength = body.getSize()
 	for k, v := range headers {
-		if strings.HasPrefix(k, "X-Bz-Info") || strings.HasPrefix(k, "X-Bz-File-Name") {
-			v = escape(v)
-		}
-		req.Header.Set(k, v)
+		req.Header.Set(k, escapeHeader(k, v))
 	}
-	req.Header.Set("X-Blazer-Request-ID", fmt.Sprintf("%d", atomic.AddInt64(&reqID, 1)))
+	setRequestID(ctx, req)
 	req.Header.Set("X-Blazer-Method", method)
 	o.addHeaders(req)
-	logRequest(req, args)
-	resp, err := makeNetRequest(ctx, req, o.getTransport())
+	if !o.noCompression {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+	o.logRequest(req, args)
+	start := time.Now()
+	resp, err := o.makeNetRequest(ctx, req)
 	if err != nil {
+		o.dump(req, args, nil, nil, start, err)
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		resp.Body = gz
+	}
 	if resp.StatusCode != 200 {
-		return mkErr(resp)
+		return o.mkErr(resp, args, start)
 	}
 	var replyArgs []byte
 	if b2resp != nil {
@@ -425,11 +601,11 @@ func (o *b2Options) makeRequest(ctx context.Context, method, verb, uri string, b
 	} else {
 		ra, err := ioutil.ReadAll(resp.Body)
 		if err != nil {
-			blog.V(1).Infof("%s: couldn't read response: %v", method, err)
+			o.log.V(1).Infof("%s: couldn't read response: %v", method, err)
 		}
 		replyArgs = ra
 	}
-	logResponse(resp, replyArgs)
+	o.logResponse(resp, replyArgs)
 	return nil
 }
 
@@ -447,16 +623,50 @@ func AuthorizeAccount(ctx context.Context, account, key string, opts ...AuthOpti
 	if err := b2opts.makeRequest(ctx, "b2_authorize_account", "GET", b2opts.getAPIBase()+b2types.V1api+"b2_authorize_account", nil, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
-	return &B2{
+	return &B2{s: session{
 		accountID:   b2resp.AccountID,
 		authToken:   b2resp.AuthToken,
 		apiURI:      b2resp.URI,
 		downloadURI: b2resp.DownloadURI,
 		minPartSize: b2resp.PartSize,
+		absPartSize: b2resp.AbsMinPartSize,
 		bucket:      b2resp.Allowed.Bucket,
 		pfx:         b2resp.Allowed.Prefix,
+		caps:        b2resp.Allowed.Capabilities,
 		opts:        b2opts,
-	}, nil
+	}}, nil
+}
+
+// FromToken returns a B2 that makes its calls with an authorization token
+// obtained elsewhere, such as from a service that calls b2_authorize_account
+// on the caller's behalf.  apiURL and downloadURL are the URLs returned with
+// the token, and accountID the account it was issued for.  Nothing is sent
+// to B2, so the token is not checked until it is used.
+//
+// Only b2_authorize_account reports the recommended and minimum part sizes;
+// they are taken to be B2's usual 100MB and 5MB.  Nor are the capabilities
+// and restrictions of the key the token was issued for known.
+func FromToken(accountID, apiURL, downloadURL, token string, opts ...AuthOption) *B2 {
+	b2opts := &b2Options{}
+	for _, f := range opts {
+		f(b2opts)
+	}
+	return &B2{s: session{
+		accountID:   accountID,
+		authToken:   token,
+		apiURI:      apiURL,
+		downloadURI: downloadURL,
+		minPartSize: 1e8,
+		absPartSize: 5e6,
+		opts:        b2opts,
+	}}
+}
+
+// AuthToken returns the authorization token that b makes its calls with.  It
+// can be handed to programs that should not hold the application key; see
+// FromToken.
+func (b *B2) AuthToken() string {
+	return b.session().authToken
 }
 
 // An AuthOption allows callers to choose per-session settings.
@@ -506,6 +716,76 @@ func ForceCapExceeded() AuthOption {
 	}
 }
 
+// DisableCompression returns an AuthOption that prevents the client from
+// requesting gzip-compressed responses from the JSON API.  Compression is
+// enabled by default.
+func DisableCompression() AuthOption {
+	return func(o *b2Options) {
+		o.noCompression = true
+	}
+}
+
+// Logger returns an AuthOption that logs requests and responses with l,
+// instead of the default logger.
+func Logger(l *blog.Logger) AuthOption {
+	return func(o *b2Options) {
+		o.log = l
+	}
+}
+
+// DumpFailures returns an AuthOption that writes each request that fails with
+// an error that cannot be retried, and its response, to a file in dir.  The
+// exchange is redacted and truncated as it would be for logging.
+func DumpFailures(dir string) AuthOption {
+	return func(o *b2Options) {
+		o.dumpDir = dir
+	}
+}
+
+// A Fault is an error response that a session returns in place of making a
+// request, for testing.
+type Fault struct {
+	// Status is the HTTP status, and Code the B2 error code.  A zero Status
+	// fails the request as though the connection had been lost.
+	Status int
+	Code   string
+
+	// Message is the error message.
+	Message string
+
+	// RetryAfter is the wait B2 asks for, in seconds, as with the Retry-After
+	// header.
+	RetryAfter int
+}
+
+func (f *Fault) err(method string) error {
+	msg := f.Message
+	if msg == "" {
+		msg = "injected fault"
+	}
+	e := b2err{
+		msg:     msg,
+		method:  method,
+		retry:   f.RetryAfter,
+		code:    f.Status,
+		msgCode: f.Code,
+	}
+	if f.Status == 0 && e.retry == 0 {
+		e.retry = 1
+	}
+	return e
+}
+
+// InjectFaults returns an AuthOption that calls f with the name of the API
+// method, such as "b2_upload_file", before each request.  If f returns a
+// Fault, the request is not sent, and fails with an error that is handled
+// exactly as if B2 had returned it.
+func InjectFaults(f func(method string) *Fault) AuthOption {
+	return func(o *b2Options) {
+		o.faults = f
+	}
+}
+
 // SetAPIBase returns an AuthOption that uses the given URL as the base for API
 // requests.
 func SetAPIBase(url string) AuthOption {
@@ -515,26 +795,34 @@ func SetAPIBase(url string) AuthOption {
 }
 
 type LifecycleRule struct {
-	Prefix                 string
-	DaysNewUntilHidden     int
-	DaysHiddenUntilDeleted int
+	Prefix                      string
+	DaysNewUntilHidden          int
+	DaysHiddenUntilDeleted      int
+	DaysUnfinishedUntilCanceled int
+
+	// Extra holds the fields of the rule that B2 returned but this package
+	// does not know, so that rules that are read and written back keep them.
+	Extra map[string]json.RawMessage
 }
 
 // CreateBucket wraps b2_create_bucket.
 func (b *B2) CreateBucket(ctx context.Context, name, btype string, info map[string]string, rules []LifecycleRule) (*Bucket, error) {
-	if btype != "allPublic" {
+	ses := b.session()
+	if btype == "" {
 		btype = "allPrivate"
 	}
 	var b2rules []b2types.LifecycleRule
 	for _, rule := range rules {
 		b2rules = append(b2rules, b2types.LifecycleRule{
-			Prefix:                 rule.Prefix,
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
+			Prefix:                      rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			Extra:                       rule.Extra,
 		})
 	}
 	b2req := &b2types.CreateBucketRequest{
-		AccountID:      b.accountID,
+		AccountID:      ses.accountID,
 		Name:           name,
 		Type:           btype,
 		Info:           info,
@@ -542,24 +830,28 @@ func (b *B2) CreateBucket(ctx context.Context, name, btype string, info map[stri
 	}
 	b2resp := &b2types.CreateBucketResponse{}
 	headers := map[string]string{
-		"Authorization": b.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.opts.makeRequest(ctx, "b2_create_bucket", "POST", b.apiURI+b2types.V1api+"b2_create_bucket", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_create_bucket", "POST", ses.apiURI+b2types.V1api+"b2_create_bucket", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	var respRules []LifecycleRule
 	for _, rule := range b2resp.LifecycleRules {
 		respRules = append(respRules, LifecycleRule{
-			Prefix:                 rule.Prefix,
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
+			Prefix:                      rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			Extra:                       rule.Extra,
 		})
 	}
 	return &Bucket{
 		Name:           name,
+		Type:           b2resp.Type,
 		Info:           b2resp.Info,
 		LifecycleRules: respRules,
 		ID:             b2resp.BucketID,
+		Extra:          b2resp.Extra,
 		rev:            b2resp.Revision,
 		b2:             b,
 	}, nil
@@ -567,14 +859,15 @@ func (b *B2) CreateBucket(ctx context.Context, name, btype string, info map[stri
 
 // DeleteBucket wraps b2_delete_bucket.
 func (b *Bucket) DeleteBucket(ctx context.Context) error {
+	ses := b.b2.session()
 	b2req := &b2types.DeleteBucketRequest{
-		AccountID: b.b2.accountID,
+		AccountID: ses.accountID,
 		BucketID:  b.ID,
 	}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	return b.b2.opts.makeRequest(ctx, "b2_delete_bucket", "POST", b.b2.apiURI+b2types.V1api+"b2_delete_bucket", b2req, nil, headers, nil)
+	return ses.opts.makeRequest(ctx, "b2_delete_bucket", "POST", ses.apiURI+b2types.V1api+"b2_delete_bucket", b2req, nil, headers, nil)
 }
 
 // Bucket holds B2 bucket details.
@@ -584,42 +877,55 @@ type Bucket struct {
 	Info           map[string]string
 	LifecycleRules []LifecycleRule
 	ID             string
-	rev            int
-	b2             *B2
+
+	// Extra holds the fields of the bucket that B2 reported but this package
+	// does not know, such as settings B2 has added since.  Update sends back
+	// those that b2_update_bucket is known to accept; B2 leaves the rest as
+	// they are.
+	Extra map[string]json.RawMessage
+
+	rev int
+	b2  *B2
 }
 
 // Update wraps b2_update_bucket.
 func (b *Bucket) Update(ctx context.Context) (*Bucket, error) {
+	ses := b.b2.session()
 	var rules []b2types.LifecycleRule
 	for _, rule := range b.LifecycleRules {
 		rules = append(rules, b2types.LifecycleRule{
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
-			Prefix:                 rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			Extra:                       rule.Extra,
+			Prefix:                      rule.Prefix,
 		})
 	}
 	b2req := &b2types.UpdateBucketRequest{
-		AccountID: b.b2.accountID,
+		AccountID: ses.accountID,
 		BucketID:  b.ID,
 		// Name:           b.Name,
 		Type:           b.Type,
 		Info:           b.Info,
 		LifecycleRules: rules,
 		IfRevisionIs:   b.rev,
+		Extra:          settable(b.Extra),
 	}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
 	b2resp := &b2types.UpdateBucketResponse{}
-	if err := b.b2.opts.makeRequest(ctx, "b2_update_bucket", "POST", b.b2.apiURI+b2types.V1api+"b2_update_bucket", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_update_bucket", "POST", ses.apiURI+b2types.V1api+"b2_update_bucket", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	var respRules []LifecycleRule
 	for _, rule := range b2resp.LifecycleRules {
 		respRules = append(respRules, LifecycleRule{
-			Prefix:                 rule.Prefix,
-			DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-			DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
+			Prefix:                      rule.Prefix,
+			DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+			DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+			DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+			Extra:                       rule.Extra,
 		})
 	}
 	return &Bucket{
@@ -628,28 +934,72 @@ func (b *Bucket) Update(ctx context.Context) (*Bucket, error) {
 		Info:           b2resp.Info,
 		LifecycleRules: respRules,
 		ID:             b2resp.BucketID,
+		Extra:          b2resp.Extra,
 		b2:             b.b2,
 	}, nil
 }
 
+// updatableFields are the bucket fields, other than those Bucket has fields
+// for, that b2_update_bucket accepts in the form b2_list_buckets reports them.
+//
+// B2 leaves fields that are not sent unchanged.  fileLockConfiguration, in
+// particular, is not an update parameter: its parts are set with
+// fileLockEnabled and defaultRetention, which need the writeBucketRetentions
+// capability, and so it is not sent back.
+var updatableFields = map[string]bool{
+	"corsRules":                   true,
+	"defaultServerSideEncryption": true,
+	"replicationConfiguration":    true,
+}
+
+// settable returns the bucket fields in extra that can be sent back to B2.
+// B2 reports some settings wrapped in an object that says whether the key may
+// read them; these are unwrapped, or dropped if the key may not.  Fields that
+// b2_update_bucket does not accept are dropped too.
+func settable(extra map[string]json.RawMessage) map[string]json.RawMessage {
+	if len(extra) == 0 {
+		return nil
+	}
+	m := make(map[string]json.RawMessage)
+	for k, v := range extra {
+		if !updatableFields[k] {
+			continue
+		}
+		var wrapped struct {
+			Authorized *bool           `json:"isClientAuthorizedToRead"`
+			Value      json.RawMessage `json:"value"`
+		}
+		if json.Unmarshal(v, &wrapped) == nil && wrapped.Authorized != nil {
+			if !*wrapped.Authorized || wrapped.Value == nil {
+				continue
+			}
+			v = wrapped.Value
+		}
+		m[k] = v
+	}
+	return m
+}
+
 // BaseURL returns the base part of the download URLs.
 func (b *Bucket) BaseURL() string {
-	return b.b2.downloadURI
+	ses := b.b2.session()
+	return ses.downloadURI
 }
 
 // ListBuckets wraps b2_list_buckets.  If name is non-empty, only that bucket
 // will be returned if it exists; else nothing will be returned.
 func (b *B2) ListBuckets(ctx context.Context, name string) ([]*Bucket, error) {
+	ses := b.session()
 	b2req := &b2types.ListBucketsRequest{
-		AccountID: b.accountID,
-		Bucket:    b.bucket,
+		AccountID: ses.accountID,
+		Bucket:    ses.bucket,
 		Name:      name,
 	}
 	b2resp := &b2types.ListBucketsResponse{}
 	headers := map[string]string{
-		"Authorization": b.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.opts.makeRequest(ctx, "b2_list_buckets", "POST", b.apiURI+b2types.V1api+"b2_list_buckets", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_buckets", "POST", ses.apiURI+b2types.V1api+"b2_list_buckets", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	var buckets []*Bucket
@@ -657,9 +1007,11 @@ func (b *B2) ListBuckets(ctx context.Context, name string) ([]*Bucket, error) {
 		var rules []LifecycleRule
 		for _, rule := range bucket.LifecycleRules {
 			rules = append(rules, LifecycleRule{
-				Prefix:                 rule.Prefix,
-				DaysNewUntilHidden:     rule.DaysNewUntilHidden,
-				DaysHiddenUntilDeleted: rule.DaysHiddenUntilDeleted,
+				Prefix:                      rule.Prefix,
+				DaysNewUntilHidden:          rule.DaysNewUntilHidden,
+				DaysHiddenUntilDeleted:      rule.DaysHiddenUntilDeleted,
+				DaysUnfinishedUntilCanceled: rule.DaysUnfinishedUntilCanceled,
+				Extra:                       rule.Extra,
 			})
 		}
 		buckets = append(buckets, &Bucket{
@@ -668,6 +1020,7 @@ func (b *B2) ListBuckets(ctx context.Context, name string) ([]*Bucket, error) {
 			Info:           bucket.Info,
 			LifecycleRules: rules,
 			ID:             bucket.BucketID,
+			Extra:          bucket.Extra,
 			rev:            bucket.Revision,
 			b2:             b,
 		})
@@ -697,14 +1050,15 @@ func (url *URL) Reload(ctx context.Context) error {
 
 // GetUploadURL wraps b2_get_upload_url.
 func (b *Bucket) GetUploadURL(ctx context.Context) (*URL, error) {
+	ses := b.b2.session()
 	b2req := &b2types.GetUploadURLRequest{
 		BucketID: b.ID,
 	}
 	b2resp := &b2types.GetUploadURLResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_get_upload_url", "POST", b.b2.apiURI+b2types.V1api+"b2_get_upload_url", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_get_upload_url", "POST", ses.apiURI+b2types.V1api+"b2_get_upload_url", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &URL{
@@ -745,29 +1099,44 @@ func (url *URL) UploadFile(ctx context.Context, r io.Reader, size int, name, con
 		headers[fmt.Sprintf("X-Bz-Info-%s", k)] = v
 	}
 	b2resp := &b2types.UploadFileResponse{}
-	if err := url.b2.opts.makeRequest(ctx, "b2_upload_file", "POST", url.uri, nil, b2resp, headers, &requestBody{body: r, size: int64(size)}); err != nil {
+	if err := url.b2.session().opts.makeRequest(ctx, "b2_upload_file", "POST", url.uri, nil, b2resp, headers, &requestBody{body: r, size: int64(size)}); err != nil {
 		return nil, err
 	}
+	if sha1 == "hex_digits_at_end" {
+		// The trailing 40 bytes are the checksum, not the file.
+		size -= 40
+	}
 	return &File{
 		Name:      name,
 		Size:      int64(size),
 		Timestamp: millitime(b2resp.Timestamp),
 		Status:    b2resp.Action,
 		ID:        b2resp.FileID,
-		b2:        url.b2,
+		Info: (&FileInfo{
+			Name:        name,
+			SHA1:        b2resp.SHA1,
+			MD5:         b2resp.MD5,
+			Size:        int64(size),
+			ContentType: b2resp.ContentType,
+			Info:        b2resp.Info,
+			Status:      b2resp.Action,
+			Timestamp:   millitime(b2resp.Timestamp),
+		}).setProtection(b2resp.Retention, b2resp.LegalHold, b2resp.Encryption),
+		b2: url.b2,
 	}, nil
 }
 
 // DeleteFileVersion wraps b2_delete_file_version.
 func (f *File) DeleteFileVersion(ctx context.Context) error {
+	ses := f.b2.session()
 	b2req := &b2types.DeleteFileVersionRequest{
 		Name:   f.Name,
 		FileID: f.ID,
 	}
 	headers := map[string]string{
-		"Authorization": f.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	return f.b2.opts.makeRequest(ctx, "b2_delete_file_version", "POST", f.b2.apiURI+b2types.V1api+"b2_delete_file_version", b2req, nil, headers, nil)
+	return ses.opts.makeRequest(ctx, "b2_delete_file_version", "POST", ses.apiURI+b2types.V1api+"b2_delete_file_version", b2req, nil, headers, nil)
 }
 
 // LargeFile holds information necessary to implement B2 large file support.
@@ -782,6 +1151,7 @@ type LargeFile struct {
 
 // StartLargeFile wraps b2_start_large_file.
 func (b *Bucket) StartLargeFile(ctx context.Context, name, contentType string, info map[string]string) (*LargeFile, error) {
+	ses := b.b2.session()
 	b2req := &b2types.StartLargeFileRequest{
 		BucketID:    b.ID,
 		Name:        name,
@@ -790,9 +1160,9 @@ func (b *Bucket) StartLargeFile(ctx context.Context, name, contentType string, i
 	}
 	b2resp := &b2types.StartLargeFileResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_start_large_file", "POST", b.b2.apiURI+b2types.V1api+"b2_start_large_file", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_start_large_file", "POST", ses.apiURI+b2types.V1api+"b2_start_large_file", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &LargeFile{
@@ -804,24 +1174,27 @@ func (b *Bucket) StartLargeFile(ctx context.Context, name, contentType string, i
 
 // CancelLargeFile wraps b2_cancel_large_file.
 func (l *LargeFile) CancelLargeFile(ctx context.Context) error {
+	ses := l.b2.session()
 	b2req := &b2types.CancelLargeFileRequest{
 		ID: l.ID,
 	}
 	headers := map[string]string{
-		"Authorization": l.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	return l.b2.opts.makeRequest(ctx, "b2_cancel_large_file", "POST", l.b2.apiURI+b2types.V1api+"b2_cancel_large_file", b2req, nil, headers, nil)
+	return ses.opts.makeRequest(ctx, "b2_cancel_large_file", "POST", ses.apiURI+b2types.V1api+"b2_cancel_large_file", b2req, nil, headers, nil)
 }
 
 // FilePart is a piece of a started, but not finished, large file upload.
 type FilePart struct {
-	Number int
-	SHA1   string
-	Size   int64
+	Number    int
+	SHA1      string
+	Size      int64
+	Timestamp time.Time
 }
 
 // ListParts wraps b2_list_parts.
 func (f *File) ListParts(ctx context.Context, next, count int) ([]*FilePart, int, error) {
+	ses := f.b2.session()
 	b2req := &b2types.ListPartsRequest{
 		ID:    f.ID,
 		Start: next,
@@ -829,17 +1202,18 @@ func (f *File) ListParts(ctx context.Context, next, count int) ([]*FilePart, int
 	}
 	b2resp := &b2types.ListPartsResponse{}
 	headers := map[string]string{
-		"Authorization": f.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := f.b2.opts.makeRequest(ctx, "b2_list_parts", "POST", f.b2.apiURI+b2types.V1api+"b2_list_parts", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_parts", "POST", ses.apiURI+b2types.V1api+"b2_list_parts", b2req, b2resp, headers, nil); err != nil {
 		return nil, 0, err
 	}
 	var parts []*FilePart
 	for _, part := range b2resp.Parts {
 		parts = append(parts, &FilePart{
-			Number: part.Number,
-			SHA1:   part.SHA1,
-			Size:   part.Size,
+			Number:    part.Number,
+			SHA1:      part.SHA1,
+			Size:      part.Size,
+			Timestamp: millitime(part.Timestamp),
 		})
 	}
 	return parts, b2resp.Next, nil
@@ -868,25 +1242,17 @@ type FileChunk struct {
 	file  *LargeFile
 }
 
-type getUploadPartURLRequest struct {
-	ID string `json:"fileId"`
-}
-
-type getUploadPartURLResponse struct {
-	URL   string `json:"uploadUrl"`
-	Token string `json:"authorizationToken"`
-}
-
 // GetUploadPartURL wraps b2_get_upload_part_url.
 func (l *LargeFile) GetUploadPartURL(ctx context.Context) (*FileChunk, error) {
-	b2req := &getUploadPartURLRequest{
+	ses := l.b2.session()
+	b2req := &b2types.GetUploadPartURLRequest{
 		ID: l.ID,
 	}
-	b2resp := &getUploadPartURLResponse{}
+	b2resp := &b2types.GetUploadPartURLResponse{}
 	headers := map[string]string{
-		"Authorization": l.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := l.b2.opts.makeRequest(ctx, "b2_get_upload_part_url", "POST", l.b2.apiURI+b2types.V1api+"b2_get_upload_part_url", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_get_upload_part_url", "POST", ses.apiURI+b2types.V1api+"b2_get_upload_part_url", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &FileChunk{
@@ -918,7 +1284,7 @@ func (fc *FileChunk) UploadPart(ctx context.Context, r io.Reader, sha1 string, s
 	if sha1 == "hex_digits_at_end" {
 		r = &keepFinalBytes{r: r, remain: size}
 	}
-	if err := fc.file.b2.opts.makeRequest(ctx, "b2_upload_part", "POST", fc.url, nil, nil, headers, &requestBody{body: r, size: int64(size)}); err != nil {
+	if err := fc.file.b2.session().opts.makeRequest(ctx, "b2_upload_part", "POST", fc.url, nil, nil, headers, &requestBody{body: r, size: int64(size)}); err != nil {
 		return 0, err
 	}
 	fc.file.mu.Lock()
@@ -931,8 +1297,36 @@ func (fc *FileChunk) UploadPart(ctx context.Context, r io.Reader, sha1 string, s
 	return size, nil
 }
 
+// CopyPart wraps b2_copy_part.  It copies size bytes of the file with the
+// given ID, starting at offset, into part number index of the large file.  A
+// size of zero or less copies the whole of the source file.
+func (l *LargeFile) CopyPart(ctx context.Context, sourceID string, index int, offset, size int64) (int64, error) {
+	ses := l.b2.session()
+	b2req := &b2types.CopyPartRequest{
+		SourceID:    sourceID,
+		LargeFileID: l.ID,
+		Number:      index,
+	}
+	if size > 0 {
+		b2req.Range = fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)
+	}
+	b2resp := &b2types.CopyPartResponse{}
+	headers := map[string]string{
+		"Authorization": ses.authToken,
+	}
+	if err := ses.opts.makeRequest(ctx, "b2_copy_part", "POST", ses.apiURI+b2types.V1api+"b2_copy_part", b2req, b2resp, headers, nil); err != nil {
+		return 0, err
+	}
+	l.mu.Lock()
+	l.hashes[index] = b2resp.SHA1
+	l.size += b2resp.Size
+	l.mu.Unlock()
+	return b2resp.Size, nil
+}
+
 // FinishLargeFile wraps b2_finish_large_file.
 func (l *LargeFile) FinishLargeFile(ctx context.Context) (*File, error) {
+	ses := l.b2.session()
 	l.mu.Lock()
 	defer l.mu.Unlock()
 	b2req := &b2types.FinishLargeFileRequest{
@@ -947,9 +1341,9 @@ func (l *LargeFile) FinishLargeFile(ctx context.Context) (*File, error) {
 		b2req.Hashes[k-1] = v
 	}
 	headers := map[string]string{
-		"Authorization": l.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := l.b2.opts.makeRequest(ctx, "b2_finish_large_file", "POST", l.b2.apiURI+b2types.V1api+"b2_finish_large_file", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_finish_large_file", "POST", ses.apiURI+b2types.V1api+"b2_finish_large_file", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &File{
@@ -958,12 +1352,22 @@ func (l *LargeFile) FinishLargeFile(ctx context.Context) (*File, error) {
 		Timestamp: millitime(b2resp.Timestamp),
 		Status:    b2resp.Action,
 		ID:        b2resp.FileID,
-		b2:        l.b2,
+		Info: (&FileInfo{
+			Name:        b2resp.Name,
+			SHA1:        b2resp.SHA1,
+			Size:        l.size,
+			ContentType: b2resp.ContentType,
+			Info:        b2resp.Info,
+			Status:      b2resp.Action,
+			Timestamp:   millitime(b2resp.Timestamp),
+		}).setProtection(b2resp.Retention, b2resp.LegalHold, b2resp.Encryption),
+		b2: l.b2,
 	}, nil
 }
 
 // ListUnfinishedLargeFiles wraps b2_list_unfinished_large_files.
 func (b *Bucket) ListUnfinishedLargeFiles(ctx context.Context, count int, continuation string) ([]*File, string, error) {
+	ses := b.b2.session()
 	b2req := &b2types.ListUnfinishedLargeFilesRequest{
 		BucketID:     b.ID,
 		Continuation: continuation,
@@ -971,9 +1375,9 @@ func (b *Bucket) ListUnfinishedLargeFiles(ctx context.Context, count int, contin
 	}
 	b2resp := &b2types.ListUnfinishedLargeFilesResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_list_unfinished_large_files", "POST", b.b2.apiURI+b2types.V1api+"b2_list_unfinished_large_files", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_unfinished_large_files", "POST", ses.apiURI+b2types.V1api+"b2_list_unfinished_large_files", b2req, b2resp, headers, nil); err != nil {
 		return nil, "", err
 	}
 	cont := b2resp.Continuation
@@ -997,8 +1401,9 @@ func (b *Bucket) ListUnfinishedLargeFiles(ctx context.Context, count int, contin
 
 // ListFileNames wraps b2_list_file_names.
 func (b *Bucket) ListFileNames(ctx context.Context, count int, continuation, prefix, delimiter string) ([]*File, string, error) {
+	ses := b.b2.session()
 	if prefix == "" {
-		prefix = b.b2.pfx
+		prefix = ses.pfx
 	}
 	b2req := &b2types.ListFileNamesRequest{
 		Count:        count,
@@ -1009,9 +1414,9 @@ func (b *Bucket) ListFileNames(ctx context.Context, count int, continuation, pre
 	}
 	b2resp := &b2types.ListFileNamesResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_list_file_names", "POST", b.b2.apiURI+b2types.V1api+"b2_list_file_names", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_file_names", "POST", ses.apiURI+b2types.V1api+"b2_list_file_names", b2req, b2resp, headers, nil); err != nil {
 		return nil, "", err
 	}
 	cont := b2resp.Continuation
@@ -1022,7 +1427,7 @@ func (b *Bucket) ListFileNames(ctx context.Context, count int, continuation, pre
 			Size:      f.Size,
 			Status:    f.Action,
 			Timestamp: millitime(f.Timestamp),
-			Info: &FileInfo{
+			Info: (&FileInfo{
 				Name:        f.Name,
 				SHA1:        f.SHA1,
 				MD5:         f.MD5,
@@ -1031,7 +1436,7 @@ func (b *Bucket) ListFileNames(ctx context.Context, count int, continuation, pre
 				Info:        f.Info,
 				Status:      f.Action,
 				Timestamp:   millitime(f.Timestamp),
-			},
+			}).setProtection(f.Retention, f.LegalHold, f.Encryption),
 			ID: f.FileID,
 			b2: b.b2,
 		})
@@ -1041,8 +1446,9 @@ func (b *Bucket) ListFileNames(ctx context.Context, count int, continuation, pre
 
 // ListFileVersions wraps b2_list_file_versions.
 func (b *Bucket) ListFileVersions(ctx context.Context, count int, startName, startID, prefix, delimiter string) ([]*File, string, string, error) {
+	ses := b.b2.session()
 	if prefix == "" {
-		prefix = b.b2.pfx
+		prefix = ses.pfx
 	}
 	b2req := &b2types.ListFileVersionsRequest{
 		BucketID:  b.ID,
@@ -1054,9 +1460,9 @@ func (b *Bucket) ListFileVersions(ctx context.Context, count int, startName, sta
 	}
 	b2resp := &b2types.ListFileVersionsResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_list_file_versions", "POST", b.b2.apiURI+b2types.V1api+"b2_list_file_versions", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_file_versions", "POST", ses.apiURI+b2types.V1api+"b2_list_file_versions", b2req, b2resp, headers, nil); err != nil {
 		return nil, "", "", err
 	}
 	var files []*File
@@ -1066,7 +1472,7 @@ func (b *Bucket) ListFileVersions(ctx context.Context, count int, startName, sta
 			Size:      f.Size,
 			Status:    f.Action,
 			Timestamp: millitime(f.Timestamp),
-			Info: &FileInfo{
+			Info: (&FileInfo{
 				Name:        f.Name,
 				SHA1:        f.SHA1,
 				MD5:         f.MD5,
@@ -1075,7 +1481,7 @@ func (b *Bucket) ListFileVersions(ctx context.Context, count int, startName, sta
 				Info:        f.Info,
 				Status:      f.Action,
 				Timestamp:   millitime(f.Timestamp),
-			},
+			}).setProtection(f.Retention, f.LegalHold, f.Encryption),
 			ID: f.FileID,
 			b2: b.b2,
 		})
@@ -1085,6 +1491,7 @@ func (b *Bucket) ListFileVersions(ctx context.Context, count int, startName, sta
 
 // GetDownloadAuthorization wraps b2_get_download_authorization.
 func (b *Bucket) GetDownloadAuthorization(ctx context.Context, prefix string, valid time.Duration, contentDisposition string) (string, error) {
+	ses := b.b2.session()
 	b2req := &b2types.GetDownloadAuthorizationRequest{
 		BucketID:           b.ID,
 		Prefix:             prefix,
@@ -1093,9 +1500,9 @@ func (b *Bucket) GetDownloadAuthorization(ctx context.Context, prefix string, va
 	}
 	b2resp := &b2types.GetDownloadAuthorizationResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_get_download_authorization", "POST", b.b2.apiURI+b2types.V1api+"b2_get_download_authorization", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_get_download_authorization", "POST", ses.apiURI+b2types.V1api+"b2_get_download_authorization", b2req, b2resp, headers, nil); err != nil {
 		return "", err
 	}
 	return b2resp.Token, nil
@@ -1123,34 +1530,59 @@ func mkRange(offset, size int64) string {
 
 // DownloadFileByName wraps b2_download_file_by_name.
 func (b *Bucket) DownloadFileByName(ctx context.Context, name string, offset, size int64, header bool) (*FileReader, error) {
-	uri := fmt.Sprintf("%s/file/%s/%s", b.b2.downloadURI, b.Name, escape(name))
+	return b.b2.downloadFile(ctx, "b2_download_file_by_name", downloadPath(b.Name, name), "", offset, size, header)
+}
+
+// DownloadFileByNameWithToken wraps b2_download_file_by_name, authorized with
+// token, such as one from GetDownloadAuthorization, instead of the account's
+// own token.
+func (b *Bucket) DownloadFileByNameWithToken(ctx context.Context, name, token string, offset, size int64, header bool) (*FileReader, error) {
+	return b.b2.downloadFile(ctx, "b2_download_file_by_name", downloadPath(b.Name, name), token, offset, size, header)
+}
+
+// DownloadFileByID wraps b2_download_file_by_id.
+func (f *File) DownloadFileByID(ctx context.Context, offset, size int64, header bool) (*FileReader, error) {
+	path := b2types.V1api + "b2_download_file_by_id?fileId=" + url.QueryEscape(f.ID)
+	return f.b2.downloadFile(ctx, "b2_download_file_by_id", path, "", offset, size, header)
+}
+
+// downloadFile downloads from path on the download URL, authorized with token,
+// or if token is empty with the account's token.  The URL and the account's
+// token are taken together, so that they always belong to one authorization.
+func (b *B2) downloadFile(ctx context.Context, apiMethod, path, token string, offset, size int64, header bool) (*FileReader, error) {
+	ses := b.session()
+	if token == "" {
+		token = ses.authToken
+	}
 	method := "GET"
 	if header {
 		method = "HEAD"
 	}
-	req, err := http.NewRequest(method, uri, nil)
+	req, err := http.NewRequest(method, ses.downloadURI+path, nil)
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", b.b2.authToken)
-	req.Header.Set("X-Blazer-Request-ID", fmt.Sprintf("%d", atomic.AddInt64(&reqID, 1)))
-	req.Header.Set("X-Blazer-Method", "b2_download_file_by_name")
-	b.b2.opts.addHeaders(req)
+	req.Header.Set("Authorization", token)
+	setRequestID(ctx, req)
+	req.Header.Set("X-Blazer-Method", apiMethod)
+	ses.opts.addHeaders(req)
 	rng := mkRange(offset, size)
 	if rng != "" {
 		req.Header.Set("Range", rng)
 	}
-	logRequest(req, nil)
-	resp, err := makeNetRequest(ctx, req, b.b2.opts.getTransport())
+	ses.opts.logRequest(req, nil)
+	start := time.Now()
+	resp, err := ses.opts.makeNetRequest(ctx, req)
 	if err != nil {
+		ses.opts.dump(req, nil, nil, nil, start, err)
 		return nil, err
 	}
-	logResponse(resp, nil)
+	ses.opts.logResponse(resp, nil)
 	if resp.StatusCode != 200 && resp.StatusCode != 206 {
 		defer resp.Body.Close()
-		return nil, mkErr(resp)
+		return nil, ses.opts.mkErr(resp, nil, start)
 	}
-	clen, err := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	clen, err := contentLength(resp)
 	if err != nil {
 		resp.Body.Close()
 		return nil, err
@@ -1165,6 +1597,7 @@ func (b *Bucket) DownloadFileByName(ctx context.Context, name string, offset, si
 			resp.Body.Close()
 			return nil, err
 		}
+		name = infoKey(name)
 		val, err := unescape(resp.Header.Get(key))
 		if err != nil {
 			resp.Body.Close()
@@ -1173,8 +1606,8 @@ func (b *Bucket) DownloadFileByName(ctx context.Context, name string, offset, si
 		info[name] = val
 	}
 	sha1 := resp.Header.Get("X-Bz-Content-Sha1")
-	if sha1 == "none" && info["Large_file_sha1"] != "" {
-		sha1 = info["Large_file_sha1"]
+	if sha1 == "none" && info["large_file_sha1"] != "" {
+		sha1 = info["large_file_sha1"]
 	}
 	return &FileReader{
 		ReadCloser:    resp.Body,
@@ -1186,17 +1619,53 @@ func (b *Bucket) DownloadFileByName(ctx context.Context, name string, offset, si
 	}, nil
 }
 
+// wellKnownInfo are the info keys that B2 and blazer give meaning to, in the
+// case in which they are stored.
+var wellKnownInfo = []string{
+	"src_last_modified_millis",
+	"large_file_sha1",
+	"b2-content-disposition",
+	"b2-cache-control",
+	"b2-expires",
+}
+
+// infoKey returns the info key for the name of an X-Bz-Info- header.  net/http
+// canonicalizes header names, so the well-known keys are restored to the case
+// in which they are stored; other keys are returned as they arrived.
+func infoKey(name string) string {
+	for _, k := range wellKnownInfo {
+		if strings.EqualFold(name, k) {
+			return k
+		}
+	}
+	return name
+}
+
+// contentLength returns the length of the response body.  Responses with empty
+// bodies, such as downloads of zero-byte files, need not have a Content-Length
+// header.
+func contentLength(resp *http.Response) (int64, error) {
+	if v := resp.Header.Get("Content-Length"); v != "" {
+		return strconv.ParseInt(v, 10, 64)
+	}
+	if resp.ContentLength > 0 {
+		return resp.ContentLength, nil
+	}
+	return 0, nil
+}
+
 // HideFile wraps b2_hide_file.
 func (b *Bucket) HideFile(ctx context.Context, name string) (*File, error) {
+	ses := b.b2.session()
 	b2req := &b2types.HideFileRequest{
 		BucketID: b.ID,
 		File:     name,
 	}
 	b2resp := &b2types.HideFileResponse{}
 	headers := map[string]string{
-		"Authorization": b.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.b2.opts.makeRequest(ctx, "b2_hide_file", "POST", b.b2.apiURI+b2types.V1api+"b2_hide_file", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_hide_file", "POST", ses.apiURI+b2types.V1api+"b2_hide_file", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &File{
@@ -1218,24 +1687,52 @@ type FileInfo struct {
 	Info        map[string]string
 	Status      string
 	Timestamp   time.Time
+
+	// These are empty if the file has no such setting, or if the current key
+	// is not allowed to read it.
+	RetentionMode string
+	RetainUntil   time.Time
+	LegalHold     string
+	SSEMode       string
+	SSEAlgorithm  string
+}
+
+// setProtection records the retention, legal hold, and encryption settings
+// returned by B2.
+func (fi *FileInfo) setProtection(r *b2types.FileRetention, h *b2types.LegalHold, e *b2types.ServerSideEncryption) *FileInfo {
+	if r != nil && r.Value != nil {
+		fi.RetentionMode = r.Value.Mode
+		if r.Value.Until != 0 {
+			fi.RetainUntil = millitime(r.Value.Until)
+		}
+	}
+	if h != nil && h.Value != nil {
+		fi.LegalHold = *h.Value
+	}
+	if e != nil {
+		fi.SSEMode = e.Mode
+		fi.SSEAlgorithm = e.Algorithm
+	}
+	return fi
 }
 
 // GetFileInfo wraps b2_get_file_info.
 func (f *File) GetFileInfo(ctx context.Context) (*FileInfo, error) {
+	ses := f.b2.session()
 	b2req := &b2types.GetFileInfoRequest{
 		ID: f.ID,
 	}
 	b2resp := &b2types.GetFileInfoResponse{}
 	headers := map[string]string{
-		"Authorization": f.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := f.b2.opts.makeRequest(ctx, "b2_get_file_info", "POST", f.b2.apiURI+b2types.V1api+"b2_get_file_info", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_get_file_info", "POST", ses.apiURI+b2types.V1api+"b2_get_file_info", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	f.Status = b2resp.Action
 	f.Name = b2resp.Name
 	f.Timestamp = millitime(b2resp.Timestamp)
-	f.Info = &FileInfo{
+	f.Info = (&FileInfo{
 		Name:        b2resp.Name,
 		SHA1:        b2resp.SHA1,
 		MD5:         b2resp.MD5,
@@ -1244,10 +1741,71 @@ func (f *File) GetFileInfo(ctx context.Context) (*FileInfo, error) {
 		Info:        b2resp.Info,
 		Status:      b2resp.Action,
 		Timestamp:   millitime(b2resp.Timestamp),
-	}
+	}).setProtection(b2resp.Retention, b2resp.LegalHold, b2resp.Encryption)
 	return f.Info, nil
 }
 
+// CopyFile wraps b2_copy_file.  It copies the file, with its metadata, to a
+// new file with the given name in the same bucket.
+func (f *File) CopyFile(ctx context.Context, name string) (*File, error) {
+	return f.CopyRange(ctx, name, 0, -1)
+}
+
+// CopyRange wraps b2_copy_file.  It copies size bytes of the file, starting
+// at offset, along with its metadata, to a new file with the given name in
+// the same bucket.  A size of zero or less copies the whole file.
+func (f *File) CopyRange(ctx context.Context, name string, offset, size int64) (*File, error) {
+	return f.copyRange(ctx, &b2types.CopyFileRequest{
+		SourceID:          f.ID,
+		Name:              name,
+		MetadataDirective: "COPY",
+	}, offset, size)
+}
+
+// CopyRangeWithInfo is CopyRange, but gives the new file contentType and info
+// instead of the metadata of the file it is copied from.
+func (f *File) CopyRangeWithInfo(ctx context.Context, name string, offset, size int64, contentType string, info map[string]string) (*File, error) {
+	return f.copyRange(ctx, &b2types.CopyFileRequest{
+		SourceID:          f.ID,
+		Name:              name,
+		MetadataDirective: "REPLACE",
+		ContentType:       contentType,
+		Info:              info,
+	}, offset, size)
+}
+
+func (f *File) copyRange(ctx context.Context, b2req *b2types.CopyFileRequest, offset, size int64) (*File, error) {
+	ses := f.b2.session()
+	if size > 0 {
+		b2req.Range = fmt.Sprintf("bytes=%d-%d", offset, offset+size-1)
+	}
+	b2resp := &b2types.CopyFileResponse{}
+	headers := map[string]string{
+		"Authorization": ses.authToken,
+	}
+	if err := ses.opts.makeRequest(ctx, "b2_copy_file", "POST", ses.apiURI+b2types.V1api+"b2_copy_file", b2req, b2resp, headers, nil); err != nil {
+		return nil, err
+	}
+	return &File{
+		Name:      b2resp.Name,
+		Size:      b2resp.Size,
+		Timestamp: millitime(b2resp.Timestamp),
+		Status:    b2resp.Action,
+		ID:        b2resp.FileID,
+		Info: (&FileInfo{
+			Name:        b2resp.Name,
+			SHA1:        b2resp.SHA1,
+			MD5:         b2resp.MD5,
+			Size:        b2resp.Size,
+			ContentType: b2resp.ContentType,
+			Info:        b2resp.Info,
+			Status:      b2resp.Action,
+			Timestamp:   millitime(b2resp.Timestamp),
+		}).setProtection(b2resp.Retention, b2resp.LegalHold, b2resp.Encryption),
+		b2: f.b2,
+	}, nil
+}
+
 // Key is a B2 application key.
 type Key struct {
 	ID           string
@@ -1255,13 +1813,16 @@ type Key struct {
 	Name         string
 	Capabilities []string
 	Expires      time.Time
+	BucketID     string // the key is restricted to this bucket, if set
+	Prefix       string // and to objects with this prefix, if set
 	b2           *B2
 }
 
 // CreateKey wraps b2_create_key.
 func (b *B2) CreateKey(ctx context.Context, name string, caps []string, valid time.Duration, bucketID string, prefix string) (*Key, error) {
+	ses := b.session()
 	b2req := &b2types.CreateKeyRequest{
-		AccountID:    b.accountID,
+		AccountID:    ses.accountID,
 		Capabilities: caps,
 		Name:         name,
 		Valid:        int(valid.Seconds()),
@@ -1270,9 +1831,9 @@ func (b *B2) CreateKey(ctx context.Context, name string, caps []string, valid ti
 	}
 	b2resp := &b2types.CreateKeyResponse{}
 	headers := map[string]string{
-		"Authorization": b.authToken,
+		"Authorization": ses.authToken,
 	}
-	if err := b.opts.makeRequest(ctx, "b2_create_key", "POST", b.apiURI+b2types.V1api+"b2_create_key", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_create_key", "POST", ses.apiURI+b2types.V1api+"b2_create_key", b2req, b2resp, headers, nil); err != nil {
 		return nil, err
 	}
 	return &Key{
@@ -1281,33 +1842,37 @@ func (b *B2) CreateKey(ctx context.Context, name string, caps []string, valid ti
 		Secret:       b2resp.Secret,
 		Capabilities: b2resp.Capabilities,
 		Expires:      millitime(b2resp.Expires),
+		BucketID:     b2resp.BucketID,
+		Prefix:       b2resp.Prefix,
 		b2:           b,
 	}, nil
 }
 
 // Delete wraps b2_delete_key.
 func (k *Key) Delete(ctx context.Context) error {
+	ses := k.b2.session()
 	b2req := &b2types.DeleteKeyRequest{
 		KeyID: k.ID,
 	}
 	headers := map[string]string{
-		"Authorization": k.b2.authToken,
+		"Authorization": ses.authToken,
 	}
-	return k.b2.opts.makeRequest(ctx, "b2_delete_key", "POST", k.b2.apiURI+b2types.V1api+"b2_delete_key", b2req, nil, headers, nil)
+	return ses.opts.makeRequest(ctx, "b2_delete_key", "POST", ses.apiURI+b2types.V1api+"b2_delete_key", b2req, nil, headers, nil)
 }
 
 // ListKeys wraps b2_list_keys.
 func (b *B2) ListKeys(ctx context.Context, max int, next string) ([]*Key, string, error) {
+	ses := b.session()
 	b2req := &b2types.ListKeysRequest{
-		AccountID: b.accountID,
+		AccountID: ses.accountID,
 		Max:       max,
 		Next:      next,
 	}
 	headers := map[string]string{
-		"Authorization": b.authToken,
+		"Authorization": ses.authToken,
 	}
 	b2resp := &b2types.ListKeysResponse{}
-	if err := b.opts.makeRequest(ctx, "b2_list_keys", "POST", b.apiURI+b2types.V1api+"b2_list_keys", b2req, b2resp, headers, nil); err != nil {
+	if err := ses.opts.makeRequest(ctx, "b2_list_keys", "POST", ses.apiURI+b2types.V1api+"b2_list_keys", b2req, b2resp, headers, nil); err != nil {
 		return nil, "", err
 	}
 	var keys []*Key
@@ -1317,8 +1882,28 @@ func (b *B2) ListKeys(ctx context.Context, max int, next string) ([]*Key, string
 			ID:           key.ID,
 			Capabilities: key.Capabilities,
 			Expires:      millitime(key.Expires),
+			BucketID:     key.BucketID,
+			Prefix:       key.Prefix,
 			b2:           b,
 		})
 	}
 	return keys, b2resp.Next, nil
 }
+
+// Call makes an arbitrary B2 API call, such as one that this package does not
+// yet wrap.  The request, if any, is sent as JSON, and the reply is decoded
+// into resp, if it is not nil.  Errors may be inspected with Action, Code,
+// and the other functions in this package, as for any other call.
+func (b *B2) Call(ctx context.Context, apiName string, req, resp interface{}) error {
+	ses := b.session()
+	if apiName == "" || strings.ContainsAny(apiName, "/?#% ") {
+		return fmt.Errorf("base: invalid API name %q", apiName)
+	}
+	if req == nil {
+		req = struct{}{}
+	}
+	headers := map[string]string{
+		"Authorization": ses.authToken,
+	}
+	return ses.opts.makeRequest(ctx, apiName, "POST", ses.apiURI+b2types.V1api+apiName, req, resp, headers, nil)
+}
@@ -0,0 +1,326 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func FuzzMkErr(f *testing.F) {
+	f.Add(400, `{"status": 400, "code": "bad_request", "message": "no"}`, "", "b2_list_buckets")
+	f.Add(503, `{"status": 503, "code": "service_unavailable", "message": "busy"}`, "5", "b2_upload_file")
+	f.Add(403, `{"code": "cap_exceeded"}`, "-1", "")
+	f.Add(500, `<html>oops</html>`, "soon", "b2_upload_part")
+	f.Add(401, ``, "99999999999999999999", "b2_get_upload_url")
+	f.Fuzz(func(t *testing.T, status int, body, retry, method string) {
+		req := &http.Request{Header: make(http.Header)}
+		req.Header.Set("X-Blazer-Method", method)
+		resp := &http.Response{
+			StatusCode: status,
+			Header:     make(http.Header),
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}
+		if retry != "" {
+			resp.Header.Set("Retry-After", retry)
+		}
+		o := &b2Options{}
+		err := o.mkErr(resp, nil, time.Now())
+		if err == nil {
+			t.Fatal("mkErr returned nil")
+		}
+		_ = err.Error()
+		if code, _ := Code(err); code != status {
+			t.Errorf("Code: got %d, want %d", code, status)
+		}
+		Backoff(err)
+		Action(err)
+	})
+}
+
+type resetTransport struct{}
+
+func (resetTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, syscall.ECONNRESET
+}
+
+func TestUploadActions(t *testing.T) {
+	table := []struct {
+		err  b2err
+		want ErrAction
+	}{
+		{err: b2err{method: "b2_upload_file", code: 408}, want: AttemptNewUpload},
+		{err: b2err{method: "b2_upload_part", code: 408, retry: 5}, want: AttemptNewUpload},
+		{err: b2err{method: "b2_upload_part", code: 503}, want: AttemptNewUpload},
+		{err: b2err{method: "b2_upload_file", code: 503, retry: 5}, want: Retry},
+		{err: b2err{method: "b2_upload_file", code: 0, retry: 1}, want: AttemptNewUpload},
+		{err: b2err{method: "b2_list_buckets", code: 0, retry: 1}, want: Retry},
+		{err: b2err{code: 0, retry: 1}, want: Retry},
+	}
+	for _, e := range table {
+		if got := Action(e.err); got != e.want {
+			t.Errorf("Action(%+v): got %v, want %v", e.err, got, e.want)
+		}
+	}
+
+	// Connections that fail are attributed to the call that made them.
+	o := &b2Options{transport: resetTransport{}}
+	req, err := http.NewRequest("POST", "http://upload.invalid/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, want := range map[string]ErrAction{
+		"b2_upload_file":  AttemptNewUpload,
+		"b2_upload_part":  AttemptNewUpload,
+		"b2_list_buckets": Retry,
+	} {
+		req.Header.Set("X-Blazer-Method", method)
+		_, err := o.makeNetRequest(context.Background(), req)
+		if got := Action(err); got != want {
+			t.Errorf("%s: connection reset: got %v, want %v", method, got, want)
+		}
+		if !strings.Contains(err.Error(), "connection reset") {
+			t.Errorf("%s: got error %q", method, err)
+		}
+	}
+}
+
+func TestInjectFaults(t *testing.T) {
+	var calls []string
+	o := &b2Options{
+		transport: resetTransport{},
+		faults: func(method string) *Fault {
+			calls = append(calls, method)
+			switch method {
+			case "b2_upload_file":
+				return &Fault{Status: 503, Code: "service_unavailable"}
+			case "b2_list_buckets":
+				return &Fault{Status: 401, Code: "expired_auth_token"}
+			case "b2_get_file_info":
+				return &Fault{Status: 503, RetryAfter: 7}
+			}
+			return nil
+		},
+	}
+	for method, want := range map[string]ErrAction{
+		"b2_upload_file":   AttemptNewUpload,
+		"b2_list_buckets":  ReAuthenticate,
+		"b2_get_file_info": Retry,
+		"b2_hide_file":     Retry, // not injected; the connection resets
+	} {
+		req, err := http.NewRequest("POST", "http://api.invalid/", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("X-Blazer-Method", method)
+		_, err = o.makeNetRequest(context.Background(), req)
+		if got := Action(err); got != want {
+			t.Errorf("%s: got %v, want %v", method, got, want)
+		}
+		if got := Method(err); got != method {
+			t.Errorf("%s: got method %q", method, got)
+		}
+	}
+	if len(calls) != 4 {
+		t.Errorf("got %d calls to the fault function, want 4", len(calls))
+	}
+	err := (&Fault{Status: 503, RetryAfter: 7}).err("b2_get_file_info")
+	if got := Backoff(err); got != 7*time.Second {
+		t.Errorf("Backoff: got %v, want 7s", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	table := []struct {
+		v    string
+		want int
+		err  bool
+	}{
+		{v: "0", want: 0},
+		{v: "5", want: 5},
+		{v: " 30 ", want: 30},
+		{v: "-1", err: true},
+		{v: "9999999999999", want: maxRetryAfter},
+		{v: "Fri, 01 Jun 2018 12:00:10 GMT", want: 10},
+		{v: "Friday, 01-Jun-18 12:01:00 GMT", want: 60},
+		{v: "Fri Jun  1 12:00:03 2018", want: 3},
+		{v: "Fri, 01 Jun 2018 11:59:00 GMT", want: 0},
+		{v: "Sat, 01 Jun 2019 12:00:00 GMT", want: maxRetryAfter},
+		{v: "soon", err: true},
+	}
+	for _, e := range table {
+		got, err := parseRetryAfter(e.v, now)
+		if (err != nil) != e.err || got != e.want {
+			t.Errorf("parseRetryAfter(%q): got %d, %v; want %d, err %v", e.v, got, err, e.want, e.err)
+		}
+	}
+}
+
+func TestRetryAfterDate(t *testing.T) {
+	resp := &http.Response{
+		StatusCode: 503,
+		Header:     http.Header{"Retry-After": {time.Now().Add(90 * time.Second).UTC().Format(http.TimeFormat)}},
+		Body:       ioutil.NopCloser(strings.NewReader(`{"code": "service_unavailable"}`)),
+		Request:    &http.Request{Header: http.Header{}},
+	}
+	err := (&b2Options{}).mkErr(resp, nil, time.Now())
+	if d := Backoff(err); d < 85*time.Second || d > 91*time.Second {
+		t.Errorf("Backoff: got %v, want about 90s", d)
+	}
+	if Action(err) != Retry {
+		t.Errorf("Action: got %v, want Retry", Action(err))
+	}
+}
+
+func TestCall(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v1/b2_authorize_account":
+			fmt.Fprintf(w, `{"accountId": "acct", "authorizationToken": "token", "apiUrl": %q}`, srv.URL)
+		case "/b2api/v1/b2_new_thing":
+			var req map[string]string
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || r.Header.Get("Authorization") != "token" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprintf(w, `{"echo": %q}`, req["accountId"])
+		case "/b2api/v1/b2_busy_thing":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `{"status": 503, "code": "service_unavailable", "message": "busy"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	b, err := AuthorizeAccount(ctx, "acct", "key", SetAPIBase(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Echo string `json:"echo"`
+	}
+	if err := b.Call(ctx, "b2_new_thing", map[string]string{"accountId": b.AccountID()}, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Echo != "acct" {
+		t.Errorf("b2_new_thing: got %q, want acct", resp.Echo)
+	}
+
+	err = b.Call(ctx, "b2_busy_thing", nil, nil)
+	if Action(err) != Retry {
+		t.Errorf("b2_busy_thing: got %v (%v), want Retry", err, Action(err))
+	}
+	if code, msgCode, _ := MsgCode(err); code != 503 || msgCode != "service_unavailable" {
+		t.Errorf("b2_busy_thing: got %d %q", code, msgCode)
+	}
+	if !strings.HasPrefix(err.Error(), "b2_busy_thing: 503") {
+		t.Errorf("b2_busy_thing: got error %q", err)
+	}
+
+	if err := b.Call(ctx, "../b2_new_thing", nil, nil); err == nil {
+		t.Error("Call with a path: got no error")
+	}
+}
+
+func TestBucketExtra(t *testing.T) {
+	var sent map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/b2api/v1/b2_list_buckets":
+			fmt.Fprint(w, `{"buckets": [{
+				"bucketId": "id", "bucketName": "name", "bucketType": "allPrivate", "revision": 3,
+				"corsRules": [{"corsRuleName": "all"}],
+				"defaultServerSideEncryption": {"isClientAuthorizedToRead": true, "value": {"mode": "SSE-B2"}},
+				"replicationConfiguration": {"isClientAuthorizedToRead": false, "value": null},
+				"fileLockConfiguration": {"isClientAuthorizedToRead": true, "value": {"isFileLockEnabled": true, "defaultRetention": {"mode": null}}},
+				"options": ["s3"]
+			}]}`)
+		case "/b2api/v1/b2_update_bucket":
+			if err := json.NewDecoder(r.Body).Decode(&sent); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"bucketId": "id", "bucketName": "name", "bucketType": "allPublic", "revision": 4, "newSetting": 1}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	b := FromToken("acct", srv.URL, srv.URL, "token")
+	buckets, err := b.ListBuckets(ctx, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := buckets[0]
+	if len(bucket.Extra) != 5 {
+		t.Errorf("Extra: got %d fields, want 5", len(bucket.Extra))
+	}
+	bucket.Type = "allPublic"
+	nb, err := bucket.Update(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"accountId":                   `"acct"`,
+		"bucketId":                    `"id"`,
+		"bucketType":                  `"allPublic"`,
+		"ifRevisionIs":                `3`,
+		"corsRules":                   `[{"corsRuleName":"all"}]`,
+		"defaultServerSideEncryption": `{"mode":"SSE-B2"}`,
+	}
+	if len(sent) != len(want) {
+		t.Errorf("b2_update_bucket: sent %d fields, want %d", len(sent), len(want))
+	}
+	for k, v := range want {
+		if got := string(sent[k]); got != v {
+			t.Errorf("b2_update_bucket: sent %s: %s, want %s", k, got, v)
+		}
+	}
+	if string(nb.Extra["newSetting"]) != "1" {
+		t.Errorf("updated bucket: got Extra %q, want newSetting", nb.Extra)
+	}
+}
+
+func TestInfoKey(t *testing.T) {
+	table := []struct {
+		header, want string
+	}{
+		{header: "Src_last_modified_millis", want: "src_last_modified_millis"},
+		{header: "Large_file_sha1", want: "large_file_sha1"},
+		{header: "B2-Cache-Control", want: "b2-cache-control"},
+		{header: "Color", want: "Color"},
+		{header: "MiXeD-Case", want: "MiXeD-Case"},
+	}
+	for _, e := range table {
+		if got := infoKey(e.header); got != e.want {
+			t.Errorf("infoKey(%q): got %q, want %q", e.header, got, e.want)
+		}
+	}
+}
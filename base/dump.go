@@ -0,0 +1,93 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exchangeDump is the record of a failed request that is written by
+// DumpFailures.
+type exchangeDump struct {
+	Method          string            `json:"method"`
+	RequestID       string            `json:"requestId"`
+	CorrelationID   string            `json:"correlationId,omitempty"`
+	Verb            string            `json:"verb"`
+	URL             string            `json:"url"`
+	Start           time.Time         `json:"start"`
+	Duration        string            `json:"duration"`
+	RequestHeaders  map[string]string `json:"requestHeaders"`
+	RequestBody     string            `json:"requestBody,omitempty"`
+	Status          string            `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"responseHeaders,omitempty"`
+	ResponseBody    string            `json:"responseBody,omitempty"`
+	Error           string            `json:"error"`
+}
+
+func (o *b2Options) headerMap(h http.Header) map[string]string {
+	m := make(map[string]string)
+	for k, v := range h {
+		if strings.HasPrefix(k, "X-Blazer-") {
+			continue
+		}
+		m[k] = o.log.Header(k, strings.Join(v, ","))
+	}
+	return m
+}
+
+// dump writes the exchange to the dump directory, if there is one and err
+// cannot be retried.  resp and reply are nil if no response was received.
+func (o *b2Options) dump(req *http.Request, args []byte, resp *http.Response, reply []byte, start time.Time, err error) {
+	if o.dumpDir == "" || Action(err) != Punt || err == context.Canceled || err == context.DeadlineExceeded {
+		return
+	}
+	d := exchangeDump{
+		Method:         req.Header.Get("X-Blazer-Method"),
+		RequestID:      req.Header.Get("X-Blazer-Request-ID"),
+		CorrelationID:  req.Header.Get("X-Blazer-Correlation-ID"),
+		Verb:           req.Method,
+		URL:            req.URL.String(),
+		Start:          start,
+		Duration:       time.Since(start).String(),
+		RequestHeaders: o.headerMap(req.Header),
+		Error:          err.Error(),
+	}
+	if args != nil {
+		d.RequestBody = o.log.Body(args)
+	}
+	if resp != nil {
+		d.Status = resp.Status
+		d.ResponseHeaders = o.headerMap(resp.Header)
+		if reply != nil {
+			d.ResponseBody = o.log.Body(reply)
+		}
+	}
+	enc, jerr := json.MarshalIndent(d, "", "  ")
+	if jerr != nil {
+		o.log.V(1).Infof("couldn't encode failed %s: %v", d.Method, jerr)
+		return
+	}
+	name := fmt.Sprintf("%s-%s-%s.json", start.UTC().Format("20060102T150405.000000000"), d.Method, d.RequestID)
+	if werr := ioutil.WriteFile(filepath.Join(o.dumpDir, name), enc, 0600); werr != nil {
+		o.log.V(1).Infof("couldn't dump failed %s: %v", d.Method, werr)
+	}
+}
@@ -0,0 +1,83 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package base
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDumpFailures(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "b2_authorize_account"):
+			w.Write([]byte(`{"accountId": "id", "authorizationToken": "token", "apiUrl": "` + "http://" + r.Host + `"}`))
+		case strings.HasSuffix(r.URL.Path, "b2_delete_bucket"):
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": 503, "code": "service_unavailable", "message": "busy"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": 400, "code": "bad_request", "message": "no"}`))
+		}
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "dump")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ctx := context.Background()
+	b, err := AuthorizeAccount(ctx, "id", "key", SetAPIBase(srv.URL), DumpFailures(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket := &Bucket{b2: b, ID: "bucket"}
+	if err := bucket.DeleteBucket(ctx); err == nil {
+		t.Fatal("DeleteBucket: got nil error")
+	}
+	if _, err := b.CreateBucket(ctx, "bucket", "", nil, nil); err == nil {
+		t.Fatal("CreateBucket: got nil error")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("got %d dumps, want 1 (retryable errors are not dumped)", len(files))
+	}
+	data, err := ioutil.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := &exchangeDump{}
+	if err := json.Unmarshal(data, d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Method != "b2_create_bucket" || d.Status != "400 Bad Request" || !strings.Contains(d.ResponseBody, "bad_request") {
+		t.Errorf("unexpected dump: %s", data)
+	}
+	if got := d.RequestHeaders["Authorization"]; got != "[redacted]" {
+		t.Errorf("Authorization header: got %q, want [redacted]", got)
+	}
+}
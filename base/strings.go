@@ -15,14 +15,60 @@
 package base
 
 import (
+	"fmt"
 	"net/url"
 	"strings"
 )
 
+// B2 requires file names and file info values to be percent-encoded whenever
+// they appear in HTTP headers or in URLs.  These functions implement the
+// encoding documented at https://www.backblaze.com/b2/docs/string_encoding.html,
+// and are the only place in this package where it is done.
+
+// escape returns the minimal encoding of s that B2 accepts.  Letters, digits,
+// "/", and the punctuation characters B2 leaves unencoded are passed through;
+// all other bytes are percent-encoded.  Spaces are encoded as "%20" rather
+// than "+", so that the result is valid both in headers and in URL paths.
 func escape(s string) string {
-	return strings.Replace(url.QueryEscape(s), "%2F", "/", -1)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if shouldEscape(c) {
+			fmt.Fprintf(&b, "%%%02X", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func shouldEscape(c byte) bool {
+	switch {
+	case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+		return false
+	}
+	switch c {
+	case '!', '$', '\'', '(', ')', '*', '-', '.', '/', ':', ';', '=', '@', '_', '~':
+		return false
+	}
+	return true
 }
 
+// unescape decodes a string encoded by B2 or by escape.  Both the minimal and
+// full encodings are accepted, and "+" is decoded as a space.
 func unescape(s string) (string, error) {
 	return url.QueryUnescape(s)
 }
+
+// escapeHeader returns the value v of header k, encoded if B2 requires it.
+func escapeHeader(k, v string) string {
+	if strings.HasPrefix(k, "X-Bz-Info-") || k == "X-Bz-File-Name" {
+		return escape(v)
+	}
+	return v
+}
+
+// downloadPath returns the URL path for the named file in the given bucket.
+func downloadPath(bucket, name string) string {
+	return "/file/" + escape(bucket) + "/" + escape(name)
+}
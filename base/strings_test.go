@@ -1,26 +1,26 @@
 package base
 
 import (
-	"fmt"
+	"net/http"
 	"testing"
 )
 
+// crashes identified by go-fuzz
+var fuzzCrashers = []string{
+	"&\x020000",
+	"&\x020000\x9c",
+	"&\x020\x9c0",
+	"&\x0230j",
+	"&\x02\x98000",
+	"&\x02\x983\xc8j00",
+	"00\x000",
+	"00\x0000",
+	"00\x0000000000000",
+	"\x11\x030",
+}
+
 func TestEncodeDecode(t *testing.T) {
-	// crashes identified by go-fuzz
-	origs := []string{
-		"&\x020000",
-		"&\x020000\x9c",
-		"&\x020\x9c0",
-		"&\x0230j",
-		"&\x02\x98000",
-		"&\x02\x983\xc8j00",
-		"00\x000",
-		"00\x0000",
-		"00\x0000000000000",
-		"\x11\x030",
-	}
-
-	for _, orig := range origs {
+	for _, orig := range fuzzCrashers {
 		escaped := escape(orig)
 		unescaped, err := unescape(escaped)
 		if err != nil {
@@ -34,19 +34,113 @@ func TestEncodeDecode(t *testing.T) {
 	}
 }
 
-// hook for go-fuzz: https://github.com/dvyukov/go-fuzz
-func Fuzz(data []byte) int {
-	orig := string(data)
-	escaped := escape(orig)
+func TestEscapeNames(t *testing.T) {
+	table := []struct {
+		name, want string
+	}{
+		{name: "simple.txt", want: "simple.txt"},
+		{name: "with space.txt", want: "with%20space.txt"},
+		{name: "a+b.txt", want: "a%2Bb.txt"},
+		{name: "a + b", want: "a%20%2B%20b"},
+		{name: "100%.txt", want: "100%25.txt"},
+		{name: "dir/sub dir/file", want: "dir/sub%20dir/file"},
+		{name: "q?a=b&c#d", want: "q%3Fa=b%26c%23d"},
+		{name: "!$'()*-.:;=@_~", want: "!$'()*-.:;=@_~"},
+		{name: "caf\u00e9", want: "caf%C3%A9"},
+		{name: "\u81ea\u7531", want: "%E8%87%AA%E7%94%B1"},
+		{name: "\U0001F600.png", want: "%F0%9F%98%80.png"},
+		{name: "tab\tnewline\n", want: "tab%09newline%0A"},
+		{name: "back\\slash", want: "back%5Cslash"},
+		{name: "%2F", want: "%252F"},
+		{name: "++", want: "%2B%2B"},
+		{name: "\x7f\x00", want: "%7F%00"},
+	}
+	for _, e := range table {
+		got := escape(e.name)
+		if got != e.want {
+			t.Errorf("escape(%q): got %q, want %q", e.name, got, e.want)
+		}
+		back, err := unescape(got)
+		if err != nil {
+			t.Errorf("unescape(%q): %v", got, err)
+			continue
+		}
+		if back != e.name {
+			t.Errorf("unescape(escape(%q)): got %q", e.name, back)
+		}
 
-	unescaped, err := unescape(escaped)
-	if err != nil {
-		return 0
+		// Download URLs must be sent exactly as encoded, and decode to the name.
+		uri := "https://f000.backblazeb2.com" + downloadPath("bucket", e.name)
+		req, err := http.NewRequest("GET", uri, nil)
+		if err != nil {
+			t.Errorf("%q: NewRequest: %v", e.name, err)
+			continue
+		}
+		if got, want := req.URL.EscapedPath(), "/file/bucket/"+e.want; got != want {
+			t.Errorf("%q: request path: got %q, want %q", e.name, got, want)
+		}
+		if got, want := req.URL.Path, "/file/bucket/"+e.name; got != want {
+			t.Errorf("%q: decoded path: got %q, want %q", e.name, got, want)
+		}
+
+		// Headers must be encoded the same way.
+		if got := escapeHeader("X-Bz-File-Name", e.name); got != e.want {
+			t.Errorf("escapeHeader(X-Bz-File-Name, %q): got %q, want %q", e.name, got, e.want)
+		}
+		if got := escapeHeader("X-Bz-Info-key", e.name); got != e.want {
+			t.Errorf("escapeHeader(X-Bz-Info-key, %q): got %q, want %q", e.name, got, e.want)
+		}
+	}
+	if got := escapeHeader("Content-Type", "a b"); got != "a b" {
+		t.Errorf("escapeHeader(Content-Type): got %q, want it unchanged", got)
 	}
+}
 
-	if unescaped != orig {
-		panic(fmt.Sprintf("unescaped: \"%#v\", != orig: \"%#v\"", unescaped, orig))
+func TestUnescapeForms(t *testing.T) {
+	// B2 may return either the minimal or the full encoding.
+	table := []struct {
+		in, want string
+	}{
+		{in: "a+b", want: "a b"},
+		{in: "a%20b", want: "a b"},
+		{in: "a%2Bb", want: "a+b"},
+		{in: "a%2fb", want: "a/b"},
+		{in: "%E8%87%AA", want: "\u81ea"},
+		{in: "%e8%87%aa", want: "\u81ea"},
+	}
+	for _, e := range table {
+		got, err := unescape(e.in)
+		if err != nil {
+			t.Errorf("unescape(%q): %v", e.in, err)
+			continue
+		}
+		if got != e.want {
+			t.Errorf("unescape(%q): got %q, want %q", e.in, got, e.want)
+		}
 	}
+	if _, err := unescape("bad%zz"); err == nil {
+		t.Error("unescape(bad%zz): got no error")
+	}
+}
 
-	return 1
+func FuzzEscape(f *testing.F) {
+	for _, s := range fuzzCrashers {
+		f.Add(s)
+	}
+	f.Add("dir/sub dir/caf\u00e9 +%.txt")
+	f.Fuzz(func(t *testing.T, orig string) {
+		escaped := escape(orig)
+		unescaped, err := unescape(escaped)
+		if err != nil {
+			t.Fatalf("unescape(%q): %v", escaped, err)
+		}
+		if unescaped != orig {
+			t.Fatalf("unescape(escape(%q)): got %q", orig, unescaped)
+		}
+		if got := escapeHeader("X-Bz-File-Name", orig); got != escaped {
+			t.Fatalf("escapeHeader(X-Bz-File-Name, %q): got %q, want %q", orig, got, escaped)
+		}
+		// Arbitrary input must be rejected or decoded, never panic.
+		unescape(orig)
+	})
 }
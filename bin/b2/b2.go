@@ -0,0 +1,339 @@
+// b2 is a small utility for working with specific versions of objects in
+// Backblaze B2, by file ID.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/internal/cli"
+)
+
+const (
+	apiID  = "B2_ACCOUNT_ID"
+	apiKey = "B2_SECRET_KEY"
+)
+
+var apiBase = flag.String("api", "", "the B2 API endpoint, such as that of a bonfire server; the default is B2's")
+
+// Output goes here, so that tests can capture it.
+var (
+	stdout io.Writer = os.Stdout
+	stderr io.Writer = os.Stderr
+)
+
+func main() {
+	subcommands.Register(subcommands.HelpCommand(), "")
+	subcommands.Register(subcommands.FlagsCommand(), "")
+	subcommands.Register(&accountInfo{}, "")
+	subcommands.Register(&getFileInfo{}, "")
+	subcommands.Register(&downloadByID{}, "")
+	subcommands.Register(&deleteByID{}, "")
+	subcommands.Register(&verify{}, "")
+	flag.Parse()
+	ctx := context.Background()
+	os.Exit(int(subcommands.Execute(ctx)))
+}
+
+// newClient returns a client authorized with the key in the environment.
+func newClient(ctx context.Context) (*b2.Client, error) {
+	acct := os.Getenv(apiID)
+	key := os.Getenv(apiKey)
+	if acct == "" || key == "" {
+		return nil, fmt.Errorf("both %s and %s must be set in the environment", apiID, apiKey)
+	}
+	opts := []b2.ClientOption{b2.UserAgent("b2")}
+	if *apiBase != "" {
+		opts = append(opts, b2.APIBase(*apiBase))
+	}
+	return b2.NewClient(ctx, acct, key, opts...)
+}
+
+// object returns the object version with the given ID in the named bucket.
+func object(ctx context.Context, bucket, id string) (*b2.Object, error) {
+	client, err := newClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b, err := client.Bucket(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	return b.ObjectByID(ctx, id)
+}
+
+// report prints err to stderr.
+func report(err error) {
+	cli.Report(stderr, apiID, err)
+}
+
+type accountInfo struct{}
+
+func (a *accountInfo) Name() string     { return "show-account-info" }
+func (a *accountInfo) Synopsis() string { return "print what the key in the environment may do" }
+func (a *accountInfo) Usage() string {
+	return `b2 show-account-info
+
+Prints the account ID, the key's capabilities and restrictions, the API and
+download URLs, and the part sizes B2 recommends.  Any key may be used.
+`
+}
+func (a *accountInfo) SetFlags(*flag.FlagSet) {}
+
+func (a *accountInfo) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 0 {
+		fmt.Fprint(stderr, a.Usage())
+		return subcommands.ExitUsageError
+	}
+	client, err := newClient(ctx)
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	ai := client.AccountInfo()
+	out := struct {
+		AccountID               string   `json:"accountId"`
+		Capabilities            []string `json:"capabilities"`
+		BucketID                string   `json:"bucketId,omitempty"`
+		Prefix                  string   `json:"namePrefix,omitempty"`
+		APIURL                  string   `json:"apiUrl"`
+		DownloadURL             string   `json:"downloadUrl"`
+		RecommendedPartSize     int      `json:"recommendedPartSize"`
+		AbsoluteMinimumPartSize int      `json:"absoluteMinimumPartSize"`
+	}(*ai)
+	return printJSON(out)
+}
+
+func printJSON(v interface{}) subcommands.ExitStatus {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type getFileInfo struct{}
+
+func (g *getFileInfo) Name() string     { return "get-file-info" }
+func (g *getFileInfo) Synopsis() string { return "print the attributes of an object version" }
+func (g *getFileInfo) Usage() string {
+	return `b2 get-file-info bucket fileID
+
+The key in the environment needs the listBuckets and readFiles capabilities.
+`
+}
+func (g *getFileInfo) SetFlags(*flag.FlagSet) {}
+
+func (g *getFileInfo) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		fmt.Fprint(stderr, g.Usage())
+		return subcommands.ExitUsageError
+	}
+	obj, err := object(ctx, f.Arg(0), f.Arg(1))
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	out := struct {
+		ID              string            `json:"fileId"`
+		Name            string            `json:"fileName"`
+		Size            int64             `json:"size"`
+		ContentType     string            `json:"contentType"`
+		SHA1            string            `json:"contentSha1"`
+		UploadTimestamp time.Time         `json:"uploadTimestamp"`
+		Info            map[string]string `json:"fileInfo,omitempty"`
+	}{
+		ID:              obj.ID(),
+		Name:            attrs.Name,
+		Size:            attrs.Size,
+		ContentType:     attrs.ContentType,
+		SHA1:            attrs.SHA1,
+		UploadTimestamp: attrs.UploadTimestamp,
+		Info:            attrs.Info,
+	}
+	return printJSON(out)
+}
+
+type downloadByID struct{}
+
+func (d *downloadByID) Name() string     { return "download-file-by-id" }
+func (d *downloadByID) Synopsis() string { return "download an object version" }
+func (d *downloadByID) Usage() string {
+	return `b2 download-file-by-id bucket fileID localFile
+
+If localFile is "-", the object is written to standard output.  The key in the
+environment needs the listBuckets and readFiles capabilities.
+`
+}
+func (d *downloadByID) SetFlags(*flag.FlagSet) {}
+
+func (d *downloadByID) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 3 {
+		fmt.Fprint(stderr, d.Usage())
+		return subcommands.ExitUsageError
+	}
+	obj, err := object(ctx, f.Arg(0), f.Arg(1))
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	var w io.WriteCloser = nopCloser{stdout}
+	if f.Arg(2) != "-" {
+		file, err := os.Create(f.Arg(2))
+		if err != nil {
+			fmt.Fprintf(stderr, "%v\n", err)
+			return subcommands.ExitFailure
+		}
+		w = file
+	}
+	r := obj.NewReader(ctx, b2.ReadVersion(obj.ID()))
+	if _, err := io.Copy(w, r); err != nil {
+		r.Close()
+		w.Close()
+		report(err)
+		return subcommands.ExitFailure
+	}
+	if err := r.Close(); err != nil {
+		w.Close()
+		report(err)
+		return subcommands.ExitFailure
+	}
+	if err := w.Close(); err != nil {
+		fmt.Fprintf(stderr, "%v\n", err)
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type deleteByID struct{}
+
+func (d *deleteByID) Name() string     { return "delete-file-version-by-id" }
+func (d *deleteByID) Synopsis() string { return "delete an object version" }
+func (d *deleteByID) Usage() string {
+	return `b2 delete-file-version-by-id bucket fileID
+
+Only the given version is deleted.  The key in the environment needs the
+listBuckets, readFiles, and deleteFiles capabilities.
+`
+}
+func (d *deleteByID) SetFlags(*flag.FlagSet) {}
+
+func (d *deleteByID) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 2 {
+		fmt.Fprint(stderr, d.Usage())
+		return subcommands.ExitUsageError
+	}
+	obj, err := object(ctx, f.Arg(0), f.Arg(1))
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	if err := obj.Delete(ctx); err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	fmt.Fprintf(stdout, "deleted %s (%s)\n", obj.Name(), obj.ID())
+	return subcommands.ExitSuccess
+}
+
+type verify struct {
+	concurrency int
+	sample      int64
+	resume      string
+}
+
+func (v *verify) Name() string     { return "verify" }
+func (v *verify) Synopsis() string { return "check the hashes of objects against what B2 recorded" }
+func (v *verify) Usage() string {
+	return `b2 verify [-concurrency n] [-sample bytes] [-resume cursor] bucket [prefix]
+
+Downloads every object whose name begins with prefix and compares its SHA1 hash
+with the one B2 recorded on upload, then prints a summary.  The cursor printed
+as verification progresses may be passed to -resume to continue an interrupted
+run.  The exit status is 1 if any object is corrupt or unreadable.  The key in
+the environment needs the listBuckets, listFiles, and readFiles capabilities.
+`
+}
+
+func (v *verify) SetFlags(f *flag.FlagSet) {
+	f.IntVar(&v.concurrency, "concurrency", 4, "the number of objects to read at once")
+	f.Int64Var(&v.sample, "sample", 0, "if positive, read only the first and last this many bytes of larger objects")
+	f.StringVar(&v.resume, "resume", "", "continue from a cursor printed by an earlier run")
+}
+
+func (v *verify) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{}) subcommands.ExitStatus {
+	if f.NArg() != 1 && f.NArg() != 2 {
+		fmt.Fprint(stderr, v.Usage())
+		return subcommands.ExitUsageError
+	}
+	client, err := newClient(ctx)
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	b, err := client.Bucket(ctx, f.Arg(0))
+	if err != nil {
+		report(err)
+		return subcommands.ExitFailure
+	}
+	opts := []b2.VerifyOption{
+		b2.VerifyConcurrency(v.concurrency),
+		b2.VerifySample(v.sample),
+		b2.VerifyProgress(func(r *b2.VerifyReport) {
+			fmt.Fprintf(stderr, "%d objects checked; resume with -resume %s\n", r.Objects, r.Cursor)
+		}),
+	}
+	if v.resume != "" {
+		opts = append(opts, b2.VerifyResume(v.resume))
+	}
+	rep, err := b.Verify(ctx, f.Arg(1), opts...)
+	if err != nil {
+		report(err)
+		if rep == nil || rep.Cursor == "" {
+			return subcommands.ExitFailure
+		}
+	}
+	var failures []string
+	for _, fail := range rep.Failures {
+		failures = append(failures, fail.String())
+	}
+	out := struct {
+		Objects      int64    `json:"objects"`
+		Verified     int64    `json:"verified"`
+		Unverifiable int64    `json:"unverifiable"`
+		Sampled      int64    `json:"sampled"`
+		Bytes        int64    `json:"bytes"`
+		Failures     []string `json:"failures,omitempty"`
+		Cursor       string   `json:"cursor"`
+	}{
+		Objects:      rep.Objects,
+		Verified:     rep.Verified,
+		Unverifiable: rep.Unverifiable,
+		Sampled:      rep.Sampled,
+		Bytes:        rep.Bytes,
+		Failures:     failures,
+		Cursor:       rep.Cursor,
+	}
+	if status := printJSON(out); status != subcommands.ExitSuccess || err != nil || len(failures) > 0 {
+		return subcommands.ExitFailure
+	}
+	return subcommands.ExitSuccess
+}
+
+type nopCloser struct{ io.Writer }
+
+func (nopCloser) Close() error { return nil }
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/subcommands"
+	"github.com/kurin/blazer/b2"
+)
+
+// bonfireURL is the address of the bonfire server started by TestMain, or
+// empty if it could not be started.
+var bonfireURL string
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	os.Exit(runTests(m))
+}
+
+func runTests(m *testing.M) int {
+	dir, err := ioutil.TempDir("", "b2cli")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer os.RemoveAll(dir)
+	stop, err := startBonfire(dir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "not running tests against bonfire: %v\n", err)
+	} else {
+		defer stop()
+	}
+	return m.Run()
+}
+
+// startBonfire builds bonfire from this repository and runs it with the
+// accounts used by these tests.
+func startBonfire(dir string) (func(), error) {
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		return nil, err
+	}
+	bin := filepath.Join(dir, "bonfire")
+	build := exec.Command(gobin, "build", "-o", bin, "./bin/bonfire")
+	build.Dir = filepath.Join("..", "..", "bonfire")
+	if out, err := build.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("building bonfire: %v: %s", err, out)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	addr := l.Addr().String()
+	l.Close()
+	cfg := fmt.Sprintf(`{"listen": %q, "url": "http://%s", "dataDir": %q,
+		"accounts": [{"id": "team-a", "key": "secret"}]}`, addr, addr, filepath.Join(dir, "data"))
+	cfgFile := filepath.Join(dir, "bonfire.json")
+	if err := ioutil.WriteFile(cfgFile, []byte(cfg), 0644); err != nil {
+		return nil, err
+	}
+	cmd := exec.Command(bin, "-config", cfgFile)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	stop := func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	}
+	url := "http://" + addr
+	for i := 0; i < 100; i++ {
+		resp, err := http.Get(url + "/b2api/v1/b2_authorize_account")
+		if err == nil {
+			resp.Body.Close()
+			bonfireURL = url
+			return stop, nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	stop()
+	return nil, fmt.Errorf("bonfire did not start on %s", addr)
+}
+
+// run runs cmd with the given key and arguments against bonfire, and
+// returns its output.
+func run(t *testing.T, key string, cmd subcommands.Command, args ...string) (string, string, subcommands.ExitStatus) {
+	if bonfireURL == "" {
+		t.Skip("bonfire is not running")
+	}
+	os.Setenv(apiID, "team-a")
+	os.Setenv(apiKey, key)
+	*apiBase = bonfireURL
+	fs := flag.NewFlagSet(cmd.Name(), flag.ContinueOnError)
+	cmd.SetFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+	var out, errs bytes.Buffer
+	stdout, stderr = &out, &errs
+	defer func() { stdout, stderr = os.Stdout, os.Stderr }()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	status := cmd.Execute(ctx, fs)
+	return out.String(), errs.String(), status
+}
+
+func TestShowAccountInfo(t *testing.T) {
+	out, errs, status := run(t, "secret", &accountInfo{})
+	if status != subcommands.ExitSuccess {
+		t.Fatalf("show-account-info: got status %v: %s", status, errs)
+	}
+	var info struct {
+		AccountID string `json:"accountId"`
+		APIURL    string `json:"apiUrl"`
+	}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		t.Fatalf("show-account-info: %v: %s", err, out)
+	}
+	if info.AccountID != "team-a" || info.APIURL != bonfireURL {
+		t.Errorf("show-account-info: got %s", out)
+	}
+
+	if _, errs, status := run(t, "wrong", &accountInfo{}); status != subcommands.ExitFailure || !strings.Contains(errs, "401") {
+		t.Errorf("show-account-info with a bad key: got status %v: %s", status, errs)
+	}
+}
+
+func TestFileIDCommands(t *testing.T) {
+	if bonfireURL == "" {
+		t.Skip("bonfire is not running")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := b2.NewClient(ctx, "team-a", "secret", b2.APIBase(bonfireURL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.NewBucket(ctx, "cli-bucket", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range []subcommands.Command{&getFileInfo{}, &downloadByID{}, &deleteByID{}} {
+		args := []string{"no-such-bucket", "id"}
+		if cmd.Name() == "download-file-by-id" {
+			args = append(args, "-")
+		}
+		if _, errs, status := run(t, "secret", cmd, args...); status != subcommands.ExitFailure || !strings.Contains(errs, "bucket not found") {
+			t.Errorf("%s in a missing bucket: got status %v: %s", cmd.Name(), status, errs)
+		}
+		if _, _, status := run(t, "secret", cmd); status != subcommands.ExitUsageError {
+			t.Errorf("%s without arguments: got status %v", cmd.Name(), status)
+		}
+	}
+	if _, errs, status := run(t, "secret", &getFileInfo{}, "cli-bucket", "no-such-id"); status != subcommands.ExitFailure || errs == "" {
+		t.Errorf("get-file-info of a missing file: got status %v: %s", status, errs)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	if _, errs, status := run(t, "secret", &verify{}, "no-such-bucket"); status != subcommands.ExitFailure || !strings.Contains(errs, "bucket not found") {
+		t.Errorf("verify in a missing bucket: got status %v: %s", status, errs)
+	}
+	if _, _, status := run(t, "secret", &verify{}); status != subcommands.ExitUsageError {
+		t.Errorf("verify without arguments: got status %v", status)
+	}
+	if _, _, status := run(t, "secret", &verify{}, "a", "b", "c"); status != subcommands.ExitUsageError {
+		t.Errorf("verify with too many arguments: got status %v", status)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/subcommands"
 	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/internal/cli"
 )
 
 const (
@@ -33,7 +34,11 @@ type create struct {
 func (c *create) Name() string     { return "create" }
 func (c *create) Synopsis() string { return "create a new application key" }
 func (c *create) Usage() string {
-	return "b2keys create [-bucket bucket] [-duration duration] [-prefix pfx] name capability [capability ...]"
+	return `b2keys create [-bucket bucket] [-duration duration] [-prefix pfx] name capability [capability ...]
+
+The key in the environment needs the writeKeys capability, and with -bucket,
+listBuckets as well.
+`
 }
 
 func (c *create) SetFlags(fs *flag.FlagSet) {
@@ -69,7 +74,7 @@ func (c *create) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{})
 
 	client, err := b2.NewClient(ctx, id, key, b2.UserAgent("b2keys"))
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		report(err)
 		return subcommands.ExitFailure
 	}
 
@@ -78,7 +83,7 @@ func (c *create) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{})
 	if *c.bucket != "" {
 		bucket, err := client.Bucket(ctx, *c.bucket)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "%v\n", err)
+			report(err)
 			return subcommands.ExitFailure
 		}
 		cr = bucket
@@ -86,7 +91,7 @@ func (c *create) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{})
 
 	b2key, err := cr.CreateKey(ctx, name, opts...)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "%v\n", err)
+		report(err)
 		return subcommands.ExitFailure
 	}
 	fmt.Printf("key=%s, secret=%s\n", b2key.ID(), b2key.Secret())
@@ -96,3 +101,8 @@ func (c *create) Execute(ctx context.Context, f *flag.FlagSet, _ ...interface{})
 type creater interface {
 	CreateKey(context.Context, string, ...b2.KeyOption) (*b2.Key, error)
 }
+
+// report prints err to stderr.
+func report(err error) {
+	cli.Report(os.Stderr, apiID, err)
+}
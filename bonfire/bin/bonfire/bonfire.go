@@ -0,0 +1,170 @@
+// Bonfire serves the B2 API from local storage, for testing clients.
+//
+// With -config, bonfire reads a JSON file such as
+//
+//	{
+//		"listen": "0.0.0.0:8822",
+//		"url": "http://bonfire.example.com:8822",
+//		"dataDir": "/var/lib/bonfire",
+//		"accounts": [
+//			{"id": "team-a", "key": "secret", "caps": {"transactions": 10000, "downloadBytes": 1000000000}},
+//			{"id": "team-b", "key": "secret", "expires": "2030-01-01T00:00:00Z"}
+//		],
+//		"latency": "20ms",
+//		"jitter": "10ms",
+//		"failureRate": 0.01,
+//		"endpoints": {
+//			"b2_upload_file": {"latency": "1s", "failureRate": 0.1, "status": 408},
+//			"b2_authorize_account": {}
+//		},
+//		"admin": true,
+//		"requestLog": "-",
+//		"metrics": true
+//	}
+//
+// An empty endpoint entry exempts that method from the default faults.  With
+// "admin" set, the faults can be read and changed while bonfire runs:
+//
+//	curl -d '{"b2_upload_part": {"latency": "5s"}}' localhost:8822/admin/faults
+//
+// and the server's clock, against which authorization tokens and keys
+// expire, can be moved forward:
+//
+//	curl -d '{"advance": "25h"}' localhost:8822/admin/clock
+//
+// Without a config, bonfire accepts any account and key, and listens on
+// localhost:8822.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/kurin/blazer/bonfire"
+	"github.com/kurin/blazer/bonfire/internal/pyre"
+)
+
+type superManager struct {
+	*bonfire.LocalBucket
+	bonfire.FS
+}
+
+var (
+	configFile = flag.String("config", "", "JSON file describing accounts, caps, and fault injection")
+	dataDir    = flag.String("data", "", "directory in which to store objects; overrides the config")
+)
+
+func loadConfig() (*bonfire.Config, error) {
+	cfg := bonfire.DefaultConfig()
+	if *configFile != "" {
+		c, err := bonfire.LoadConfig(*configFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg = c
+	}
+	if *dataDir != "" {
+		cfg.DataDir = *dataDir
+	}
+	return cfg, nil
+}
+
+// admin runs the maintenance command named by args.
+func admin(fs bonfire.FS, args []string) error {
+	if len(args) != 1 || args[0] != "migrate" {
+		return errors.New("usage: bonfire [-config file] [-data dir] admin migrate")
+	}
+	if err := fs.Migrate(); err != nil {
+		return err
+	}
+	fmt.Printf("%s is at layout version %d\n", fs, bonfire.LayoutVersion)
+	return nil
+}
+
+func main() {
+	flag.Parse()
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	port, err := cfg.Port()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fs := bonfire.FS(cfg.DataDir)
+	if flag.Arg(0) == "admin" {
+		if err := admin(fs, flag.Args()[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if err := fs.Init(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	mux := http.NewServeMux()
+
+	bm := &bonfire.LocalBucket{Port: port}
+	clock := &pyre.Clock{}
+	accts := bonfire.NewAccounts(cfg)
+	accts.Clock = clock
+
+	if err := pyre.RegisterServerOnMux(ctx, &pyre.Server{
+		Account:   accts,
+		LargeFile: fs,
+		Bucket:    bm,
+		Clock:     clock,
+	}, mux); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	sm := superManager{
+		LocalBucket: bm,
+		FS:          fs,
+	}
+
+	pyre.RegisterLargeFileManagerOnMux(fs, mux)
+	pyre.RegisterSimpleFileManagerOnMux(fs, mux)
+	pyre.RegisterDownloadManagerOnMux(sm, mux)
+	fmt.Println("ok")
+	mon := &pyre.Monitor{Account: accts.Owner}
+	switch cfg.RequestLog {
+	case "":
+	case "-":
+		mon.Log = os.Stdout
+	default:
+		f, err := os.OpenFile(cfg.RequestLog, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		mon.Log = f
+	}
+	faults, err := cfg.Faults()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	top := http.NewServeMux()
+	top.Handle("/", mon.Wrap(faults.Wrap(cfg.Handler(accts, mux))))
+	if cfg.Admin {
+		top.Handle("/admin/faults", faults)
+		top.Handle("/admin/clock", clock)
+	}
+	if cfg.Metrics {
+		top.Handle("/metrics", mon)
+	}
+	fmt.Fprintln(os.Stderr, http.ListenAndServe(cfg.Listen, top))
+	os.Exit(1)
+}
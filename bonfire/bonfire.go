@@ -27,7 +27,7 @@ import (
 	"strconv"
 	"sync"
 
-	"github.com/kurin/blazer/internal/pyre"
+	"github.com/kurin/blazer/bonfire/internal/pyre"
 )
 
 type FS string
@@ -0,0 +1,400 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bonfire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurin/blazer/bonfire/internal/pyre"
+	"github.com/kurin/blazer/internal/b2types"
+)
+
+// Config describes a bonfire server.  It is usually read from a JSON file
+// with LoadConfig; unset fields take the defaults documented below.
+type Config struct {
+	// Listen is the address to serve on.  The default is localhost:8822.
+	Listen string `json:"listen"`
+
+	// URL is the address at which clients reach the server.  The default is
+	// http://localhost, at the port given in Listen.
+	URL string `json:"url"`
+
+	// DataDir is where objects are stored.  The default is /tmp/b2.
+	DataDir string `json:"dataDir"`
+
+	// Accounts lists the accounts that may authorize.  If it is empty, any
+	// account ID and key are accepted.
+	Accounts []AccountConfig `json:"accounts"`
+
+	// Latency is added to every request.
+	Latency Duration `json:"latency"`
+
+	// Jitter adds up to this much more latency to every request.
+	Jitter Duration `json:"jitter"`
+
+	// FailureRate is the fraction, from 0 to 1, of requests that fail with
+	// a 503 before reaching the server.
+	FailureRate float64 `json:"failureRate"`
+
+	// Endpoints overrides Latency, Jitter, and FailureRate for individual
+	// B2 methods, such as "b2_upload_file".
+	Endpoints map[string]EndpointConfig `json:"endpoints"`
+
+	// Admin enables /admin/faults, which reports and changes the injected
+	// faults while the server runs, and /admin/clock, which sets the time
+	// the server reports and against which tokens and keys expire.
+	Admin bool `json:"admin"`
+
+	// RequestLog, if set, is a file to which a line of JSON is appended for
+	// each request.  "-" means standard output.
+	RequestLog string `json:"requestLog"`
+
+	// Metrics enables counts of requests by method and status at /metrics.
+	Metrics bool `json:"metrics"`
+}
+
+// AccountConfig describes a single account.
+type AccountConfig struct {
+	ID   string `json:"id"`
+	Key  string `json:"key"`
+	Caps Caps   `json:"caps"`
+
+	// Expires, if set, is when the key stops working.  Tokens issued for
+	// it expire then too.
+	Expires time.Time `json:"expires"`
+}
+
+// EndpointConfig describes the faults injected into a single B2 method.
+type EndpointConfig struct {
+	Latency     Duration `json:"latency"`
+	Jitter      Duration `json:"jitter"`
+	FailureRate float64  `json:"failureRate"`
+
+	// Status is the HTTP status of injected failures.  The default is 503.
+	Status int `json:"status"`
+}
+
+// Caps limit what an account may do.  Zero means no limit.
+type Caps struct {
+	// Transactions is the number of requests the account may make.
+	Transactions int64 `json:"transactions"`
+
+	// DownloadBytes is the number of bytes the account may download.
+	DownloadBytes int64 `json:"downloadBytes"`
+}
+
+// Duration is a time.Duration that is written in JSON as a string, such as
+// "150ms".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// LoadConfig reads a JSON Config from path.
+func LoadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	c := &Config{}
+	if err := dec.Decode(c); err != nil {
+		return nil, fmt.Errorf("bonfire: %s: %v", path, err)
+	}
+	if err := c.setDefaults(); err != nil {
+		return nil, fmt.Errorf("bonfire: %s: %v", path, err)
+	}
+	return c, nil
+}
+
+// DefaultConfig returns the configuration used when none is given.
+func DefaultConfig() *Config {
+	c := &Config{}
+	c.setDefaults()
+	return c
+}
+
+func (c *Config) setDefaults() error {
+	if c.Listen == "" {
+		c.Listen = "localhost:8822"
+	}
+	if c.DataDir == "" {
+		c.DataDir = "/tmp/b2"
+	}
+	if c.URL == "" {
+		_, port, err := net.SplitHostPort(c.Listen)
+		if err != nil {
+			return err
+		}
+		c.URL = "http://localhost:" + port
+	}
+	c.URL = strings.TrimSuffix(c.URL, "/")
+	if _, err := c.Faults(); err != nil {
+		return err
+	}
+	seen := make(map[string]bool)
+	for _, a := range c.Accounts {
+		if a.ID == "" || a.Key == "" {
+			return errors.New("every account needs an id and a key")
+		}
+		if seen[a.ID] {
+			return fmt.Errorf("account %q is listed twice", a.ID)
+		}
+		seen[a.ID] = true
+	}
+	return nil
+}
+
+// TokenLifetime is how long an authorization token lasts, as in B2.
+const TokenLifetime = 24 * time.Hour
+
+// Accounts is an AccountManager for the accounts in a Config.  It issues a
+// distinct token on each authorization, and enforces each account's caps.
+type Accounts struct {
+	// Clock, if set, is the time against which tokens and keys expire.
+	Clock *pyre.Clock
+
+	url   string
+	accts map[string]AccountConfig
+
+	mu     sync.Mutex
+	tokens map[string]token
+	usage  map[string]*Caps
+}
+
+type token struct {
+	acct    string
+	expires time.Time
+}
+
+// NewAccounts returns the accounts described by c.
+func NewAccounts(c *Config) *Accounts {
+	a := &Accounts{
+		url:    c.URL,
+		accts:  make(map[string]AccountConfig),
+		tokens: make(map[string]token),
+		usage:  make(map[string]*Caps),
+	}
+	for _, acct := range c.Accounts {
+		a.accts[acct.ID] = acct
+	}
+	return a
+}
+
+func (a *Accounts) Authorize(acct, key string) (string, error) {
+	now := a.Clock.Now()
+	expires := now.Add(TokenLifetime)
+	if len(a.accts) > 0 {
+		cfg, ok := a.accts[acct]
+		if !ok || cfg.Key != key {
+			return "", errors.New("bad account ID or key")
+		}
+		if !cfg.Expires.IsZero() {
+			if !now.Before(cfg.Expires) {
+				return "", errors.New("key has expired")
+			}
+			if cfg.Expires.Before(expires) {
+				expires = cfg.Expires
+			}
+		}
+	}
+	tok := acct + "-" + uuid.New().String()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokens[tok] = token{acct: acct, expires: expires}
+	return tok, nil
+}
+
+func (a *Accounts) CheckCreds(token, api string) error {
+	if _, ok := a.account(token); !ok {
+		return errors.New("bad auth token")
+	}
+	if a.expired(token) {
+		return errors.New("auth token has expired")
+	}
+	return nil
+}
+
+func (a *Accounts) APIRoot(string) string                 { return a.url }
+func (a *Accounts) DownloadRoot(string) string            { return a.url }
+func (a *Accounts) UploadHost(string) (string, error)     { return a.url, nil }
+func (a *Accounts) UploadPartHost(string) (string, error) { return a.url, nil }
+func (a *Accounts) Sizes(string) (rec int32, min int32)   { return 1e5, 1 }
+
+// Owner returns the ID of the account that holds token, or "" if the token
+// was not issued by a.
+func (a *Accounts) Owner(token string) string {
+	id, _ := a.account(token)
+	return id
+}
+
+func (a *Accounts) account(tok string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	t, ok := a.tokens[tok]
+	return t.acct, ok
+}
+
+// expired reports whether tok was issued by a and has since expired.
+func (a *Accounts) expired(tok string) bool {
+	a.mu.Lock()
+	t, ok := a.tokens[tok]
+	a.mu.Unlock()
+	return ok && !a.Clock.Now().Before(t.expires)
+}
+
+// charge records a transaction for the account holding token, and returns the
+// cap, if any, that forbids it.
+func (a *Accounts) charge(token string, download bool) string {
+	id, ok := a.account(token)
+	if !ok {
+		return ""
+	}
+	caps := a.accts[id].Caps
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	u, ok := a.usage[id]
+	if !ok {
+		u = &Caps{}
+		a.usage[id] = u
+	}
+	if caps.Transactions > 0 && u.Transactions >= caps.Transactions {
+		return "transaction_cap_exceeded"
+	}
+	if download && caps.DownloadBytes > 0 && u.DownloadBytes >= caps.DownloadBytes {
+		return "download_cap_exceeded"
+	}
+	u.Transactions++
+	return ""
+}
+
+func (a *Accounts) downloaded(token string, n int64) {
+	id, ok := a.account(token)
+	if !ok {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if u, ok := a.usage[id]; ok {
+		u.DownloadBytes += n
+	}
+}
+
+// Faults returns the faults that c injects.
+func (c *Config) Faults() (*pyre.Faults, error) {
+	f := &pyre.Faults{}
+	def := pyre.Fault{Latency: c.Latency.Duration, Jitter: c.Jitter.Duration, ErrorRate: c.FailureRate}
+	if err := f.Set("", def); err != nil {
+		return nil, err
+	}
+	for method, e := range c.Endpoints {
+		ft := pyre.Fault{
+			Latency:   e.Latency.Duration,
+			Jitter:    e.Jitter.Duration,
+			ErrorRate: e.FailureRate,
+			Status:    e.Status,
+		}
+		if ft == (pyre.Fault{}) {
+			// An empty entry exempts the method from the defaults.
+			ft.Exempt = true
+		}
+		if err := f.Set(method, ft); err != nil {
+			return nil, fmt.Errorf("endpoint %s: %v", method, err)
+		}
+	}
+	return f, nil
+}
+
+// Handler wraps h to reject expired tokens and enforce the caps of the
+// accounts in a.
+func (c *Config) Handler(a *Accounts, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		token := r.Header.Get("Authorization")
+		if a.expired(token) {
+			writeError(rw, http.StatusUnauthorized, "expired_auth_token", "authorization token has expired")
+			return
+		}
+		download := strings.HasPrefix(r.URL.Path, "/file/")
+		if cap := a.charge(token, download); cap != "" {
+			writeError(rw, http.StatusForbidden, cap, "cap exceeded")
+			return
+		}
+		if !download {
+			h.ServeHTTP(rw, r)
+			return
+		}
+		cw := &countingWriter{ResponseWriter: rw}
+		h.ServeHTTP(cw, r)
+		a.downloaded(token, cw.n)
+	})
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
+
+func writeError(rw http.ResponseWriter, status int, code, msg string) {
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	json.NewEncoder(rw).Encode(b2types.ErrorMessage{
+		Status: status,
+		Code:   code,
+		Msg:    msg,
+	})
+}
+
+// Port returns the port on which c listens.
+func (c *Config) Port() (int, error) {
+	_, port, err := net.SplitHostPort(c.Listen)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(port)
+}
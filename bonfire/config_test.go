@@ -0,0 +1,300 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bonfire
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/bonfire/internal/pyre"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bonfire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := []struct {
+		json string
+		want *Config
+		err  bool
+	}{
+		{
+			json: `{}`,
+			want: &Config{Listen: "localhost:8822", URL: "http://localhost:8822", DataDir: "/tmp/b2"},
+		},
+		{
+			json: `{"listen": ":9000", "latency": "50ms", "failureRate": 0.1,
+				"accounts": [{"id": "a", "key": "k", "caps": {"transactions": 10}}]}`,
+			want: &Config{
+				Listen:      ":9000",
+				URL:         "http://localhost:9000",
+				DataDir:     "/tmp/b2",
+				Latency:     Duration{50 * time.Millisecond},
+				FailureRate: 0.1,
+				Accounts:    []AccountConfig{{ID: "a", Key: "k", Caps: Caps{Transactions: 10}}},
+			},
+		},
+		{json: `{"failureRate": 2}`, err: true},
+		{json: `{"endpoints": {"b2_upload_file": {"failureRate": -1}}}`, err: true},
+		{json: `{"endpoints": {"b2_upload_file": {"status": 200}}}`, err: true},
+		{json: `{"latency": "soon"}`, err: true},
+		{json: `{"accounts": [{"id": "a"}]}`, err: true},
+		{json: `{"accounts": [{"id": "a", "key": "k"}, {"id": "a", "key": "j"}]}`, err: true},
+		{json: `{"listen": "localhost"}`, err: true},
+		{json: `{"lisen": ":80"}`, err: true},
+	}
+	for i, e := range table {
+		path := filepath.Join(dir, "config.json")
+		if err := ioutil.WriteFile(path, []byte(e.json), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := LoadConfig(path)
+		if (err != nil) != e.err {
+			t.Errorf("%d: LoadConfig: got err %v, want err %v", i, err, e.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if got.Listen != e.want.Listen || got.URL != e.want.URL || got.DataDir != e.want.DataDir ||
+			got.Latency != e.want.Latency || got.FailureRate != e.want.FailureRate || len(got.Accounts) != len(e.want.Accounts) {
+			t.Errorf("%d: got %+v, want %+v", i, got, e.want)
+			continue
+		}
+		for j := range got.Accounts {
+			if got.Accounts[j] != e.want.Accounts[j] {
+				t.Errorf("%d: account %d: got %+v, want %+v", i, j, got.Accounts[j], e.want.Accounts[j])
+			}
+		}
+	}
+}
+
+func TestAccountCaps(t *testing.T) {
+	cfg := &Config{
+		Listen: "localhost:8822",
+		Accounts: []AccountConfig{
+			{ID: "small", Key: "key", Caps: Caps{Transactions: 2, DownloadBytes: 5}},
+			{ID: "big", Key: "key"},
+		},
+	}
+	if err := cfg.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	accts := NewAccounts(cfg)
+	mux := http.NewServeMux()
+	if err := pyre.RegisterServerOnMux(context.Background(), &pyre.Server{Account: accts}, mux); err != nil {
+		t.Fatal(err)
+	}
+	mux.HandleFunc("/file/", func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("0123456789"))
+	})
+	srv := httptest.NewServer(cfg.Handler(accts, mux))
+	defer srv.Close()
+
+	do := func(path, auth string) int {
+		req, err := http.NewRequest("GET", srv.URL+path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", auth)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	basic := func(acct, key string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(acct+":"+key))
+	}
+
+	if code := do("/b2api/v1/b2_authorize_account", basic("small", "wrong")); code != 401 {
+		t.Errorf("bad key: got %d, want 401", code)
+	}
+	if code := do("/b2api/v1/b2_authorize_account", basic("nobody", "key")); code != 401 {
+		t.Errorf("unknown account: got %d, want 401", code)
+	}
+
+	small, err := accts.Authorize("small", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	big, err := accts.Authorize("big", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if small == big || !strings.HasPrefix(small, "small-") {
+		t.Errorf("tokens: got %q and %q", small, big)
+	}
+	if err := accts.CheckCreds(small, ""); err != nil {
+		t.Errorf("CheckCreds: %v", err)
+	}
+	if err := accts.CheckCreds("bogus", ""); err == nil {
+		t.Error("CheckCreds(bogus): got no error")
+	}
+
+	if code := do("/file/bucket/obj", small); code != 200 {
+		t.Errorf("first download: got %d, want 200", code)
+	}
+	// The first download exhausted the download cap.
+	if code := do("/file/bucket/obj", small); code != 403 {
+		t.Errorf("second download: got %d, want 403", code)
+	}
+	if code := do("/b2api/v1/b2_list_file_versions", small); code != 200 {
+		t.Errorf("second transaction: got %d, want 200", code)
+	}
+	if code := do("/b2api/v1/b2_list_file_versions", small); code != 403 {
+		t.Errorf("third transaction: got %d, want 403", code)
+	}
+	for i := 0; i < 5; i++ {
+		if code := do("/file/bucket/obj", big); code != 200 {
+			t.Errorf("uncapped download %d: got %d, want 200", i, code)
+		}
+	}
+
+}
+
+func TestAccountExpiry(t *testing.T) {
+	clock := &pyre.Clock{}
+	start := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock.Set(start)
+	cfg := &Config{
+		Listen: "localhost:8822",
+		Accounts: []AccountConfig{
+			{ID: "short", Key: "key", Expires: start.Add(time.Hour)},
+			{ID: "long", Key: "key"},
+		},
+	}
+	if err := cfg.setDefaults(); err != nil {
+		t.Fatal(err)
+	}
+	accts := NewAccounts(cfg)
+	accts.Clock = clock
+	mux := http.NewServeMux()
+	mux.HandleFunc("/b2api/v1/b2_list_buckets", func(http.ResponseWriter, *http.Request) {})
+	srv := httptest.NewServer(cfg.Handler(accts, mux))
+	defer srv.Close()
+	admin := httptest.NewServer(clock)
+	defer admin.Close()
+
+	status := func(token string) (int, string) {
+		req, err := http.NewRequest("POST", srv.URL+"/b2api/v1/b2_list_buckets", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Authorization", token)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var msg struct{ Code string }
+		json.NewDecoder(resp.Body).Decode(&msg)
+		return resp.StatusCode, msg.Code
+	}
+	advance := func(d string) {
+		resp, err := http.Post(admin.URL, "application/json", strings.NewReader(`{"advance": "`+d+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != 200 {
+			t.Fatalf("advance %s: got %d", d, resp.StatusCode)
+		}
+	}
+
+	short, err := accts.Authorize("short", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	long, err := accts.Authorize("long", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := status(short); code != 200 {
+		t.Errorf("fresh token: got %d, want 200", code)
+	}
+
+	advance("1h")
+	if code, c := status(short); code != 401 || c != "expired_auth_token" {
+		t.Errorf("token of an expired key: got %d %q, want 401 expired_auth_token", code, c)
+	}
+	if _, err := accts.Authorize("short", "key"); err == nil {
+		t.Error("Authorize with an expired key: got no error")
+	}
+	if code, _ := status(long); code != 200 {
+		t.Errorf("token after 1h: got %d, want 200", code)
+	}
+
+	advance("23h")
+	if code, c := status(long); code != 401 || c != "expired_auth_token" {
+		t.Errorf("token after 24h: got %d %q, want 401 expired_auth_token", code, c)
+	}
+	if err := accts.CheckCreds(long, ""); err == nil {
+		t.Error("CheckCreds of an expired token: got no error")
+	}
+	long, err = accts.Authorize("long", "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, _ := status(long); code != 200 {
+		t.Errorf("new token: got %d, want 200", code)
+	}
+
+	resp, err := http.Post(admin.URL, "application/json", strings.NewReader(`{"advance": "-1h"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 400 {
+		t.Errorf("moving the clock backwards: got %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestConfigFaults(t *testing.T) {
+	cfg := &Config{
+		Latency:     Duration{time.Second},
+		FailureRate: 0.5,
+		Endpoints: map[string]EndpointConfig{
+			"b2_upload_file":       {Jitter: Duration{time.Minute}, Status: 408},
+			"b2_authorize_account": {},
+		},
+	}
+	f, err := cfg.Faults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for method, want := range map[string]pyre.Fault{
+		"b2_list_buckets":      {Latency: time.Second, ErrorRate: 0.5},
+		"b2_upload_file":       {Jitter: time.Minute, Status: 408},
+		"b2_authorize_account": {Exempt: true},
+	} {
+		if got := f.Get(method); got != want {
+			t.Errorf("%s: got %+v, want %+v", method, got, want)
+		}
+	}
+}
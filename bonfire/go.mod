@@ -0,0 +1,11 @@
+module github.com/kurin/blazer/bonfire
+
+go 1.13
+
+require (
+	github.com/google/uuid v1.6.0
+	github.com/kurin/blazer v0.5.3
+)
+
+// bonfire tracks the client's internal types at head.
+replace github.com/kurin/blazer => ../
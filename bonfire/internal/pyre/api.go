@@ -0,0 +1,492 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pyre
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/kurin/blazer/internal/b2types"
+)
+
+const apiPrefix = "/b2api/v1/"
+
+// apiErr is an error with the status and code that B2 would return for it.
+type apiErr struct {
+	status int
+	code   string
+	err    error
+}
+
+func (e apiErr) Error() string { return e.err.Error() }
+
+func badRequest(err error) error   { return apiErr{status: 400, code: "bad_request", err: err} }
+func unauthorized(err error) error { return apiErr{status: 401, code: "unauthorized", err: err} }
+
+func writeError(rw http.ResponseWriter, err error) {
+	aErr, ok := err.(apiErr)
+	if !ok {
+		aErr = apiErr{status: 400, code: "bad_request", err: err}
+	}
+	msg := b2types.ErrorMessage{
+		Status: aErr.status,
+		Code:   aErr.code,
+		Msg:    aErr.err.Error(),
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(msg.Status)
+	if err := json.NewEncoder(rw).Encode(msg); err != nil {
+		fmt.Fprintln(os.Stdout, err)
+	}
+}
+
+type authKey struct{}
+
+func getAuth(ctx context.Context) (string, error) {
+	auth, ok := ctx.Value(authKey{}).(string)
+	if !ok {
+		return "", errors.New("no authorization in context")
+	}
+	return auth, nil
+}
+
+// apiHandler serves a single B2 API call.  The request body is decoded into
+// req, and the response, if there is no error, is encoded as JSON.
+type apiHandler struct {
+	req  func() interface{}
+	call func(ctx context.Context, req interface{}) (interface{}, error)
+}
+
+func (h apiHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), authKey{}, r.Header.Get("Authorization"))
+	req := h.req()
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			writeError(rw, badRequest(err))
+			return
+		}
+	}
+	resp, err := h.call(ctx, req)
+	if err != nil {
+		writeError(rw, err)
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(resp); err != nil {
+		fmt.Fprintln(os.Stdout, err)
+	}
+}
+
+// RegisterServerOnMux registers the B2 JSON API calls handled by srv.
+func RegisterServerOnMux(ctx context.Context, srv *Server, mux *http.ServeMux) error {
+	calls := map[string]apiHandler{
+		"b2_authorize_account": {
+			req: func() interface{} { return &struct{}{} },
+			call: func(ctx context.Context, _ interface{}) (interface{}, error) {
+				return srv.AuthorizeAccount(ctx)
+			},
+		},
+		"b2_list_buckets": {
+			req: func() interface{} { return &b2types.ListBucketsRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ListBuckets(ctx, req.(*b2types.ListBucketsRequest))
+			},
+		},
+		"b2_create_bucket": {
+			req: func() interface{} { return &b2types.CreateBucketRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.CreateBucket(ctx, req.(*b2types.CreateBucketRequest))
+			},
+		},
+		"b2_delete_bucket": {
+			req: func() interface{} { return &b2types.DeleteBucketRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.DeleteBucket(ctx, req.(*b2types.DeleteBucketRequest))
+			},
+		},
+		"b2_get_upload_url": {
+			req: func() interface{} { return &b2types.GetUploadURLRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetUploadURL(ctx, req.(*b2types.GetUploadURLRequest))
+			},
+		},
+		"b2_start_large_file": {
+			req: func() interface{} { return &b2types.StartLargeFileRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.StartLargeFile(ctx, req.(*b2types.StartLargeFileRequest))
+			},
+		},
+		"b2_get_upload_part_url": {
+			req: func() interface{} { return &b2types.GetUploadPartURLRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.GetUploadPartURL(ctx, req.(*b2types.GetUploadPartURLRequest))
+			},
+		},
+		"b2_finish_large_file": {
+			req: func() interface{} { return &b2types.FinishLargeFileRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.FinishLargeFile(ctx, req.(*b2types.FinishLargeFileRequest))
+			},
+		},
+		"b2_list_file_versions": {
+			req: func() interface{} { return &b2types.ListFileVersionsRequest{} },
+			call: func(ctx context.Context, req interface{}) (interface{}, error) {
+				return srv.ListFileVersions(ctx, req.(*b2types.ListFileVersionsRequest))
+			},
+		},
+	}
+	for name, h := range calls {
+		mux.Handle(apiPrefix+name, h)
+	}
+	return nil
+}
+
+type AccountManager interface {
+	Authorize(acct, key string) (string, error)
+	CheckCreds(token, api string) error
+	APIRoot(acct string) string
+	DownloadRoot(acct string) string
+	UploadPartHost(fileID string) (string, error)
+	UploadHost(id string) (string, error)
+	Sizes(acct string) (recommended, minimum int32)
+}
+
+type BucketManager interface {
+	AddBucket(id, name string, bs []byte) error
+	RemoveBucket(id string) error
+	UpdateBucket(id string, rev int, bs []byte) error
+	ListBuckets(acct string) ([][]byte, error)
+	GetBucket(id string) ([]byte, error)
+}
+
+type LargeFileOrganizer interface {
+	Start(bucketID, fileName, fileID string, bs []byte) error
+	Get(fileID string) ([]byte, error)
+	Parts(fileID string) ([]string, error)
+	Finish(fileID string) error
+}
+
+type Server struct {
+	Account   AccountManager
+	Bucket    BucketManager
+	LargeFile LargeFileOrganizer
+	List      ListManager
+
+	// Clock, if set, is the time the server reports.
+	Clock *Clock
+}
+
+// These mirror the corresponding B2 JSON objects, where the b2types
+// definitions omit fields that the client doesn't need.
+
+type getUploadURLResponse struct {
+	BucketID string `json:"bucketId"`
+	URI      string `json:"uploadUrl"`
+	Token    string `json:"authorizationToken"`
+}
+
+type startLargeFileResponse struct {
+	ID          string            `json:"fileId"`
+	Name        string            `json:"fileName"`
+	AccountID   string            `json:"accountId"`
+	BucketID    string            `json:"bucketId"`
+	ContentType string            `json:"contentType"`
+	Info        map[string]string `json:"fileInfo"`
+	Timestamp   int64             `json:"uploadTimestamp"`
+}
+
+func millis(t time.Time) int64 {
+	return t.UnixNano() / 1e6
+}
+
+func (s *Server) AuthorizeAccount(ctx context.Context) (*b2types.AuthorizeAccountResponse, error) {
+	auth, err := getAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(auth, "Basic ") {
+		return nil, unauthorized(errors.New("basic auth required"))
+	}
+	auth = strings.TrimPrefix(auth, "Basic ")
+	bs, err := base64.StdEncoding.DecodeString(auth)
+	if err != nil {
+		return nil, unauthorized(err)
+	}
+	split := strings.Split(string(bs), ":")
+	if len(split) != 2 {
+		return nil, unauthorized(errors.New("bad auth"))
+	}
+	acct, key := split[0], split[1]
+	token, err := s.Account.Authorize(acct, key)
+	if err != nil {
+		return nil, unauthorized(err)
+	}
+	rec, min := s.Account.Sizes(acct)
+	return &b2types.AuthorizeAccountResponse{
+		AccountID:      acct,
+		AuthToken:      token,
+		URI:            s.Account.APIRoot(acct),
+		DownloadURI:    s.Account.DownloadRoot(acct),
+		PartSize:       int(rec),
+		MinPartSize:    int(rec),
+		AbsMinPartSize: int(min),
+	}, nil
+}
+
+func (s *Server) ListBuckets(ctx context.Context, req *b2types.ListBucketsRequest) (*b2types.ListBucketsResponse, error) {
+	resp := &b2types.ListBucketsResponse{}
+	buckets, err := s.Bucket.ListBuckets(req.AccountID)
+	if err != nil {
+		return nil, err
+	}
+	for _, bs := range buckets {
+		var bucket b2types.CreateBucketResponse
+		if err := json.Unmarshal(bs, &bucket); err != nil {
+			return nil, err
+		}
+		resp.Buckets = append(resp.Buckets, bucket)
+	}
+	return resp, nil
+}
+
+func (s *Server) CreateBucket(ctx context.Context, req *b2types.CreateBucketRequest) (*b2types.CreateBucketResponse, error) {
+	bucket := &b2types.CreateBucketResponse{
+		BucketID:       uuid.New().String(),
+		Name:           req.Name,
+		Type:           req.Type,
+		Info:           req.Info,
+		LifecycleRules: req.LifecycleRules,
+		Revision:       1,
+	}
+	bs, err := json.Marshal(bucket)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Bucket.AddBucket(bucket.BucketID, bucket.Name, bs); err != nil {
+		return nil, err
+	}
+	return bucket, nil
+}
+
+func (s *Server) DeleteBucket(ctx context.Context, req *b2types.DeleteBucketRequest) (*b2types.CreateBucketResponse, error) {
+	bs, err := s.Bucket.GetBucket(req.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	var bucket b2types.CreateBucketResponse
+	if err := json.Unmarshal(bs, &bucket); err != nil {
+		return nil, err
+	}
+	if err := s.Bucket.RemoveBucket(req.BucketID); err != nil {
+		return nil, err
+	}
+	return &bucket, nil
+}
+
+func (s *Server) GetUploadURL(ctx context.Context, req *b2types.GetUploadURLRequest) (*getUploadURLResponse, error) {
+	host, err := s.Account.UploadHost(req.BucketID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := getAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &getUploadURLResponse{
+		URI:      fmt.Sprintf("%s/b2api/v1/b2_upload_file/%s", host, req.BucketID),
+		BucketID: req.BucketID,
+		Token:    token,
+	}, nil
+}
+
+func (s *Server) StartLargeFile(ctx context.Context, req *b2types.StartLargeFileRequest) (*startLargeFileResponse, error) {
+	fileID := uuid.New().String()
+	resp := &startLargeFileResponse{
+		ID:          fileID,
+		Name:        req.Name,
+		BucketID:    req.BucketID,
+		ContentType: req.ContentType,
+		Info:        req.Info,
+		Timestamp:   millis(s.Clock.Now()),
+	}
+	bs, err := json.Marshal(resp)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.LargeFile.Start(req.BucketID, req.Name, fileID, bs); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (s *Server) GetUploadPartURL(ctx context.Context, req *b2types.GetUploadPartURLRequest) (*b2types.GetUploadPartURLResponse, error) {
+	host, err := s.Account.UploadPartHost(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	token, err := getAuth(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &b2types.GetUploadPartURLResponse{
+		ID:    req.ID,
+		URL:   fmt.Sprintf("%s/b2api/v1/b2_upload_part/%s", host, req.ID),
+		Token: token,
+	}, nil
+}
+
+func (s *Server) FinishLargeFile(ctx context.Context, req *b2types.FinishLargeFileRequest) (*b2types.FinishLargeFileResponse, error) {
+	parts, err := s.LargeFile.Parts(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !reflect.DeepEqual(parts, req.Hashes) {
+		return nil, badRequest(errors.New("sha1 array mismatch"))
+	}
+	if err := s.LargeFile.Finish(req.ID); err != nil {
+		return nil, err
+	}
+	resp := &b2types.FinishLargeFileResponse{
+		FileID: req.ID,
+		Action: "upload",
+		SHA1:   "none",
+	}
+	bs, err := s.LargeFile.Get(req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(bs) > 0 {
+		var start startLargeFileResponse
+		if err := json.Unmarshal(bs, &start); err != nil {
+			return nil, err
+		}
+		resp.Name = start.Name
+		resp.ContentType = start.ContentType
+		resp.Info = start.Info
+		resp.Timestamp = start.Timestamp
+	}
+	return resp, nil
+}
+
+func (s *Server) ListFileVersions(ctx context.Context, req *b2types.ListFileVersionsRequest) (*b2types.ListFileVersionsResponse, error) {
+	return &b2types.ListFileVersionsResponse{}, nil
+}
+
+type objTuple struct {
+	name, version string
+}
+
+type ListManager interface {
+	// NextN returns the next n objects, sorted by lexicographical order by name,
+	// beginning at and including, if it exists, fileName.  If withPrefix is not
+	// empty, it only returns names that begin with that prefix.  If skipPrefix
+	// is not empty, then the no files with that prefix are returned.  If the two
+	// conflict, skipPrefix wins (i.e., do not return the entry).
+	//
+	// If fewer than n entries are returned, this signifies that no more names
+	// exist that meet these criteria.
+	NextN(bucketID, fileName, withPrefix, skipPrefix string, n int) ([]VersionedObject, error)
+}
+
+type VersionedObject interface {
+	Name() string
+	NextNVersions(begin string, n int) ([]string, error)
+}
+
+func getDirNames(lm ListManager, bucket, name, prefix, delim string, n int) ([]string, error) {
+	var sfx string
+	var out []string
+	for n > 0 {
+		vo, err := lm.NextN(bucket, name, prefix, sfx, 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(vo) == 0 {
+			return out, nil
+		}
+		v := vo[0]
+		name = v.Name()
+		suffix := name[len(prefix):]
+		i := strings.Index(suffix, delim)
+		if i < 0 {
+			sfx = ""
+			out = append(out, name)
+			name += "\000"
+			n--
+			continue
+		}
+		sfx = v.Name()[:len(prefix)+i+1]
+		out = append(out, sfx)
+		n--
+	}
+	return out, nil
+}
+
+//func getNextObjects(lm ListManager, bucket, name, prefix, delimiter string, n int) ([]VersionedObject, error) {
+//	if delimiter == "" {
+//		return lm.NextN(bucket, name, prefix, "", n)
+//	}
+//	afterPfx := strings.TrimPrefix(name, prefix)
+//	i := strings.Index(afterPfx, delimiter)
+//	if i == 0 {
+//
+//	}
+//	if i < 0 {
+//		return lm.NextN(bucket, name, prefix, "", n)
+//	}
+//	skipPfx := name[:len(prefix)+i]
+//	// TO
+//}
+//
+//func listFileVersions(lm ListManager, bucket, name, version, prefix, delimiter string, n int) ([]objTuple, error) {
+//	var tups []objTuple
+//	var got int
+//	for {
+//		objs, err := getNextObjects(bucket, name, prefix, delimiter, n-got)
+//		if err != nil {
+//			return nil, err
+//		}
+//		if len(objs) == 0 {
+//			break
+//		}
+//		for _, o := range objs {
+//			var begin string
+//			if len(tups) == 0 {
+//				begin = version
+//			}
+//			vers, err := lm.NextNVersions(begin, n-got)
+//			if err != nil {
+//				return nil, err
+//			}
+//			got += len(vers)
+//			for _, ver := range vers {
+//				tups = append(tups, objTuple{name: o.Name(), version: ver})
+//			}
+//			if got >= n {
+//				return tups[:n], nil
+//			}
+//		}
+//	}
+//	return tups, nil
+//}
@@ -0,0 +1,432 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pyre
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/internal/b2types"
+)
+
+type testVersionedObject struct {
+	name     string
+	versions []string
+}
+
+func (t testVersionedObject) Name() string { return t.name }
+
+func (t testVersionedObject) NextNVersions(b string, n int) ([]string, error) {
+	var out []string
+	var seen bool
+	if b == "" {
+		seen = true
+	}
+	for _, v := range t.versions {
+		if b == v {
+			seen = true
+		}
+		if !seen {
+			continue
+		}
+		if len(out) >= n {
+			return out, nil
+		}
+		out = append(out, v)
+	}
+	return out, nil
+}
+
+type testListManager struct {
+	objs map[string][]string
+	m    sync.Mutex
+}
+
+func (t *testListManager) NextN(b, fn, pfx, spfx string, n int) ([]VersionedObject, error) {
+	t.m.Lock()
+	defer t.m.Unlock()
+
+	var out []VersionedObject
+	var keys []string
+	for k := range t.objs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		if k < fn {
+			continue
+		}
+		if !strings.HasPrefix(k, pfx) {
+			continue
+		}
+		if spfx != "" && strings.HasPrefix(k, spfx) {
+			continue
+		}
+		out = append(out, testVersionedObject{name: k, versions: t.objs[k]})
+		n--
+		if n <= 0 {
+			return out, nil
+		}
+	}
+	return out, nil
+}
+
+func TestGetDirNames(t *testing.T) {
+	table := []struct {
+		lm    ListManager
+		name  string
+		pfx   string
+		delim string
+		num   int
+		want  []string
+	}{
+		{
+			lm: &testListManager{
+				objs: map[string][]string{
+					"/usr/local/etc/foo/bar": {"a"},
+					"/usr/local/etc/foo/baz": {"a"},
+					"/usr/local/etc/foo":     {"a"},
+					"/usr/local/etc/fool":    {"a"},
+				},
+			},
+			num:   2,
+			pfx:   "/usr/local/etc/",
+			delim: "/",
+			want:  []string{"/usr/local/etc/foo", "/usr/local/etc/foo/"},
+		},
+		{
+			lm: &testListManager{
+				objs: map[string][]string{
+					"/usr/local/etc/foo/bar": {"a"},
+					"/usr/local/etc/foo/baz": {"a"},
+					"/usr/local/etc/foo":     {"a"},
+					"/usr/local/etc/fool":    {"a"},
+					"/usr/local/etc/bar":     {"a"},
+				},
+			},
+			num:   4,
+			pfx:   "/usr/local/etc/",
+			delim: "/",
+			want:  []string{"/usr/local/etc/bar", "/usr/local/etc/foo", "/usr/local/etc/foo/", "/usr/local/etc/fool"},
+		},
+	}
+
+	for _, e := range table {
+		got, err := getDirNames(e.lm, "", e.name, e.pfx, e.delim, e.num)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(got, e.want) {
+			t.Errorf("getDirNames(%v, %q, %q, %q, %d): got %v, want %v", e.lm, e.name, e.pfx, e.delim, e.num, got, e.want)
+		}
+	}
+}
+
+type testAccount struct{}
+
+func (testAccount) Authorize(acct, key string) (string, error) {
+	if key != "secret" {
+		return "", errors.New("bad key")
+	}
+	return "token", nil
+}
+func (testAccount) CheckCreds(token, api string) error           { return nil }
+func (testAccount) APIRoot(acct string) string                   { return "http://api" }
+func (testAccount) DownloadRoot(acct string) string              { return "http://download" }
+func (testAccount) UploadPartHost(fileID string) (string, error) { return "http://upload", nil }
+func (testAccount) UploadHost(id string) (string, error)         { return "http://upload", nil }
+func (testAccount) Sizes(acct string) (int32, int32)             { return 100, 5 }
+
+type testBuckets struct {
+	b map[string][]byte
+}
+
+func (t *testBuckets) AddBucket(id, name string, bs []byte) error {
+	t.b[id] = bs
+	return nil
+}
+func (t *testBuckets) RemoveBucket(id string) error                     { delete(t.b, id); return nil }
+func (t *testBuckets) UpdateBucket(id string, rev int, bs []byte) error { return nil }
+func (t *testBuckets) GetBucket(id string) ([]byte, error)              { return t.b[id], nil }
+func (t *testBuckets) ListBuckets(acct string) ([][]byte, error) {
+	var bss [][]byte
+	for _, bs := range t.b {
+		bss = append(bss, bs)
+	}
+	return bss, nil
+}
+
+func TestAPIHandlers(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := &Server{
+		Account: testAccount{},
+		Bucket:  &testBuckets{b: make(map[string][]byte)},
+	}
+	if err := RegisterServerOnMux(context.Background(), srv, mux); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method, api, auth string, req, resp interface{}) int {
+		var body bytes.Buffer
+		if req != nil {
+			if err := json.NewEncoder(&body).Encode(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r := httptest.NewRequest(method, apiPrefix+api, &body)
+		r.Header.Set("Authorization", auth)
+		rw := httptest.NewRecorder()
+		mux.ServeHTTP(rw, r)
+		if err := json.NewDecoder(rw.Body).Decode(resp); err != nil {
+			t.Fatalf("%s: decoding response: %v", api, err)
+		}
+		return rw.Code
+	}
+
+	var auth b2types.AuthorizeAccountResponse
+	if code := call("GET", "b2_authorize_account", "Basic YWNjdDpzZWNyZXQ=", nil, &auth); code != 200 {
+		t.Fatalf("b2_authorize_account: got status %d", code)
+	}
+	want := b2types.AuthorizeAccountResponse{
+		AccountID:      "acct",
+		AuthToken:      "token",
+		URI:            "http://api",
+		DownloadURI:    "http://download",
+		MinPartSize:    100,
+		PartSize:       100,
+		AbsMinPartSize: 5,
+	}
+	if !reflect.DeepEqual(auth, want) {
+		t.Errorf("b2_authorize_account: got %+v, want %+v", auth, want)
+	}
+
+	var errMsg b2types.ErrorMessage
+	if code := call("GET", "b2_authorize_account", "Basic YWNjdDp3cm9uZw==", nil, &errMsg); code != 401 {
+		t.Errorf("b2_authorize_account with bad key: got status %d, want 401", code)
+	}
+	if errMsg.Status != 401 || errMsg.Code != "unauthorized" {
+		t.Errorf("b2_authorize_account with bad key: got %+v", errMsg)
+	}
+
+	var bucket b2types.CreateBucketResponse
+	creq := &b2types.CreateBucketRequest{AccountID: "acct", Name: "bucket", Type: "allPrivate"}
+	if code := call("POST", "b2_create_bucket", "token", creq, &bucket); code != 200 {
+		t.Fatalf("b2_create_bucket: got status %d", code)
+	}
+	if bucket.BucketID == "" || bucket.Name != "bucket" || bucket.Type != "allPrivate" {
+		t.Errorf("b2_create_bucket: got %+v", bucket)
+	}
+
+	var list b2types.ListBucketsResponse
+	if code := call("POST", "b2_list_buckets", "token", &b2types.ListBucketsRequest{AccountID: "acct"}, &list); code != 200 {
+		t.Fatalf("b2_list_buckets: got status %d", code)
+	}
+	if len(list.Buckets) != 1 || !reflect.DeepEqual(list.Buckets[0], bucket) {
+		t.Errorf("b2_list_buckets: got %+v, want [%+v]", list.Buckets, bucket)
+	}
+
+	var url getUploadURLResponse
+	if code := call("POST", "b2_get_upload_url", "token", &b2types.GetUploadURLRequest{BucketID: bucket.BucketID}, &url); code != 200 {
+		t.Fatalf("b2_get_upload_url: got status %d", code)
+	}
+	if url.URI != "http://upload/b2api/v1/b2_upload_file/"+bucket.BucketID || url.Token != "token" {
+		t.Errorf("b2_get_upload_url: got %+v", url)
+	}
+
+	errMsg = b2types.ErrorMessage{}
+	if code := call("POST", "b2_list_buckets", "token", "not an object", &errMsg); code != 400 || errMsg.Code != "bad_request" {
+		t.Errorf("b2_list_buckets with bad body: got status %d, %+v", code, errMsg)
+	}
+}
+
+func TestMonitor(t *testing.T) {
+	mux := http.NewServeMux()
+	srv := &Server{
+		Account: testAccount{},
+		Bucket:  &testBuckets{b: make(map[string][]byte)},
+	}
+	if err := RegisterServerOnMux(context.Background(), srv, mux); err != nil {
+		t.Fatal(err)
+	}
+	var log bytes.Buffer
+	mon := &Monitor{
+		Log:     &log,
+		Account: func(token string) string { return "owner-of-" + token },
+	}
+	h := mon.Wrap(mux)
+
+	call := func(api, auth string, req interface{}) {
+		var body bytes.Buffer
+		if err := json.NewEncoder(&body).Encode(req); err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest("POST", apiPrefix+api, &body)
+		r.Header.Set("Authorization", auth)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	call("b2_authorize_account", "Basic YWNjdDpzZWNyZXQ=", struct{}{})
+	call("b2_create_bucket", "token", &b2types.CreateBucketRequest{AccountID: "acct", Name: "bucket"})
+	call("b2_get_upload_url", "token", &b2types.GetUploadURLRequest{BucketID: "bucket-id"})
+	call("b2_get_upload_url", "token", &b2types.GetUploadURLRequest{BucketID: "bucket-id"})
+	call("b2_list_buckets", "token", "not an object")
+
+	if got := mon.Count("b2_get_upload_url"); got != 2 {
+		t.Errorf("Count(b2_get_upload_url): got %d, want 2", got)
+	}
+	if got := mon.Count("b2_upload_file"); got != 0 {
+		t.Errorf("Count(b2_upload_file): got %d, want 0", got)
+	}
+
+	var recs []RequestRecord
+	dec := json.NewDecoder(&log)
+	for dec.More() {
+		var rec RequestRecord
+		if err := dec.Decode(&rec); err != nil {
+			t.Fatal(err)
+		}
+		recs = append(recs, rec)
+	}
+	want := []RequestRecord{
+		{Method: "b2_authorize_account", Account: "acct", Status: 200},
+		{Method: "b2_create_bucket", Account: "owner-of-token", Bucket: "bucket", Status: 200},
+		{Method: "b2_get_upload_url", Account: "owner-of-token", Bucket: "bucket-id", Status: 200},
+		{Method: "b2_get_upload_url", Account: "owner-of-token", Bucket: "bucket-id", Status: 200},
+		{Method: "b2_list_buckets", Account: "owner-of-token", Status: 400},
+	}
+	if len(recs) != len(want) {
+		t.Fatalf("got %d log records, want %d:\n%s", len(recs), len(want), log.String())
+	}
+	for i, rec := range recs {
+		if rec.Time.IsZero() || rec.LatencyMS < 0 {
+			t.Errorf("record %d: bad time or latency: %+v", i, rec)
+		}
+		rec.Time, rec.LatencyMS = time.Time{}, 0
+		if rec != want[i] {
+			t.Errorf("record %d: got %+v, want %+v", i, rec, want[i])
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	mon.ServeHTTP(rw, httptest.NewRequest("GET", "/metrics", nil))
+	metrics := rw.Body.String()
+	for _, line := range []string{
+		`pyre_requests_total{method="b2_get_upload_url",status="200"} 2`,
+		`pyre_requests_total{method="b2_list_buckets",status="400"} 1`,
+	} {
+		if !strings.Contains(metrics, line+"\n") {
+			t.Errorf("metrics: missing %q in:\n%s", line, metrics)
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	ok := http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {})
+	f := &Faults{}
+	h := f.Wrap(ok)
+	status := func(api string) (int, string) {
+		rw := httptest.NewRecorder()
+		h.ServeHTTP(rw, httptest.NewRequest("POST", apiPrefix+api, nil))
+		var msg b2types.ErrorMessage
+		json.NewDecoder(rw.Body).Decode(&msg)
+		return rw.Code, msg.Code
+	}
+
+	if code, _ := status("b2_list_buckets"); code != 200 {
+		t.Errorf("no faults: got %d, want 200", code)
+	}
+	if err := f.Set("", Fault{ErrorRate: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("b2_upload_file", Fault{ErrorRate: 1, Status: 408}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("b2_authorize_account", Fault{Exempt: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("b2_list_buckets", Fault{ErrorRate: 2}); err == nil {
+		t.Error("Set with error rate 2: got no error")
+	}
+	if err := f.Set("b2_list_buckets", Fault{Exempt: true, Latency: time.Second}); err == nil {
+		t.Error("Set of an exempt fault with latency: got no error")
+	}
+	for _, e := range []struct {
+		api, code string
+		status    int
+	}{
+		{api: "b2_list_buckets", status: 503, code: "service_unavailable"},
+		{api: "b2_upload_file", status: 408, code: "request_timeout"},
+		{api: "b2_authorize_account", status: 200},
+	} {
+		if status, code := status(e.api); status != e.status || code != e.code {
+			t.Errorf("%s: got %d %q, want %d %q", e.api, status, code, e.status, e.code)
+		}
+	}
+
+	// Injected latency gives way to the client's deadline.
+	f.Set("b2_upload_part", Fault{Latency: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("POST", apiPrefix+"b2_upload_part", nil).WithContext(ctx))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("injected latency outlived the request")
+	}
+
+	admin := func(method, body string) map[string]Fault {
+		rw := httptest.NewRecorder()
+		f.ServeHTTP(rw, httptest.NewRequest(method, "/admin/faults", strings.NewReader(body)))
+		if rw.Code != 200 {
+			t.Fatalf("%s %s: got %d: %s", method, body, rw.Code, rw.Body)
+		}
+		got := make(map[string]Fault)
+		if err := json.NewDecoder(rw.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+	got := admin("POST", `{"b2_upload_file": {"latency": "5ms", "jitter": "1ms"}, "": {}}`)
+	want := map[string]Fault{
+		"b2_upload_file":       {Latency: 5 * time.Millisecond, Jitter: time.Millisecond},
+		"b2_authorize_account": {Exempt: true},
+		"b2_upload_part":       {Latency: time.Hour},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("admin POST: got %v, want %v", got, want)
+	}
+	if got := admin("DELETE", ""); len(got) != 0 {
+		t.Errorf("admin DELETE: got %v", got)
+	}
+	rw := httptest.NewRecorder()
+	f.ServeHTTP(rw, httptest.NewRequest("POST", "/admin/faults", strings.NewReader(`{"b2_upload_file": {"latency": "soon"}}`)))
+	if rw.Code != 400 {
+		t.Errorf("admin POST with bad latency: got %d, want 400", rw.Code)
+	}
+}
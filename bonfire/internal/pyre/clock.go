@@ -0,0 +1,114 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pyre
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Clock is the time as the server sees it.  It keeps pace with the wall
+// clock, but can be set or moved forward, so that tests of anything that
+// expires need not wait for it.  A nil *Clock tells the real time.
+type Clock struct {
+	mu     sync.Mutex
+	offset time.Duration
+}
+
+// Now returns the current time on c.
+func (c *Clock) Now() time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Now().Add(c.offset)
+}
+
+// Set sets c to t, from which it continues to run.
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = time.Until(t)
+}
+
+// Advance moves c forward by d.
+func (c *Clock) Advance(d time.Duration) error {
+	if d < 0 {
+		return errors.New("the clock cannot go backwards")
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset += d
+	return nil
+}
+
+// Reset returns c to the real time.
+func (c *Clock) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.offset = 0
+}
+
+type clockJSON struct {
+	Now     *time.Time `json:"now,omitempty"`
+	Advance string     `json:"advance,omitempty"`
+	Offset  string     `json:"offset,omitempty"`
+}
+
+// ServeHTTP reports the time on c, and its offset from the real time, on
+// GET.  A POST of {"now": "2030-01-01T00:00:00Z"} sets the clock, and one of
+// {"advance": "24h"} moves it forward.  DELETE resets it to the real time.
+func (c *Clock) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var req clockJSON
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(rw, badRequest(err))
+			return
+		}
+		if req.Now != nil {
+			c.Set(*req.Now)
+		}
+		if req.Advance != "" {
+			d, err := time.ParseDuration(req.Advance)
+			if err == nil {
+				err = c.Advance(d)
+			}
+			if err != nil {
+				writeError(rw, badRequest(err))
+				return
+			}
+		}
+	case "DELETE":
+		c.Reset()
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	now := c.Now()
+	c.mu.Lock()
+	offset := c.offset
+	c.mu.Unlock()
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(clockJSON{Now: &now, Offset: offset.Round(time.Millisecond).String()}); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,243 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pyre
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A Fault describes trouble to inject into requests.
+type Fault struct {
+	// Latency is added to each request.
+	Latency time.Duration
+
+	// Jitter adds up to this much more latency, chosen uniformly.
+	Jitter time.Duration
+
+	// ErrorRate is the fraction, from 0 to 1, of requests that fail without
+	// reaching the server.
+	ErrorRate float64
+
+	// Status is the HTTP status of injected failures.  The default is 503.
+	Status int
+
+	// Exempt spares the method from the default Fault.  An exempt Fault may
+	// set nothing else.
+	Exempt bool
+}
+
+type faultJSON struct {
+	Latency   string  `json:"latency,omitempty"`
+	Jitter    string  `json:"jitter,omitempty"`
+	ErrorRate float64 `json:"errorRate,omitempty"`
+	Status    int     `json:"status,omitempty"`
+	Exempt    bool    `json:"exempt,omitempty"`
+}
+
+func (f Fault) MarshalJSON() ([]byte, error) {
+	fj := faultJSON{ErrorRate: f.ErrorRate, Status: f.Status, Exempt: f.Exempt}
+	if f.Latency != 0 {
+		fj.Latency = f.Latency.String()
+	}
+	if f.Jitter != 0 {
+		fj.Jitter = f.Jitter.String()
+	}
+	return json.Marshal(fj)
+}
+
+func (f *Fault) UnmarshalJSON(b []byte) error {
+	var fj faultJSON
+	if err := json.Unmarshal(b, &fj); err != nil {
+		return err
+	}
+	var nf Fault
+	var err error
+	if fj.Latency != "" {
+		if nf.Latency, err = time.ParseDuration(fj.Latency); err != nil {
+			return err
+		}
+	}
+	if fj.Jitter != "" {
+		if nf.Jitter, err = time.ParseDuration(fj.Jitter); err != nil {
+			return err
+		}
+	}
+	nf.ErrorRate = fj.ErrorRate
+	nf.Status = fj.Status
+	nf.Exempt = fj.Exempt
+	if err := nf.validate(); err != nil {
+		return err
+	}
+	*f = nf
+	return nil
+}
+
+func (f Fault) validate() error {
+	if f.Latency < 0 || f.Jitter < 0 {
+		return errors.New("latency and jitter must not be negative")
+	}
+	if f.ErrorRate < 0 || f.ErrorRate > 1 {
+		return fmt.Errorf("errorRate %v is not between 0 and 1", f.ErrorRate)
+	}
+	if f.Status != 0 && (f.Status < 400 || f.Status > 599) {
+		return fmt.Errorf("status %d is not an error", f.Status)
+	}
+	if f.Exempt && f != (Fault{Exempt: true}) {
+		return errors.New("an exempt fault may set nothing else")
+	}
+	return nil
+}
+
+// faultCodes are the B2 error codes that accompany injected statuses.
+var faultCodes = map[int]string{
+	408: "request_timeout",
+	429: "too_many_requests",
+	500: "internal_error",
+	503: "service_unavailable",
+}
+
+// Faults injects Faults into requests, by B2 method.  Methods without a
+// Fault of their own use the default Fault, which is set with the method "".
+// Faults may be changed while requests are served, either directly or over
+// HTTP with its ServeHTTP method.
+type Faults struct {
+	mu  sync.Mutex
+	m   map[string]Fault
+	rng *rand.Rand
+}
+
+// Set sets the Fault for method, such as "b2_upload_file".  The zero Fault
+// removes it.
+func (f *Faults) Set(method string, ft Fault) error {
+	if err := ft.validate(); err != nil {
+		return err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.m == nil {
+		f.m = make(map[string]Fault)
+	}
+	if ft == (Fault{}) {
+		delete(f.m, method)
+		return nil
+	}
+	f.m[method] = ft
+	return nil
+}
+
+// Get returns the Fault that applies to method.
+func (f *Faults) Get(method string) Fault {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ft, ok := f.m[method]; ok {
+		return ft
+	}
+	return f.m[""]
+}
+
+// roll returns the delay to add to a request, and the status with which to
+// fail it, or 0.
+func (f *Faults) roll(method string) (time.Duration, int) {
+	ft := f.Get(method)
+	if ft == (Fault{}) || ft.Exempt {
+		return 0, 0
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.rng == nil {
+		f.rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	d := ft.Latency
+	if ft.Jitter > 0 {
+		d += time.Duration(f.rng.Int63n(int64(ft.Jitter) + 1))
+	}
+	var status int
+	if ft.ErrorRate > 0 && f.rng.Float64() < ft.ErrorRate {
+		status = ft.Status
+		if status == 0 {
+			status = http.StatusServiceUnavailable
+		}
+	}
+	return d, status
+}
+
+// Wrap returns a handler that injects faults before serving requests with h.
+func (f *Faults) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		d, status := f.roll(apiMethod(r.URL.Path))
+		if d > 0 {
+			t := time.NewTimer(d)
+			select {
+			case <-t.C:
+			case <-r.Context().Done():
+				t.Stop()
+				return
+			}
+		}
+		if status != 0 {
+			code, ok := faultCodes[status]
+			if !ok {
+				code = "injected_failure"
+			}
+			writeError(rw, apiErr{status: status, code: code, err: errors.New("injected failure")})
+			return
+		}
+		h.ServeHTTP(rw, r)
+	})
+}
+
+// ServeHTTP reports the current faults, as a JSON object keyed by method, on
+// GET.  A POST of such an object sets those faults, and DELETE removes them
+// all.
+func (f *Faults) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET":
+	case "POST":
+		var set map[string]Fault
+		if err := json.NewDecoder(r.Body).Decode(&set); err != nil {
+			writeError(rw, badRequest(err))
+			return
+		}
+		for method, ft := range set {
+			if err := f.Set(method, ft); err != nil {
+				writeError(rw, badRequest(err))
+				return
+			}
+		}
+	case "DELETE":
+		f.mu.Lock()
+		f.m = nil
+		f.mu.Unlock()
+	default:
+		http.Error(rw, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	f.mu.Lock()
+	all := make(map[string]Fault)
+	for k, v := range f.m {
+		all[k] = v
+	}
+	f.mu.Unlock()
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(all); err != nil {
+		fmt.Println(err)
+	}
+}
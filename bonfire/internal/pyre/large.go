@@ -21,6 +21,8 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"github.com/kurin/blazer/internal/b2types"
 )
 
 const uploadFilePartPrefix = "/b2api/v1/b2_upload_part/"
@@ -33,21 +35,14 @@ type largeFileServer struct {
 	fm LargeFileManager
 }
 
-type uploadPartRequest struct {
-	ID   string `json:"fileId"`
-	Part int    `json:"partNumber"`
-	Size int64  `json:"contentLength"`
-	Hash string `json:"contentSha1"`
-}
-
-func parseUploadPartHeaders(r *http.Request) (uploadPartRequest, error) {
-	var ur uploadPartRequest
-	ur.Hash = r.Header.Get("X-Bz-Content-Sha1")
+func parseUploadPartHeaders(r *http.Request) (b2types.UploadPartResponse, error) {
+	var ur b2types.UploadPartResponse
+	ur.SHA1 = r.Header.Get("X-Bz-Content-Sha1")
 	part, err := strconv.ParseInt(r.Header.Get("X-Bz-Part-Number"), 10, 64)
 	if err != nil {
 		return ur, err
 	}
-	ur.Part = int(part)
+	ur.Number = int(part)
 	size, err := strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64)
 	if err != nil {
 		return ur, err
@@ -64,7 +59,7 @@ func (fs *largeFileServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
 		fmt.Println("oh no")
 		return
 	}
-	w, err := fs.fm.PartWriter(req.ID, req.Part)
+	w, err := fs.fm.PartWriter(req.ID, req.Number)
 	if err != nil {
 		http.Error(rw, err.Error(), 500)
 		fmt.Println("oh no")
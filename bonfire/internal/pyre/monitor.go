@@ -0,0 +1,205 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pyre
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Monitor records every request it sees.  It writes a line of JSON for
+// each request to Log, if set, and serves counts of requests by method and
+// status, in the Prometheus text format.
+type Monitor struct {
+	// Log receives one JSON object per request.
+	Log io.Writer
+
+	// Account, if set, returns the account that holds an auth token.
+	Account func(token string) string
+
+	mu     sync.Mutex
+	counts map[methodStatus]int64
+}
+
+type methodStatus struct {
+	method string
+	status int
+}
+
+// RequestRecord is the log entry for a single request.
+type RequestRecord struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Account   string    `json:"account,omitempty"`
+	Bucket    string    `json:"bucket,omitempty"`
+	Status    int       `json:"status"`
+	LatencyMS float64   `json:"latencyMs"`
+}
+
+// Wrap returns a handler that records each request before serving it with h.
+func (m *Monitor) Wrap(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rec := RequestRecord{
+			Time:    time.Now(),
+			Method:  apiMethod(r.URL.Path),
+			Account: m.account(r),
+			Bucket:  bucketOf(r),
+		}
+		sw := &statusWriter{ResponseWriter: rw, status: http.StatusOK}
+		h.ServeHTTP(sw, r)
+		rec.Status = sw.status
+		rec.LatencyMS = float64(time.Since(rec.Time)) / float64(time.Millisecond)
+		m.record(rec)
+	})
+}
+
+func (m *Monitor) record(rec RequestRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.counts == nil {
+		m.counts = make(map[methodStatus]int64)
+	}
+	m.counts[methodStatus{method: rec.Method, status: rec.Status}]++
+	if m.Log == nil {
+		return
+	}
+	if err := json.NewEncoder(m.Log).Encode(rec); err != nil {
+		fmt.Println(err)
+	}
+}
+
+// Count returns the number of requests for the given method, such as
+// "b2_upload_file", regardless of status.
+func (m *Monitor) Count(method string) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var n int64
+	for k, v := range m.counts {
+		if k.method == method {
+			n += v
+		}
+	}
+	return n
+}
+
+// ServeHTTP serves the request counts.
+func (m *Monitor) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	var keys []methodStatus
+	for k := range m.counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].status < keys[j].status
+	})
+	buf := &bytes.Buffer{}
+	fmt.Fprintln(buf, "# TYPE pyre_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(buf, "pyre_requests_total{method=%q,status=\"%d\"} %d\n", k.method, k.status, m.counts[k])
+	}
+	m.mu.Unlock()
+	rw.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	rw.Write(buf.Bytes())
+}
+
+func (m *Monitor) account(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if strings.HasPrefix(auth, "Basic ") {
+		bs, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+		if err != nil {
+			return ""
+		}
+		return strings.SplitN(string(bs), ":", 2)[0]
+	}
+	if m.Account == nil || auth == "" {
+		return ""
+	}
+	return m.Account(auth)
+}
+
+// apiMethod returns the B2 call that path serves.
+func apiMethod(path string) string {
+	if strings.HasPrefix(path, "/file/") {
+		return "b2_download_file_by_name"
+	}
+	if !strings.HasPrefix(path, apiPrefix) {
+		return "unknown"
+	}
+	return strings.SplitN(strings.TrimPrefix(path, apiPrefix), "/", 2)[0]
+}
+
+// bucketOf returns the bucket ID or name that r refers to, if any.  JSON
+// requests are read and then restored, so that the handler sees them intact.
+func bucketOf(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, uploadFilePrefix):
+		return strings.TrimPrefix(r.URL.Path, uploadFilePrefix)
+	case strings.HasPrefix(r.URL.Path, "/file/"):
+		return strings.SplitN(strings.TrimPrefix(r.URL.Path, "/file/"), "/", 2)[0]
+	case strings.HasPrefix(r.URL.Path, uploadFilePartPrefix):
+		return ""
+	}
+	if r.Method != "POST" || r.Body == nil {
+		return ""
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+	var req struct {
+		BucketID   string `json:"bucketId"`
+		BucketName string `json:"bucketName"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	if req.BucketID != "" {
+		return req.BucketID
+	}
+	return req.BucketName
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wrote {
+		sw.status = status
+		sw.wrote = true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(p []byte) (int, error) {
+	sw.wrote = true
+	return sw.ResponseWriter.Write(p)
+}
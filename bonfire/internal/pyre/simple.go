@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -47,7 +48,11 @@ type uploadRequest struct {
 
 func parseUploadHeaders(r *http.Request) (*uploadRequest, error) {
 	ur := &uploadRequest{info: make(map[string]string)}
-	ur.name = r.Header.Get("X-Bz-File-Name")
+	name, err := url.QueryUnescape(r.Header.Get("X-Bz-File-Name"))
+	if err != nil {
+		return nil, err
+	}
+	ur.name = name
 	ur.contentType = r.Header.Get("Content-Type")
 	ur.sha1 = r.Header.Get("X-Bz-Content-Sha1")
 	size, err := strconv.ParseInt(r.Header.Get("Content-Length"), 10, 64)
@@ -56,11 +61,15 @@ func parseUploadHeaders(r *http.Request) (*uploadRequest, error) {
 	}
 	ur.size = size
 	for k := range r.Header {
-		if !strings.HasPrefix("X-Bz-Info-", k) {
+		if !strings.HasPrefix(k, "X-Bz-Info-") {
 			continue
 		}
-		name := strings.TrimPrefix("X-Bz-Info-", k)
-		ur.info[name] = r.Header.Get(k)
+		name := strings.TrimPrefix(k, "X-Bz-Info-")
+		val, err := url.QueryUnescape(r.Header.Get(k))
+		if err != nil {
+			return nil, err
+		}
+		ur.info[name] = val
 	}
 	ur.bucket = strings.TrimPrefix(r.URL.Path, uploadFilePrefix)
 	return ur, nil
@@ -0,0 +1,113 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bonfire
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// LayoutVersion is the version of the on-disk layout written by this
+// version of bonfire.
+const LayoutVersion = 1
+
+// versionFile holds the layout version at the root of an FS.  Bucket and
+// file IDs never begin with a dot, so it cannot collide with stored data.
+const versionFile = ".layout-version"
+
+// A Migration upgrades the data directory at dir by one layout version.
+type Migration func(dir string) error
+
+// migrations[i] upgrades a directory from version i to version i+1.  Version
+// 0 is the unversioned layout written before versions were recorded; it is
+// identical to version 1, which only adds the version file.
+var migrations = []Migration{
+	func(string) error { return nil },
+}
+
+// Version returns the layout version of f.  An empty or missing directory
+// reports LayoutVersion, since it can be used as is; a directory holding data
+// but no version file reports 0.
+func (f FS) Version() (int, error) {
+	data, err := ioutil.ReadFile(filepath.Join(string(f), versionFile))
+	if err == nil {
+		v, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return 0, fmt.Errorf("bonfire: %s: bad layout version %q", f, data)
+		}
+		return v, nil
+	}
+	if !os.IsNotExist(err) {
+		return 0, err
+	}
+	fis, err := ioutil.ReadDir(string(f))
+	if os.IsNotExist(err) || err == nil && len(fis) == 0 {
+		return LayoutVersion, nil
+	}
+	return 0, err
+}
+
+// Init prepares f for use, creating it if necessary.  It returns an error if
+// f was written by a different version of bonfire and must be migrated first.
+func (f FS) Init() error {
+	v, err := f.Version()
+	if err != nil {
+		return err
+	}
+	switch {
+	case v < LayoutVersion:
+		return fmt.Errorf("bonfire: %s has layout version %d, want %d; run \"bonfire admin migrate\"", f, v, LayoutVersion)
+	case v > LayoutVersion:
+		return fmt.Errorf("bonfire: %s has layout version %d, which is newer than this bonfire supports (%d)", f, v, LayoutVersion)
+	}
+	return f.setVersion(v)
+}
+
+// Migrate upgrades f to LayoutVersion, one version at a time.  The version is
+// recorded after each step, so an interrupted migration resumes where it
+// stopped.
+func (f FS) Migrate() error {
+	v, err := f.Version()
+	if err != nil {
+		return err
+	}
+	if v > LayoutVersion {
+		return fmt.Errorf("bonfire: %s has layout version %d, which is newer than this bonfire supports (%d)", f, v, LayoutVersion)
+	}
+	for ; v < LayoutVersion; v++ {
+		if err := migrations[v](string(f)); err != nil {
+			return fmt.Errorf("bonfire: migrating %s from version %d: %v", f, v, err)
+		}
+		if err := f.setVersion(v + 1); err != nil {
+			return err
+		}
+	}
+	return f.setVersion(v)
+}
+
+func (f FS) setVersion(v int) error {
+	if err := os.MkdirAll(string(f), 0755); err != nil {
+		return err
+	}
+	tmp := filepath.Join(string(f), versionFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(strconv.Itoa(v)+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(string(f), versionFile))
+}
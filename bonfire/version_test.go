@@ -0,0 +1,74 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bonfire
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayoutVersion(t *testing.T) {
+	if len(migrations) != LayoutVersion {
+		t.Fatalf("%d migrations for layout version %d", len(migrations), LayoutVersion)
+	}
+
+	dir, err := ioutil.TempDir("", "bonfire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A fresh directory is stamped with the current version.
+	fresh := FS(filepath.Join(dir, "fresh"))
+	if err := fresh.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := fresh.Version(); err != nil || v != LayoutVersion {
+		t.Errorf("fresh: got version %d, %v; want %d", v, err, LayoutVersion)
+	}
+
+	// Data written without a version must be migrated before use.
+	old := FS(filepath.Join(dir, "old"))
+	w, err := old.Writer("bucket", "name", "id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if v, err := old.Version(); err != nil || v != 0 {
+		t.Errorf("old: got version %d, %v; want 0", v, err)
+	}
+	if err := old.Init(); err == nil {
+		t.Error("old: Init succeeded without migrating")
+	}
+	if err := old.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Init(); err != nil {
+		t.Errorf("old: Init after migrating: %v", err)
+	}
+
+	// Directories from a newer bonfire are left alone.
+	if err := old.setVersion(LayoutVersion + 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := old.Init(); err == nil {
+		t.Error("new: Init succeeded")
+	}
+	if err := old.Migrate(); err == nil {
+		t.Error("new: Migrate succeeded")
+	}
+}
@@ -0,0 +1,5 @@
+module github.com/kurin/blazer
+
+go 1.18
+
+require github.com/google/subcommands v1.2.0
@@ -68,7 +68,7 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
-var _dataStatusHtml = []byte("\x1f\x8b\x08\x00\x00\x09\x6e\x88\x00\xff\xd4\x93\x41\x6f\xe3\x20\x10\x85\xef\xf9\x15\xb3\x56\x8e\x51\x90\x73\x5c\x4d\xb8\xec\xee\x79\xa3\xaa\x52\xd5\x23\x36\xa3\x60\x09\x43\x84\x71\x9a\xc8\xe2\xbf\x57\x18\x83\xa3\xb6\x87\x5e\x7b\xf2\x98\xf7\xe6\xf1\xbe\x03\xf8\xeb\xef\xff\x3f\xcf\xaf\xa7\x7f\xa0\x7c\xaf\xf9\x06\xf3\x87\x84\xe4\x1b\x00\xf4\x9d\xd7\xc4\x9b\x03\xb4\xba\x23\xe3\x61\xf0\xc2\x8f\x03\xb2\x74\xbe\x41\x96\x9c\xd8\x58\x79\x8f\x0b\xd3\xb4\xed\xc9\x2b\x2b\x07\xf8\x7d\x84\x3c\xee\x43\x48\x9a\x1c\x9d\xf0\x9d\x35\xb3\xba\xfe\x14\xdd\x8b\x46\x53\xd4\xd2\x90\xce\x51\xd5\xbc\xb5\xa3\xf1\xd0\xdc\xa1\xb5\x92\x90\xa9\x3a\xb5\x8b\xae\x38\xc5\x65\x27\xcc\x99\x60\xb9\x3e\x66\xe4\x26\x73\x48\x74\xbb\x64\x8d\xa3\xe4\xa5\x69\x08\xc8\xbc\xcc\x52\xc9\xc9\xed\xe6\xa4\x52\x75\xc9\x5a\x43\x3a\x23\xe9\x06\x4b\xf1\x7c\x79\xf1\x7f\xcc\x26\x23\x73\x1b\x96\xeb\xac\xa7\xc8\x0a\x50\x64\x1e\x2f\xda\x0a\x39\x64\xda\x87\x6e\x46\xf4\xb4\x83\xed\x55\xe8\xd8\x6e\xff\xe2\x3a\x4f\xae\x70\xaa\x03\x9f\xa6\x64\x82\x58\x40\x1d\x3e\xc1\x75\x72\x07\xdb\x8b\xb3\xe7\x99\xee\x2a\xf4\xfe\xe4\xec\xd9\xd1\xb0\x02\x46\xb4\x36\x3a\x43\x00\xbc\x2c\x2a\x5c\x85\x1e\xe9\x58\x4d\xd3\xbc\x1d\x42\x05\xbd\xb8\x1d\xab\xba\xe2\xc8\xb2\x89\x63\xe3\x80\x7d\x05\xfd\x80\xaa\x6a\x2e\xed\x9b\xf9\x26\xe1\x13\x09\xf9\xa3\x08\x91\xa5\x17\x81\x2c\xbd\xa8\xf7\x00\x00\x00\xff\xff\xd4\xf0\x90\xb4\x69\x03\x00\x00")
+var _dataStatusHtml = []byte("\x1f\x8b\x08\x00\x00\x00\x00\x00\x02\xff\xd5\x54\x41\x4f\xeb\x30\x0c\xbe\xef\x57\x98\x8a\x77\x43\x2d\xe3\xf8\x94\xf5\x30\xe0\x0a\x13\x20\x3d\xbd\x63\xba\x18\x1a\xa9\x4d\xab\x34\x65\x4c\xa8\xff\x1d\xbb\x59\x9a\x32\x26\xc1\x95\x53\x1c\xfb\xb3\xfd\x7d\xb1\x5b\x71\x76\x73\x7f\xfd\xf4\x7f\x73\x0b\xa5\xab\xab\x7c\x21\xc2\x81\x52\xe5\x0b\x00\xe1\xb4\xab\x30\x2f\xae\x60\x5b\x69\x34\x0e\x3a\x27\x5d\xdf\x89\xcc\xfb\x17\x22\xf3\x48\x51\x34\x6a\xcf\x09\xef\xef\xe7\x35\xba\xb2\x51\x1d\xfc\x5d\x41\x30\xd3\x61\xf0\x31\xd5\x5b\xe9\x74\x63\xc6\x68\xbc\x4c\x71\x27\x8b\x0a\x39\xe6\x0d\xef\x17\xe5\x32\xdf\x36\x3d\x75\x2f\xf6\xb0\x6d\x14\x52\xd7\xa5\x67\xc7\x28\xb6\x38\xd9\x4a\xf3\x82\x70\x68\xcf\x35\x02\x93\xb1\x08\xa3\xad\x87\xb2\xa9\xf2\x89\xe9\x30\x90\x1c\x15\x42\x53\x9d\xc0\x6e\xac\x34\x51\x3d\xd4\x8a\x45\xb4\x51\xf8\x06\x07\xe2\xa1\xf9\x84\x3f\xae\x8d\x46\x05\x36\x59\xa0\x13\xbd\xe4\x0b\x82\x58\x73\xdf\x56\x8d\x54\x5d\x50\x3b\xe3\x66\x64\x8d\x17\x70\xfe\x2a\x2b\x66\x97\xfe\xb3\xda\xa1\x9d\x74\x96\x57\xc4\xcb\x83\x80\x09\xd0\xfd\x58\x9c\x56\x94\xde\xda\xe6\x65\x54\x47\x75\xd2\x0d\x5d\x2c\x76\x51\x20\x4b\xdb\x32\x72\x18\x40\xb4\x87\x28\x10\xb4\xc7\x55\x42\x8f\xc7\xae\x61\x48\xa0\x96\x6f\xab\x64\x99\xe4\x22\x0b\xa0\x5c\x14\x16\xb2\x53\xa2\xf9\xb6\xd3\xae\xf4\x2d\x1f\x69\x97\x62\x3f\xd1\x12\xeb\x0a\x0d\xa4\x1b\x69\xd9\x0f\x2d\x9f\x17\x94\x92\xae\xf7\x0e\xd9\x53\xf0\x39\x7a\x1e\xd0\x59\x3d\xfa\xac\xb7\xd8\xdb\x5a\x6d\xdc\x33\x24\x7f\xd2\xcb\xe7\x04\xd2\xb5\x34\x6a\xa7\x95\x2b\x09\xb5\xce\xe8\x1d\xdb\x38\xff\xb8\x38\xf3\x77\x09\xad\x67\x33\xb6\x11\x15\x46\x9e\xde\xf5\x75\x81\xf6\xf3\x6c\x67\xe1\x9b\x93\xc3\x9f\x01\xbe\x90\x3f\x8d\xfb\x4e\xce\x3c\x27\xae\xd3\xd7\x37\x9f\xad\xd5\x71\x70\xb6\x7b\xb4\x64\xaa\xd9\x99\x1f\xae\xdc\x03\x7d\xf6\xbf\x62\xe5\x82\x2d\x32\xff\x8b\x22\x6e\xe3\x2f\xee\x03\x53\x5b\x8b\x0b\xfa\x04\x00\x00")
 
 func dataStatusHtmlBytes() ([]byte, error) {
 	return bindataRead(
@@ -83,7 +83,7 @@ func dataStatusHtml() (*asset, error) {
 		return nil, err
 	}
 
-	info := bindataFileInfo{name: "data/status.html", size: 873, mode: os.FileMode(436), modTime: time.Unix(1520578750, 0)}
+	info := bindataFileInfo{name: "data/status.html", size: 1274, mode: os.FileMode(436), modTime: time.Unix(1520578750, 0)}
 	a := &asset{bytes: bytes, info: info}
 	return a, nil
 }
@@ -15,8 +15,16 @@
 // Package b2types implements internal types common to the B2 API.
 package b2types
 
-// You know what would be amazing?  If I could autogen this from like a JSON
-// file.  Wouldn't that be amazing?  That would be amazing.
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// Every type here is the body of a request to or a response from some B2 API
+// call, or is part of one.  Calls, in schema.go, lists which is which, and
+// the tests check each type against golden JSON in testdata.  New fields must
+// be added to the golden JSON as well.
 
 const (
 	V1api = "/b2api/v1/"
@@ -46,9 +54,66 @@ type Allowance struct {
 }
 
 type LifecycleRule struct {
-	DaysHiddenUntilDeleted int    `json:"daysFromHidingToDeleting,omitempty"`
-	DaysNewUntilHidden     int    `json:"daysFromUploadingToHiding,omitempty"`
-	Prefix                 string `json:"fileNamePrefix"`
+	DaysHiddenUntilDeleted      int    `json:"daysFromHidingToDeleting,omitempty"`
+	DaysNewUntilHidden          int    `json:"daysFromUploadingToHiding,omitempty"`
+	DaysUnfinishedUntilCanceled int    `json:"daysFromStartingToCancelingUnfinishedLargeFiles,omitempty"`
+	Prefix                      string `json:"fileNamePrefix"`
+
+	// Extra holds the fields of the rule that are not known here, so that
+	// rules that are read and written back keep them.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// lifecycleRule is LifecycleRule without its JSON methods.
+type lifecycleRule LifecycleRule
+
+func (r *LifecycleRule) UnmarshalJSON(data []byte) error {
+	extra, err := decodeExtra(data, (*lifecycleRule)(r))
+	r.Extra = extra
+	return err
+}
+
+func (r LifecycleRule) MarshalJSON() ([]byte, error) {
+	return encodeExtra(lifecycleRule(r), r.Extra)
+}
+
+// decodeExtra decodes data into v, a pointer to a struct without JSON methods
+// of its own, and returns the fields of data that v's type does not know.
+func decodeExtra(data []byte, v interface{}) (map[string]json.RawMessage, error) {
+	if err := json.Unmarshal(data, v); err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	rt := reflect.TypeOf(v).Elem()
+	for i := 0; i < rt.NumField(); i++ {
+		delete(fields, strings.Split(rt.Field(i).Tag.Get("json"), ",")[0])
+	}
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	return fields, nil
+}
+
+// encodeExtra encodes v, a struct without JSON methods of its own, along with
+// the fields of extra that v does not set itself.
+func encodeExtra(v interface{}, extra map[string]json.RawMessage) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		if _, ok := fields[k]; !ok {
+			fields[k] = v
+		}
+	}
+	return json.Marshal(fields)
 }
 
 type CreateBucketRequest struct {
@@ -66,6 +131,22 @@ type CreateBucketResponse struct {
 	Info           map[string]string `json:"bucketInfo"`
 	LifecycleRules []LifecycleRule   `json:"lifecycleRules"`
 	Revision       int               `json:"revision"`
+
+	// Extra holds the fields of the bucket that are not known here.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// bucketResponse is CreateBucketResponse without its JSON methods.
+type bucketResponse CreateBucketResponse
+
+func (b *CreateBucketResponse) UnmarshalJSON(data []byte) error {
+	extra, err := decodeExtra(data, (*bucketResponse)(b))
+	b.Extra = extra
+	return err
+}
+
+func (b CreateBucketResponse) MarshalJSON() ([]byte, error) {
+	return encodeExtra(bucketResponse(b), b.Extra)
 }
 
 type DeleteBucketRequest struct {
@@ -90,10 +171,35 @@ type UpdateBucketRequest struct {
 	Info           map[string]string `json:"bucketInfo,omitempty"`
 	LifecycleRules []LifecycleRule   `json:"lifecycleRules,omitempty"`
 	IfRevisionIs   int               `json:"ifRevisionIs,omitempty"`
+
+	// Extra holds settings of the bucket that are not known here, to be
+	// sent as they are.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// updateBucketRequest is UpdateBucketRequest without its JSON methods.
+type updateBucketRequest UpdateBucketRequest
+
+func (b *UpdateBucketRequest) UnmarshalJSON(data []byte) error {
+	extra, err := decodeExtra(data, (*updateBucketRequest)(b))
+	b.Extra = extra
+	return err
+}
+
+func (b UpdateBucketRequest) MarshalJSON() ([]byte, error) {
+	return encodeExtra(updateBucketRequest(b), b.Extra)
 }
 
 type UpdateBucketResponse CreateBucketResponse
 
+func (b *UpdateBucketResponse) UnmarshalJSON(data []byte) error {
+	return (*CreateBucketResponse)(b).UnmarshalJSON(data)
+}
+
+func (b UpdateBucketResponse) MarshalJSON() ([]byte, error) {
+	return CreateBucketResponse(b).MarshalJSON()
+}
+
 type GetUploadURLRequest struct {
 	BucketID string `json:"bucketId"`
 }
@@ -134,32 +240,58 @@ type ListPartsRequest struct {
 type ListPartsResponse struct {
 	Next  int `json:"nextPartNumber"`
 	Parts []struct {
-		ID     string `json:"fileId"`
-		Number int    `json:"partNumber"`
-		SHA1   string `json:"contentSha1"`
-		Size   int64  `json:"contentLength"`
+		ID        string `json:"fileId"`
+		Number    int    `json:"partNumber"`
+		SHA1      string `json:"contentSha1"`
+		Size      int64  `json:"contentLength"`
+		Timestamp int64  `json:"uploadTimestamp"`
 	} `json:"parts"`
 }
 
-type getUploadPartURLRequest struct {
+type GetUploadPartURLRequest struct {
 	ID string `json:"fileId"`
 }
 
-type getUploadPartURLResponse struct {
+type GetUploadPartURLResponse struct {
+	ID    string `json:"fileId"`
 	URL   string `json:"uploadUrl"`
 	Token string `json:"authorizationToken"`
 }
 
+type UploadPartResponse struct {
+	ID     string `json:"fileId"`
+	Number int    `json:"partNumber"`
+	Size   int64  `json:"contentLength"`
+	SHA1   string `json:"contentSha1"`
+}
+
+type CopyPartRequest struct {
+	SourceID    string `json:"sourceFileId"`
+	LargeFileID string `json:"largeFileId"`
+	Number      int    `json:"partNumber"`
+	Range       string `json:"range,omitempty"`
+}
+
+type CopyPartResponse UploadPartResponse
+
 type FinishLargeFileRequest struct {
 	ID     string   `json:"fileId"`
 	Hashes []string `json:"partSha1Array"`
 }
 
 type FinishLargeFileResponse struct {
-	Name      string `json:"fileName"`
-	FileID    string `json:"fileId"`
-	Timestamp int64  `json:"uploadTimestamp"`
-	Action    string `json:"action"`
+	Name        string            `json:"fileName"`
+	FileID      string            `json:"fileId"`
+	Timestamp   int64             `json:"uploadTimestamp"`
+	Action      string            `json:"action"`
+	Size        int64             `json:"contentLength"`
+	SHA1        string            `json:"contentSha1"`
+	ContentType string            `json:"contentType"`
+	Info        map[string]string `json:"fileInfo"`
+
+	Retention  *FileRetention        `json:"fileRetention,omitempty"`
+	LegalHold  *LegalHold            `json:"legalHold,omitempty"`
+	Encryption *ServerSideEncryption `json:"serverSideEncryption,omitempty"`
 }
 
 type ListFileNamesRequest struct {
@@ -201,6 +333,18 @@ type HideFileResponse struct {
 	Action    string `json:"action"`
 }
 
+type CopyFileRequest struct {
+	SourceID          string            `json:"sourceFileId"`
+	DestBucketID      string            `json:"destinationBucketId,omitempty"`
+	Name              string            `json:"fileName"`
+	Range             string            `json:"range,omitempty"`
+	MetadataDirective string            `json:"metadataDirective,omitempty"`
+	ContentType       string            `json:"contentType,omitempty"`
+	Info              map[string]string `json:"fileInfo,omitempty"`
+}
+
+type CopyFileResponse GetFileInfoResponse
+
 type GetFileInfoRequest struct {
 	ID string `json:"fileId"`
 }
@@ -217,6 +361,33 @@ type GetFileInfoResponse struct {
 	Info        map[string]string `json:"fileInfo,omitempty"`
 	Action      string            `json:"action,omitempty"`
 	Timestamp   int64             `json:"uploadTimestamp,omitempty"`
+
+	Retention  *FileRetention        `json:"fileRetention,omitempty"`
+	LegalHold  *LegalHold            `json:"legalHold,omitempty"`
+	Encryption *ServerSideEncryption `json:"serverSideEncryption,omitempty"`
+}
+
+// FileRetention, LegalHold, and ServerSideEncryption are reported for each
+// file.  If the key in use cannot read a file's retention or legal hold,
+// Readable is false and Value is nil.
+type FileRetention struct {
+	Readable bool            `json:"isClientAuthorizedToRead"`
+	Value    *RetentionValue `json:"value"`
+}
+
+type RetentionValue struct {
+	Mode  string `json:"mode"`
+	Until int64  `json:"retainUntilTimestamp"`
+}
+
+type LegalHold struct {
+	Readable bool    `json:"isClientAuthorizedToRead"`
+	Value    *string `json:"value"`
+}
+
+type ServerSideEncryption struct {
+	Mode      string `json:"mode"`
+	Algorithm string `json:"algorithm"`
 }
 
 type GetDownloadAuthorizationRequest struct {
@@ -0,0 +1,54 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2types
+
+// A Call describes the JSON bodies of a B2 API call.  Request and Response
+// are zero values of the corresponding types, or nil if that direction has
+// no JSON body (for instance, because its data is sent in headers).
+type Call struct {
+	Name     string
+	Request  interface{}
+	Response interface{}
+}
+
+// Calls is the canonical list of B2 API calls used by the client and
+// implemented by the emulator.
+var Calls = []Call{
+	{Name: "b2_authorize_account", Response: AuthorizeAccountResponse{}},
+	{Name: "b2_create_bucket", Request: CreateBucketRequest{}, Response: CreateBucketResponse{}},
+	{Name: "b2_delete_bucket", Request: DeleteBucketRequest{}, Response: CreateBucketResponse{}},
+	{Name: "b2_update_bucket", Request: UpdateBucketRequest{}, Response: UpdateBucketResponse{}},
+	{Name: "b2_list_buckets", Request: ListBucketsRequest{}, Response: ListBucketsResponse{}},
+	{Name: "b2_get_upload_url", Request: GetUploadURLRequest{}, Response: GetUploadURLResponse{}},
+	{Name: "b2_upload_file", Response: UploadFileResponse{}},
+	{Name: "b2_delete_file_version", Request: DeleteFileVersionRequest{}},
+	{Name: "b2_start_large_file", Request: StartLargeFileRequest{}, Response: StartLargeFileResponse{}},
+	{Name: "b2_cancel_large_file", Request: CancelLargeFileRequest{}},
+	{Name: "b2_list_parts", Request: ListPartsRequest{}, Response: ListPartsResponse{}},
+	{Name: "b2_get_upload_part_url", Request: GetUploadPartURLRequest{}, Response: GetUploadPartURLResponse{}},
+	{Name: "b2_upload_part", Response: UploadPartResponse{}},
+	{Name: "b2_finish_large_file", Request: FinishLargeFileRequest{}, Response: FinishLargeFileResponse{}},
+	{Name: "b2_list_unfinished_large_files", Request: ListUnfinishedLargeFilesRequest{}, Response: ListUnfinishedLargeFilesResponse{}},
+	{Name: "b2_list_file_names", Request: ListFileNamesRequest{}, Response: ListFileNamesResponse{}},
+	{Name: "b2_list_file_versions", Request: ListFileVersionsRequest{}, Response: ListFileVersionsResponse{}},
+	{Name: "b2_get_download_authorization", Request: GetDownloadAuthorizationRequest{}, Response: GetDownloadAuthorizationResponse{}},
+	{Name: "b2_hide_file", Request: HideFileRequest{}, Response: HideFileResponse{}},
+	{Name: "b2_copy_file", Request: CopyFileRequest{}, Response: CopyFileResponse{}},
+	{Name: "b2_copy_part", Request: CopyPartRequest{}, Response: CopyPartResponse{}},
+	{Name: "b2_get_file_info", Request: GetFileInfoRequest{}, Response: GetFileInfoResponse{}},
+	{Name: "b2_create_key", Request: CreateKeyRequest{}, Response: CreateKeyResponse{}},
+	{Name: "b2_delete_key", Request: DeleteKeyRequest{}, Response: DeleteKeyResponse{}},
+	{Name: "b2_list_keys", Request: ListKeysRequest{}, Response: ListKeysResponse{}},
+}
@@ -0,0 +1,204 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package b2types
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"reflect"
+	"testing"
+)
+
+type golden struct {
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response"`
+}
+
+func readGolden(t *testing.T) map[string]golden {
+	t.Helper()
+	data, err := ioutil.ReadFile("testdata/calls.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := make(map[string]golden)
+	if err := json.Unmarshal(data, &g); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// roundTrip decodes data into a new value of v's type, rejecting unknown
+// fields, and checks that encoding it again yields the same JSON.
+func roundTrip(t *testing.T, name string, v interface{}, data json.RawMessage) {
+	t.Helper()
+	if v == nil {
+		if data != nil {
+			t.Errorf("%s: golden JSON given for a call with no body", name)
+		}
+		return
+	}
+	if data == nil {
+		t.Errorf("%s: no golden JSON for %T", name, v)
+		return
+	}
+	p := reflect.New(reflect.TypeOf(v))
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(p.Interface()); err != nil {
+		t.Errorf("%s: decoding into %T: %v", name, v, err)
+		return
+	}
+	out, err := json.Marshal(p.Interface())
+	if err != nil {
+		t.Errorf("%s: encoding %T: %v", name, v, err)
+		return
+	}
+	var want, got interface{}
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: %T does not round-trip:\ngot  %s\nwant %s", name, v, out, data)
+	}
+}
+
+func TestGoldenRoundTrip(t *testing.T) {
+	g := readGolden(t)
+	seen := map[string]bool{"error": true}
+	for _, c := range Calls {
+		seen[c.Name] = true
+		gc, ok := g[c.Name]
+		if !ok {
+			t.Errorf("%s: missing from testdata/calls.json", c.Name)
+			continue
+		}
+		roundTrip(t, c.Name+" request", c.Request, gc.Request)
+		roundTrip(t, c.Name+" response", c.Response, gc.Response)
+	}
+	roundTrip(t, "error", ErrorMessage{}, g["error"].Response)
+	for name := range g {
+		if !seen[name] {
+			t.Errorf("%s: golden JSON for a call not in Calls", name)
+		}
+	}
+}
+
+func TestAllTypesInSchema(t *testing.T) {
+	reached := make(map[string]bool)
+	var walk func(reflect.Type)
+	walk = func(rt reflect.Type) {
+		for rt.Kind() == reflect.Slice || rt.Kind() == reflect.Map || rt.Kind() == reflect.Ptr {
+			rt = rt.Elem()
+		}
+		if rt.Kind() != reflect.Struct || reached[rt.Name()] && rt.Name() != "" {
+			return
+		}
+		if rt.Name() != "" {
+			reached[rt.Name()] = true
+		}
+		for i := 0; i < rt.NumField(); i++ {
+			walk(rt.Field(i).Type)
+		}
+	}
+	walk(reflect.TypeOf(ErrorMessage{}))
+	for _, c := range Calls {
+		if c.Request != nil {
+			walk(reflect.TypeOf(c.Request))
+		}
+		if c.Response != nil {
+			walk(reflect.TypeOf(c.Response))
+		}
+	}
+
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "b2types.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Named types like UploadFileResponse share their underlying struct
+	// with another type, so a type is covered if either name is reached.
+	alias := make(map[string]string)
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			ts := s.(*ast.TypeSpec)
+			if id, ok := ts.Type.(*ast.Ident); ok {
+				alias[ts.Name.Name] = id.Name
+			}
+		}
+	}
+	for _, d := range f.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			name := s.(*ast.TypeSpec).Name.Name
+			if !ast.IsExported(name) || reached[name] {
+				continue
+			}
+			if _, ok := alias[name]; ok {
+				t.Errorf("%s is not used by any call in Calls", name)
+				continue
+			}
+			found := false
+			for a, u := range alias {
+				if u == name && reached[a] {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("%s is not used by any call in Calls", name)
+			}
+		}
+	}
+}
+
+func TestLifecycleRuleExtra(t *testing.T) {
+	data := []byte(`{"daysFromHidingToDeleting":1,"daysFromStartingToCancelingUnfinishedLargeFiles":2,"fileNamePrefix":"p/","daysFromSomethingNew":{"x":3}}`)
+	var r LifecycleRule
+	if err := json.Unmarshal(data, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.DaysHiddenUntilDeleted != 1 || r.DaysUnfinishedUntilCanceled != 2 || r.Prefix != "p/" {
+		t.Errorf("known fields: got %+v", r)
+	}
+	if len(r.Extra) != 1 || string(r.Extra["daysFromSomethingNew"]) != `{"x":3}` {
+		t.Errorf("Extra: got %q, want only daysFromSomethingNew", r.Extra)
+	}
+	r.DaysNewUntilHidden = 4
+	out, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got, want interface{}
+	if err := json.Unmarshal(out, &got); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal([]byte(`{"daysFromHidingToDeleting":1,"daysFromUploadingToHiding":4,"daysFromStartingToCancelingUnfinishedLargeFiles":2,"fileNamePrefix":"p/","daysFromSomethingNew":{"x":3}}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Marshal: got %s", out)
+	}
+}
@@ -15,18 +15,60 @@
 // Package blog implements a private logger, in the manner of glog, without
 // polluting the flag namespace or leaving files all over /tmp.
 //
-// It has almost no features, and a bunch of global state.
+// Each client may carry its own Logger, which controls verbosity, sampling,
+// and how request and response data are redacted before they are logged.
+// Code without a Logger uses the default, whose level is read from the
+// B2_LOG_LEVEL environment variable.
 package blog
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
+	"net/textproto"
 	"os"
+	"regexp"
 	"strconv"
+	"strings"
 )
 
-var level int32
+// DefaultMaxBody is the number of bytes of a request or response body that
+// are logged when a Logger does not set MaxBody.
+const DefaultMaxBody = 1024
 
-type Verbose bool
+const redacted = "[redacted]"
+
+// A Logger writes leveled log messages.  A nil *Logger is the default logger.
+type Logger struct {
+	// Level is the verbosity of the logger.  Messages written with V(n) are
+	// logged only if n <= Level.  At level 1, errors and retries are logged;
+	// at level 2, every request and response is too.
+	Level int32
+
+	// Output receives each message.  If nil, messages are written with the
+	// standard log package.
+	Output func(string)
+
+	// MaxBody is the number of bytes of each request and response body to
+	// log; the rest is elided.  Zero means DefaultMaxBody, and a negative
+	// value logs bodies in full.
+	MaxBody int
+
+	// Sample, if greater than one, logs only one in every Sample requests
+	// and their responses.  Other messages are not sampled.
+	Sample int
+
+	// Allow lists the file info keys whose values may be logged, whether they
+	// appear in headers or in JSON bodies.  The values of all other info keys
+	// are redacted.  Authorization tokens are always redacted.
+	Allow []string
+
+	// Redact, if set, is called with each header and info key and its value,
+	// after the redaction above, and returns the value to log.
+	Redact func(key, value string) string
+}
+
+var std = &Logger{}
 
 func init() {
 	lvl := os.Getenv("B2_LOG_LEVEL")
@@ -34,21 +76,144 @@ func init() {
 	if err != nil {
 		return
 	}
-	level = int32(i)
+	std.Level = int32(i)
+}
+
+func (l *Logger) get() *Logger {
+	if l == nil {
+		return std
+	}
+	return l
+}
+
+// Verbose logs messages if its level is enabled.
+type Verbose struct {
+	l *Logger
+}
+
+// V returns a Verbose that logs to the default logger.
+func V(target int32) Verbose {
+	return std.V(target)
+}
+
+// V returns a Verbose that logs to l if target is at or below its level.
+func (l *Logger) V(target int32) Verbose {
+	l = l.get()
+	if target > l.Level {
+		return Verbose{}
+	}
+	return Verbose{l: l}
+}
+
+// Enabled reports whether messages will be logged.
+func (v Verbose) Enabled() bool {
+	return v.l != nil
 }
 
 func (v Verbose) Info(a ...interface{}) {
-	if v {
-		log.Print(a...)
+	if v.l != nil {
+		v.l.output(fmt.Sprint(a...))
 	}
 }
 
 func (v Verbose) Infof(format string, a ...interface{}) {
-	if v {
-		log.Printf(format, a...)
+	if v.l != nil {
+		v.l.output(fmt.Sprintf(format, a...))
 	}
 }
 
-func V(target int32) Verbose {
-	return Verbose(target <= level)
+func (l *Logger) output(s string) {
+	if l.Output != nil {
+		l.Output(s)
+		return
+	}
+	log.Print(s)
+}
+
+// Sampled reports whether the request with the given sequence number should
+// be logged.
+func (l *Logger) Sampled(id int64) bool {
+	l = l.get()
+	return l.Sample <= 1 || id%int64(l.Sample) == 0
+}
+
+const infoHeaderPrefix = "X-Bz-Info-"
+
+func (l *Logger) allowed(key string) bool {
+	for _, k := range l.get().Allow {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Logger) hook(key, value string) string {
+	if l := l.get(); l.Redact != nil {
+		return l.Redact(key, value)
+	}
+	return value
+}
+
+// Header returns the value of the given HTTP header as it should be logged.
+func (l *Logger) Header(key, value string) string {
+	key = textproto.CanonicalMIMEHeaderKey(key)
+	switch {
+	case key == "Authorization":
+		value = redacted
+	case strings.HasPrefix(key, infoHeaderPrefix):
+		if !l.allowed(key[len(infoHeaderPrefix):]) {
+			value = redacted
+		}
+	}
+	return l.hook(key, value)
+}
+
+var authRegexp = regexp.MustCompile(`"(authorizationToken|applicationKey)": *"[^"]*"`)
+
+// Body returns the given request or response body as it should be logged.
+// JSON bodies have their authorization tokens, application keys, and file
+// info values redacted; other bodies are only truncated.
+func (l *Logger) Body(b []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err == nil {
+		if enc, err := json.Marshal(l.redactJSON("", v)); err == nil {
+			b = enc
+		}
+	} else {
+		b = authRegexp.ReplaceAll(b, []byte(`"$1": "`+redacted+`"`))
+	}
+	max := l.get().MaxBody
+	if max == 0 {
+		max = DefaultMaxBody
+	}
+	if max < 0 || len(b) <= max {
+		return string(b)
+	}
+	return fmt.Sprintf("%s... (%d more bytes)", b[:max], len(b)-max)
+}
+
+func (l *Logger) redactJSON(key string, v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			switch {
+			case k == "authorizationToken", k == "applicationKey":
+				v[k] = redacted
+			case key == "fileInfo":
+				s := fmt.Sprint(e)
+				if !l.allowed(k) {
+					s = redacted
+				}
+				v[k] = l.hook(k, s)
+			default:
+				v[k] = l.redactJSON(k, e)
+			}
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = l.redactJSON(key, e)
+		}
+	}
+	return v
 }
@@ -0,0 +1,94 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package blog
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	var got []string
+	l := &Logger{Level: 1, Output: func(s string) { got = append(got, s) }}
+	l.V(1).Infof("one %d", 1)
+	l.V(2).Info("two")
+	if len(got) != 1 || got[0] != "one 1" {
+		t.Errorf("got %q, want [\"one 1\"]", got)
+	}
+	if l.V(2).Enabled() {
+		t.Errorf("V(2) enabled at level 1")
+	}
+}
+
+func TestSampled(t *testing.T) {
+	l := &Logger{Sample: 3}
+	var n int
+	for i := int64(1); i <= 9; i++ {
+		if l.Sampled(i) {
+			n++
+		}
+	}
+	if n != 3 {
+		t.Errorf("sampled %d of 9 requests, want 3", n)
+	}
+}
+
+func TestRedaction(t *testing.T) {
+	l := &Logger{
+		Allow: []string{"color"},
+		Redact: func(key, value string) string {
+			if key == "X-Bz-File-Name" {
+				return "[name]"
+			}
+			return value
+		},
+	}
+	headers := []struct {
+		key, value, want string
+	}{
+		{"Authorization", "secret", "[redacted]"},
+		{"X-Bz-Info-Color", "blue", "blue"},
+		{"x-bz-info-owner", "alice", "[redacted]"},
+		{"X-Bz-File-Name", "taxes.pdf", "[name]"},
+		{"Content-Length", "10", "10"},
+	}
+	for _, h := range headers {
+		if got := l.Header(h.key, h.value); got != h.want {
+			t.Errorf("Header(%q, %q): got %q, want %q", h.key, h.value, got, h.want)
+		}
+	}
+
+	body := `{"authorizationToken": "secret", "files": [{"fileInfo": {"color": "blue", "owner": "alice"}}]}`
+	want := `{"authorizationToken":"[redacted]","files":[{"fileInfo":{"color":"blue","owner":"[redacted]"}}]}`
+	if got := l.Body([]byte(body)); got != want {
+		t.Errorf("Body: got %s, want %s", got, want)
+	}
+	if got := l.Body([]byte(`not json "authorizationToken": "secret"`)); strings.Contains(got, "secret") {
+		t.Errorf("Body: token not redacted: %s", got)
+	}
+	body = `{"applicationKeyId": "id", "applicationKey": "secret"}`
+	want = `{"applicationKey":"[redacted]","applicationKeyId":"id"}`
+	if got := l.Body([]byte(body)); got != want {
+		t.Errorf("Body: got %s, want %s", got, want)
+	}
+	if got := l.Body([]byte(`not json "applicationKey": "secret"`)); strings.Contains(got, "secret") {
+		t.Errorf("Body: application key not redacted: %s", got)
+	}
+
+	l.MaxBody = 4
+	if got, want := l.Body([]byte("abcdefgh")), "abcd... (4 more bytes)"; got != want {
+		t.Errorf("Body: got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,35 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cli holds what the command-line tools in bin share.
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kurin/blazer/b2"
+)
+
+// Report prints err to w, naming the missing capability if the key in the
+// environment variable keyVar lacks one.
+func Report(w io.Writer, keyVar string, err error) {
+	var merr *b2.MissingCapabilityError
+	if errors.As(err, &merr) && merr.Capability != "" {
+		fmt.Fprintf(w, "the key in %s lacks the %s capability, or is restricted to another bucket or prefix\n", keyVar, merr.Capability)
+		return
+	}
+	fmt.Fprintf(w, "%v\n", err)
+}
@@ -27,6 +27,8 @@ import (
 	"io"
 	"io/ioutil"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/kurin/blazer/b2"
@@ -49,12 +51,18 @@ func NewGroup(bucket *b2.Bucket, name string) *Group {
 
 // Group represents a collection of B2 objects that can be modified in a
 // consistent way.  Objects in the same group contend with each other for
-// updates, but there can only be so many (maximum of 10; fewer if there are
-// other bucket attributes set) groups in a given bucket.
+// updates, but not with the objects of other groups, and a bucket can hold any
+// number of groups.
+//
+// Each group records the locations of its objects in an index, which is
+// itself kept in objects under a reserved prefix.  Groups written by earlier
+// versions of this package kept their index in the bucket info; it is read
+// from there until the group is next updated, when it is moved.  Programs
+// that still use the earlier versions do not see the moved index, and must
+// be upgraded first.
 type Group struct {
 	name string
 	b    *b2.Bucket
-	ba   *b2.BucketAttrs
 }
 
 // Mutex returns a new mutex on the given group.  Only one caller can hold the
@@ -271,17 +279,93 @@ func (g *Group) NewReader(ctx context.Context, name string) (Reader, error) {
 	}, nil
 }
 
+// The group's index is kept in objects named indexPrefix/<group>/<serial>,
+// where serial is zero-padded so that the newest index sorts last.  Groups
+// saved by earlier versions of this package kept their index in the bucket
+// info, under metaKey-<group>; it is read until the group is next saved.
+const indexPrefix = metaKey + "/"
+
+func (g *Group) indexName(serial int) string {
+	return fmt.Sprintf("%s%s/%020d", indexPrefix, g.name, serial)
+}
+
+// info returns the group's current index.
 func (g *Group) info(ctx context.Context) (*consistentInfo, error) {
+	for {
+		latest, serial, err := g.latest(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if latest == "" {
+			return g.legacyInfo(ctx)
+		}
+		obj, err := g.first(ctx, latest)
+		if err != nil {
+			return nil, err
+		}
+		if obj == nil {
+			// The index was replaced and cleaned up since it was listed.
+			continue
+		}
+		return g.readInfo(ctx, obj, serial)
+	}
+}
+
+// latest returns the name and serial of the newest index, or "" if there is
+// none.
+func (g *Group) latest(ctx context.Context) (string, int, error) {
+	var latest string
+	var serial int
+	pfx := indexPrefix + g.name + "/"
+	iter := g.b.List(ctx, b2.ListPrefix(pfx))
+	for iter.Next() {
+		name := iter.Object().Name()
+		n, err := strconv.Atoi(strings.TrimPrefix(name, pfx))
+		if err != nil || name != g.indexName(n) {
+			continue // another group's, whose name has this one's as a prefix
+		}
+		latest, serial = name, n
+	}
+	return latest, serial, iter.Err()
+}
+
+func (g *Group) readInfo(ctx context.Context, obj *b2.Object, serial int) (*consistentInfo, error) {
+	r := obj.NewReader(ctx)
+	defer r.Close()
+	ci := &consistentInfo{}
+	if err := json.NewDecoder(r).Decode(ci); err != nil {
+		return nil, err
+	}
+	if ci.Serial != serial {
+		return nil, fmt.Errorf("consistent: %s: index has serial %d", obj.Name(), ci.Serial)
+	}
+	if ci.Locations == nil {
+		ci.Locations = make(map[string]string)
+	}
+	return ci, nil
+}
+
+// first returns the first version uploaded with the given name, which is the
+// one that counts if several were uploaded, or nil if there is none.
+func (g *Group) first(ctx context.Context, name string) (*b2.Object, error) {
+	var first *b2.Object
+	iter := g.b.List(ctx, b2.ListPrefix(name), b2.ListHidden())
+	for iter.Next() {
+		// Versions of a name are listed newest first.
+		if obj := iter.Object(); obj.Name() == name {
+			first = obj
+		}
+	}
+	return first, iter.Err()
+}
+
+// legacyInfo returns the group's index from the bucket info.
+func (g *Group) legacyInfo(ctx context.Context) (*consistentInfo, error) {
 	attrs, err := g.b.Attrs(ctx)
 	if err != nil {
 		return nil, err
 	}
-	g.ba = attrs
-	imap := attrs.Info
-	if imap == nil {
-		return nil, nil
-	}
-	enc, ok := imap[metaKey+"-"+g.name]
+	enc, ok := attrs.Info[metaKey+"-"+g.name]
 	if !ok {
 		return &consistentInfo{
 			Version:   1,
@@ -292,7 +376,7 @@ func (g *Group) info(ctx context.Context) (*consistentInfo, error) {
 	if err != nil {
 		return nil, err
 	}
-	ci := &consistentInfo{}
+	ci := &consistentInfo{legacy: true}
 	if err := json.Unmarshal(b, ci); err != nil {
 		return nil, err
 	}
@@ -302,34 +386,80 @@ func (g *Group) info(ctx context.Context) (*consistentInfo, error) {
 	return ci, nil
 }
 
+// save replaces the group's index with ci, which must have been read with
+// info and then modified.  The new index is uploaded under the next serial;
+// if another caller uploaded one there first, or a later serial exists, save
+// removes its own and returns errUpdateConflict.
 func (g *Group) save(ctx context.Context, ci *consistentInfo) error {
 	ci.Serial++
 	b, err := json.Marshal(ci)
 	if err != nil {
 		return err
 	}
-	s := base64.StdEncoding.EncodeToString(b)
+	name := g.indexName(ci.Serial)
+	obj := g.b.Object(name)
+	if err := obj.WriteFrom(ctx, bytes.NewReader(b), int64(len(b))); err != nil {
+		return err
+	}
+	first, err := g.first(ctx, name)
+	if err != nil {
+		obj.Delete(ctx)
+		return err
+	}
+	if first == nil || first.ID() != obj.ID() {
+		obj.Delete(ctx)
+		return errUpdateConflict
+	}
+	// A caller whose index is several saves stale may have won a serial
+	// that was already purged; newer serials show that it lost.
+	_, serial, err := g.latest(ctx)
+	if err != nil {
+		obj.Delete(ctx)
+		return err
+	}
+	if serial > ci.Serial {
+		obj.Delete(ctx)
+		return errUpdateConflict
+	}
+	// Readers may still be reading the previous index, but not the one
+	// before it.
+	if ci.Serial > 2 {
+		g.purge(ctx, g.indexName(ci.Serial-2))
+	}
+	if ci.legacy {
+		g.dropLegacy(ctx)
+	}
+	return nil
+}
+
+// purge deletes every version of the named object.  Failures leave garbage
+// behind, but do no other harm, and are ignored.
+func (g *Group) purge(ctx context.Context, name string) {
+	iter := g.b.List(ctx, b2.ListPrefix(name), b2.ListHidden())
+	for iter.Next() {
+		if obj := iter.Object(); obj.Name() == name {
+			obj.Delete(ctx)
+		}
+	}
+}
 
+// dropLegacy removes the group's index from the bucket info, once it has been
+// saved as an object.  Failures leave the stale entry behind, which is no
+// longer read, and are ignored.
+func (g *Group) dropLegacy(ctx context.Context) {
 	for {
-		oldAI, err := g.info(ctx)
+		attrs, err := g.b.Attrs(ctx)
 		if err != nil {
-			return err
-		}
-		if oldAI.Serial != ci.Serial-1 {
-			return errUpdateConflict
-		}
-		if g.ba.Info == nil {
-			g.ba.Info = make(map[string]string)
+			return
 		}
-		g.ba.Info[metaKey+"-"+g.name] = s
-		err = g.b.Update(ctx, g.ba)
-		if err == nil {
-			return nil
+		if _, ok := attrs.Info[metaKey+"-"+g.name]; !ok {
+			return
 		}
-		if !b2.IsUpdateConflict(err) {
-			return err
+		delete(attrs.Info, metaKey+"-"+g.name)
+		err = g.b.Update(ctx, attrs)
+		if err == nil || !b2.IsUpdateConflict(err) {
+			return
 		}
-		// Bucket update conflict; try again.
 	}
 }
 
@@ -401,6 +531,8 @@ type consistentInfo struct {
 	// by comparing the "key" of the file it is replacing.
 	Serial    int
 	Locations map[string]string
+
+	legacy bool // read from the bucket info
 }
 
 func random() (string, error) {
@@ -0,0 +1,8 @@
+module github.com/kurin/blazer/x/consistent
+
+go 1.13
+
+require github.com/kurin/blazer v0.5.3
+
+// x/consistent tracks the b2 package at head.
+replace github.com/kurin/blazer => ../../
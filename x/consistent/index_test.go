@@ -0,0 +1,199 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package consistent
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+func startFakeTest(ctx context.Context, t *testing.T) (*b2.Bucket, func()) {
+	s := b2fake.New(b2fake.Options{})
+	client, err := s.Client(ctx)
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		s.Close()
+		t.Fatal(err)
+	}
+	return bucket, s.Close
+}
+
+func readString(ctx context.Context, t *testing.T, g *Group, name string) string {
+	r, err := g.NewReader(ctx, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestOperationIndex(t *testing.T) {
+	ctx := context.Background()
+	bucket, done := startFakeTest(ctx, t)
+	defer done()
+
+	// More groups than the bucket info could ever have held.
+	var groups []*Group
+	for i := 0; i < 12; i++ {
+		groups = append(groups, NewGroup(bucket, fmt.Sprintf("group%d", i)))
+	}
+	groups = append(groups, NewGroup(bucket, "group1/nested"))
+
+	incr := func(g *Group) error {
+		return g.Operate(ctx, "counter", func(b []byte) ([]byte, error) {
+			var n int
+			if len(b) > 0 {
+				i, err := strconv.Atoi(string(b))
+				if err != nil {
+					return nil, err
+				}
+				n = i
+			}
+			return []byte(strconv.Itoa(n + 1)), nil
+		})
+	}
+	// Until a group has an index it consults the bucket attrs, which a
+	// *b2.Bucket can't fetch concurrently, so give each one an index first.
+	for _, g := range groups {
+		if err := incr(g); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var wg sync.WaitGroup
+	for _, g := range groups {
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func(g *Group) {
+				defer wg.Done()
+				for j := 0; j < 3; j++ {
+					if err := incr(g); err != nil {
+						t.Error(err)
+					}
+				}
+			}(g)
+		}
+	}
+	wg.Wait()
+
+	for _, g := range groups {
+		if got := readString(ctx, t, g, "counter"); got != "10" {
+			t.Errorf("%s: got %q, want 10", g.name, got)
+		}
+	}
+	attrs, err := bucket.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attrs.Info) != 0 {
+		t.Errorf("bucket info: got %v, want none", attrs.Info)
+	}
+}
+
+func TestLegacyMigration(t *testing.T) {
+	ctx := context.Background()
+	bucket, done := startFakeTest(ctx, t)
+	defer done()
+
+	// A group saved in the bucket info by an earlier version.
+	old := "legacy value"
+	if err := bucket.Object("doc/abc").WriteFrom(ctx, strings.NewReader(old), int64(len(old))); err != nil {
+		t.Fatal(err)
+	}
+	enc := base64.StdEncoding.EncodeToString([]byte(`{"Version":1,"Serial":3,"Locations":{"doc":"abc"}}`))
+	info := map[string]string{metaKey + "-legacy": enc, "other": "kept"}
+	if err := bucket.Update(ctx, &b2.BucketAttrs{Info: info}); err != nil {
+		t.Fatal(err)
+	}
+
+	g := NewGroup(bucket, "legacy")
+	if got := readString(ctx, t, g, "doc"); got != old {
+		t.Fatalf("before migration: got %q, want %q", got, old)
+	}
+	if err := g.Operate(ctx, "doc", func(b []byte) ([]byte, error) {
+		return append(b, " and more"...), nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := readString(ctx, t, g, "doc"), old+" and more"; got != want {
+		t.Errorf("after migration: got %q, want %q", got, want)
+	}
+
+	attrs, err := bucket.Attrs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := attrs.Info[metaKey+"-legacy"]; ok || attrs.Info["other"] != "kept" {
+		t.Errorf("bucket info after migration: got %v, want only the other key", attrs.Info)
+	}
+	ci, err := g.info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.Serial != 4 || ci.legacy {
+		t.Errorf("index after migration: got serial %d (legacy %v), want 4 from an object", ci.Serial, ci.legacy)
+	}
+}
+
+func TestStaleSave(t *testing.T) {
+	ctx := context.Background()
+	bucket, done := startFakeTest(ctx, t)
+	defer done()
+
+	g := NewGroup(bucket, "stale")
+	set := func(v string) error {
+		return g.Operate(ctx, "doc", func([]byte) ([]byte, error) {
+			return []byte(v), nil
+		})
+	}
+	if err := set("one"); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := g.info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"two", "three", "four"} {
+		if err := set(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The serial after stale's has been purged, so stale is the only upload
+	// there, but it is not the newest index.
+	stale.Locations["doc"] = "lost"
+	if err := g.save(ctx, stale); err != errUpdateConflict {
+		t.Errorf("stale save: got %v, want errUpdateConflict", err)
+	}
+	if got := readString(ctx, t, g, "doc"); got != "four" {
+		t.Errorf("after stale save: got %q, want %q", got, "four")
+	}
+}
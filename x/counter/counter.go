@@ -0,0 +1,63 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package counter counts events over a sliding window of time.
+package counter
+
+import (
+	"time"
+
+	"github.com/kurin/blazer/x/window"
+)
+
+// A Counter counts events that have occurred over a span of time extending
+// from some fixed interval ago to now.
+type Counter struct {
+	w *window.Of[int64]
+}
+
+// New returns a counter for events over the given duration, tracked at the
+// given resolution.  A size of 0 counts events forever.
+func New(size, resolution time.Duration, opts ...window.Option) *Counter {
+	return &Counter{
+		w: window.NewOf(size, resolution, window.Sum[int64], opts...),
+	}
+}
+
+// Add records n events.
+func (c *Counter) Add(n int64) {
+	c.w.Insert(n)
+}
+
+// Count returns the number of events in the window.
+func (c *Counter) Count() int64 {
+	return c.w.Reduce()
+}
+
+// Rate returns the number of events per second, averaged over the whole
+// window.  It returns 0 for counters that count forever.
+func (c *Counter) Rate() float64 {
+	return window.Rate(c.w)
+}
+
+// Snapshot returns the number of events in each interval of the window that
+// saw any, oldest first.
+func (c *Counter) Snapshot() []window.Interval[int64] {
+	return c.w.Snapshot()
+}
+
+// Merge adds the events counted by o to c.
+func (c *Counter) Merge(o *Counter) {
+	c.w.Merge(o.w)
+}
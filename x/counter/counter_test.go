@@ -0,0 +1,64 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package counter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/x/window"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func TestCounter(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	c := New(10*time.Second, time.Second, window.WithClock(clk))
+	for i := 0; i < 4; i++ {
+		c.Add(5)
+		clk.now = clk.now.Add(2 * time.Second)
+	}
+	if got := c.Count(); got != 20 {
+		t.Errorf("Count: got %d, want 20", got)
+	}
+	if got := c.Rate(); got != 2 {
+		t.Errorf("Rate: got %v, want 2", got)
+	}
+	snap := c.Snapshot()
+	if len(snap) != 4 {
+		t.Fatalf("Snapshot: got %d intervals, want 4", len(snap))
+	}
+	for i, iv := range snap {
+		want := start.Add(time.Duration(2*i) * time.Second)
+		if !iv.Start.Equal(want) || iv.Value != 5 {
+			t.Errorf("interval %d: got %v at %v, want 5 at %v", i, iv.Value, iv.Start, want)
+		}
+	}
+
+	o := New(10*time.Second, time.Second, window.WithClock(clk))
+	o.Add(7)
+	c.Merge(o)
+	if got := c.Count(); got != 27 {
+		t.Errorf("after Merge: got %d, want 27", got)
+	}
+
+	clk.now = clk.now.Add(5 * time.Second)
+	if got := c.Count(); got != 17 {
+		t.Errorf("after 5s: got %d, want 17", got)
+	}
+}
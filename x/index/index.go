@@ -0,0 +1,406 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package index keeps a local index of the objects in a B2 bucket, so that
+// tools that sync with a bucket can compare local files against it instead
+// of fetching the attributes of every object on every run.
+//
+// The index maps each object's name to its size, modification time, SHA1,
+// and file ID.  Refresh brings it up to date from the versions uploaded since
+// the last refresh; Rebuild replaces it with a complete listing.
+//
+// The index has these limits:
+//
+//   - B2 lists versions by name, not by time, so Refresh still reads the
+//     listing of every version in the bucket.  It is O(objects) in list
+//     calls; what it saves is the work of applying unchanged versions.
+//   - Upload timestamps are not assigned in listing order: a large file is
+//     stamped when it is started, and uploads racing a Refresh may appear
+//     after it with earlier timestamps.  Refresh therefore re-reads a window
+//     before its watermark (see WithOverlap) and skips versions it already
+//     has.  A version stamped earlier than that window is missed until the
+//     next Rebuild.
+//   - B2 keeps no record of versions that were deleted outright, so
+//     deletions made by other clients are only seen by Rebuild.
+//
+// The index is stored as a single JSON file, read whole by Open and rewritten
+// atomically by Save, so it suits buckets whose index fits comfortably in
+// memory.
+package index
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+)
+
+// formatVersion is the version of the index file format.
+const formatVersion = 1
+
+// defaultOverlap is how far before its watermark Refresh lists by default.
+const defaultOverlap = time.Hour
+
+// An Entry describes the current version of one object.
+type Entry struct {
+	Name string `json:"name"`
+	Size int64  `json:"size"`
+
+	// ModTime is the object's modification time, taken from the source set
+	// with WithModTime.  By default this is the LastModified attribute, which
+	// uploaders conventionally set to the source file's modification time, or
+	// the upload time if the object has none.
+	ModTime time.Time `json:"mtime"`
+
+	SHA1     string    `json:"sha1"`
+	FileID   string    `json:"fileId"`
+	Uploaded time.Time `json:"uploaded"`
+}
+
+// An Index is a local index of the objects in a bucket.  It is safe for
+// concurrent use.
+type Index struct {
+	path    string
+	mtime   b2.ModTimeSource
+	overlap time.Duration
+
+	mu      sync.Mutex
+	bucket  string
+	mark    time.Time
+	entries map[string]Entry
+	dirty   bool
+}
+
+type indexFile struct {
+	Version   int       `json:"version"`
+	Bucket    string    `json:"bucket"`
+	Watermark time.Time `json:"watermark"`
+	Entries   []Entry   `json:"entries"`
+}
+
+// An Option alters the behavior of an Index.
+type Option func(*Index)
+
+// WithModTime sets where the index takes each entry's ModTime from.  The
+// source is not saved with the index; entries recorded with another source
+// are not corrected until the next Rebuild.
+func WithModTime(src b2.ModTimeSource) Option {
+	return func(ix *Index) {
+		ix.mtime = src
+	}
+}
+
+// WithOverlap sets how far before the last refresh Refresh lists again, to
+// pick up versions whose upload timestamps are earlier than versions that
+// were already listed, such as large files that took longer than d to
+// upload.  The default is an hour.
+func WithOverlap(d time.Duration) Option {
+	return func(ix *Index) {
+		ix.overlap = d
+	}
+}
+
+// Open reads the index stored at path.  If there is no such file, Open returns
+// an empty index that Save will create.
+func Open(path string, opts ...Option) (*Index, error) {
+	ix := &Index{
+		path:    path,
+		overlap: defaultOverlap,
+		entries: make(map[string]Entry),
+	}
+	for _, opt := range opts {
+		opt(ix)
+	}
+	bs, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f indexFile
+	if err := json.Unmarshal(bs, &f); err != nil {
+		return nil, fmt.Errorf("index: %s: %v", path, err)
+	}
+	if f.Version != formatVersion {
+		return nil, fmt.Errorf("index: %s: format version %d, want %d", path, f.Version, formatVersion)
+	}
+	ix.bucket = f.Bucket
+	ix.mark = f.Watermark
+	for _, e := range f.Entries {
+		ix.entries[e.Name] = e
+	}
+	return ix, nil
+}
+
+// Save writes the index to its file, if it has changed since it was opened or
+// last saved.
+func (ix *Index) Save() error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if !ix.dirty {
+		return nil
+	}
+	f := indexFile{
+		Version:   formatVersion,
+		Bucket:    ix.bucket,
+		Watermark: ix.mark,
+		Entries:   ix.sorted(),
+	}
+	bs, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(ix.path), filepath.Base(ix.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(bs); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), ix.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	ix.dirty = false
+	return nil
+}
+
+// Get returns the entry for the named object.
+func (ix *Index) Get(name string) (Entry, bool) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	e, ok := ix.entries[name]
+	return e, ok
+}
+
+// Put records e, such as for an object the caller has just uploaded, so that
+// the index need not wait for the next Refresh to learn of it.
+func (ix *Index) Put(e Entry) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	ix.entries[e.Name] = e
+	ix.dirty = true
+}
+
+// Remove forgets the named object, such as one the caller has just deleted.
+func (ix *Index) Remove(name string) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if _, ok := ix.entries[name]; ok {
+		delete(ix.entries, name)
+		ix.dirty = true
+	}
+}
+
+// Changed reports whether a local file of the given size and modification
+// time differs from the named object, or the object is not in the index.
+// Modification times are compared to the millisecond, as B2 records them.
+func (ix *Index) Changed(name string, size int64, mtime time.Time) bool {
+	e, ok := ix.Get(name)
+	if !ok {
+		return true
+	}
+	return e.Size != size || !e.ModTime.Truncate(time.Millisecond).Equal(mtime.Truncate(time.Millisecond))
+}
+
+// Len returns the number of objects in the index.
+func (ix *Index) Len() int {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return len(ix.entries)
+}
+
+// Entries returns every entry in the index, sorted by name.
+func (ix *Index) Entries() []Entry {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	return ix.sorted()
+}
+
+func (ix *Index) sorted() []Entry {
+	es := make([]Entry, 0, len(ix.entries))
+	for _, e := range ix.entries {
+		es = append(es, e)
+	}
+	sort.Slice(es, func(i, j int) bool { return es[i].Name < es[j].Name })
+	return es
+}
+
+// Refresh updates the index with the versions uploaded to bucket since the
+// last Refresh or Rebuild, less the overlap set with WithOverlap, and returns
+// how many entries changed.  An index that has never been refreshed is
+// rebuilt.  Deletions are not seen; see the package documentation.
+func (ix *Index) Refresh(ctx context.Context, bucket *b2.Bucket) (int, error) {
+	ix.mu.Lock()
+	mark := ix.mark
+	ix.mu.Unlock()
+	if mark.IsZero() {
+		return ix.Rebuild(ctx, bucket)
+	}
+	if err := ix.checkBucket(bucket); err != nil {
+		return 0, err
+	}
+	var n int
+	var prev string
+	iter := bucket.List(ctx, b2.ListSince(mark.Add(-ix.overlap)))
+	for iter.Next() {
+		o := iter.Object()
+		attrs, err := o.Attrs(ctx)
+		if err != nil {
+			return n, err
+		}
+		// Versions are listed newest first; only the newest upload or hide
+		// marker of each name matters.  Unfinished large files are listed
+		// too, and must not hide the upload before them.
+		if attrs.Status != b2.Uploaded && attrs.Status != b2.Hider {
+			continue
+		}
+		if attrs.Name == prev {
+			continue
+		}
+		prev = attrs.Name
+		if ix.apply(o.ID(), attrs) {
+			n++
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return n, err
+	}
+	ix.advance(iter.Watermark())
+	return n, nil
+}
+
+// Rebuild replaces the index with a complete listing of bucket, and returns
+// how many entries changed.
+func (ix *Index) Rebuild(ctx context.Context, bucket *b2.Bucket) (int, error) {
+	if err := ix.checkBucket(bucket); err != nil {
+		return 0, err
+	}
+	fresh := make(map[string]Entry)
+	var mark time.Time
+	iter := bucket.List(ctx)
+	for iter.Next() {
+		o := iter.Object()
+		attrs, err := o.Attrs(ctx)
+		if err != nil {
+			return 0, err
+		}
+		if attrs.Status != b2.Uploaded {
+			continue
+		}
+		fresh[attrs.Name] = entry(o.ID(), attrs, ix.mtime)
+		if attrs.UploadTimestamp.After(mark) {
+			mark = attrs.UploadTimestamp
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	var n int
+	for name, e := range ix.entries {
+		if f, ok := fresh[name]; !ok || !f.same(e) {
+			n++
+		}
+	}
+	for name := range fresh {
+		if _, ok := ix.entries[name]; !ok {
+			n++
+		}
+	}
+	ix.entries = fresh
+	ix.bucket = bucket.Name()
+	if mark.IsZero() {
+		// An empty bucket has no watermark; refresh from now on.
+		mark = time.Unix(0, 0)
+	}
+	ix.mark = mark
+	ix.dirty = true
+	return n, nil
+}
+
+func (ix *Index) checkBucket(bucket *b2.Bucket) error {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if ix.bucket != "" && ix.bucket != bucket.Name() {
+		return fmt.Errorf("index: %s indexes bucket %q, not %q", ix.path, ix.bucket, bucket.Name())
+	}
+	return nil
+}
+
+// apply records a single version from a listing of changes, and reports
+// whether it changed the index.  The version already indexed and older ones
+// are ignored, as are unfinished large files.
+func (ix *Index) apply(id string, attrs *b2.Attrs) bool {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	old, ok := ix.entries[attrs.Name]
+	if ok && (old.FileID == id || !attrs.UploadTimestamp.After(old.Uploaded)) {
+		return false
+	}
+	switch attrs.Status {
+	case b2.Uploaded:
+		ix.entries[attrs.Name] = entry(id, attrs, ix.mtime)
+	case b2.Hider:
+		if !ok {
+			return false
+		}
+		delete(ix.entries, attrs.Name)
+	default:
+		return false
+	}
+	ix.dirty = true
+	return true
+}
+
+func (ix *Index) advance(mark time.Time) {
+	ix.mu.Lock()
+	defer ix.mu.Unlock()
+	if mark.After(ix.mark) {
+		ix.mark = mark
+		ix.dirty = true
+	}
+}
+
+func entry(id string, attrs *b2.Attrs, src b2.ModTimeSource) Entry {
+	return Entry{
+		Name:     attrs.Name,
+		Size:     attrs.Size,
+		ModTime:  attrs.ModTime(src),
+		SHA1:     attrs.SHA1,
+		FileID:   id,
+		Uploaded: attrs.UploadTimestamp,
+	}
+}
+
+func (e Entry) same(f Entry) bool {
+	return e.Name == f.Name && e.Size == f.Size && e.ModTime.Equal(f.ModTime) &&
+		e.SHA1 == f.SHA1 && e.FileID == f.FileID && e.Uploaded.Equal(f.Uploaded)
+}
@@ -0,0 +1,323 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package index
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/kurin/blazer/b2/b2fake"
+)
+
+const (
+	apiID      = "B2_ACCOUNT_ID"
+	apiKey     = "B2_SECRET_KEY"
+	bucketName = "index-test"
+)
+
+func TestApply(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	ix := &Index{entries: make(map[string]Entry)}
+
+	table := []struct {
+		id    string
+		attrs b2.Attrs
+		want  bool
+		has   bool
+	}{
+		{
+			id:    "1",
+			attrs: b2.Attrs{Name: "a", Size: 3, SHA1: "aaa", Status: b2.Uploaded, UploadTimestamp: t0},
+			want:  true,
+			has:   true,
+		},
+		{ // the same version, listed again
+			id:    "1",
+			attrs: b2.Attrs{Name: "a", Size: 3, SHA1: "aaa", Status: b2.Uploaded, UploadTimestamp: t0.Add(time.Minute)},
+			has:   true,
+		},
+		{ // an older version
+			id:    "0",
+			attrs: b2.Attrs{Name: "a", Size: 2, Status: b2.Uploaded, UploadTimestamp: t0.Add(-time.Hour)},
+			has:   true,
+		},
+		{ // an unfinished large file
+			id:    "2",
+			attrs: b2.Attrs{Name: "a", Status: b2.Started, UploadTimestamp: t0.Add(time.Hour)},
+			has:   true,
+		},
+		{
+			id:    "3",
+			attrs: b2.Attrs{Name: "a", Status: b2.Hider, UploadTimestamp: t0.Add(time.Hour)},
+			want:  true,
+		},
+		{ // a hide marker for an object the index never saw
+			id:    "4",
+			attrs: b2.Attrs{Name: "b", Status: b2.Hider, UploadTimestamp: t0},
+		},
+	}
+	for i, e := range table {
+		if got := ix.apply(e.id, &e.attrs); got != e.want {
+			t.Errorf("%d: apply: got %v, want %v", i, got, e.want)
+		}
+		if _, ok := ix.Get(e.attrs.Name); ok != e.has {
+			t.Errorf("%d: Get(%q): got %v, want %v", i, e.attrs.Name, ok, e.has)
+		}
+	}
+}
+
+func TestModTime(t *testing.T) {
+	t0 := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mtime := t0.Add(-time.Hour)
+	table := []struct {
+		src   b2.ModTimeSource
+		attrs b2.Attrs
+		want  time.Time
+	}{
+		{b2.ModTimeInfoOrUpload, b2.Attrs{Name: "a", LastModified: mtime, UploadTimestamp: t0}, mtime},
+		{b2.ModTimeInfoOrUpload, b2.Attrs{Name: "a", UploadTimestamp: t0}, t0},
+		{b2.ModTimeInfo, b2.Attrs{Name: "a", UploadTimestamp: t0}, time.Time{}},
+		{b2.ModTimeUpload, b2.Attrs{Name: "a", LastModified: mtime, UploadTimestamp: t0}, t0},
+	}
+	for i, e := range table {
+		ix, err := Open(filepath.Join(os.TempDir(), "no-such-index"), WithModTime(e.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		e.attrs.Status = b2.Uploaded
+		ix.apply("1", &e.attrs)
+		got, _ := ix.Get("a")
+		if !got.ModTime.Equal(e.want) {
+			t.Errorf("%d: ModTime: got %v, want %v", i, got.ModTime, e.want)
+		}
+		if ix.Changed("a", 0, e.want) {
+			t.Errorf("%d: Changed with the same mtime: got true", i)
+		}
+	}
+}
+
+func TestSaveOpen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "index.json")
+
+	ix, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 0 {
+		t.Errorf("new index: got %d entries", ix.Len())
+	}
+	mtime := time.Date(2020, 1, 1, 0, 0, 0, 123456789, time.UTC)
+	ix.bucket = "bucket"
+	ix.Put(Entry{Name: "b", Size: 2, ModTime: mtime, SHA1: "bbb", FileID: "2"})
+	ix.Put(Entry{Name: "a", Size: 1, ModTime: mtime, SHA1: "aaa", FileID: "1"})
+	ix.Put(Entry{Name: "c"})
+	ix.Remove("c")
+	ix.advance(mtime)
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	ix, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := ix.Entries()
+	if len(es) != 2 || es[0].Name != "a" || es[1].Name != "b" || es[1].SHA1 != "bbb" {
+		t.Errorf("Entries(): got %+v", es)
+	}
+	if ix.bucket != "bucket" || !ix.mark.Equal(mtime) {
+		t.Errorf("reopened index: got bucket %q and watermark %v", ix.bucket, ix.mark)
+	}
+
+	if ix.Changed("a", 1, mtime.Add(400*time.Microsecond)) {
+		t.Error("Changed: got true for the same size and millisecond")
+	}
+	if !ix.Changed("a", 1, mtime.Add(time.Second)) {
+		t.Error("Changed: got false for a newer file")
+	}
+	if !ix.Changed("a", 2, mtime) {
+		t.Error("Changed: got false for a different size")
+	}
+	if !ix.Changed("c", 0, mtime) {
+		t.Error("Changed: got false for a missing entry")
+	}
+
+	if err := ioutil.WriteFile(path, []byte(`{"version": 99}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err == nil {
+		t.Error("Open of a future format: got no error")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+	bucket, done := startLiveTest(ctx, t)
+	defer done()
+
+	write := func(name, body string) {
+		w := bucket.Object(name).NewWriter(ctx)
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a", "1")
+	write("b", "22")
+
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ix, err := Open(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := ix.Refresh(ctx, bucket); err != nil || n != 2 {
+		t.Fatalf("first Refresh: got %d, %v; want 2, nil", n, err)
+	}
+
+	write("a", "333")
+	if err := bucket.Object("b").Hide(ctx); err != nil {
+		t.Fatal(err)
+	}
+	write("c", "4444")
+	if n, err := ix.Refresh(ctx, bucket); err != nil || n != 3 {
+		t.Fatalf("second Refresh: got %d, %v; want 3, nil", n, err)
+	}
+	var names []string
+	for _, e := range ix.Entries() {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "a,c" {
+		t.Errorf("Entries(): got %s, want a,c", got)
+	}
+	if e, _ := ix.Get("a"); e.Size != 3 {
+		t.Errorf("a: got size %d, want 3", e.Size)
+	}
+	if n, err := ix.Refresh(ctx, bucket); err != nil || n != 0 {
+		t.Errorf("unchanged Refresh: got %d, %v; want 0, nil", n, err)
+	}
+	if n, err := ix.Rebuild(ctx, bucket); err != nil || n != 0 {
+		t.Errorf("Rebuild: got %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestRefreshUnfinished(t *testing.T) {
+	ctx := context.Background()
+	s := b2fake.New(b2fake.Options{PartSize: 1e3, MinimumPartSize: 1e3})
+	defer s.Close()
+	client, err := s.Client(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bucket, err := client.NewBucket(ctx, "bucket", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(w *b2.Writer, size int) error {
+		if _, err := w.Write(make([]byte, size)); err != nil {
+			w.Close()
+			return err
+		}
+		return w.Close()
+	}
+
+	if err := write(bucket.Object("a").NewWriter(ctx), 1); err != nil {
+		t.Fatal(err)
+	}
+	dir, err := ioutil.TempDir("", "index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ix, err := Open(filepath.Join(dir, "index.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ix.Refresh(ctx, bucket); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new upload of a, and then a large file that is never finished.
+	if err := write(bucket.Object("a").NewWriter(ctx), 2); err != nil {
+		t.Fatal(err)
+	}
+	fail := &b2fake.Error{Status: 400, Code: "bad_request"}
+	s.Fail("b2_finish_large_file", 1, fail)
+	s.Fail("b2_cancel_large_file", 1, fail)
+	w := bucket.Object("a").NewWriter(ctx)
+	w.ChunkSize = 1e3
+	if err := write(w, 3e3); err == nil {
+		t.Fatal("large file: got no error")
+	}
+
+	if n, err := ix.Refresh(ctx, bucket); err != nil || n != 1 {
+		t.Fatalf("Refresh: got %d, %v; want 1, nil", n, err)
+	}
+	if e, _ := ix.Get("a"); e.Size != 2 {
+		t.Errorf("a: got size %d, want 2", e.Size)
+	}
+}
+
+func startLiveTest(ctx context.Context, t *testing.T) (*b2.Bucket, func()) {
+	id := os.Getenv(apiID)
+	key := os.Getenv(apiKey)
+	if id == "" || key == "" {
+		t.Skipf("B2_ACCOUNT_ID or B2_SECRET_KEY unset; skipping integration tests")
+		return nil, nil
+	}
+	client, err := b2.NewClient(ctx, id, key)
+	if err != nil {
+		t.Fatal(err)
+		return nil, nil
+	}
+	bucket, err := client.NewBucket(ctx, id+"-"+bucketName, nil)
+	if err != nil {
+		t.Fatal(err)
+		return nil, nil
+	}
+	f := func() {
+		iter := bucket.List(ctx, b2.ListHidden())
+		for iter.Next() {
+			if err := iter.Object().Delete(ctx); err != nil {
+				t.Error(err)
+			}
+		}
+		if err := iter.Err(); err != nil && !b2.IsNotExist(err) {
+			t.Error(err)
+		}
+		if err := bucket.Delete(ctx); err != nil && !b2.IsNotExist(err) {
+			t.Error(err)
+		}
+	}
+	return bucket, f
+}
@@ -0,0 +1,80 @@
+// Copyright 2018, the Blazer authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package transport
+
+import (
+	"net/http"
+	"time"
+)
+
+// DefaultMaxIdleConnsPerHost is the number of idle connections per host kept
+// by transports returned from Pooled, unless overridden.
+const DefaultMaxIdleConnsPerHost = 32
+
+// Pooled returns an http.Transport tuned for B2's access pattern, in which
+// many concurrent uploads and downloads are spread over a handful of hosts.
+// The standard library's default transport keeps only two idle connections
+// per host, which throttles concurrent large file parts.  The result can be
+// passed to b2.Transport.
+func Pooled(opts ...PoolOption) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	if t.MaxIdleConns > 0 && t.MaxIdleConns < t.MaxIdleConnsPerHost {
+		t.MaxIdleConns = t.MaxIdleConnsPerHost
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// A PoolOption adjusts the connection pool settings of a transport returned by
+// Pooled.
+type PoolOption func(*http.Transport)
+
+// MaxIdleConnsPerHost sets the number of idle connections that are kept for
+// each host.  Values less than 1 use the standard library's default.
+func MaxIdleConnsPerHost(n int) PoolOption {
+	return func(t *http.Transport) {
+		t.MaxIdleConnsPerHost = n
+		if t.MaxIdleConns > 0 && t.MaxIdleConns < n {
+			t.MaxIdleConns = n
+		}
+	}
+}
+
+// MaxIdleConns sets the total number of idle connections kept across all
+// hosts.  Zero means no limit.
+func MaxIdleConns(n int) PoolOption {
+	return func(t *http.Transport) {
+		t.MaxIdleConns = n
+	}
+}
+
+// IdleConnTimeout sets how long an idle connection remains in the pool before
+// it is closed.  Zero means no limit.
+func IdleConnTimeout(d time.Duration) PoolOption {
+	return func(t *http.Transport) {
+		t.IdleConnTimeout = d
+	}
+}
+
+// ForceAttemptHTTP2 controls whether the transport attempts to negotiate
+// HTTP/2.  It is enabled by default.
+func ForceAttemptHTTP2(b bool) PoolOption {
+	return func(t *http.Transport) {
+		t.ForceAttemptHTTP2 = b
+	}
+}
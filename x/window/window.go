@@ -22,58 +22,98 @@ import (
 	"time"
 )
 
-// A Window efficiently records events that have occurred over a span of time
-// extending from some fixed interval ago to now.  Events that pass beyond this
-// horizon are discarded.
-type Window struct {
+// An Of records events of type T that have occurred over a span of time
+// extending from some fixed interval ago to now.  Events that pass beyond
+// this horizon are discarded.
+type Of[T any] struct {
 	mu      sync.Mutex
-	events  []interface{}
+	events  []T
+	set     []bool // whether events[i] holds a value
+	size    time.Duration
 	res     time.Duration
 	last    time.Time
-	reduce  Reducer
+	reduce  func(T, T) T
 	forever bool
-	e       interface{}
+	e       T
+	eset    bool
+	clock   Clock
 }
 
-// A Reducer should take two values from the window and combine them into a
-// third value that will be stored in the window.  The values i or j may be
-// nil.  The underlying types for both arguments and the output should be
-// identical.
-//
-// If the reducer is any kind of slice or list, then data usage will grow
-// linearly with the number of events added to the window.
-//
-// Reducer will be called on its own output: Reducer(Reducer(x, y), z).
-type Reducer func(i, j interface{}) interface{}
-
-// New returns an initialized window for events over the given duration at the
-// given resolution.  Windows with tight resolution (i.e., small values for
+// NewOf returns an initialized window for events over the given duration at
+// the given resolution.  Windows with tight resolution (i.e., small values for
 // that argument) will be more accurate, at the cost of some memory.
 //
+// Events are combined with reduce, which is called on its own output:
+// reduce(reduce(x, y), z).  It is never called with values from intervals in
+// which no events occurred, and it must not modify its arguments.  If reduce
+// builds any kind of slice or list, then data usage will grow linearly with
+// the number of events added to the window.
+//
 // A size of 0 means "forever"; old events will never be removed.
-func New(size, resolution time.Duration, r Reducer) *Window {
+func NewOf[T any](size, resolution time.Duration, reduce func(T, T) T, opts ...Option) *Of[T] {
+	w := &Of[T]{
+		size:   size,
+		reduce: reduce,
+		clock:  systemClock{},
+	}
 	if size > 0 {
-		return &Window{
-			res:    resolution,
-			events: make([]interface{}, size/resolution),
-			reduce: r,
-		}
+		w.res = resolution
+		w.events = make([]T, size/resolution)
+		w.set = make([]bool, size/resolution)
+	} else {
+		w.forever = true
 	}
-	return &Window{
-		forever: true,
-		reduce:  r,
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.clock != nil {
+		w.clock = o.clock
+	}
+	return w
+}
+
+// A Clock reports the current time.  Windows use the system clock unless
+// another is given with WithClock.
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+type options struct {
+	clock Clock
+}
+
+// An Option configures a window.
+type Option func(*options)
+
+// WithClock sets the clock a window uses to timestamp and expire events.  A
+// fake clock makes code that uses windows testable without waiting in real
+// time.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		o.clock = c
 	}
 }
 
-func (w *Window) bucket(now time.Time) int {
+func (w *Of[T]) bucket(now time.Time) int {
 	nanos := now.UnixNano()
 	abs := nanos / int64(w.res)
 	return int(abs) % len(w.events)
 }
 
+func (w *Of[T]) clear(i int) {
+	var zero T
+	w.events[i] = zero
+	w.set[i] = false
+}
+
 // sweep keeps the window valid.  It needs to be called from every method that
 // views or updates the window, and the caller needs to hold the mutex.
-func (w *Window) sweep(now time.Time) {
+func (w *Of[T]) sweep(now time.Time) {
 	if w.forever {
 		return
 	}
@@ -86,7 +126,7 @@ func (w *Window) sweep(now time.Time) {
 	if diff < 0 {
 		// time went backwards somehow; zero events and return
 		for i := range w.events {
-			w.events[i] = nil
+			w.clear(i)
 		}
 		return
 	}
@@ -105,7 +145,7 @@ func (w *Window) sweep(now time.Time) {
 		// We've gone longer than this window measures since the last sweep, just
 		// zero the thing and have done.
 		for i := range w.events {
-			w.events[i] = nil
+			w.clear(i)
 		}
 		return
 	}
@@ -116,47 +156,248 @@ func (w *Window) sweep(now time.Time) {
 	old := int64(last.UnixNano()) / int64(w.res)
 	new := int64(now.UnixNano()) / int64(w.res)
 	for i := old + 1; i <= new; i++ {
-		b := int(i) % len(w.events)
-		w.events[b] = nil
+		w.clear(int(i) % len(w.events))
 	}
 }
 
 // Insert adds the given event.
-func (w *Window) Insert(e interface{}) {
-	w.insertAt(time.Now(), e)
+func (w *Of[T]) Insert(e T) {
+	w.insertAt(w.clock.Now(), e)
 }
 
-func (w *Window) insertAt(t time.Time, e interface{}) {
+func (w *Of[T]) insertAt(t time.Time, e T) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if w.forever {
-		w.e = w.reduce(w.e, e)
+		if w.eset {
+			e = w.reduce(w.e, e)
+		}
+		w.e, w.eset = e, true
 		return
 	}
 
 	w.sweep(t)
-	w.events[w.bucket(t)] = w.reduce(w.events[w.bucket(t)], e)
+	b := w.bucket(t)
+	if w.set[b] {
+		e = w.reduce(w.events[b], e)
+	}
+	w.events[b], w.set[b] = e, true
 }
 
-// Reduce runs the window's reducer over the valid values and returns the
-// result.
-func (w *Window) Reduce() interface{} {
-	return w.reducedAt(time.Now())
+// Reduce combines the events in the window and returns the result.  If there
+// are none, it returns the zero value of T.
+func (w *Of[T]) Reduce() T {
+	v, _ := w.reducedAt(w.clock.Now())
+	return v
 }
 
-func (w *Window) reducedAt(t time.Time) interface{} {
+func (w *Of[T]) reducedAt(t time.Time) (T, bool) {
+	var n T
+	var ok bool
+	w.each(t, func(e T) {
+		if !ok {
+			n, ok = e, true
+			return
+		}
+		n = w.reduce(n, e)
+	})
+	return n, ok
+}
+
+// each calls f with the value of every interval that is valid at t.
+func (w *Of[T]) each(t time.Time, f func(T)) {
 	w.mu.Lock()
 	defer w.mu.Unlock()
 
 	if w.forever {
-		return w.e
+		if w.eset {
+			f(w.e)
+		}
+		return
 	}
 
 	w.sweep(t)
-	var n interface{}
 	for i := range w.events {
-		n = w.reduce(n, w.events[i])
+		if w.set[i] {
+			f(w.events[i])
+		}
+	}
+}
+
+// An Interval holds the reduced value of the events in one interval of a
+// window, and the time at which the interval began.
+type Interval[T any] struct {
+	Start time.Time
+	Value T
+}
+
+// Snapshot returns the window's intervals that hold events, oldest first.  A
+// window that keeps events forever has a single interval, whose Start is the
+// zero time.
+func (w *Of[T]) Snapshot() []Interval[T] {
+	return w.snapshotAt(w.clock.Now())
+}
+
+func (w *Of[T]) snapshotAt(t time.Time) []Interval[T] {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.forever {
+		if !w.eset {
+			return nil
+		}
+		return []Interval[T]{{Value: w.e}}
+	}
+
+	w.sweep(t)
+	var s []Interval[T]
+	n := int64(len(w.events))
+	cur := t.UnixNano() / int64(w.res)
+	for abs := cur - n + 1; abs <= cur; abs++ {
+		b := int(abs % n)
+		if !w.set[b] {
+			continue
+		}
+		s = append(s, Interval[T]{
+			Start: time.Unix(0, abs*int64(w.res)),
+			Value: w.events[b],
+		})
+	}
+	return s
+}
+
+// Merge adds the events of o to w.  Each of o's intervals is added to the
+// interval of w in which it began, so merging windows with different
+// resolutions is only as accurate as the coarser of the two.  Intervals that
+// began before w's span are dropped.
+func (w *Of[T]) Merge(o *Of[T]) {
+	ivs := o.Snapshot()
+	now := w.clock.Now()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.forever {
+		for _, iv := range ivs {
+			e := iv.Value
+			if w.eset {
+				e = w.reduce(w.e, e)
+			}
+			w.e, w.eset = e, true
+		}
+		return
+	}
+
+	w.sweep(now)
+	n := int64(len(w.events))
+	cur := now.UnixNano() / int64(w.res)
+	for _, iv := range ivs {
+		abs := cur
+		if !iv.Start.IsZero() {
+			abs = iv.Start.UnixNano() / int64(w.res)
+		}
+		if abs <= cur-n {
+			continue
+		}
+		if abs > cur {
+			abs = cur
+		}
+		b := int(abs % n)
+		e := iv.Value
+		if w.set[b] {
+			e = w.reduce(w.events[b], e)
+		}
+		w.events[b], w.set[b] = e, true
+	}
+}
+
+// Number is the set of types that Sum, Max, and Rate work with.
+type Number interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 |
+		~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr |
+		~float32 | ~float64
+}
+
+// Sum is a reducer that adds events.
+func Sum[T Number](a, b T) T {
+	return a + b
+}
+
+// Max is a reducer that keeps the largest event.
+func Max[T Number](a, b T) T {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// Rate returns the per-second rate of events in a window that reduces with
+// Sum, averaged over the window's whole span.  It returns 0 for windows that
+// keep events forever.
+func Rate[T Number](w *Of[T]) float64 {
+	if w.forever {
+		return 0
+	}
+	return float64(w.Reduce()) / w.size.Seconds()
+}
+
+// A Window is a window of untyped events.  It predates Of, and is kept for
+// compatibility; new code should use Of.
+type Window struct {
+	w      *Of[interface{}]
+	reduce Reducer
+}
+
+// A Reducer should take two values from the window and combine them into a
+// third value that will be stored in the window.  The values i or j may be
+// nil.  The underlying types for both arguments and the output should be
+// identical.
+//
+// If the reducer is any kind of slice or list, then data usage will grow
+// linearly with the number of events added to the window.
+//
+// Reducer will be called on its own output: Reducer(Reducer(x, y), z).
+type Reducer func(i, j interface{}) interface{}
+
+// New returns an initialized window for events over the given duration at the
+// given resolution.  Windows with tight resolution (i.e., small values for
+// that argument) will be more accurate, at the cost of some memory.
+//
+// A size of 0 means "forever"; old events will never be removed.
+func New(size, resolution time.Duration, r Reducer, opts ...Option) *Window {
+	return &Window{
+		w:      NewOf[interface{}](size, resolution, r, opts...),
+		reduce: r,
+	}
+}
+
+// Insert adds the given event.
+func (w *Window) Insert(e interface{}) {
+	w.w.Insert(e)
+}
+
+func (w *Window) insertAt(t time.Time, e interface{}) {
+	w.w.insertAt(t, e)
+}
+
+// Reduce runs the window's reducer over the valid values and returns the
+// result.
+func (w *Window) Reduce() interface{} {
+	return w.reducedAt(w.w.clock.Now())
+}
+
+func (w *Window) reducedAt(t time.Time) interface{} {
+	// Reducers have always been given nil to start with, and some rely on it
+	// to copy rather than modify the values stored in the window, or to
+	// produce a zero value of the right type for an empty window.
+	var n interface{}
+	var ok bool
+	w.w.each(t, func(e interface{}) {
+		n, ok = w.reduce(n, e), true
+	})
+	if !ok {
+		return w.reduce(nil, nil)
 	}
 	return n
 }
@@ -101,3 +101,102 @@ func TestWindows(t *testing.T) {
 		}
 	}
 }
+
+type fakeClock struct{ now time.Time }
+
+func (f *fakeClock) Now() time.Time { return f.now }
+
+func TestWithClock(t *testing.T) {
+	clk := &fakeClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	w := New(time.Minute, time.Second, adder, WithClock(clk))
+	for i := 0; i < 5; i++ {
+		w.Insert(1)
+		clk.now = clk.now.Add(10 * time.Second)
+	}
+	if got := w.Reduce(); got != 5 {
+		t.Errorf("got %v, want 5", got)
+	}
+	clk.now = clk.now.Add(25 * time.Second)
+	if got := w.Reduce(); got != 3 {
+		t.Errorf("after 25s: got %v, want 3", got)
+	}
+}
+
+func TestTyped(t *testing.T) {
+	clk := &fakeClock{now: time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)}
+	sum := NewOf(10*time.Second, time.Second, Sum[int], WithClock(clk))
+	max := NewOf(10*time.Second, time.Second, Max[float64], WithClock(clk))
+	if got := sum.Reduce(); got != 0 {
+		t.Errorf("empty sum: got %d, want 0", got)
+	}
+	for _, v := range []int{3, -1, 4, -1, 5} {
+		sum.Insert(v)
+		max.Insert(float64(-v))
+		clk.now = clk.now.Add(time.Second)
+	}
+	if got := sum.Reduce(); got != 10 {
+		t.Errorf("sum: got %d, want 10", got)
+	}
+	if got := max.Reduce(); got != 1 {
+		t.Errorf("max: got %v, want 1", got)
+	}
+	if got := Rate(sum); got != 1 {
+		t.Errorf("rate: got %v, want 1", got)
+	}
+	clk.now = clk.now.Add(7 * time.Second)
+	if got := sum.Reduce(); got != 4 {
+		t.Errorf("sum after 7s: got %d, want 4", got)
+	}
+}
+
+func TestSnapshotMerge(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	a := NewOf(5*time.Second, time.Second, Sum[int], WithClock(clk))
+	b := NewOf(5*time.Second, time.Second, Sum[int], WithClock(clk))
+	a.Insert(1)
+	clk.now = clk.now.Add(2 * time.Second)
+	b.Insert(2)
+	a.Insert(3)
+	a.Merge(b)
+	got := a.Snapshot()
+	want := []Interval[int]{
+		{Start: start, Value: 1},
+		{Start: start.Add(2 * time.Second), Value: 5},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if !got[i].Start.Equal(want[i].Start) || got[i].Value != want[i].Value {
+			t.Errorf("interval %d: got %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestMergeOlder(t *testing.T) {
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	clk := &fakeClock{now: start}
+	a := NewOf(5*time.Second, time.Second, Sum[int], WithClock(clk))
+	b := NewOf(10*time.Second, time.Second, Sum[int], WithClock(clk))
+	b.Insert(100)
+	clk.now = clk.now.Add(5 * time.Second)
+	b.Insert(3)
+	clk.now = clk.now.Add(2 * time.Second)
+	a.Insert(6)
+
+	// b's intervals began before a's last event, and the first before a's
+	// span.
+	a.Merge(b)
+	if got := a.Reduce(); got != 9 {
+		t.Errorf("after Merge: got %d, want 9", got)
+	}
+	a.Insert(1)
+	if got := a.Reduce(); got != 10 {
+		t.Errorf("after Merge and Insert: got %d, want 10", got)
+	}
+	clk.now = clk.now.Add(4 * time.Second)
+	if got := a.Reduce(); got != 7 {
+		t.Errorf("after 4s: got %d, want 7", got)
+	}
+}